@@ -0,0 +1,194 @@
+// Package client - Go SDK сервиса объявлений. Один интерфейс Client работает поверх gRPC
+// (NewGRPC) или HTTP (NewHTTP): идемпотентные вызовы повторяются при сбоях транспорта,
+// у каждой попытки свой тайм-аут, ошибки сервиса приходят как *Error с категорией
+// ErrNotFound, ErrForbidden, ErrInvalid и т.д.
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	DefaultTimeout  = 10 * time.Second
+	DefaultAttempts = 3
+	DefaultBackoff  = 100 * time.Millisecond
+)
+
+// Client - методы сервиса объявлений, одинаковые для обоих транспортов
+type Client interface {
+	CreateAd(ctx context.Context, title string, text string, userID int64) (*Ad, error)
+	ChangeAdStatus(ctx context.Context, adID int64, userID int64, published bool) (*Ad, error)
+	UpdateAd(ctx context.Context, adID int64, userID int64, title string, text string) (*Ad, error)
+	DeleteAd(ctx context.Context, adID int64, userID int64) error
+	GetAd(ctx context.Context, adID int64) (*Ad, error)
+	// ListAds без опций возвращает только опубликованные объявления
+	ListAds(ctx context.Context, opts ...ListOption) ([]Ad, error)
+	// SearchAds ищет объявления, заголовок которых начинается с title
+	SearchAds(ctx context.Context, title string) ([]Ad, error)
+	// WatchAds подписывается на события объявлений. Поток не переподключается сам:
+	// после ошибки Recv нужно вызвать WatchAds ещё раз
+	WatchAds(ctx context.Context, opts ...WatchOption) (AdStream, error)
+
+	CreateUser(ctx context.Context, nickname string, email string) (*User, error)
+	// UpdateUser меняет email, только если password - текущий пароль пользователя; для смены ника он не нужен
+	UpdateUser(ctx context.Context, userID int64, nickname string, email string, password string) (*User, error)
+	GetUser(ctx context.Context, userID int64) (*User, error)
+	DeleteUser(ctx context.Context, userID int64) error
+
+	Close() error
+}
+
+type Ad struct {
+	ID           int64     `json:"id" yaml:"id"`
+	Title        string    `json:"title" yaml:"title"`
+	Text         string    `json:"text" yaml:"text"`
+	AuthorID     int64     `json:"author_id" yaml:"author_id"`
+	Published    bool      `json:"published" yaml:"published"`
+	DateCreating time.Time `json:"date_creating" yaml:"date_creating"`
+	DateUpdate   time.Time `json:"date_update" yaml:"date_update"`
+}
+
+type User struct {
+	ID       int64  `json:"id" yaml:"id"`
+	Nickname string `json:"nickname" yaml:"nickname"`
+	Email    string `json:"email" yaml:"email"`
+}
+
+// типы событий AdEvent.Type
+const (
+	AdCreated       = "ad.created"
+	AdUpdated       = "ad.updated"
+	AdStatusChanged = "ad.status_changed"
+	AdDeleted       = "ad.deleted"
+)
+
+// AdEvent - событие объявления, те же события сервис рассылает в вебхуки
+type AdEvent struct {
+	ID        int64     `json:"id" yaml:"id"`
+	Type      string    `json:"type" yaml:"type"`
+	Ad        Ad        `json:"ad" yaml:"ad"`
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+}
+
+// AdStream - поток событий из WatchAds
+type AdStream interface {
+	// Recv ждёт следующее событие; io.EOF - сервер штатно закрыл поток
+	Recv() (AdEvent, error)
+	Close() error
+}
+
+type listOptions struct {
+	published *bool
+	authorID  *int64
+	createdOn *time.Time
+}
+
+// ListOption - фильтр ListAds
+type ListOption func(*listOptions)
+
+// Published оставляет только опубликованные (true) или снятые с публикации (false) объявления
+func Published(published bool) ListOption {
+	return func(o *listOptions) {
+		o.published = &published
+	}
+}
+
+// ByAuthor оставляет только объявления автора
+func ByAuthor(userID int64) ListOption {
+	return func(o *listOptions) {
+		o.authorID = &userID
+	}
+}
+
+// CreatedOn оставляет объявления, созданные в тот же день (UTC), что и date
+func CreatedOn(date time.Time) ListOption {
+	return func(o *listOptions) {
+		date = date.UTC()
+		o.createdOn = &date
+	}
+}
+
+type watchOptions struct {
+	authorID *int64
+}
+
+// WatchOption - фильтр WatchAds
+type WatchOption func(*watchOptions)
+
+// WatchAuthor присылает только события объявлений автора
+func WatchAuthor(userID int64) WatchOption {
+	return func(o *watchOptions) {
+		o.authorID = &userID
+	}
+}
+
+type options struct {
+	timeout     time.Duration
+	attempts    int
+	backoff     time.Duration
+	tlsConfig   *tls.Config
+	httpClient  *http.Client
+	dialOptions []grpc.DialOption
+}
+
+// Option настраивает клиент
+type Option func(*options)
+
+// WithTimeout задаёт тайм-аут одной попытки вызова; на WatchAds не действует.
+// По умолчанию DefaultTimeout
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithRetry задаёт число попыток идемпотентного вызова и задержку перед второй попыткой,
+// дальше задержка удваивается. attempts = 1 отключает повторы.
+// По умолчанию DefaultAttempts и DefaultBackoff
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.attempts = attempts
+		o.backoff = backoff
+	}
+}
+
+// WithTLSConfig включает TLS; для mTLS в cfg задаётся клиентский сертификат
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = cfg
+	}
+}
+
+// WithHTTPClient задаёт http.Client для NewHTTP. Тайм-аут у него лучше не задавать:
+// он оборвёт WatchAds, а у обычных вызовов уже есть WithTimeout
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
+
+// WithDialOptions добавляет опции grpc.Dial для NewGRPC
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.dialOptions = append(o.dialOptions, opts...)
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{timeout: DefaultTimeout, attempts: DefaultAttempts, backoff: DefaultBackoff}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.timeout <= 0 {
+		o.timeout = DefaultTimeout
+	}
+	if o.attempts <= 0 {
+		o.attempts = 1
+	}
+	return o
+}
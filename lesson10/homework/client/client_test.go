@@ -0,0 +1,313 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/app"
+	"homework10/internal/outbox"
+	grpcPort "homework10/internal/ports/grpc"
+	"homework10/internal/ports/httpgin"
+)
+
+// testService - сервис в памяти и диспетчер событий, который тест прокручивает вручную
+type testService struct {
+	repo       app.Repository
+	app        app.App
+	broker     *outbox.Broker
+	dispatcher *outbox.Dispatcher
+}
+
+func newTestService() *testService {
+	repo := adrepo.New()
+	broker := outbox.NewBroker(outbox.DefaultBrokerBuffer)
+	return &testService{
+		repo:       repo,
+		app:        app.NewApp(repo),
+		broker:     broker,
+		dispatcher: outbox.NewDispatcher(repo, outbox.Config{Broker: broker}),
+	}
+}
+
+// verifyEmail подтверждает email пользователя в обход письма, чтобы он мог публиковать объявления
+func (s *testService) verifyEmail(t *testing.T, userID int64) {
+	ctx := context.Background()
+	user, err := s.repo.GetUserById(ctx, userID)
+	require.NoError(t, err)
+	user.EmailVerified = true
+	_, err = s.repo.ChangeUser(ctx, &user)
+	require.NoError(t, err)
+}
+
+func newGRPCClient(t *testing.T, s *testService) Client {
+	srv, lis := grpcPort.TestNewGRPCServer(1024*1024, s.app, grpcPort.WithAdEvents(s.broker))
+	t.Cleanup(srv.Stop)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+
+	dialer := func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}
+	c, err := NewGRPC("bufnet", WithDialOptions(grpc.WithContextDialer(dialer)))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = c.Close()
+	})
+	return c
+}
+
+func newHTTPClient(t *testing.T, s *testService) Client {
+	srv := httptest.NewServer(httpgin.NewHTTPServer(":0", s.app, httpgin.WithAdEvents(s.broker)).Handler)
+	t.Cleanup(srv.Close)
+
+	c, err := NewHTTP(srv.URL)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = c.Close()
+	})
+	return c
+}
+
+// forEachTransport прогоняет один сценарий через оба транспорта
+func forEachTransport(t *testing.T, test func(t *testing.T, s *testService, c Client)) {
+	transports := map[string]func(*testing.T, *testService) Client{
+		"grpc": newGRPCClient,
+		"http": newHTTPClient,
+	}
+	for name, newClient := range transports {
+		t.Run(name, func(t *testing.T) {
+			s := newTestService()
+			test(t, s, newClient(t, s))
+		})
+	}
+}
+
+func TestClient_AdLifecycle(t *testing.T) {
+	forEachTransport(t, func(t *testing.T, s *testService, c Client) {
+		ctx := context.Background()
+
+		user, err := c.CreateUser(ctx, "seller", "seller@mail.ru")
+		require.NoError(t, err)
+		assert.Equal(t, "seller", user.Nickname)
+		s.verifyEmail(t, user.ID)
+
+		ad, err := c.CreateAd(ctx, "hello", "world", user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", ad.Title)
+		assert.Equal(t, user.ID, ad.AuthorID)
+		assert.False(t, ad.Published)
+
+		ad, err = c.ChangeAdStatus(ctx, ad.ID, user.ID, true)
+		require.NoError(t, err)
+		assert.True(t, ad.Published)
+
+		ad, err = c.UpdateAd(ctx, ad.ID, user.ID, "hello again", "new world")
+		require.NoError(t, err)
+		assert.Equal(t, "hello again", ad.Title)
+
+		got, err := c.GetAd(ctx, ad.ID)
+		require.NoError(t, err)
+		assert.Equal(t, ad.Text, got.Text)
+
+		found, err := c.SearchAds(ctx, "hello")
+		require.NoError(t, err)
+		assert.Len(t, found, 1)
+
+		require.NoError(t, c.DeleteAd(ctx, ad.ID, user.ID))
+		_, err = c.GetAd(ctx, ad.ID)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		require.NoError(t, c.DeleteUser(ctx, user.ID))
+		_, err = c.GetUser(ctx, user.ID)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestClient_ListAds(t *testing.T) {
+	forEachTransport(t, func(t *testing.T, s *testService, c Client) {
+		ctx := context.Background()
+
+		first, err := c.CreateUser(ctx, "first", "first@mail.ru")
+		require.NoError(t, err)
+		s.verifyEmail(t, first.ID)
+		second, err := c.CreateUser(ctx, "second", "second@mail.ru")
+		require.NoError(t, err)
+
+		published, err := c.CreateAd(ctx, "published", "text", first.ID)
+		require.NoError(t, err)
+		_, err = c.ChangeAdStatus(ctx, published.ID, first.ID, true)
+		require.NoError(t, err)
+		_, err = c.CreateAd(ctx, "draft", "text", second.ID)
+		require.NoError(t, err)
+
+		list, err := c.ListAds(ctx)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, published.ID, list[0].ID)
+
+		list, err = c.ListAds(ctx, Published(false), ByAuthor(second.ID))
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, "draft", list[0].Title)
+
+		list, err = c.ListAds(ctx, Published(true), CreatedOn(time.Now().AddDate(0, 0, -1)))
+		require.NoError(t, err)
+		assert.Empty(t, list)
+	})
+}
+
+func TestClient_Errors(t *testing.T) {
+	forEachTransport(t, func(t *testing.T, _ *testService, c Client) {
+		ctx := context.Background()
+
+		_, err := c.CreateUser(ctx, "", "seller@mail.ru")
+		assert.ErrorIs(t, err, ErrInvalid)
+		var serviceErr *Error
+		require.ErrorAs(t, err, &serviceErr)
+		assert.Equal(t, codes.InvalidArgument, serviceErr.Code)
+		require.NotEmpty(t, serviceErr.Violations)
+		assert.Equal(t, "nickname", serviceErr.Violations[0].Field)
+
+		_, err = c.GetUser(ctx, 100)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		user, err := c.CreateUser(ctx, "seller", "seller@mail.ru")
+		require.NoError(t, err)
+		ad, err := c.CreateAd(ctx, "hello", "world", user.ID)
+		require.NoError(t, err)
+
+		_, err = c.ChangeAdStatus(ctx, ad.ID, user.ID+1, true)
+		assert.ErrorIs(t, err, ErrForbidden)
+		require.ErrorAs(t, err, &serviceErr)
+		assert.Equal(t, codes.PermissionDenied, serviceErr.Code)
+	})
+}
+
+func TestClient_WatchAds(t *testing.T) {
+	forEachTransport(t, func(t *testing.T, s *testService, c Client) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		first, err := c.CreateUser(ctx, "first", "first@mail.ru")
+		require.NoError(t, err)
+		second, err := c.CreateUser(ctx, "second", "second@mail.ru")
+		require.NoError(t, err)
+
+		stream, err := c.WatchAds(ctx, WatchAuthor(second.ID))
+		require.NoError(t, err)
+		defer stream.Close()
+		assert.Eventually(t, func() bool {
+			return s.broker.Subscribers() == 1
+		}, time.Second, 10*time.Millisecond)
+
+		_, err = c.CreateAd(ctx, "foreign", "text", first.ID)
+		require.NoError(t, err)
+		ad, err := c.CreateAd(ctx, "mine", "text", second.ID)
+		require.NoError(t, err)
+		s.dispatcher.Flush(ctx)
+
+		event, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, AdCreated, event.Type)
+		assert.Equal(t, ad.ID, event.Ad.ID)
+		assert.Equal(t, "mine", event.Ad.Title)
+	})
+}
+
+func TestClient_WatchAdsDisabled(t *testing.T) {
+	s := newTestService()
+	s.broker = nil
+
+	_, err := newHTTPClient(t, s).WatchAds(context.Background())
+	assert.ErrorIs(t, err, ErrUnsupported)
+
+	stream, err := newGRPCClient(t, s).WatchAds(context.Background())
+	require.NoError(t, err)
+	// gRPC-поток отдаёт статус только на первом Recv
+	_, err = stream.Recv()
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestClient_HTTPRetriesWithSameIdempotencyKey(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		keys  []string
+		calls atomic.Int32
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get(idempotencyKeyHeader))
+		mu.Unlock()
+
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"id":1,"nickname":"seller","email":"seller@mail.ru"},"error":null}`))
+	}))
+	defer srv.Close()
+
+	c, err := NewHTTP(srv.URL, WithRetry(3, time.Millisecond))
+	require.NoError(t, err)
+
+	user, err := c.CreateUser(context.Background(), "seller", "seller@mail.ru")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), user.ID)
+
+	require.Len(t, keys, 2)
+	assert.NotEmpty(t, keys[0])
+	assert.Equal(t, keys[0], keys[1])
+}
+
+func TestClient_NoRetryOnDomainError(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"data":null,"error":"id is not found"}`))
+	}))
+	defer srv.Close()
+
+	c, err := NewHTTP(srv.URL, WithRetry(3, time.Millisecond))
+	require.NoError(t, err)
+
+	_, err = c.GetAd(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, "id is not found", err.Error())
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestClient_KeysMatchServer(t *testing.T) {
+	assert.Equal(t, httpgin.IdempotencyKeyHeader, idempotencyKeyHeader)
+	assert.Equal(t, grpcPort.IdempotencyKeyMetadata, idempotencyKeyMetadata)
+}
+
+func TestClient_RetryStopsOnCancel(t *testing.T) {
+	o := newOptions([]Option{WithRetry(5, time.Hour)})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var calls int
+	err := o.call(ctx, true, func(context.Context) error {
+		calls++
+		cancel()
+		return &Error{Kind: ErrUnavailable}
+	})
+	assert.True(t, errors.Is(err, ErrUnavailable))
+	assert.Equal(t, 1, calls)
+}
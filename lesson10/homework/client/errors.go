@@ -0,0 +1,70 @@
+package client
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	"homework10/internal/app"
+	"homework10/internal/ports/errmap"
+)
+
+// Категории ошибок. Первые пять повторяют категории доменных ошибок сервиса,
+// остальные описывают сбои транспорта. Проверяются через errors.Is
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrForbidden = errors.New("forbidden")
+	ErrInvalid   = errors.New("invalid argument")
+	ErrConflict  = errors.New("conflict")
+	ErrInternal  = errors.New("internal error")
+
+	ErrUnavailable = errors.New("service unavailable")
+	// ErrRateLimited - сервер ограничил частоту запросов или отключил отстающий WatchAds
+	ErrRateLimited = errors.New("rate limited")
+	ErrUnsupported = errors.New("not supported by the server")
+)
+
+var kindErrors = map[app.Kind]error{
+	app.KindNotFound:  ErrNotFound,
+	app.KindForbidden: ErrForbidden,
+	app.KindInvalid:   ErrInvalid,
+	app.KindConflict:  ErrConflict,
+	app.KindInternal:  ErrInternal,
+}
+
+// FieldViolation - поле запроса, не прошедшее валидацию
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
+// Error - ошибка, которую вернул сервис
+type Error struct {
+	Kind       error      // одна из категорий выше
+	Code       codes.Code // gRPC-код; для HTTP - код, которым сервис ответил бы по gRPC
+	Message    string
+	Violations []FieldViolation // только для ErrInvalid
+	RetryAfter time.Duration    // подсказка сервера, когда повторить запрос
+}
+
+func (e *Error) Error() string {
+	if len(e.Violations) == 0 {
+		return e.Message
+	}
+	parts := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		parts = append(parts, v.Field+" "+v.Description)
+	}
+	return e.Message + ": " + strings.Join(parts, "; ")
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// errorOfKind собирает ошибку из категории доменной ошибки сервиса
+func errorOfKind(kind app.Kind, message string) *Error {
+	return &Error{Kind: kindErrors[kind], Code: errmap.GRPCCodeOfKind(kind), Message: message}
+}
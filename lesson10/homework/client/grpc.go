@@ -0,0 +1,263 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"homework10/internal/ports/errmap"
+	"homework10/internal/ports/grpc/proto"
+)
+
+const idempotencyKeyMetadata = "idempotency-key"
+
+type grpcClient struct {
+	conn *grpc.ClientConn
+	api  proto.AdServiceClient
+	opts options
+}
+
+// NewGRPC подключается к gRPC-серверу сервиса; target - адрес в формате grpc.Dial
+func NewGRPC(target string, opts ...Option) (Client, error) {
+	o := newOptions(opts)
+	creds := insecure.NewCredentials()
+	if o.tlsConfig != nil {
+		creds = credentials.NewTLS(o.tlsConfig)
+	}
+	conn, err := grpc.Dial(target, append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, o.dialOptions...)...)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", target, err)
+	}
+	return &grpcClient{conn: conn, api: proto.NewAdServiceClient(conn), opts: o}, nil
+}
+
+func (c *grpcClient) Close() error {
+	return c.conn.Close()
+}
+
+func (c *grpcClient) CreateAd(ctx context.Context, title string, text string, userID int64) (*Ad, error) {
+	ctx = metadata.AppendToOutgoingContext(ctx, idempotencyKeyMetadata, newIdempotencyKey())
+	return c.ad(ctx, func(ctx context.Context) (*proto.AdResponse, error) {
+		return c.api.CreateAd(ctx, &proto.CreateAdRequest{Title: title, Text: text, UserId: userID})
+	})
+}
+
+func (c *grpcClient) ChangeAdStatus(ctx context.Context, adID int64, userID int64, published bool) (*Ad, error) {
+	return c.ad(ctx, func(ctx context.Context) (*proto.AdResponse, error) {
+		return c.api.ChangeAdStatus(ctx, &proto.ChangeAdStatusRequest{AdId: adID, UserId: userID, Published: published})
+	})
+}
+
+func (c *grpcClient) UpdateAd(ctx context.Context, adID int64, userID int64, title string, text string) (*Ad, error) {
+	return c.ad(ctx, func(ctx context.Context) (*proto.AdResponse, error) {
+		return c.api.UpdateAd(ctx, &proto.UpdateAdRequest{AdId: adID, UserId: userID, Title: title, Text: text})
+	})
+}
+
+func (c *grpcClient) DeleteAd(ctx context.Context, adID int64, userID int64) error {
+	return c.opts.call(ctx, true, func(ctx context.Context) error {
+		_, err := c.api.DeleteAd(ctx, &proto.DeleteAdRequest{AdId: adID, UserId: userID})
+		return fromStatus(err)
+	})
+}
+
+func (c *grpcClient) GetAd(ctx context.Context, adID int64) (*Ad, error) {
+	return c.ad(ctx, func(ctx context.Context) (*proto.AdResponse, error) {
+		return c.api.GetAd(ctx, &proto.GetAdRequest{AdId: adID})
+	})
+}
+
+func (c *grpcClient) ListAds(ctx context.Context, opts ...ListOption) ([]Ad, error) {
+	var o listOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	req := &proto.GetListAdsWithFilterRequest{Published: o.published, UserId: o.authorID}
+	if o.createdOn != nil {
+		req.DateCreating = timestamppb.New(*o.createdOn)
+	}
+	return c.ads(ctx, func(ctx context.Context) (*proto.ListAdResponse, error) {
+		return c.api.ListAdsWithFilter(ctx, req)
+	})
+}
+
+func (c *grpcClient) SearchAds(ctx context.Context, title string) ([]Ad, error) {
+	return c.ads(ctx, func(ctx context.Context) (*proto.ListAdResponse, error) {
+		return c.api.ListAdsByTitle(ctx, &proto.GetListAdsByTitleRequest{Title: title})
+	})
+}
+
+func (c *grpcClient) WatchAds(ctx context.Context, opts ...WatchOption) (AdStream, error) {
+	var o watchOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := c.api.WatchAds(ctx, &proto.WatchAdsRequest{UserId: o.authorID})
+	if err != nil {
+		cancel()
+		return nil, fromStatus(err)
+	}
+	return &grpcAdStream{stream: stream, cancel: cancel}, nil
+}
+
+func (c *grpcClient) CreateUser(ctx context.Context, nickname string, email string) (*User, error) {
+	ctx = metadata.AppendToOutgoingContext(ctx, idempotencyKeyMetadata, newIdempotencyKey())
+	return c.user(ctx, func(ctx context.Context) (*proto.UserResponse, error) {
+		return c.api.CreateUser(ctx, &proto.CreateUserRequest{Nickname: nickname, Email: email})
+	})
+}
+
+func (c *grpcClient) UpdateUser(ctx context.Context, userID int64, nickname string, email string, password string) (*User, error) {
+	return c.user(ctx, func(ctx context.Context) (*proto.UserResponse, error) {
+		return c.api.UpdateUser(ctx, &proto.UpdateUserRequest{UserId: userID, Nickname: nickname, Email: email, Password: password})
+	})
+}
+
+func (c *grpcClient) GetUser(ctx context.Context, userID int64) (*User, error) {
+	return c.user(ctx, func(ctx context.Context) (*proto.UserResponse, error) {
+		return c.api.GetUser(ctx, &proto.GetUserRequest{Id: userID})
+	})
+}
+
+func (c *grpcClient) DeleteUser(ctx context.Context, userID int64) error {
+	return c.opts.call(ctx, true, func(ctx context.Context) error {
+		_, err := c.api.DeleteUser(ctx, &proto.DeleteUserRequest{Id: userID})
+		return fromStatus(err)
+	})
+}
+
+func (c *grpcClient) ad(ctx context.Context, do func(context.Context) (*proto.AdResponse, error)) (*Ad, error) {
+	var ad Ad
+	err := c.opts.call(ctx, true, func(ctx context.Context) error {
+		resp, err := do(ctx)
+		if err != nil {
+			return fromStatus(err)
+		}
+		ad = adFromProto(resp)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ad, nil
+}
+
+func (c *grpcClient) ads(ctx context.Context, do func(context.Context) (*proto.ListAdResponse, error)) ([]Ad, error) {
+	var list []Ad
+	err := c.opts.call(ctx, true, func(ctx context.Context) error {
+		resp, err := do(ctx)
+		if err != nil {
+			return fromStatus(err)
+		}
+		list = make([]Ad, 0, len(resp.GetList()))
+		for _, ad := range resp.GetList() {
+			list = append(list, adFromProto(ad))
+		}
+		return nil
+	})
+	return list, err
+}
+
+func (c *grpcClient) user(ctx context.Context, do func(context.Context) (*proto.UserResponse, error)) (*User, error) {
+	var user User
+	err := c.opts.call(ctx, true, func(ctx context.Context) error {
+		resp, err := do(ctx)
+		if err != nil {
+			return fromStatus(err)
+		}
+		user = User{ID: resp.GetId(), Nickname: resp.GetNickname(), Email: resp.GetEmail()}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+type grpcAdStream struct {
+	stream proto.AdService_WatchAdsClient
+	cancel context.CancelFunc
+}
+
+func (s *grpcAdStream) Recv() (AdEvent, error) {
+	event, err := s.stream.Recv()
+	if errors.Is(err, io.EOF) {
+		return AdEvent{}, io.EOF
+	}
+	if err != nil {
+		return AdEvent{}, fromStatus(err)
+	}
+	return AdEvent{
+		ID:        event.GetId(),
+		Type:      event.GetType(),
+		Ad:        adFromProto(event.GetAd()),
+		CreatedAt: event.GetCreatedAt().AsTime(),
+	}, nil
+}
+
+func (s *grpcAdStream) Close() error {
+	s.cancel()
+	return nil
+}
+
+func adFromProto(ad *proto.AdResponse) Ad {
+	return Ad{
+		ID:           ad.GetId(),
+		Title:        ad.GetTitle(),
+		Text:         ad.GetText(),
+		AuthorID:     ad.GetUserId(),
+		Published:    ad.GetPublished(),
+		DateCreating: ad.GetDateCreating().AsTime(),
+		DateUpdate:   ad.GetDateUpdate().AsTime(),
+	}
+}
+
+// fromStatus переводит gRPC-статус в *Error. Ошибки контекста возвращаются как есть
+func fromStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	switch st.Code() {
+	case codes.Canceled:
+		return context.Canceled
+	case codes.DeadlineExceeded:
+		return context.DeadlineExceeded
+	case codes.Unavailable:
+		return &Error{Kind: ErrUnavailable, Code: st.Code(), Message: st.Message()}
+	case codes.ResourceExhausted:
+		return &Error{Kind: ErrRateLimited, Code: st.Code(), Message: st.Message()}
+	case codes.Unimplemented:
+		return &Error{Kind: ErrUnsupported, Code: st.Code(), Message: st.Message()}
+	}
+
+	kind, ok := errmap.KindFromCode(st.Code())
+	if !ok {
+		return &Error{Kind: ErrInternal, Code: st.Code(), Message: st.Message()}
+	}
+	serviceErr := errorOfKind(kind, st.Message())
+	serviceErr.Code = st.Code()
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, v := range badRequest.GetFieldViolations() {
+				serviceErr.Violations = append(serviceErr.Violations, FieldViolation{Field: v.GetField(), Description: v.GetDescription()})
+			}
+		}
+	}
+	return serviceErr
+}
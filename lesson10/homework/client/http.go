@@ -0,0 +1,383 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	"homework10/internal/ports/errmap"
+	"homework10/internal/ports/problem"
+)
+
+const (
+	apiPrefix            = "/api/v1" // префикс REST API на сервере
+	idempotencyKeyHeader = "Idempotency-Key"
+)
+
+type httpClient struct {
+	base string
+	http *http.Client
+	opts options
+}
+
+// NewHTTP работает с REST API сервиса; baseURL - адрес сервера без /api/v1, например http://localhost:18080
+func NewHTTP(baseURL string, opts ...Option) (Client, error) {
+	o := newOptions(opts)
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse base url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("base url %q: scheme should be http or https", baseURL)
+	}
+
+	client := o.httpClient
+	if client == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = o.tlsConfig
+		client = &http.Client{Transport: transport}
+	}
+	return &httpClient{base: strings.TrimSuffix(u.String(), "/") + apiPrefix, http: client, opts: o}, nil
+}
+
+func (c *httpClient) Close() error {
+	c.http.CloseIdleConnections()
+	return nil
+}
+
+type adData struct {
+	ID           int64     `json:"id"`
+	Title        string    `json:"title"`
+	Text         string    `json:"text"`
+	AuthorID     int64     `json:"author_id"`
+	Published    bool      `json:"published"`
+	DateUpdate   time.Time `json:"date_update"`
+	DateCreating time.Time `json:"date_creating"`
+}
+
+func (a adData) ad() Ad {
+	return Ad{
+		ID:           a.ID,
+		Title:        a.Title,
+		Text:         a.Text,
+		AuthorID:     a.AuthorID,
+		Published:    a.Published,
+		DateCreating: a.DateCreating,
+		DateUpdate:   a.DateUpdate,
+	}
+}
+
+type eventData struct {
+	ID        int64     `json:"id"`
+	Type      string    `json:"type"`
+	Ad        adData    `json:"ad"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// response - обёртка, в которой сервер отдаёт и данные, и ошибки
+type response[T any] struct {
+	Data  T       `json:"data"`
+	Error *string `json:"error"`
+}
+
+func (c *httpClient) CreateAd(ctx context.Context, title string, text string, userID int64) (*Ad, error) {
+	header := http.Header{idempotencyKeyHeader: {newIdempotencyKey()}}
+	body := map[string]any{"title": title, "text": text, "user_id": userID}
+	return c.ad(ctx, http.MethodPost, "/ads", body, header)
+}
+
+func (c *httpClient) ChangeAdStatus(ctx context.Context, adID int64, userID int64, published bool) (*Ad, error) {
+	body := map[string]any{"user_id": userID, "published": published}
+	return c.ad(ctx, http.MethodPut, "/ads/"+strconv.FormatInt(adID, 10)+"/status", body, nil)
+}
+
+func (c *httpClient) UpdateAd(ctx context.Context, adID int64, userID int64, title string, text string) (*Ad, error) {
+	body := map[string]any{"user_id": userID, "title": title, "text": text}
+	return c.ad(ctx, http.MethodPut, "/ads/"+strconv.FormatInt(adID, 10), body, nil)
+}
+
+func (c *httpClient) DeleteAd(ctx context.Context, adID int64, userID int64) error {
+	var out response[string]
+	return c.do(ctx, http.MethodDelete, "/ads/"+strconv.FormatInt(adID, 10), map[string]any{"user_id": userID}, nil, &out)
+}
+
+func (c *httpClient) GetAd(ctx context.Context, adID int64) (*Ad, error) {
+	return c.ad(ctx, http.MethodGet, "/ads/"+strconv.FormatInt(adID, 10), nil, nil)
+}
+
+func (c *httpClient) ListAds(ctx context.Context, opts ...ListOption) ([]Ad, error) {
+	var o listOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	query := url.Values{}
+	if o.published != nil {
+		query.Set("published", strconv.FormatBool(*o.published))
+	}
+	if o.authorID != nil {
+		query.Set("user_id", strconv.FormatInt(*o.authorID, 10))
+	}
+	if o.createdOn != nil {
+		query.Set("date_creating", o.createdOn.Format("2006-01-02"))
+	}
+	path := "/ads"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return c.ads(ctx, path)
+}
+
+func (c *httpClient) SearchAds(ctx context.Context, title string) ([]Ad, error) {
+	return c.ads(ctx, "/ads/search/"+url.PathEscape(title))
+}
+
+func (c *httpClient) WatchAds(ctx context.Context, opts ...WatchOption) (AdStream, error) {
+	var o watchOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	path := "/ads/events"
+	if o.authorID != nil {
+		path += "?user_id=" + strconv.FormatInt(*o.authorID, 10)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.base+path, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		cancel()
+		return nil, transportError(ctx, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		cancel()
+		return nil, responseError(resp)
+	}
+	return &sseAdStream{body: resp.Body, reader: bufio.NewReader(resp.Body), cancel: cancel}, nil
+}
+
+func (c *httpClient) CreateUser(ctx context.Context, nickname string, email string) (*User, error) {
+	header := http.Header{idempotencyKeyHeader: {newIdempotencyKey()}}
+	return c.user(ctx, http.MethodPost, "/users", map[string]any{"nickname": nickname, "email": email}, header)
+}
+
+func (c *httpClient) UpdateUser(ctx context.Context, userID int64, nickname string, email string, password string) (*User, error) {
+	body := map[string]any{"nickname": nickname, "email": email, "password": password}
+	return c.user(ctx, http.MethodPut, "/users/"+strconv.FormatInt(userID, 10), body, nil)
+}
+
+func (c *httpClient) GetUser(ctx context.Context, userID int64) (*User, error) {
+	return c.user(ctx, http.MethodGet, "/users/"+strconv.FormatInt(userID, 10), nil, nil)
+}
+
+func (c *httpClient) DeleteUser(ctx context.Context, userID int64) error {
+	var out response[string]
+	return c.do(ctx, http.MethodDelete, "/users/"+strconv.FormatInt(userID, 10), nil, nil, &out)
+}
+
+func (c *httpClient) ad(ctx context.Context, method string, path string, body any, header http.Header) (*Ad, error) {
+	var out response[adData]
+	if err := c.do(ctx, method, path, body, header, &out); err != nil {
+		return nil, err
+	}
+	ad := out.Data.ad()
+	return &ad, nil
+}
+
+func (c *httpClient) ads(ctx context.Context, path string) ([]Ad, error) {
+	var out response[[]adData]
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	list := make([]Ad, 0, len(out.Data))
+	for _, ad := range out.Data {
+		list = append(list, ad.ad())
+	}
+	return list, nil
+}
+
+func (c *httpClient) user(ctx context.Context, method string, path string, body any, header http.Header) (*User, error) {
+	var out response[User]
+	if err := c.do(ctx, method, path, body, header, &out); err != nil {
+		return nil, err
+	}
+	return &out.Data, nil
+}
+
+// do отправляет запрос с повторами; все методы REST API, кроме POST, идемпотентны,
+// а POST идемпотентен благодаря заголовку Idempotency-Key
+func (c *httpClient) do(ctx context.Context, method string, path string, body any, header http.Header, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	idempotent := method != http.MethodPost || header.Get(idempotencyKeyHeader) != ""
+
+	return c.opts.call(ctx, idempotent, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, method, c.base+path, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := c.http.Do(req)
+		if err != nil {
+			return transportError(ctx, err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return responseError(resp)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("decode response: %w", err)
+		}
+		return nil
+	})
+}
+
+// transportError: запрос не дошёл до сервиса или ответ не дошёл до нас
+func transportError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return &Error{Kind: ErrUnavailable, Code: codes.Unavailable, Message: err.Error()}
+}
+
+// responseError разбирает ответ с ошибкой: обычную обёртку или problem+json с полями
+func responseError(resp *http.Response) error {
+	raw, _ := io.ReadAll(resp.Body)
+	message := strings.TrimSpace(string(raw))
+
+	var violations []FieldViolation
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == problem.ContentType {
+		var p problem.Problem
+		if json.Unmarshal(raw, &p) == nil {
+			message = p.Title
+			if p.Detail != "" {
+				message = p.Detail
+			}
+			for _, param := range p.InvalidParams {
+				violations = append(violations, FieldViolation{Field: param.Name, Description: param.Reason})
+			}
+		}
+	} else {
+		var body response[json.RawMessage]
+		if json.Unmarshal(raw, &body) == nil && body.Error != nil {
+			message = *body.Error
+		}
+	}
+	if message == "" {
+		message = resp.Status
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		serviceErr := &Error{Kind: ErrRateLimited, Code: codes.ResourceExhausted, Message: message}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			serviceErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		return serviceErr
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return &Error{Kind: ErrUnavailable, Code: codes.Unavailable, Message: message}
+	case http.StatusNotImplemented:
+		return &Error{Kind: ErrUnsupported, Code: codes.Unimplemented, Message: message}
+	}
+
+	kind, ok := errmap.KindFromHTTPStatus(resp.StatusCode)
+	if !ok {
+		return &Error{Kind: ErrInternal, Code: codes.Unknown, Message: message}
+	}
+	serviceErr := errorOfKind(kind, message)
+	serviceErr.Violations = violations
+	return serviceErr
+}
+
+// sseAdStream читает server-sent events из /ads/events
+type sseAdStream struct {
+	body   io.ReadCloser
+	reader *bufio.Reader
+	cancel context.CancelFunc
+}
+
+func (s *sseAdStream) Recv() (AdEvent, error) {
+	var eventType string
+	var data strings.Builder
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return AdEvent{}, io.EOF
+			}
+			return AdEvent{}, &Error{Kind: ErrUnavailable, Code: codes.Unavailable, Message: err.Error()}
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			if data.Len() == 0 {
+				continue
+			}
+			return decodeEvent(eventType, data.String())
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+		}
+	}
+}
+
+func (s *sseAdStream) Close() error {
+	s.cancel()
+	return s.body.Close()
+}
+
+func decodeEvent(eventType string, data string) (AdEvent, error) {
+	if eventType == "error" {
+		var body response[json.RawMessage]
+		message := data
+		if json.Unmarshal([]byte(data), &body) == nil && body.Error != nil {
+			message = *body.Error
+		}
+		return AdEvent{}, &Error{Kind: ErrRateLimited, Code: codes.ResourceExhausted, Message: message}
+	}
+
+	var event eventData
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		return AdEvent{}, fmt.Errorf("decode event: %w", err)
+	}
+	return AdEvent{ID: event.ID, Type: event.Type, Ad: event.Ad.ad(), CreatedAt: event.CreatedAt}, nil
+}
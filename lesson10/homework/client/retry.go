@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// call выполняет вызов с тайм-аутом на попытку. Идемпотентные вызовы повторяются,
+// пока сбой временный: сервис недоступен, ограничил частоту запросов или попытка не уложилась в тайм-аут
+func (o *options) call(ctx context.Context, idempotent bool, do func(ctx context.Context) error) error {
+	backoff := o.backoff
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, o.timeout)
+		err := do(attemptCtx)
+		cancel()
+
+		if err == nil || !idempotent || attempt >= o.attempts || ctx.Err() != nil || !temporary(err) {
+			return err
+		}
+
+		wait := backoff
+		var serviceErr *Error
+		if errors.As(err, &serviceErr) && serviceErr.RetryAfter > wait {
+			wait = serviceErr.RetryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+}
+
+func temporary(err error) bool {
+	return errors.Is(err, ErrUnavailable) || errors.Is(err, ErrRateLimited) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// newIdempotencyKey - ключ, с которым повтор создания не создаёт дубликат
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"homework10/client"
+)
+
+// command - лист дерева подкоманд: ads list, users get и т.д.
+type command struct {
+	usage string // аргументы и флаги для справки
+	run   func(ctx context.Context, env *env, args []string) error
+}
+
+// env - то, что нужно командам: клиент и печать ответов
+type env struct {
+	client  client.Client
+	printer printer
+	stderr  io.Writer
+}
+
+var commands = map[string]map[string]command{
+	"ads": {
+		"list":    {usage: "[--published=true|false] [--author USER_ID] [--date YYYY-MM-DD] [--title PREFIX]", run: adsList},
+		"get":     {usage: "AD_ID", run: adsGet},
+		"create":  {usage: "--user USER_ID --title TITLE --text TEXT", run: adsCreate},
+		"publish": {usage: "AD_ID --user USER_ID [--unpublish]", run: adsPublish},
+		"delete":  {usage: "AD_ID --user USER_ID", run: adsDelete},
+	},
+	"users": {
+		"get":    {usage: "USER_ID", run: usersGet},
+		"create": {usage: "--nickname NICKNAME --email EMAIL", run: usersCreate},
+		"delete": {usage: "USER_ID", run: usersDelete},
+	},
+	"watch": {
+		"": {usage: "[--author USER_ID]", run: watch},
+	},
+}
+
+// usageError - ошибка в аргументах командной строки
+type usageError struct {
+	err error
+}
+
+func (e usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e usageError) Unwrap() error {
+	return e.err
+}
+
+func usagef(format string, args ...any) error {
+	return usageError{err: fmt.Errorf(format, args...)}
+}
+
+// parse разбирает флаги команды вперемешку с позиционными аргументами:
+// "ads publish 5 --user 1" и "ads publish --user 1 5" равнозначны
+func parse(fs *flag.FlagSet, args []string, positional ...string) ([]int64, error) {
+	var values []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, usageError{err: err}
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		values = append(values, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(values) != len(positional) {
+		return nil, usagef("%s: expected %d argument(s), got %d", fs.Name(), len(positional), len(values))
+	}
+
+	ids := make([]int64, 0, len(values))
+	for idx, raw := range values {
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, usagef("%s: %s %q is not a number", fs.Name(), positional[idx], raw)
+		}
+		ids = append(ids, value)
+	}
+	return ids, nil
+}
+
+func newFlagSet(name string, output io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(output)
+	return fs
+}
+
+// requiredFlag проверяет, что флаг передан явно
+func requiredFlag(fs *flag.FlagSet, names ...string) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	for _, name := range names {
+		if !set[name] {
+			return usagef("%s: flag --%s is required", fs.Name(), name)
+		}
+	}
+	return nil
+}
+
+func adsList(ctx context.Context, env *env, args []string) error {
+	fs := newFlagSet("ads list", env.stderr)
+	published := fs.String("published", "", "true - only published ads (the default), false - only drafts")
+	author := fs.Int64("author", 0, "only ads of this user")
+	day := fs.String("date", "", "only ads created on this day (UTC)")
+	title := fs.String("title", "", "search ads whose title starts with the prefix; other filters are ignored")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+
+	if *title != "" {
+		list, err := env.client.SearchAds(ctx, *title)
+		if err != nil {
+			return err
+		}
+		return env.printer.ads(list)
+	}
+
+	var opts []client.ListOption
+	if *published != "" {
+		value, err := strconv.ParseBool(*published)
+		if err != nil {
+			return usagef("ads list: --published %q should be true or false", *published)
+		}
+		opts = append(opts, client.Published(value))
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "author" {
+			opts = append(opts, client.ByAuthor(*author))
+		}
+	})
+	if *day != "" {
+		date, err := time.Parse(time.DateOnly, *day)
+		if err != nil {
+			return usagef("ads list: --date %q should look like 2006-01-02", *day)
+		}
+		opts = append(opts, client.CreatedOn(date))
+	}
+
+	list, err := env.client.ListAds(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	return env.printer.ads(list)
+}
+
+func adsGet(ctx context.Context, env *env, args []string) error {
+	ids, err := parse(newFlagSet("ads get", env.stderr), args, "AD_ID")
+	if err != nil {
+		return err
+	}
+	ad, err := env.client.GetAd(ctx, ids[0])
+	if err != nil {
+		return err
+	}
+	return env.printer.ad(ad)
+}
+
+func adsCreate(ctx context.Context, env *env, args []string) error {
+	fs := newFlagSet("ads create", env.stderr)
+	user := fs.Int64("user", 0, "author of the ad")
+	title := fs.String("title", "", "title of the ad")
+	text := fs.String("text", "", "text of the ad")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := requiredFlag(fs, "user", "title", "text"); err != nil {
+		return err
+	}
+
+	ad, err := env.client.CreateAd(ctx, *title, *text, *user)
+	if err != nil {
+		return err
+	}
+	return env.printer.ad(ad)
+}
+
+func adsPublish(ctx context.Context, env *env, args []string) error {
+	fs := newFlagSet("ads publish", env.stderr)
+	user := fs.Int64("user", 0, "author of the ad")
+	unpublish := fs.Bool("unpublish", false, "take the ad off publication instead")
+	ids, err := parse(fs, args, "AD_ID")
+	if err != nil {
+		return err
+	}
+	if err := requiredFlag(fs, "user"); err != nil {
+		return err
+	}
+
+	ad, err := env.client.ChangeAdStatus(ctx, ids[0], *user, !*unpublish)
+	if err != nil {
+		return err
+	}
+	return env.printer.ad(ad)
+}
+
+func adsDelete(ctx context.Context, env *env, args []string) error {
+	fs := newFlagSet("ads delete", env.stderr)
+	user := fs.Int64("user", 0, "author of the ad")
+	ids, err := parse(fs, args, "AD_ID")
+	if err != nil {
+		return err
+	}
+	if err := requiredFlag(fs, "user"); err != nil {
+		return err
+	}
+
+	if err := env.client.DeleteAd(ctx, ids[0], *user); err != nil {
+		return err
+	}
+	return env.printer.deleted("ad", ids[0])
+}
+
+func usersGet(ctx context.Context, env *env, args []string) error {
+	ids, err := parse(newFlagSet("users get", env.stderr), args, "USER_ID")
+	if err != nil {
+		return err
+	}
+	user, err := env.client.GetUser(ctx, ids[0])
+	if err != nil {
+		return err
+	}
+	return env.printer.user(user)
+}
+
+func usersCreate(ctx context.Context, env *env, args []string) error {
+	fs := newFlagSet("users create", env.stderr)
+	nickname := fs.String("nickname", "", "nickname of the user")
+	email := fs.String("email", "", "email of the user")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := requiredFlag(fs, "nickname", "email"); err != nil {
+		return err
+	}
+
+	user, err := env.client.CreateUser(ctx, *nickname, *email)
+	if err != nil {
+		return err
+	}
+	return env.printer.user(user)
+}
+
+func usersDelete(ctx context.Context, env *env, args []string) error {
+	ids, err := parse(newFlagSet("users delete", env.stderr), args, "USER_ID")
+	if err != nil {
+		return err
+	}
+	if err := env.client.DeleteUser(ctx, ids[0]); err != nil {
+		return err
+	}
+	return env.printer.deleted("user", ids[0])
+}
+
+// watch печатает события, пока его не прервут; прерывание - штатное завершение
+func watch(ctx context.Context, env *env, args []string) error {
+	fs := newFlagSet("watch", env.stderr)
+	author := fs.Int64("author", 0, "only events of this user's ads")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+
+	var opts []client.WatchOption
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "author" {
+			opts = append(opts, client.WatchAuthor(*author))
+		}
+	})
+	stream, err := env.client.WatchAds(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	for {
+		event, err := stream.Recv()
+		if errors.Is(err, io.EOF) || ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := env.printer.event(event); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"homework10/internal/tlsutil"
+)
+
+// envPrefix - префикс переменных окружения, ADSCTL_CONFIG задаёт путь к файлу
+const envPrefix = "ADSCTL_"
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// config - куда и как подключаться. Приоритет: флаги > переменные окружения > файл > значения по умолчанию
+type config struct {
+	Endpoint string        `yaml:"endpoint"`
+	Output   string        `yaml:"output"`
+	Timeout  time.Duration `yaml:"timeout"`
+	TLS      tlsConfig     `yaml:"tls"`
+}
+
+// tlsConfig - пустые CAFile и CertFile означают plaintext. CertFile и KeyFile - учётные данные
+// клиента, если сервер требует mutual TLS
+type tlsConfig struct {
+	CAFile     string `yaml:"ca_file"`
+	CertFile   string `yaml:"cert_file"`
+	KeyFile    string `yaml:"key_file"`
+	ServerName string `yaml:"server_name"`
+}
+
+func defaultConfig() config {
+	return config{Endpoint: "localhost:50054", Output: outputTable, Timeout: 10 * time.Second}
+}
+
+// defaultConfigPath - файл, который читается, если путь не задан явно; его может и не быть
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "adsctl", "config.yaml")
+}
+
+// loadConfig читает файл и окружение; флаги поверх накладывает вызывающий
+func loadConfig(path string, lookupEnv func(string) (string, bool)) (config, error) {
+	cfg := defaultConfig()
+
+	explicit := path != ""
+	if !explicit {
+		path, explicit = lookupEnv(envPrefix + "CONFIG")
+	}
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	if path != "" {
+		err := readConfigFile(path, &cfg)
+		if err != nil && (explicit || !errors.Is(err, fs.ErrNotExist)) {
+			return cfg, err
+		}
+	}
+
+	for name, value := range map[string]*string{
+		"ENDPOINT":        &cfg.Endpoint,
+		"OUTPUT":          &cfg.Output,
+		"TLS_CA_FILE":     &cfg.TLS.CAFile,
+		"TLS_CERT_FILE":   &cfg.TLS.CertFile,
+		"TLS_KEY_FILE":    &cfg.TLS.KeyFile,
+		"TLS_SERVER_NAME": &cfg.TLS.ServerName,
+	} {
+		if raw, ok := lookupEnv(envPrefix + name); ok {
+			*value = raw
+		}
+	}
+	if raw, ok := lookupEnv(envPrefix + "TIMEOUT"); ok {
+		timeout, err := time.ParseDuration(raw)
+		if err != nil {
+			return cfg, fmt.Errorf("env %sTIMEOUT: %w", envPrefix, err)
+		}
+		cfg.Timeout = timeout
+	}
+	return cfg, nil
+}
+
+func readConfigFile(path string, cfg *config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *config) validate() error {
+	var errs []error
+	if c.Endpoint == "" {
+		errs = append(errs, errors.New("endpoint is required"))
+	}
+	if c.Output != outputTable && c.Output != outputJSON && c.Output != outputYAML {
+		errs = append(errs, fmt.Errorf("output %q should be table, json or yaml", c.Output))
+	}
+	if c.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("timeout %s should be positive", c.Timeout))
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("tls cert_file and key_file should be set together"))
+	}
+	return errors.Join(errs...)
+}
+
+// tlsClientConfig собирает настройки TLS; nil означает plaintext
+func (c *config) tlsClientConfig() (*tls.Config, error) {
+	if c.TLS.CAFile == "" && c.TLS.CertFile == "" {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: c.TLS.ServerName}
+	if c.TLS.CAFile != "" {
+		pool, err := tlsutil.LoadCertPool(c.TLS.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+	if c.TLS.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.TLS.CertFile, c.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
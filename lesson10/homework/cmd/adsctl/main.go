@@ -0,0 +1,176 @@
+// adsctl - консольный клиент сервиса объявлений поверх gRPC AdService.
+//
+// Код выхода: 0 - успех, 1..16 - код gRPC-статуса, которым ответил сервис
+// (5 - не найдено, 7 - нет прав, 3 - ошибка валидации, 14 - сервис недоступен и т.д.),
+// 64 - неверные аргументы командной строки, 78 - неверная конфигурация
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"google.golang.org/grpc/codes"
+
+	"homework10/client"
+)
+
+// коды выхода для ошибок, до которых дело не дошло до сервиса (sysexits.h)
+const (
+	exitUsage  = 64
+	exitConfig = 78
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.LookupEnv, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run выполняет одну команду и возвращает код выхода. opts добавляются к опциям клиента
+func run(ctx context.Context, args []string, lookupEnv func(string) (string, bool), stdout io.Writer, stderr io.Writer, opts ...client.Option) int {
+	fs := newFlagSet("adsctl", stderr)
+	fs.Usage = func() {
+		usage(fs, stderr)
+	}
+	path := fs.String("config", "", "path to a YAML config file (env "+envPrefix+"CONFIG, default "+defaultConfigPath()+")")
+	endpoint := fs.String("endpoint", "", "grpc address of the service (env "+envPrefix+"ENDPOINT)")
+	output := fs.String("o", "", "output format: table, json or yaml (env "+envPrefix+"OUTPUT)")
+	timeout := fs.Duration("timeout", 0, "timeout of a single call (env "+envPrefix+"TIMEOUT)")
+	caFile := fs.String("tls-ca-file", "", "PEM CA bundle, enables TLS (env "+envPrefix+"TLS_CA_FILE)")
+	certFile := fs.String("tls-cert-file", "", "PEM client certificate for mutual TLS (env "+envPrefix+"TLS_CERT_FILE)")
+	keyFile := fs.String("tls-key-file", "", "PEM private key of the client certificate (env "+envPrefix+"TLS_KEY_FILE)")
+	serverName := fs.String("tls-server-name", "", "server name to verify instead of the endpoint host (env "+envPrefix+"TLS_SERVER_NAME)")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return exitUsage
+	}
+
+	cmd, cmdArgs, err := lookupCommand(fs.Args())
+	if err != nil {
+		fmt.Fprintln(stderr, "adsctl:", err)
+		usage(fs, stderr)
+		return exitUsage
+	}
+
+	cfg, err := loadConfig(*path, lookupEnv)
+	if err != nil {
+		fmt.Fprintln(stderr, "adsctl:", err)
+		return exitConfig
+	}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "endpoint":
+			cfg.Endpoint = *endpoint
+		case "o":
+			cfg.Output = *output
+		case "timeout":
+			cfg.Timeout = *timeout
+		case "tls-ca-file":
+			cfg.TLS.CAFile = *caFile
+		case "tls-cert-file":
+			cfg.TLS.CertFile = *certFile
+		case "tls-key-file":
+			cfg.TLS.KeyFile = *keyFile
+		case "tls-server-name":
+			cfg.TLS.ServerName = *serverName
+		}
+	})
+	if err := cfg.validate(); err != nil {
+		fmt.Fprintln(stderr, "adsctl: invalid config:", err)
+		return exitConfig
+	}
+	tlsConfig, err := cfg.tlsClientConfig()
+	if err != nil {
+		fmt.Fprintln(stderr, "adsctl:", err)
+		return exitConfig
+	}
+
+	opts = append([]client.Option{client.WithTimeout(cfg.Timeout), client.WithTLSConfig(tlsConfig)}, opts...)
+	c, err := client.NewGRPC(cfg.Endpoint, opts...)
+	if err != nil {
+		fmt.Fprintln(stderr, "adsctl:", err)
+		return exitConfig
+	}
+	defer c.Close()
+
+	err = cmd.run(ctx, &env{client: c, printer: printer{format: cfg.Output, w: stdout}, stderr: stderr}, cmdArgs)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(stderr, "adsctl:", err)
+	}
+	return exitCode(err)
+}
+
+// lookupCommand находит команду по первым одному-двум словам
+func lookupCommand(args []string) (command, []string, error) {
+	if len(args) == 0 {
+		return command{}, nil, errors.New("command is required")
+	}
+	group, ok := commands[args[0]]
+	if !ok {
+		return command{}, nil, fmt.Errorf("unknown command %q", args[0])
+	}
+	if cmd, ok := group[""]; ok {
+		return cmd, args[1:], nil
+	}
+	if len(args) < 2 {
+		return command{}, nil, fmt.Errorf("%s: subcommand is required", args[0])
+	}
+	cmd, ok := group[args[1]]
+	if !ok {
+		return command{}, nil, fmt.Errorf("%s: unknown subcommand %q", args[0], args[1])
+	}
+	return cmd, args[2:], nil
+}
+
+func exitCode(err error) int {
+	var usageErr usageError
+	var serviceErr *client.Error
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.As(err, &serviceErr):
+		return int(serviceErr.Code)
+	case errors.Is(err, context.DeadlineExceeded):
+		return int(codes.DeadlineExceeded)
+	case errors.Is(err, context.Canceled):
+		return int(codes.Canceled)
+	}
+	return int(codes.Unknown)
+}
+
+func usage(fs *flag.FlagSet, w io.Writer) {
+	fmt.Fprintln(w, "usage: adsctl [flags] <command> [args]")
+	fmt.Fprintln(w, "\ncommands:")
+	groups := make([]string, 0, len(commands))
+	for group := range commands {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		names := make([]string, 0, len(commands[group]))
+		for name := range commands[group] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "  %s %s\n", strings.TrimSpace(group+" "+name), commands[group][name].usage)
+		}
+	}
+	fmt.Fprintln(w, "\nflags:")
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+	fmt.Fprintf(w, "\nexit codes: 0 ok, 1-16 grpc status code of the failed call, %d bad arguments, %d bad config\n", exitUsage, exitConfig)
+}
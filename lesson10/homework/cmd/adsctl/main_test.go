@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"gopkg.in/yaml.v3"
+
+	"homework10/client"
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/app"
+	"homework10/internal/outbox"
+	grpcPort "homework10/internal/ports/grpc"
+)
+
+func fakeEnv(values map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := values[key]
+		return value, ok
+	}
+}
+
+// adsctl запускает команды против сервиса в памяти
+type adsctl struct {
+	t      *testing.T
+	dial   client.Option
+	config string
+	repo   app.Repository
+}
+
+func newAdsctl(t *testing.T, opts ...grpcPort.Option) *adsctl {
+	repo := adrepo.New()
+	ctl := newAdsctlWithApp(t, app.NewApp(repo), opts...)
+	ctl.repo = repo
+	return ctl
+}
+
+// verifyEmail подтверждает email пользователя в обход письма, чтобы он мог публиковать объявления
+func (a *adsctl) verifyEmail(userID int64) {
+	ctx := context.Background()
+	user, err := a.repo.GetUserById(ctx, userID)
+	require.NoError(a.t, err)
+	user.EmailVerified = true
+	_, err = a.repo.ChangeUser(ctx, &user)
+	require.NoError(a.t, err)
+}
+
+func newAdsctlWithApp(t *testing.T, a app.App, opts ...grpcPort.Option) *adsctl {
+	srv, lis := grpcPort.TestNewGRPCServer(1024*1024, a, opts...)
+	t.Cleanup(srv.Stop)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+
+	dialer := func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}
+	// пустой файл конфигурации, чтобы не подхватить настройки машины
+	config := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(config, nil, 0o600))
+	return &adsctl{t: t, dial: client.WithDialOptions(grpc.WithContextDialer(dialer)), config: config}
+}
+
+func (a *adsctl) run(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, a.env(), &stdout, &stderr, a.dial, client.WithRetry(1, 0))
+	return code, stdout.String(), stderr.String()
+}
+
+func (a *adsctl) env() func(string) (string, bool) {
+	return fakeEnv(map[string]string{"ADSCTL_ENDPOINT": "bufnet", "ADSCTL_CONFIG": a.config})
+}
+
+// runJSON выполняет команду с -o json и разбирает ответ в out
+func (a *adsctl) runJSON(out any, args ...string) {
+	code, stdout, stderr := a.run(append([]string{"-o", "json"}, args...)...)
+	require.Equal(a.t, 0, code, stderr)
+	require.NoError(a.t, json.Unmarshal([]byte(stdout), out))
+}
+
+func TestAdsctl_Ads(t *testing.T) {
+	ctl := newAdsctl(t)
+
+	var user client.User
+	ctl.runJSON(&user, "users", "create", "--nickname", "seller", "--email", "seller@mail.ru")
+	assert.Equal(t, "seller", user.Nickname)
+	ctl.verifyEmail(user.ID)
+
+	var ad client.Ad
+	ctl.runJSON(&ad, "ads", "create", "--user", id(user.ID), "--title", "hello", "--text", "world")
+	assert.Equal(t, "hello", ad.Title)
+	assert.False(t, ad.Published)
+
+	ctl.runJSON(&ad, "ads", "publish", id(ad.ID), "--user", id(user.ID))
+	assert.True(t, ad.Published)
+
+	var list []client.Ad
+	ctl.runJSON(&list, "ads", "list", "--author", id(user.ID))
+	require.Len(t, list, 1)
+	assert.Equal(t, ad.ID, list[0].ID)
+
+	ctl.runJSON(&list, "ads", "list", "--published=false")
+	assert.Empty(t, list)
+
+	ctl.runJSON(&list, "ads", "list", "--title", "hel")
+	assert.Len(t, list, 1)
+
+	code, stdout, _ := ctl.run("ads", "delete", "--user", id(user.ID), id(ad.ID))
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "ad 0 deleted\n", stdout)
+
+	code, _, stderr := ctl.run("ads", "get", id(ad.ID))
+	assert.Equal(t, int(codes.NotFound), code)
+	assert.Contains(t, stderr, "id is not found")
+}
+
+func TestAdsctl_Output(t *testing.T) {
+	ctl := newAdsctl(t)
+	var user client.User
+	ctl.runJSON(&user, "users", "create", "--nickname", "seller", "--email", "seller@mail.ru")
+	ctl.verifyEmail(user.ID)
+	var ad client.Ad
+	ctl.runJSON(&ad, "ads", "create", "--user", id(user.ID), "--title", "hello", "--text", "world")
+	ctl.runJSON(&ad, "ads", "publish", id(ad.ID), "--user", id(user.ID))
+
+	code, stdout, _ := ctl.run("ads", "list")
+	assert.Equal(t, 0, code)
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, []string{"ID", "AUTHOR", "PUBLISHED", "CREATED", "TITLE"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"0", "0", "true"}, strings.Fields(lines[1])[:3])
+
+	code, stdout, _ = ctl.run("users", "get", id(user.ID))
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "nickname: seller")
+
+	code, stdout, _ = ctl.run("-o", "yaml", "ads", "get", id(ad.ID))
+	assert.Equal(t, 0, code)
+	var fromYAML client.Ad
+	require.NoError(t, yaml.Unmarshal([]byte(stdout), &fromYAML))
+	assert.Equal(t, "world", fromYAML.Text)
+	assert.True(t, fromYAML.Published)
+}
+
+func TestAdsctl_ExitCodes(t *testing.T) {
+	ctl := newAdsctl(t)
+	var user client.User
+	ctl.runJSON(&user, "users", "create", "--nickname", "seller", "--email", "seller@mail.ru")
+	ctl.verifyEmail(user.ID)
+	var ad client.Ad
+	ctl.runJSON(&ad, "ads", "create", "--user", id(user.ID), "--title", "hello", "--text", "world")
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"not found", []string{"users", "get", "100"}, int(codes.NotFound)},
+		{"forbidden", []string{"ads", "publish", id(ad.ID), "--user", "100"}, int(codes.PermissionDenied)},
+		{"invalid", []string{"users", "create", "--nickname", "", "--email", "a@mail.ru"}, int(codes.InvalidArgument)},
+		{"no command", nil, exitUsage},
+		{"unknown command", []string{"orders", "list"}, exitUsage},
+		{"unknown subcommand", []string{"ads", "archive"}, exitUsage},
+		{"missing argument", []string{"ads", "get"}, exitUsage},
+		{"not a number", []string{"ads", "get", "first"}, exitUsage},
+		{"missing flag", []string{"ads", "delete", "1"}, exitUsage},
+		{"unknown flag", []string{"ads", "list", "--color"}, exitUsage},
+		{"help", []string{"ads", "list", "-h"}, 0},
+		{"bad output", []string{"-o", "xml", "ads", "list"}, exitConfig},
+		{"half of a key pair", []string{"--tls-cert-file", "cert.pem", "ads", "list"}, exitConfig},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code, _, stderr := ctl.run(test.args...)
+			assert.Equal(t, test.code, code, stderr)
+		})
+	}
+}
+
+// syncBuffer - stdout, который читает тест, пока watch в него пишет
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestAdsctl_Watch(t *testing.T) {
+	repo := adrepo.New()
+	broker := outbox.NewBroker(outbox.DefaultBrokerBuffer)
+	dispatcher := outbox.NewDispatcher(repo, outbox.Config{Broker: broker})
+	a := app.NewApp(repo)
+	ctl := newAdsctlWithApp(t, a, grpcPort.WithAdEvents(broker))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var stdout syncBuffer
+	done := make(chan int)
+	go func() {
+		done <- run(ctx, []string{"-o", "json", "watch"}, ctl.env(), &stdout, &bytes.Buffer{}, ctl.dial)
+	}()
+	require.Eventually(t, func() bool {
+		return broker.Subscribers() == 1
+	}, time.Second, 10*time.Millisecond)
+
+	var user client.User
+	ctl.runJSON(&user, "users", "create", "--nickname", "seller", "--email", "seller@mail.ru")
+	ctl.runJSON(&client.Ad{}, "ads", "create", "--user", id(user.ID), "--title", "hello", "--text", "world")
+	dispatcher.Flush(context.Background())
+
+	require.Eventually(t, func() bool {
+		return strings.Contains(stdout.String(), "\n")
+	}, time.Second, 10*time.Millisecond)
+	var event client.AdEvent
+	require.NoError(t, json.Unmarshal([]byte(strings.SplitN(stdout.String(), "\n", 2)[0]), &event))
+	assert.Equal(t, client.AdCreated, event.Type)
+	assert.Equal(t, "hello", event.Ad.Title)
+
+	// прерывание watch - штатное завершение
+	cancel()
+	assert.Equal(t, 0, <-done)
+}
+
+func TestAdsctl_WatchDisabled(t *testing.T) {
+	code, _, stderr := newAdsctl(t).run("watch")
+	assert.Equal(t, int(codes.Unimplemented), code, stderr)
+}
+
+func TestLoadConfig_Precedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+endpoint: ads.internal:50054
+output: yaml
+tls:
+  ca_file: ca.pem
+`), 0o600))
+
+	cfg, err := loadConfig("", fakeEnv(map[string]string{"ADSCTL_CONFIG": path, "ADSCTL_OUTPUT": "json", "ADSCTL_TIMEOUT": "3s"}))
+	require.NoError(t, err)
+	assert.Equal(t, "ads.internal:50054", cfg.Endpoint) // из файла
+	assert.Equal(t, "json", cfg.Output)                 // окружение важнее файла
+	assert.Equal(t, 3*time.Second, cfg.Timeout)
+	assert.Equal(t, "ca.pem", cfg.TLS.CAFile)
+
+	_, err = loadConfig(filepath.Join(t.TempDir(), "missing.yaml"), fakeEnv(nil))
+	assert.Error(t, err, "явно заданный файл должен существовать")
+
+	require.NoError(t, os.WriteFile(path, []byte("endpont: typo\n"), 0o600))
+	_, err = loadConfig(path, fakeEnv(nil))
+	assert.Error(t, err)
+}
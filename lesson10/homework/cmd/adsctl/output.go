@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"homework10/client"
+)
+
+// printer печатает ответы сервиса в формате из --output. Список в table - таблица,
+// одна запись - пары "поле: значение"
+type printer struct {
+	format string
+	w      io.Writer
+}
+
+func (p printer) ads(list []client.Ad) error {
+	if p.format != outputTable {
+		return p.encode(list)
+	}
+	return p.table([]string{"ID", "AUTHOR", "PUBLISHED", "CREATED", "TITLE"}, len(list), func(idx int) []string {
+		ad := list[idx]
+		return []string{id(ad.ID), id(ad.AuthorID), strconv.FormatBool(ad.Published), date(ad.DateCreating), ad.Title}
+	})
+}
+
+func (p printer) ad(ad *client.Ad) error {
+	if p.format != outputTable {
+		return p.encode(ad)
+	}
+	return p.fields([][2]string{
+		{"id", id(ad.ID)},
+		{"author", id(ad.AuthorID)},
+		{"published", strconv.FormatBool(ad.Published)},
+		{"created", date(ad.DateCreating)},
+		{"updated", date(ad.DateUpdate)},
+		{"title", ad.Title},
+		{"text", ad.Text},
+	})
+}
+
+func (p printer) user(user *client.User) error {
+	if p.format != outputTable {
+		return p.encode(user)
+	}
+	return p.fields([][2]string{
+		{"id", id(user.ID)},
+		{"nickname", user.Nickname},
+		{"email", user.Email},
+	})
+}
+
+// event печатает одно событие watch: в json - строкой NDJSON, в yaml - отдельным документом
+func (p printer) event(event client.AdEvent) error {
+	switch p.format {
+	case outputJSON:
+		return json.NewEncoder(p.w).Encode(event)
+	case outputYAML:
+		if _, err := io.WriteString(p.w, "---\n"); err != nil {
+			return err
+		}
+		return p.encode(event)
+	}
+	_, err := fmt.Fprintf(p.w, "%s\t%s\tad=%d\tauthor=%d\tpublished=%t\t%s\n",
+		date(event.CreatedAt), event.Type, event.Ad.ID, event.Ad.AuthorID, event.Ad.Published, event.Ad.Title)
+	return err
+}
+
+// deleted подтверждает удаление; в json и yaml ничего не печатается, достаточно кода выхода
+func (p printer) deleted(what string, objectID int64) error {
+	if p.format != outputTable {
+		return nil
+	}
+	_, err := fmt.Fprintf(p.w, "%s %d deleted\n", what, objectID)
+	return err
+}
+
+func (p printer) encode(v any) error {
+	if p.format == outputYAML {
+		encoder := yaml.NewEncoder(p.w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(v); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+	encoder := json.NewEncoder(p.w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func (p printer) table(header []string, rows int, row func(idx int) []string) error {
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	writeRow := func(cells []string) {
+		for idx, cell := range cells {
+			if idx > 0 {
+				_, _ = io.WriteString(tw, "\t")
+			}
+			_, _ = io.WriteString(tw, cell)
+		}
+		_, _ = io.WriteString(tw, "\n")
+	}
+	writeRow(header)
+	for idx := 0; idx < rows; idx++ {
+		writeRow(row(idx))
+	}
+	return tw.Flush()
+}
+
+func (p printer) fields(pairs [][2]string) error {
+	tw := tabwriter.NewWriter(p.w, 0, 4, 1, ' ', 0)
+	for _, pair := range pairs {
+		_, _ = fmt.Fprintf(tw, "%s:\t%s\n", pair[0], pair[1])
+	}
+	return tw.Flush()
+}
+
+func id(value int64) string {
+	return strconv.FormatInt(value, 10)
+}
+
+func date(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
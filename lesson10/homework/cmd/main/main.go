@@ -3,13 +3,29 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"golang.org/x/sync/errgroup"
 	"homework10/internal/adapters/adrepo"
 	"homework10/internal/app"
+	"homework10/internal/cache"
+	"homework10/internal/config"
+	"homework10/internal/health"
+	"homework10/internal/idempotency"
+	"homework10/internal/logging"
+	"homework10/internal/mailer"
+	"homework10/internal/outbox"
+	"homework10/internal/ports/gateway"
 	grpcService "homework10/internal/ports/grpc"
 	"homework10/internal/ports/httpgin"
+	"homework10/internal/ports/multiplex"
+	"homework10/internal/ratelimit"
+	"homework10/internal/tlsutil"
+	"homework10/internal/tracing"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -17,16 +33,112 @@ import (
 	"time"
 )
 
-const (
-	grpcPort = ":50054"
-	httpPort = ":18080"
-)
-
 func main() {
-	adApp := app.NewApp(adrepo.New())
+	cfg, printConfig, err := config.Load(os.Args[1:], os.LookupEnv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if printConfig {
+		if err := cfg.Write(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-	httpServer := httpgin.NewHTTPServer(httpPort, adApp)
-	grpcServer, lis := grpcService.NewGRPCServer(grpcPort, adApp)
+	slog.SetDefault(logging.New(os.Stdout, cfg.LogLevel()))
+
+	exporter, err := tracing.NewExporter(cfg.Trace.Exporter, os.Stdout)
+	if err != nil {
+		log.Fatal(err)
+	}
+	tp := tracing.NewProvider(exporter)
+	defer func() {
+		if err := tp.Shutdown(context.Background()); err != nil {
+			log.Printf("can't flush traces: %s", err.Error())
+		}
+	}()
+
+	var repo interface {
+		app.Repository
+		outbox.Store
+	}
+	switch cfg.Repository.Type {
+	case config.RepositoryMemory:
+		repo = adrepo.New()
+	case config.RepositorySharded:
+		repo = adrepo.NewSharded(cfg.Repository.Shards)
+	}
+	var cachedRepo *cache.Repository
+	appRepo := tracing.NewRepository(repo, tp)
+	if cfg.Cache.Size > 0 {
+		cachedRepo = cache.NewRepository(appRepo, cache.Config{Size: cfg.Cache.Size, TTL: cfg.Cache.TTL})
+		appRepo = cachedRepo
+	}
+	var mail mailer.Mailer = mailer.Log{}
+	if cfg.Mail.Mailer == config.MailerSMTP {
+		mail = mailer.NewSMTP(mailer.SMTPConfig{
+			Addr:     cfg.Mail.SMTP.Addr,
+			Username: cfg.Mail.SMTP.Username,
+			Password: cfg.Mail.SMTP.Password,
+			From:     cfg.Mail.SMTP.From,
+			Timeout:  cfg.Mail.SMTP.Timeout,
+		})
+	}
+	adApp := tracing.NewApp(app.NewApp(appRepo,
+		app.WithMailer(mail),
+		app.WithTokenTTL(cfg.Mail.VerifyEmailTTL, cfg.Mail.ResetPasswordTTL),
+		app.WithPrivateWebhookTargets(cfg.Webhooks.AllowPrivateTargets),
+	), tp)
+	adEvents := outbox.NewBroker(outbox.DefaultBrokerBuffer)
+	dispatcher := outbox.NewDispatcher(repo, outbox.Config{
+		PollInterval: cfg.Webhooks.PollInterval,
+		BatchSize:    cfg.Webhooks.BatchSize,
+		MaxAttempts:  cfg.Webhooks.MaxAttempts,
+		BaseBackoff:  cfg.Webhooks.BaseBackoff,
+		MaxBackoff:   cfg.Webhooks.MaxBackoff,
+		Broker:       adEvents,
+
+		AllowPrivateTargets: cfg.Webhooks.AllowPrivateTargets,
+	})
+	limiter := ratelimit.New(ratelimit.Config{
+		Read:  ratelimit.Limit{Rate: cfg.Rate.Read.RPS, Burst: cfg.Rate.Read.Burst},
+		Write: ratelimit.Limit{Rate: cfg.Rate.Write.RPS, Burst: cfg.Rate.Write.Burst},
+	})
+	idempotencyStore := idempotency.NewMemoryStore(cfg.Idempotency.TTL)
+
+	httpTLS, err := tlsutil.ServerConfig(cfg.HTTP.TLS.CertFile, cfg.HTTP.TLS.KeyFile, cfg.HTTP.TLS.ClientCAFile)
+	if err != nil {
+		log.Fatalf("http tls: %s", err.Error())
+	}
+	grpcTLS, err := tlsutil.ServerConfig(cfg.GRPC.TLS.CertFile, cfg.GRPC.TLS.KeyFile, cfg.GRPC.TLS.ClientCAFile)
+	if err != nil {
+		log.Fatalf("grpc tls: %s", err.Error())
+	}
+
+	checker := health.New(cfg.Health.Timeout)
+	checker.Register("repository", appRepo.Ping)
+	checker.Register("dispatcher", func(ctx context.Context) error {
+		if !dispatcher.Running() {
+			return errors.New("webhook dispatcher is not running")
+		}
+		return nil
+	})
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	if cachedRepo != nil {
+		registry.MustRegister(cache.NewStatsCollector(cachedRepo))
+	}
+
+	httpServer := httpgin.NewHTTPServer(cfg.HTTP.Addr, adApp, httpgin.WithIdempotencyStore(idempotencyStore), httpgin.WithRateLimiter(limiter), httpgin.WithMetricsRegistry(registry), httpgin.WithTracerProvider(tp), httpgin.WithHealthChecker(checker), httpgin.WithTLSConfig(httpTLS), httpgin.WithAdEvents(adEvents), httpgin.WithAdminToken(cfg.Admin.Token))
+	httpServer.ReadHeaderTimeout = cfg.HTTP.ReadHeaderTimeout
+	httpServer.ReadTimeout = cfg.HTTP.ReadTimeout
+	httpServer.WriteTimeout = cfg.HTTP.WriteTimeout
+	httpServer.IdleTimeout = cfg.HTTP.IdleTimeout
+	grpcOpts := []grpcService.Option{grpcService.WithIdempotencyStore(idempotencyStore), grpcService.WithRateLimiter(limiter), grpcService.WithMetricsRegistry(registry), grpcService.WithTracerProvider(tp), grpcService.WithHealthChecker(checker), grpcService.WithAdEvents(adEvents), grpcService.WithAdminToken(cfg.Admin.Token)}
 
 	eg, ctx := errgroup.WithContext(context.Background())
 	sigQuit := make(chan os.Signal, 1)
@@ -36,70 +148,190 @@ func main() {
 		select {
 		case s := <-sigQuit:
 			log.Printf("captured signal: %v\n", s)
+			// сначала перестаём быть готовыми, чтобы балансировщик успел увести трафик
+			checker.Shutdown()
+			select {
+			case <-time.After(cfg.Shutdown.DrainDelay):
+			case <-sigQuit:
+			}
 			return fmt.Errorf("captured signal: %v", s)
 		case <-ctx.Done():
 			return nil
 		}
 	})
 
-	// run grpc server
+	// run webhook dispatcher
 	eg.Go(func() error {
-		log.Printf("starting grpc server, listening on %s\n", grpcPort)
-		defer log.Printf("close grpc server listening on %s\n", grpcPort)
+		log.Println("starting webhook dispatcher")
+		defer log.Println("close webhook dispatcher")
+
+		return dispatcher.Run(ctx)
+	})
 
-		errCh := make(chan error)
+	if cfg.ListenMode == config.ListenSingle {
+		server, err := multiplex.New(grpcService.NewServer(adApp, grpcOpts...), httpServer)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// run http and grpc on one port
+		eg.Go(func() error {
+			log.Printf("starting http and grpc server, listening on %s\n", server.Addr())
+			defer log.Printf("close http and grpc server listening on %s\n", server.Addr())
+
+			errCh := make(chan error)
+
+			defer func() {
+				checker.Shutdown()
+				shCtx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout)
+				defer cancel()
 
-		defer func() {
-			grpcServer.GracefulStop()
-			_ = lis.Close()
+				if err := server.Shutdown(shCtx); err != nil {
+					log.Printf("can't close server listening on %s: %s", server.Addr(), err.Error())
+				}
 
-			close(errCh)
-		}()
+				close(errCh)
+			}()
 
-		go func() {
-			if err := grpcServer.Serve(lis); err != nil {
-				errCh <- err
+			go func() {
+				if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+					errCh <- err
+				}
+			}()
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case err := <-errCh:
+				return fmt.Errorf("server can't listen and serve requests: %w", err)
 			}
-		}()
+		})
+	} else {
+		grpcServer, lis := grpcService.NewGRPCServer(cfg.GRPC.Addr, adApp, append(grpcOpts, grpcService.WithTLSConfig(grpcTLS))...)
 
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case err := <-errCh:
-			return fmt.Errorf("grpc server can't listen and serve requests: %w", err)
-		}
-	})
+		// run grpc server
+		eg.Go(func() error {
+			log.Printf("starting grpc server, listening on %s\n", cfg.GRPC.Addr)
+			defer log.Printf("close grpc server listening on %s\n", cfg.GRPC.Addr)
 
-	eg.Go(func() error {
-		log.Printf("starting http server, listening on %s\n", httpServer.Addr)
-		defer log.Printf("close http server listening on %s\n", httpServer.Addr)
+			errCh := make(chan error)
 
-		errCh := make(chan error)
+			defer func() {
+				checker.Shutdown()
+				stopped := make(chan struct{})
+				go func() {
+					grpcServer.GracefulStop()
+					close(stopped)
+				}()
+				select {
+				case <-stopped:
+				case <-time.After(cfg.Shutdown.Timeout):
+					log.Printf("grpc server did not stop in %s, closing connections\n", cfg.Shutdown.Timeout)
+					grpcServer.Stop()
+				}
+				_ = lis.Close()
 
-		defer func() {
-			shCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-			defer cancel()
+				close(errCh)
+			}()
 
-			if err := httpServer.Shutdown(shCtx); err != nil {
-				log.Printf("can't close http server listening on %s: %s", httpServer.Addr, err.Error())
+			go func() {
+				if err := grpcServer.Serve(lis); err != nil {
+					errCh <- err
+				}
+			}()
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case err := <-errCh:
+				return fmt.Errorf("grpc server can't listen and serve requests: %w", err)
 			}
+		})
+
+		eg.Go(func() error {
+			log.Printf("starting http server, listening on %s\n", httpServer.Addr)
+			defer log.Printf("close http server listening on %s\n", httpServer.Addr)
+
+			errCh := make(chan error)
+
+			defer func() {
+				shCtx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout)
+				defer cancel()
+
+				if err := httpServer.Shutdown(shCtx); err != nil {
+					log.Printf("can't close http server listening on %s: %s", httpServer.Addr, err.Error())
+				}
 
-			close(errCh)
-		}()
+				close(errCh)
+			}()
 
-		go func() {
-			if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
-				errCh <- err
+			go func() {
+				serve := httpServer.ListenAndServe
+				if httpServer.TLSConfig != nil {
+					serve = func() error { return httpServer.ListenAndServeTLS("", "") }
+				}
+				if err := serve(); !errors.Is(err, http.ErrServerClosed) {
+					errCh <- err
+				}
+			}()
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case err := <-errCh:
+				return fmt.Errorf("http server can't listen and serve requests: %w", err)
 			}
-		}()
+		})
+	}
 
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case err := <-errCh:
-			return fmt.Errorf("http server can't listen and serve requests: %w", err)
+	if cfg.Gateway.Addr != "" {
+		// у шлюза те же лимиты, ключи идемпотентности, токен, таймауты и TLS, что у gin; метрики - в собственном реестре,
+		// их отдаёт /metrics самого шлюза
+		gatewayServer, err := gateway.NewHTTPServer(cfg.Gateway.Addr, adApp, httpgin.WithIdempotencyStore(idempotencyStore), httpgin.WithRateLimiter(limiter), httpgin.WithMetricsRegistry(prometheus.NewRegistry()), httpgin.WithTracerProvider(tp), httpgin.WithHealthChecker(checker), httpgin.WithTLSConfig(httpTLS), httpgin.WithAdminToken(cfg.Admin.Token))
+		if err != nil {
+			log.Fatalf("rest gateway: %s", err.Error())
 		}
-	})
+		gatewayServer.ReadHeaderTimeout = cfg.HTTP.ReadHeaderTimeout
+		gatewayServer.ReadTimeout = cfg.HTTP.ReadTimeout
+		gatewayServer.WriteTimeout = cfg.HTTP.WriteTimeout
+		gatewayServer.IdleTimeout = cfg.HTTP.IdleTimeout
+
+		// run rest gateway
+		eg.Go(func() error {
+			log.Printf("starting rest gateway, listening on %s\n", gatewayServer.Addr)
+			defer log.Printf("close rest gateway listening on %s\n", gatewayServer.Addr)
+
+			errCh := make(chan error)
+
+			defer func() {
+				shCtx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout)
+				defer cancel()
+
+				if err := gatewayServer.Shutdown(shCtx); err != nil {
+					log.Printf("can't close rest gateway listening on %s: %s", gatewayServer.Addr, err.Error())
+				}
+
+				close(errCh)
+			}()
+
+			go func() {
+				serve := gatewayServer.ListenAndServe
+				if gatewayServer.TLSConfig != nil {
+					serve = func() error { return gatewayServer.ListenAndServeTLS("", "") }
+				}
+				if err := serve(); !errors.Is(err, http.ErrServerClosed) {
+					errCh <- err
+				}
+			}()
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case err := <-errCh:
+				return fmt.Errorf("rest gateway can't listen and serve requests: %w", err)
+			}
+		})
+	}
 
 	if err := eg.Wait(); err != nil {
 		log.Printf("gracefully shutting down the servers: %s\n", err.Error())
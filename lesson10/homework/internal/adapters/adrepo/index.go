@@ -0,0 +1,135 @@
+package adrepo
+
+import (
+	"slices"
+	"strings"
+
+	"homework10/internal/ads"
+)
+
+type idSet map[int64]struct{}
+
+// adIndex - вторичные индексы объявлений для GetAds и GetAdsPage: фильтр читает только
+// объявления из самого маленького подходящего индекса, а не перебирает все.
+// Индексы меняются вместе с dictAds и только под repo.lock
+type adIndex struct {
+	byAuthor    map[int64]idSet
+	byPublished map[bool]idSet
+	byDay       []dayIDs // по возрастанию day, чтобы искать день двоичным поиском
+
+	// byID - ID по возрастанию для GetAdsPage. remove их не трогает: удалённые ID пропускаются
+	// при обходе и вычищаются compact. Новые ID обычно больше прежних, так что add дописывает в конец
+	byID []int64
+}
+
+// dayIDs - объявления, созданные в день day (в формате dateFormat)
+type dayIDs struct {
+	day string
+	ids idSet
+}
+
+func newAdIndex() adIndex {
+	return adIndex{
+		byAuthor:    make(map[int64]idSet),
+		byPublished: map[bool]idSet{true: {}, false: {}},
+	}
+}
+
+func (ix *adIndex) add(ad ads.Ad) {
+	if ix.byAuthor[ad.AuthorID] == nil {
+		ix.byAuthor[ad.AuthorID] = make(idSet)
+	}
+	ix.byAuthor[ad.AuthorID][ad.ID] = struct{}{}
+	ix.byPublished[ad.Published][ad.ID] = struct{}{}
+
+	day := ad.DateCreating.Format(dateFormat)
+	idx, ok := ix.findDay(day)
+	if !ok {
+		ix.byDay = slices.Insert(ix.byDay, idx, dayIDs{day: day, ids: make(idSet)})
+	}
+	ix.byDay[idx].ids[ad.ID] = struct{}{}
+
+	if n := len(ix.byID); n == 0 || ix.byID[n-1] < ad.ID {
+		ix.byID = append(ix.byID, ad.ID)
+	} else if idx, ok := slices.BinarySearch(ix.byID, ad.ID); !ok {
+		ix.byID = slices.Insert(ix.byID, idx, ad.ID)
+	}
+}
+
+func (ix *adIndex) remove(ad ads.Ad) {
+	delete(ix.byAuthor[ad.AuthorID], ad.ID)
+	if len(ix.byAuthor[ad.AuthorID]) == 0 {
+		delete(ix.byAuthor, ad.AuthorID)
+	}
+	delete(ix.byPublished[ad.Published], ad.ID)
+
+	if idx, ok := ix.findDay(ad.DateCreating.Format(dateFormat)); ok {
+		delete(ix.byDay[idx].ids, ad.ID)
+		if len(ix.byDay[idx].ids) == 0 {
+			ix.byDay = slices.Delete(ix.byDay, idx, idx+1)
+		}
+	}
+}
+
+// compact убирает из byID удалённые объявления, когда их становится больше, чем живых
+func (ix *adIndex) compact(dictAds map[int64]ads.Ad) {
+	if len(ix.byID) <= 2*len(dictAds) {
+		return
+	}
+	ix.byID = slices.DeleteFunc(ix.byID, func(id int64) bool {
+		_, ok := dictAds[id]
+		return !ok
+	})
+}
+
+// after возвращает ID больше afterID по возрастанию; среди них могут быть удалённые
+func (ix *adIndex) after(afterID int64) []int64 {
+	idx, found := slices.BinarySearch(ix.byID, afterID)
+	if found {
+		idx++
+	}
+	return ix.byID[idx:]
+}
+
+func (ix *adIndex) findDay(day string) (int, bool) {
+	return slices.BinarySearchFunc(ix.byDay, day, func(d dayIDs, day string) int {
+		return strings.Compare(d.day, day)
+	})
+}
+
+func (ix *adIndex) day(day string) idSet {
+	if idx, ok := ix.findDay(day); ok {
+		return ix.byDay[idx].ids
+	}
+	return nil
+}
+
+// candidates возвращает самый маленький индекс среди фильтров; indexed == false, если ни один
+// фильтр не индексирован и проверять надо все объявления. Фильтр со значением не того типа
+// не совпадает ни с одним объявлением, как и при полном переборе
+func (ix *adIndex) candidates(filters map[string]any) (smallest idSet, indexed bool) {
+	narrow := func(set idSet) {
+		if !indexed || len(set) < len(smallest) {
+			smallest, indexed = set, true
+		}
+	}
+
+	if filter, ok := filters[published]; ok {
+		value, ok := filter.(bool)
+		if !ok {
+			return nil, true
+		}
+		narrow(ix.byPublished[value])
+	}
+	if filter, ok := filters[userId]; ok {
+		value, ok := filter.(int64)
+		if !ok {
+			return nil, true
+		}
+		narrow(ix.byAuthor[value])
+	}
+	if filter, ok := filters[dateCreating]; ok {
+		narrow(ix.day(filter.(string)[:10]))
+	}
+	return smallest, indexed
+}
@@ -1,11 +1,16 @@
 package adrepo
 
 import (
+	"context"
+	"fmt"
 	"homework10/internal/ads"
 	"homework10/internal/app"
+	"homework10/internal/outbox"
 	"homework10/internal/users"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 const (
@@ -16,21 +21,51 @@ const (
 )
 
 type repositoryMap struct {
+	*tables
+
+	lock    sync.Locker // у транзакции - пустой: её вызовы уже под замком репозитория
+	journal *journal    // nil вне транзакции
+}
+
+// tables - данные репозитория; транзакция работает с теми же таблицами, что и сам репозиторий
+type tables struct {
 	dictAds        map[int64]ads.Ad
 	dictUsers      map[int64]users.User
+	userIndex      userIndex
 	dictAdsByTitle map[string][]ads.Ad
-
-	counterAds   int64
-	counterUsers int64
-
-	mu sync.Mutex
+	index          adIndex
+	tokens         tokenTable
+
+	outboxEvents []outbox.Event
+	webhooks     map[int64]outbox.Webhook
+	deadLetters  []outbox.DeadLetter
+
+	counterAds      int64
+	counterUsers    int64
+	counterEvents   int64
+	counterWebhooks int64
+
+	// ID события - counterEvents*eventStride + eventShard: у шардов шардированного репозитория
+	// ID событий не пересекаются
+	eventStride int64
+	eventShard  int64
 }
 
 func New() app.Repository {
-	return &repositoryMap{dictAds: make(map[int64]ads.Ad), dictUsers: make(map[int64]users.User), dictAdsByTitle: make(map[string][]ads.Ad), counterAds: 0, counterUsers: 0}
+	return newRepositoryMap(1, 0)
+}
+
+func newRepositoryMap(eventStride, eventShard int64) *repositoryMap {
+	return &repositoryMap{
+		tables: &tables{dictAds: make(map[int64]ads.Ad), dictUsers: make(map[int64]users.User), userIndex: newUserIndex(), tokens: newTokenTable(), dictAdsByTitle: make(map[string][]ads.Ad), index: newAdIndex(), webhooks: make(map[int64]outbox.Webhook), counterAds: 0, counterUsers: 0, eventStride: eventStride, eventShard: eventShard},
+		lock:   &sync.Mutex{},
+	}
 }
 
-func (repo *repositoryMap) GetAdById(id int64) (ads.Ad, error) {
+func (repo *repositoryMap) GetAdById(_ context.Context, id int64) (ads.Ad, error) {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
 	ad, ok := repo.dictAds[id]
 	if !ok {
 		return ad, app.IncorrectAdId
@@ -38,33 +73,83 @@ func (repo *repositoryMap) GetAdById(id int64) (ads.Ad, error) {
 	return ad, nil
 }
 
-func (repo *repositoryMap) AddAd(ad *ads.Ad) {
-	repo.mu.Lock()
-	defer repo.mu.Unlock()
+func (repo *repositoryMap) AddAd(_ context.Context, ad *ads.Ad) {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
 
+	repo.rememberAd(ad.ID, ad.Title)
+	if old, ok := repo.dictAds[ad.ID]; ok {
+		repo.index.remove(old)
+	}
 	repo.dictAds[ad.ID] = *ad
+	repo.index.add(*ad)
 	repo.dictAdsByTitle[ad.Title] = append(repo.dictAdsByTitle[ad.Title], *ad)
 	repo.counterAds++
+	repo.addOutboxEvent(outbox.AdCreated, *ad)
 }
 
-func (repo *repositoryMap) GetAdsPrimaryKey() int64 {
+func (repo *repositoryMap) GetAdsPrimaryKey(_ context.Context) int64 {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
 	return repo.counterAds
 }
 
-func (repo *repositoryMap) GetUsersPrimaryKey() int64 {
+func (repo *repositoryMap) GetUsersPrimaryKey(_ context.Context) int64 {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
 	return repo.counterUsers
 }
 
-func (repo *repositoryMap) ChangeAd(ad *ads.Ad) bool {
-	repo.mu.Lock()
-	defer repo.mu.Unlock()
+func (repo *repositoryMap) ChangeAd(_ context.Context, ad *ads.Ad) bool {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	return repo.changeAd(*ad)
+}
+
+// ChangeAds сначала применяет change к копиям всех объявлений и только потом сохраняет их:
+// до первой записи уже известно, что транзакция пройдёт целиком
+func (repo *repositoryMap) ChangeAds(_ context.Context, ids []int64, change func(ad *ads.Ad) error) ([]ads.Ad, error) {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	changed := make([]ads.Ad, 0, len(ids))
+	for _, id := range ids {
+		ad, ok := repo.dictAds[id]
+		if !ok {
+			return nil, fmt.Errorf("ad %d: %w", id, app.IncorrectAdId)
+		}
+		if err := change(&ad); err != nil {
+			return nil, fmt.Errorf("ad %d: %w", id, err)
+		}
+		changed = append(changed, ad)
+	}
+
+	for _, ad := range changed {
+		repo.changeAd(ad)
+	}
+	return changed, nil
+}
 
-	_, ok := repo.dictAds[ad.ID]
+// вызывается только под repo.lock
+func (repo *repositoryMap) changeAd(ad ads.Ad) bool {
+	old, ok := repo.dictAds[ad.ID]
 	if ok {
-		repo.dictAds[ad.ID] = *ad
+		repo.rememberAd(ad.ID, ad.Title)
+		if old.Published != ad.Published {
+			repo.addOutboxEvent(outbox.AdStatusChanged, ad)
+		} else {
+			repo.addOutboxEvent(outbox.AdUpdated, ad)
+		}
+
+		repo.index.remove(old)
+		repo.dictAds[ad.ID] = ad
+		repo.index.add(ad)
 		for idx := range repo.dictAdsByTitle[ad.Title] {
 			if repo.dictAdsByTitle[ad.Title][idx].ID == ad.ID {
-				repo.dictAdsByTitle[ad.Title][idx] = *ad
+				repo.dictAdsByTitle[ad.Title][idx] = ad
 				break
 			}
 		}
@@ -72,7 +157,10 @@ func (repo *repositoryMap) ChangeAd(ad *ads.Ad) bool {
 	return ok
 }
 
-func (repo *repositoryMap) GetAdsByTitle(pattern string) []ads.Ad {
+func (repo *repositoryMap) GetAdsByTitle(_ context.Context, pattern string) []ads.Ad {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
 	var listByTitle []ads.Ad
 	for title, list := range repo.dictAdsByTitle {
 		if strings.HasPrefix(title, pattern) {
@@ -83,29 +171,90 @@ func (repo *repositoryMap) GetAdsByTitle(pattern string) []ads.Ad {
 	return listByTitle
 }
 
-func (repo *repositoryMap) GetAds(filters map[string]any) []ads.Ad {
-	var list []ads.Ad
-	var selectedAds = repo.dictAds
+func (repo *repositoryMap) GetAds(_ context.Context, filters map[string]any) []ads.Ad {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	return repo.selectAds(filters)
+}
+
+// GetAdsPage обходит объявления по возрастанию ID начиная после afterID и останавливается на limit-м
+// подходящем. Если фильтр индексирован и подходит редким объявлениям, обход пропускал бы почти всё,
+// поэтому тогда дешевле взять кандидатов из индекса и отсортировать их
+func (repo *repositoryMap) GetAdsPage(_ context.Context, filters map[string]any, afterID int64, limit int) []ads.Ad {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
 	if len(filters) == 0 {
-		selectedAds = SelectByPublished(selectedAds, true)
-	} else {
-		if filter, ok := filters[published]; ok {
-			selectedAds = SelectByPublished(selectedAds, filter)
+		filters = map[string]any{published: true}
+	}
+	ids := repo.index.after(afterID)
+	candidates, indexed := repo.index.candidates(filters)
+
+	var page []ads.Ad
+	// обход просматривает около limit*len(ids)/len(candidates) объявлений, выборка из индекса - len(candidates)
+	if indexed && int64(len(candidates))*int64(len(candidates)) < int64(limit)*int64(len(ids)) {
+		for id := range candidates {
+			if ad := repo.dictAds[id]; id > afterID && matchAd(ad, filters) {
+				page = append(page, ad)
+			}
 		}
+		sort.Slice(page, func(i, j int) bool {
+			return page[i].ID < page[j].ID
+		})
+		if len(page) > limit {
+			page = page[:limit]
+		}
+		return page
+	}
 
-		if filter, ok := filters[userId]; ok {
-			selectedAds = SelectByUserId(selectedAds, filter)
+	for _, id := range ids {
+		if len(page) == limit {
+			break
+		}
+		if ad, ok := repo.dictAds[id]; ok && matchAd(ad, filters) {
+			page = append(page, ad)
 		}
+	}
+	return page
+}
+
+// selectAds берёт кандидатов из самого маленького подходящего индекса и проверяет на них
+// остальные фильтры, так что время зависит от размера ответа, а не от числа объявлений
+func (repo *repositoryMap) selectAds(filters map[string]any) []ads.Ad {
+	if len(filters) == 0 {
+		filters = map[string]any{published: true}
+	}
 
-		if filter, ok := filters[dateCreating]; ok {
-			selectedAds = SelectByDateCreating(selectedAds, filter)
+	candidates, indexed := repo.index.candidates(filters)
+	var selectedAds []ads.Ad
+	if !indexed {
+		for _, ad := range repo.dictAds {
+			selectedAds = append(selectedAds, ad)
 		}
+		return selectedAds
 	}
+	selectedAds = make([]ads.Ad, 0, len(candidates))
+	for id := range candidates {
+		if ad := repo.dictAds[id]; matchAd(ad, filters) {
+			selectedAds = append(selectedAds, ad)
+		}
+	}
+	return selectedAds
+}
 
-	for _, val := range selectedAds {
-		list = append(list, val)
+// matchAd проверяет фильтры так же, как SelectByPublished, SelectByUserId и SelectByDateCreating
+func matchAd(ad ads.Ad, filters map[string]any) bool {
+	if filter, ok := filters[published]; ok && ad.Published != filter {
+		return false
 	}
-	return list
+	if filter, ok := filters[userId]; ok && ad.AuthorID != filter {
+		return false
+	}
+	if filter, ok := filters[dateCreating]; ok && ad.DateCreating.Format(dateFormat) != filter.(string)[:10] {
+		return false
+	}
+	return true
 }
 
 func SelectByPublished(dict map[int64]ads.Ad, published any) map[int64]ads.Ad {
@@ -140,7 +289,10 @@ func SelectByDateCreating(dict map[int64]ads.Ad, dateCreating any) map[int64]ads
 	return repoWithFilter
 }
 
-func (repo *repositoryMap) GetUserById(id int64) (users.User, error) {
+func (repo *repositoryMap) GetUserById(_ context.Context, id int64) (users.User, error) {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
 	user, ok := repo.dictUsers[id]
 	if !ok {
 		return user, app.IncorrectUserId
@@ -148,37 +300,87 @@ func (repo *repositoryMap) GetUserById(id int64) (users.User, error) {
 	return user, nil
 }
 
-func (repo *repositoryMap) AddUser(user *users.User) {
-	repo.mu.Lock()
-	defer repo.mu.Unlock()
+func (repo *repositoryMap) GetUserByEmail(_ context.Context, email string) (users.User, error) {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	id, ok := repo.userIndex.byEmail[users.NormalizeEmail(email)]
+	if !ok {
+		return users.User{}, app.UserNotFound
+	}
+	return repo.dictUsers[id], nil
+}
+
+func (repo *repositoryMap) AddUser(_ context.Context, user *users.User) error {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
 
-	repo.dictUsers[user.ID] = *user
+	if err := repo.userIndex.conflict(*user); err != nil {
+		return err
+	}
+	repo.setUser(*user)
 	repo.counterUsers++
+	return nil
 }
 
-func (repo *repositoryMap) ChangeUser(user *users.User) bool {
-	repo.mu.Lock()
-	defer repo.mu.Unlock()
+func (repo *repositoryMap) ChangeUser(_ context.Context, user *users.User) (bool, error) {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
 
-	_, ok := repo.dictUsers[user.ID]
-	if ok {
-		repo.dictUsers[user.ID] = *user
+	if _, ok := repo.dictUsers[user.ID]; !ok {
+		return false, nil
 	}
-	return ok
+	if err := repo.userIndex.conflict(*user); err != nil {
+		return false, err
+	}
+	repo.setUser(*user)
+	return true, nil
 }
 
-func (repo *repositoryMap) DeleteUser(userId int64) {
-	repo.mu.Lock()
-	defer repo.mu.Unlock()
+func (repo *repositoryMap) TouchUser(_ context.Context, id int64, at time.Time) {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
 
-	delete(repo.dictUsers, userId)
+	if user, ok := repo.dictUsers[id]; ok {
+		repo.rememberUser(id)
+		user.LastSeenAt = at
+		repo.dictUsers[id] = user
+	}
 }
 
-func (repo *repositoryMap) DeleteAd(adId int64) {
-	repo.mu.Lock()
-	defer repo.mu.Unlock()
+// вызывается только под repo.lock
+func (repo *repositoryMap) setUser(user users.User) {
+	repo.rememberUser(user.ID)
+	if old, ok := repo.dictUsers[user.ID]; ok {
+		repo.userIndex.remove(old)
+	}
+	repo.dictUsers[user.ID] = user
+	repo.userIndex.add(user)
+}
+
+func (repo *repositoryMap) DeleteUser(_ context.Context, userId int64) {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	if user, ok := repo.dictUsers[userId]; ok {
+		repo.rememberUser(userId)
+		repo.userIndex.remove(user)
+		delete(repo.dictUsers, userId)
+		repo.deleteUserTokens(userId)
+	}
+}
+
+func (repo *repositoryMap) DeleteAd(_ context.Context, adId int64) {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
 
-	title := repo.dictAds[adId].Title
+	ad, ok := repo.dictAds[adId]
+	if ok {
+		repo.rememberAd(adId, ad.Title)
+		repo.addOutboxEvent(outbox.AdDeleted, ad)
+	}
+
+	title := ad.Title
 
 	for idx, ad := range repo.dictAdsByTitle[title] {
 		if ad.ID == adId {
@@ -191,5 +393,116 @@ func (repo *repositoryMap) DeleteAd(adId int64) {
 		}
 	}
 
+	if ok {
+		repo.index.remove(ad)
+	}
 	delete(repo.dictAds, adId)
+	repo.index.compact(repo.dictAds)
+}
+
+// вызывается только под repo.lock
+func (repo *repositoryMap) addOutboxEvent(eventType outbox.EventType, ad ads.Ad) {
+	repo.outboxEvents = append(repo.outboxEvents, outbox.Event{ID: repo.counterEvents*repo.eventStride + repo.eventShard, Type: eventType, Ad: ad, CreatedAt: time.Now().UTC()})
+	repo.counterEvents++
+}
+
+func (repo *repositoryMap) GetOutboxEvents(_ context.Context, limit int) []outbox.Event {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	if limit <= 0 || limit > len(repo.outboxEvents) {
+		limit = len(repo.outboxEvents)
+	}
+	events := make([]outbox.Event, limit)
+	copy(events, repo.outboxEvents)
+	return events
+}
+
+func (repo *repositoryMap) DeleteOutboxEvent(_ context.Context, id int64) {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	for idx := range repo.outboxEvents {
+		if repo.outboxEvents[idx].ID == id {
+			repo.rememberOutbox()
+			repo.outboxEvents = append(repo.outboxEvents[:idx], repo.outboxEvents[idx+1:]...)
+			break
+		}
+	}
+}
+
+func (repo *repositoryMap) AddWebhook(_ context.Context, webhook *outbox.Webhook) {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	rememberEntry(repo, repo.webhooks, webhook.ID)
+	repo.webhooks[webhook.ID] = *webhook
+	repo.counterWebhooks++
+}
+
+func (repo *repositoryMap) GetWebhooksPrimaryKey(_ context.Context) int64 {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	return repo.counterWebhooks
+}
+
+func (repo *repositoryMap) GetWebhooks(_ context.Context) []outbox.Webhook {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	list := make([]outbox.Webhook, 0, len(repo.webhooks))
+	for _, webhook := range repo.webhooks {
+		list = append(list, webhook)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+func (repo *repositoryMap) DeleteWebhook(_ context.Context, id int64) bool {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	_, ok := repo.webhooks[id]
+	rememberEntry(repo, repo.webhooks, id)
+	delete(repo.webhooks, id)
+	return ok
+}
+
+func (repo *repositoryMap) AddDeadLetter(_ context.Context, letter *outbox.DeadLetter) {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	repo.deadLetters = append(repo.deadLetters, *letter)
+}
+
+func (repo *repositoryMap) GetDeadLetters(_ context.Context) []outbox.DeadLetter {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	list := make([]outbox.DeadLetter, len(repo.deadLetters))
+	copy(list, repo.deadLetters)
+	return list
+}
+
+func (repo *repositoryMap) CountAds(_ context.Context) (int, int) {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	return len(repo.dictAds), len(repo.index.byPublished[true])
+}
+
+func (repo *repositoryMap) CountUsers(_ context.Context) int {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	return len(repo.dictUsers)
+}
+
+// Ping берёт замок репозитория: если его держит зависшая запись, сервис перестанет быть готовым
+func (repo *repositoryMap) Ping(ctx context.Context) error {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	return ctx.Err()
 }
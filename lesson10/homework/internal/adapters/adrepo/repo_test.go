@@ -1,12 +1,16 @@
 package adrepo
 
 import (
+	"context"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"homework10/internal/ads"
 	"homework10/internal/app"
+	"homework10/internal/outbox"
 	"homework10/internal/users"
+	"sort"
+	"sync"
 	"testing"
 	"time"
 )
@@ -31,8 +35,8 @@ func TestRepoRun(t *testing.T) {
 func (s *RepositoryMapTestSuite) TestAddAd() {
 	// Test case for adding a new ad
 	expectedAd := ads.Ad{ID: 1, Title: "Ad 1", Text: "Ad 1 description", AuthorID: 1, Published: true}
-	s.repo.AddAd(&expectedAd)
-	ad, err := s.repo.GetAdById(1)
+	s.repo.AddAd(context.Background(), &expectedAd)
+	ad, err := s.repo.GetAdById(context.Background(), 1)
 	s.NoError(err)
 	s.Equal(expectedAd, ad)
 }
@@ -40,38 +44,38 @@ func (s *RepositoryMapTestSuite) TestAddAd() {
 func (s *RepositoryMapTestSuite) TestGetAdById() {
 	// Test case for a valid ad ID
 	expectedAd := ads.Ad{ID: 1, Title: "Ad 1", Text: "Ad 1 description", AuthorID: 1, Published: true}
-	s.repo.AddAd(&expectedAd)
-	ad, err := s.repo.GetAdById(1)
+	s.repo.AddAd(context.Background(), &expectedAd)
+	ad, err := s.repo.GetAdById(context.Background(), 1)
 	s.NoError(err)
 	s.Equal(expectedAd, ad)
 
 	// Test case for an invalid ad ID
-	_, err = s.repo.GetAdById(2)
+	_, err = s.repo.GetAdById(context.Background(), 2)
 	s.ErrorIs(err, app.IncorrectAdId)
 }
 
 func (s *RepositoryMapTestSuite) TestChangeAd() {
 	// Test case for changing an existing ad
 	expectedAd := ads.Ad{ID: 1, Title: "Ad 1", Text: "Ad 1 description", AuthorID: 1, Published: true}
-	s.repo.AddAd(&expectedAd)
+	s.repo.AddAd(context.Background(), &expectedAd)
 	expectedAd.Text = "Updated description"
-	s.True(s.repo.ChangeAd(&expectedAd))
-	ad, err := s.repo.GetAdById(1)
+	s.True(s.repo.ChangeAd(context.Background(), &expectedAd))
+	ad, err := s.repo.GetAdById(context.Background(), 1)
 	s.NoError(err)
 	s.Equal(expectedAd, ad)
 
 	// Test case for changing a non-existing ad
 	expectedAd.ID = 2
-	s.False(s.repo.ChangeAd(&expectedAd))
+	s.False(s.repo.ChangeAd(context.Background(), &expectedAd))
 }
 
 func (s *RepositoryMapTestSuite) TestRepositoryMap_DeleteAd() {
 	// Test case for delete an existing ad
 	expectedAd := ads.Ad{ID: 1, Title: "Ad 1", Text: "Ad 1 description", AuthorID: 1, Published: true}
-	s.repo.AddAd(&expectedAd)
+	s.repo.AddAd(context.Background(), &expectedAd)
 
-	s.repo.DeleteAd(expectedAd.ID)
-	_, err := s.repo.GetAdById(1)
+	s.repo.DeleteAd(context.Background(), expectedAd.ID)
+	_, err := s.repo.GetAdById(context.Background(), 1)
 	s.ErrorIs(err, app.IncorrectAdId)
 }
 
@@ -80,27 +84,49 @@ func (s *RepositoryMapTestSuite) TestRepositoryMap_GetAds() {
 	ad2 := ads.Ad{ID: 2, Title: "Ad 2", Text: "Ad 2 description", AuthorID: 1, Published: true}
 	ad3 := ads.Ad{ID: 3, Title: "Ad 3", Text: "Ad 3 description", AuthorID: 1, Published: true}
 
-	s.repo.AddAd(&ad1)
-	s.repo.AddAd(&ad2)
-	s.repo.AddAd(&ad3)
+	s.repo.AddAd(context.Background(), &ad1)
+	s.repo.AddAd(context.Background(), &ad2)
+	s.repo.AddAd(context.Background(), &ad3)
 
-	adsList := s.repo.GetAds(nil)
+	adsList := s.repo.GetAds(context.Background(), nil)
 	s.Len(adsList, 3)
 }
 
+func (s *RepositoryMapTestSuite) TestRepositoryMap_GetAdsPage() {
+	for id := int64(5); id >= 1; id-- {
+		ad := ads.Ad{ID: id, Title: fmt.Sprintf("Ad %d", id), AuthorID: 1, Published: id != 3}
+		s.repo.AddAd(context.Background(), &ad)
+	}
+
+	ids := func(list []ads.Ad) []int64 {
+		result := make([]int64, 0, len(list))
+		for _, ad := range list {
+			result = append(result, ad.ID)
+		}
+		return result
+	}
+	// без фильтров, как и GetAds, отдаёт только опубликованные
+	s.Equal([]int64{1, 2}, ids(s.repo.GetAdsPage(context.Background(), nil, -1, 2)))
+	s.Equal([]int64{4, 5}, ids(s.repo.GetAdsPage(context.Background(), nil, 2, 2)))
+	s.Empty(s.repo.GetAdsPage(context.Background(), nil, 5, 2))
+
+	filters := map[string]any{"published": false}
+	s.Equal([]int64{3}, ids(s.repo.GetAdsPage(context.Background(), filters, -1, 10)))
+}
+
 func (s *RepositoryMapTestSuite) TestRepositoryMap_GetAdsWithFiltersPublished() {
 	ad1 := ads.Ad{ID: 1, Title: "Ad 1", Text: "Ad 1 description", AuthorID: 1, Published: true}
 	ad2 := ads.Ad{ID: 2, Title: "Ad 2", Text: "Ad 2 description", AuthorID: 1, Published: false}
 	ad3 := ads.Ad{ID: 3, Title: "Ad 3", Text: "Ad 3 description", AuthorID: 1, Published: true}
 
-	s.repo.AddAd(&ad1)
-	s.repo.AddAd(&ad2)
-	s.repo.AddAd(&ad3)
+	s.repo.AddAd(context.Background(), &ad1)
+	s.repo.AddAd(context.Background(), &ad2)
+	s.repo.AddAd(context.Background(), &ad3)
 
 	filters := map[string]any{
 		"published": true,
 	}
-	adsList := s.repo.GetAds(filters)
+	adsList := s.repo.GetAds(context.Background(), filters)
 	s.Len(adsList, 2)
 }
 
@@ -109,14 +135,14 @@ func (s *RepositoryMapTestSuite) TestRepositoryMap_GetAdsWithFiltersUserId() {
 	ad2 := ads.Ad{ID: 2, Title: "Ad 2", Text: "Ad 2 description", AuthorID: 1, Published: false}
 	ad3 := ads.Ad{ID: 3, Title: "Ad 3", Text: "Ad 3 description", AuthorID: 1, Published: true}
 
-	s.repo.AddAd(&ad1)
-	s.repo.AddAd(&ad2)
-	s.repo.AddAd(&ad3)
+	s.repo.AddAd(context.Background(), &ad1)
+	s.repo.AddAd(context.Background(), &ad2)
+	s.repo.AddAd(context.Background(), &ad3)
 
 	filters := map[string]any{
 		"user_id": int64(1),
 	}
-	adsList := s.repo.GetAds(filters)
+	adsList := s.repo.GetAds(context.Background(), filters)
 	s.Len(adsList, 2)
 }
 
@@ -135,21 +161,156 @@ func (s *RepositoryMapTestSuite) TestRepositoryMap_GetAdsWithAllFilters() {
 	ad4 := ads.Ad{ID: 4, Title: "Ad 4", Text: "Ad 4 description", AuthorID: 1, Published: true, DateCreating: time1}
 	ad5 := ads.Ad{ID: 5, Title: "Ad 5", Text: "Ad 5 description", AuthorID: 1, Published: true, DateCreating: time1}
 
-	s.repo.AddAd(&ad1)
-	s.repo.AddAd(&ad2)
-	s.repo.AddAd(&ad3)
-	s.repo.AddAd(&ad4)
-	s.repo.AddAd(&ad5)
+	s.repo.AddAd(context.Background(), &ad1)
+	s.repo.AddAd(context.Background(), &ad2)
+	s.repo.AddAd(context.Background(), &ad3)
+	s.repo.AddAd(context.Background(), &ad4)
+	s.repo.AddAd(context.Background(), &ad5)
 
 	filters := map[string]any{
 		"user_id":       int64(1),
 		"published":     true,
 		"date_creating": timeStr1,
 	}
-	adsList := s.repo.GetAds(filters)
+	adsList := s.repo.GetAds(context.Background(), filters)
 	s.Len(adsList, 2)
 }
 
+// selectAdsFullScan - выборка перебором всех объявлений, как до индексов
+func selectAdsFullScan(dict map[int64]ads.Ad, filters map[string]any) map[int64]ads.Ad {
+	if len(filters) == 0 {
+		return SelectByPublished(dict, true)
+	}
+	if filter, ok := filters[published]; ok {
+		dict = SelectByPublished(dict, filter)
+	}
+	if filter, ok := filters[userId]; ok {
+		dict = SelectByUserId(dict, filter)
+	}
+	if filter, ok := filters[dateCreating]; ok {
+		dict = SelectByDateCreating(dict, filter)
+	}
+	return dict
+}
+
+func adIDs(list []ads.Ad) []int64 {
+	ids := make([]int64, 0, len(list))
+	for _, ad := range list {
+		ids = append(ids, ad.ID)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// allAds собирает объявления всех шардов в одну таблицу
+func allAds(repo app.Repository) map[int64]ads.Ad {
+	shards := []*repositoryMap{}
+	switch repo := repo.(type) {
+	case *repositoryMap:
+		shards = append(shards, repo)
+	case *shardedRepository:
+		shards = repo.shards
+	}
+
+	dict := make(map[int64]ads.Ad)
+	for _, shard := range shards {
+		for id, ad := range shard.dictAds {
+			dict[id] = ad
+		}
+	}
+	return dict
+}
+
+func mapIDs(dict map[int64]ads.Ad) []int64 {
+	ids := make([]int64, 0, len(dict))
+	for id := range dict {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func addIndexedAds(repo app.Repository, count int) {
+	day := time.Date(2023, 4, 1, 12, 0, 0, 0, time.UTC)
+	for id := int64(0); id < int64(count); id++ {
+		repo.AddAd(context.Background(), &ads.Ad{
+			ID:           id,
+			Title:        fmt.Sprintf("Ad %d", id),
+			AuthorID:     id % 100,
+			Published:    id%3 != 0,
+			DateCreating: day.AddDate(0, 0, int(id%30)),
+		})
+	}
+}
+
+func (s *RepositoryMapTestSuite) TestRepositoryMap_GetAdsIndexMatchesFullScan() {
+	ctx := context.Background()
+	addIndexedAds(s.repo, 1000)
+	for id := int64(0); id < 1000; id += 7 {
+		ad, _ := s.repo.GetAdById(ctx, id)
+		ad.Published = !ad.Published
+		s.repo.ChangeAd(ctx, &ad)
+	}
+	for id := int64(0); id < 1000; id += 11 {
+		s.repo.DeleteAd(ctx, id)
+	}
+	// повторный AddAd перезаписывает объявление вместе с его местом в индексах
+	s.repo.AddAd(ctx, &ads.Ad{ID: 1, Title: "Ad 1", AuthorID: 7, Published: false})
+
+	dictAds := allAds(s.repo)
+	for _, filters := range []map[string]any{
+		nil,
+		{"published": false},
+		{"user_id": int64(7)},
+		{"user_id": int64(1000)},
+		{"date_creating": "2023-04-05"},
+		{"date_creating": "2023-04-05 00:00:00 +0000 UTC"},
+		{"user_id": int64(7), "published": true},
+		{"user_id": int64(7), "published": false, "date_creating": "2023-04-08"},
+		{"user_id": 7},
+		{"published": "true"},
+		{"unknown": 1},
+	} {
+		s.Equal(mapIDs(selectAdsFullScan(dictAds, filters)), adIDs(s.repo.GetAds(ctx, filters)), "filters %v", filters)
+		s.Equal(mapIDs(selectAdsFullScan(dictAds, filters)), pagedIDs(s.repo, filters, 10), "filters %v", filters)
+	}
+
+	total, published := s.repo.CountAds(ctx)
+	s.Equal(len(dictAds), total)
+	s.Equal(len(SelectByPublished(dictAds, true)), published)
+}
+
+// pagedIDs читает все объявления страницами по limit, как ExportAds
+func pagedIDs(repo app.Repository, filters map[string]any, limit int) []int64 {
+	ids := []int64{}
+	afterID := int64(-1)
+	for {
+		page := repo.GetAdsPage(context.Background(), filters, afterID, limit)
+		ids = append(ids, adIDs(page)...)
+		if len(page) < limit {
+			return ids
+		}
+		afterID = page[len(page)-1].ID
+	}
+}
+
+func (s *RepositoryMapTestSuite) TestRepositoryMap_GetAdsPageAfterDelete() {
+	ctx := context.Background()
+	addIndexedAds(s.repo, 300)
+	for id := int64(0); id < 300; id++ {
+		if id%10 != 0 {
+			s.repo.DeleteAd(ctx, id)
+		}
+	}
+	s.repo.AddAd(ctx, &ads.Ad{ID: 5, Title: "Ad 5", AuthorID: 5, Published: true})
+
+	dictAds := allAds(s.repo)
+	s.Len(dictAds, 31)
+	for _, filters := range []map[string]any{{"published": false}, {"published": true}, {"user_id": int64(10)}} {
+		s.Equal(mapIDs(selectAdsFullScan(dictAds, filters)), pagedIDs(s.repo, filters, 3), "filters %v", filters)
+	}
+}
+
 func (s *RepositoryMapTestSuite) TestRepositoryMap_GetAdsPrimaryKey() {
 	ad1 := ads.Ad{ID: 1, Title: "Ad 1", Text: "Ad 1 description", AuthorID: 2, Published: true}
 	ad2 := ads.Ad{ID: 2, Title: "Ad 2", Text: "Ad 2 description", AuthorID: 1, Published: false}
@@ -157,13 +318,13 @@ func (s *RepositoryMapTestSuite) TestRepositoryMap_GetAdsPrimaryKey() {
 	ad4 := ads.Ad{ID: 4, Title: "Ad 4", Text: "Ad 4 description", AuthorID: 1, Published: true}
 	ad5 := ads.Ad{ID: 5, Title: "Ad 5", Text: "Ad 5 description", AuthorID: 1, Published: true}
 
-	s.repo.AddAd(&ad1)
-	s.repo.AddAd(&ad2)
-	s.repo.AddAd(&ad3)
-	s.repo.AddAd(&ad4)
-	s.repo.AddAd(&ad5)
+	s.repo.AddAd(context.Background(), &ad1)
+	s.repo.AddAd(context.Background(), &ad2)
+	s.repo.AddAd(context.Background(), &ad3)
+	s.repo.AddAd(context.Background(), &ad4)
+	s.repo.AddAd(context.Background(), &ad5)
 
-	primaryKey := s.repo.GetAdsPrimaryKey()
+	primaryKey := s.repo.GetAdsPrimaryKey(context.Background())
 	s.Equal(primaryKey, int64(5))
 }
 
@@ -172,58 +333,154 @@ func (s *RepositoryMapTestSuite) TestRepositoryMap_GetUsersPrimaryKey() {
 	user2 := users.User{ID: 2, Nickname: "nickname 2", Email: "email 2"}
 	user3 := users.User{ID: 3, Nickname: "nickname 3", Email: "email 3"}
 
-	s.repo.AddUser(&user1)
-	s.repo.AddUser(&user2)
-	s.repo.AddUser(&user3)
+	s.repo.AddUser(context.Background(), &user1)
+	s.repo.AddUser(context.Background(), &user2)
+	s.repo.AddUser(context.Background(), &user3)
 
-	primaryKey := s.repo.GetUsersPrimaryKey()
+	primaryKey := s.repo.GetUsersPrimaryKey(context.Background())
 	s.Equal(primaryKey, int64(3))
 }
 
 func (s *RepositoryMapTestSuite) TestRepositoryMap_AddUser() {
 	expect := users.User{ID: 1, Nickname: "nickname 1", Email: "email 1"}
 
-	s.repo.AddUser(&expect)
-	got, err := s.repo.GetUserById(expect.ID)
+	s.repo.AddUser(context.Background(), &expect)
+	got, err := s.repo.GetUserById(context.Background(), expect.ID)
 	s.NoError(err)
 	s.Equal(expect, got)
 }
 
 func (s *RepositoryMapTestSuite) TestRepositoryMap_GetUser() {
 	expect := users.User{ID: 1, Nickname: "nickname 1", Email: "email 1"}
-	s.repo.AddUser(&expect)
+	s.repo.AddUser(context.Background(), &expect)
 
-	got, err := s.repo.GetUserById(expect.ID)
+	got, err := s.repo.GetUserById(context.Background(), expect.ID)
 	s.NoError(err)
 	s.Equal(expect, got)
 
-	_, err = s.repo.GetUserById(2)
+	_, err = s.repo.GetUserById(context.Background(), 2)
 	s.ErrorIs(err, app.IncorrectUserId)
 }
 
 func (s *RepositoryMapTestSuite) TestRepositoryMap_DeleteUser() {
 	expect := users.User{ID: 1, Nickname: "nickname 1", Email: "email 1"}
-	s.repo.AddUser(&expect)
+	s.repo.AddUser(context.Background(), &expect)
 
-	s.repo.DeleteUser(expect.ID)
+	s.repo.DeleteUser(context.Background(), expect.ID)
 
-	_, err := s.repo.GetUserById(expect.ID)
+	_, err := s.repo.GetUserById(context.Background(), expect.ID)
 	s.ErrorIs(err, app.IncorrectUserId)
 }
 
 func (s *RepositoryMapTestSuite) TestRepositoryMap_ChangeUser() {
 	// Test case for changing an existing user
 	expected := users.User{ID: 1, Nickname: "nickname 1", Email: "email 1"}
-	s.repo.AddUser(&expected)
+	s.repo.AddUser(context.Background(), &expected)
 	expected.Nickname = "Updated nickname"
-	s.True(s.repo.ChangeUser(&expected))
-	got, err := s.repo.GetUserById(1)
+	ok, err := s.repo.ChangeUser(context.Background(), &expected)
+	s.NoError(err)
+	s.True(ok)
+	got, err := s.repo.GetUserById(context.Background(), 1)
 	s.NoError(err)
 	s.Equal(expected, got)
 
 	// Test case for changing a non-existing user
 	expected.ID = 2
-	s.False(s.repo.ChangeUser(&expected))
+	ok, err = s.repo.ChangeUser(context.Background(), &expected)
+	s.NoError(err)
+	s.False(ok)
+}
+
+func (s *RepositoryMapTestSuite) TestRepositoryMap_UniqueUser() {
+	ctx := context.Background()
+	buda := users.User{ID: 1, Nickname: "Buda", Email: "buda@phystech.edu"}
+	s.NoError(s.repo.AddUser(ctx, &buda))
+
+	// ник сравнивается без учёта регистра, email - после нормализации
+	s.ErrorIs(s.repo.AddUser(ctx, &users.User{ID: 2, Nickname: "oleg", Email: " BUDA@phystech.edu"}), app.EmailTaken)
+	s.ErrorIs(s.repo.AddUser(ctx, &users.User{ID: 2, Nickname: " bUDA", Email: "oleg@phystech.edu"}), app.NicknameTaken)
+	s.Equal(1, s.repo.CountUsers(ctx))
+
+	oleg := users.User{ID: 2, Nickname: "oleg", Email: "oleg@phystech.edu"}
+	s.NoError(s.repo.AddUser(ctx, &oleg))
+
+	// пользователь может сохранить свои же email и ник
+	buda.Nickname = "BUDA"
+	ok, err := s.repo.ChangeUser(ctx, &buda)
+	s.NoError(err)
+	s.True(ok)
+
+	oleg.Email = "buda@phystech.edu"
+	ok, err = s.repo.ChangeUser(ctx, &oleg)
+	s.ErrorIs(err, app.EmailTaken)
+	s.False(ok)
+
+	// старые email и ник освобождаются после изменения и удаления
+	oleg.Email = "oleg.new@phystech.edu"
+	_, err = s.repo.ChangeUser(ctx, &oleg)
+	s.NoError(err)
+	s.repo.DeleteUser(ctx, buda.ID)
+	s.NoError(s.repo.AddUser(ctx, &users.User{ID: 3, Nickname: "buda", Email: "oleg@phystech.edu"}))
+}
+
+func (s *RepositoryMapTestSuite) TestRepositoryMap_GetUserByEmail() {
+	ctx := context.Background()
+	expect := users.User{ID: 1, Nickname: "buda", Email: "buda@phystech.edu"}
+	s.NoError(s.repo.AddUser(ctx, &expect))
+
+	got, err := s.repo.GetUserByEmail(ctx, "buda@phystech.edu")
+	s.NoError(err)
+	s.Equal(expect, got)
+
+	_, err = s.repo.GetUserByEmail(ctx, "oleg@phystech.edu")
+	s.ErrorIs(err, app.UserNotFound)
+}
+
+func (s *RepositoryMapTestSuite) TestRepositoryMap_UniqueUserRollback() {
+	ctx := context.Background()
+	failure := fmt.Errorf("failure")
+	err := s.repo.WithTx(ctx, func(tx app.Repository) error {
+		s.NoError(tx.AddUser(ctx, &users.User{ID: 0, Nickname: "buda", Email: "buda@phystech.edu"}))
+		return failure
+	})
+	s.ErrorIs(err, failure)
+
+	// откат освобождает email и ник
+	s.NoError(s.repo.AddUser(ctx, &users.User{ID: 0, Nickname: "buda", Email: "buda@phystech.edu"}))
+	_, err = s.repo.GetUserByEmail(ctx, "buda@phystech.edu")
+	s.NoError(err)
+}
+
+func (s *RepositoryMapTestSuite) TestRepositoryMap_Tokens() {
+	ctx := context.Background()
+	s.NoError(s.repo.AddUser(ctx, &users.User{ID: 0, Nickname: "buda", Email: "buda@phystech.edu"}))
+	verify := users.Token{Hash: "v1", UserID: 0, Email: "buda@phystech.edu", Purpose: users.PurposeVerifyEmail}
+	s.repo.AddToken(ctx, verify)
+	s.repo.AddToken(ctx, users.Token{Hash: "r1", UserID: 0, Email: "buda@phystech.edu", Purpose: users.PurposeResetPassword})
+
+	// новый токен того же назначения заменяет прежний, токен другого назначения остаётся
+	s.repo.AddToken(ctx, users.Token{Hash: "v2", UserID: 0, Email: "buda@phystech.edu", Purpose: users.PurposeVerifyEmail})
+	_, ok := s.repo.TakeToken(ctx, "v1")
+	s.False(ok)
+
+	// погашенный в откатившейся транзакции токен возвращается
+	failure := fmt.Errorf("failure")
+	err := s.repo.WithTx(ctx, func(tx app.Repository) error {
+		token, ok := tx.TakeToken(ctx, "v2")
+		s.True(ok)
+		s.Equal(users.PurposeVerifyEmail, token.Purpose)
+		return failure
+	})
+	s.ErrorIs(err, failure)
+	_, ok = s.repo.TakeToken(ctx, "v2")
+	s.True(ok)
+	_, ok = s.repo.TakeToken(ctx, "v2")
+	s.False(ok, "токен одноразовый")
+
+	// удаление пользователя удаляет его токены
+	s.repo.DeleteUser(ctx, 0)
+	_, ok = s.repo.TakeToken(ctx, "r1")
+	s.False(ok)
 }
 
 func (s *RepositoryMapTestSuite) TestRepositoryMap_GetAdsByTitle() {
@@ -232,30 +489,308 @@ func (s *RepositoryMapTestSuite) TestRepositoryMap_GetAdsByTitle() {
 	ad3 := ads.Ad{ID: 3, Title: "Another", Text: "Ad 3 description", AuthorID: 1, Published: true}
 	ad4 := ads.Ad{ID: 4, Title: "All", Text: "Ad 4 description", AuthorID: 1, Published: true}
 
-	s.repo.AddAd(&ad1)
-	s.repo.AddAd(&ad2)
-	s.repo.AddAd(&ad3)
-	s.repo.AddAd(&ad4)
+	s.repo.AddAd(context.Background(), &ad1)
+	s.repo.AddAd(context.Background(), &ad2)
+	s.repo.AddAd(context.Background(), &ad3)
+	s.repo.AddAd(context.Background(), &ad4)
 
 	title := "Ad"
-	adsList := s.repo.GetAdsByTitle(title)
+	adsList := s.repo.GetAdsByTitle(context.Background(), title)
 	s.Len(adsList, 2)
 }
 
+func (s *RepositoryMapTestSuite) TestRepositoryMap_OutboxEvents() {
+	ad := ads.Ad{ID: 0, Title: "Ad", Text: "Ad description", AuthorID: 1}
+	s.repo.AddAd(context.Background(), &ad)
+	ad.Text = "Updated description"
+	s.repo.ChangeAd(context.Background(), &ad)
+	ad.Published = true
+	s.repo.ChangeAd(context.Background(), &ad)
+	s.repo.DeleteAd(context.Background(), ad.ID)
+	s.repo.DeleteAd(context.Background(), ad.ID)
+
+	events := s.repo.GetOutboxEvents(context.Background(), 10)
+	s.Len(events, 4)
+	s.Equal(outbox.AdCreated, events[0].Type)
+	s.Equal(outbox.AdUpdated, events[1].Type)
+	s.Equal(outbox.AdStatusChanged, events[2].Type)
+	s.Equal(outbox.AdDeleted, events[3].Type)
+	s.True(events[3].Ad.Published)
+
+	s.Len(s.repo.GetOutboxEvents(context.Background(), 2), 2)
+
+	s.repo.DeleteOutboxEvent(context.Background(), events[0].ID)
+	s.Len(s.repo.GetOutboxEvents(context.Background(), 10), 3)
+}
+
+func (s *RepositoryMapTestSuite) TestRepositoryMap_ChangeAds() {
+	for id := int64(1); id <= 3; id++ {
+		ad := ads.Ad{ID: id, Title: fmt.Sprintf("Ad %d", id), AuthorID: 1}
+		s.repo.AddAd(context.Background(), &ad)
+	}
+	publish := func(ad *ads.Ad) error {
+		ad.Published = true
+		return nil
+	}
+	published := func() int {
+		_, count := s.repo.CountAds(context.Background())
+		return count
+	}
+
+	// одного объявления нет - не меняется ни одно и событий нет
+	_, err := s.repo.ChangeAds(context.Background(), []int64{1, 4, 2}, publish)
+	s.ErrorIs(err, app.IncorrectAdId)
+	s.Zero(published())
+	s.Len(s.repo.GetOutboxEvents(context.Background(), 10), 3)
+
+	// change отказала на втором объявлении - первое тоже не сохранено
+	_, err = s.repo.ChangeAds(context.Background(), []int64{1, 2}, func(ad *ads.Ad) error {
+		if ad.ID == 2 {
+			return app.IncorrectUserId
+		}
+		return publish(ad)
+	})
+	s.ErrorIs(err, app.IncorrectUserId)
+	s.ErrorContains(err, "ad 2")
+	s.Zero(published())
+
+	changed, err := s.repo.ChangeAds(context.Background(), []int64{3, 1}, publish)
+	s.NoError(err)
+	s.Require().Len(changed, 2)
+	s.Equal(int64(3), changed[0].ID)
+	s.Equal(2, published())
+
+	events := s.repo.GetOutboxEvents(context.Background(), 10)
+	s.Len(events, 5)
+	s.Equal(outbox.AdStatusChanged, events[4].Type)
+}
+
+func (s *RepositoryMapTestSuite) TestRepositoryMap_WithTxCommit() {
+	ctx := context.Background()
+	err := s.repo.WithTx(ctx, func(tx app.Repository) error {
+		user := users.User{ID: tx.GetUsersPrimaryKey(ctx), Nickname: "buda"}
+		tx.AddUser(ctx, &user)
+		ad := ads.Ad{ID: tx.GetAdsPrimaryKey(ctx), Title: "Ad", AuthorID: user.ID}
+		tx.AddAd(ctx, &ad)
+		return nil
+	})
+	s.NoError(err)
+
+	_, err = s.repo.GetUserById(ctx, 0)
+	s.NoError(err)
+	_, err = s.repo.GetAdById(ctx, 0)
+	s.NoError(err)
+	s.Equal(int64(1), s.repo.GetAdsPrimaryKey(ctx))
+	s.Len(s.repo.GetOutboxEvents(ctx, 10), 1)
+}
+
+func (s *RepositoryMapTestSuite) TestRepositoryMap_WithTxRollback() {
+	ctx := context.Background()
+	user := users.User{ID: 0, Nickname: "buda"}
+	s.repo.AddUser(ctx, &user)
+	kept := ads.Ad{ID: 0, Title: "Kept", AuthorID: 0}
+	s.repo.AddAd(ctx, &kept)
+	changed := ads.Ad{ID: 1, Title: "Changed", AuthorID: 0}
+	s.repo.AddAd(ctx, &changed)
+	webhook := outbox.Webhook{ID: 0, URL: "http://localhost/1"}
+	s.repo.AddWebhook(ctx, &webhook)
+	events := s.repo.GetOutboxEvents(ctx, 10)
+	s.repo.DeleteOutboxEvent(ctx, events[0].ID)
+
+	before := func() []any {
+		total, published := s.repo.CountAds(ctx)
+		byTitle := s.repo.GetAdsByTitle(ctx, "")
+		sort.Slice(byTitle, func(i, j int) bool { return byTitle[i].ID < byTitle[j].ID })
+		return []any{
+			s.repo.GetAdsPage(ctx, map[string]any{"user_id": int64(0)}, -1, 10), byTitle,
+			s.repo.GetAdsPage(ctx, map[string]any{"published": true}, -1, 10),
+			total, published, s.repo.CountUsers(ctx), s.repo.GetWebhooks(ctx), s.repo.GetOutboxEvents(ctx, 10),
+			s.repo.GetAdsPrimaryKey(ctx), s.repo.GetUsersPrimaryKey(ctx), s.repo.GetWebhooksPrimaryKey(ctx),
+		}
+	}
+	snapshot := before()
+
+	failure := fmt.Errorf("failure")
+	err := s.repo.WithTx(ctx, func(tx app.Repository) error {
+		ad := ads.Ad{ID: tx.GetAdsPrimaryKey(ctx), Title: "Kept", AuthorID: 0}
+		tx.AddAd(ctx, &ad)
+		changed.Published = true
+		tx.ChangeAd(ctx, &changed)
+		tx.DeleteAd(ctx, kept.ID)
+		tx.ChangeUser(ctx, &users.User{ID: 0, Nickname: "renamed"})
+		tx.AddUser(ctx, &users.User{ID: tx.GetUsersPrimaryKey(ctx), Nickname: "new"})
+		tx.DeleteWebhook(ctx, webhook.ID)
+		tx.AddWebhook(ctx, &outbox.Webhook{ID: tx.GetWebhooksPrimaryKey(ctx), URL: "http://localhost/2"})
+		tx.DeleteOutboxEvent(ctx, events[1].ID)
+		tx.AddDeadLetter(ctx, &outbox.DeadLetter{})
+		return failure
+	})
+	s.ErrorIs(err, failure)
+
+	s.Equal(snapshot, before())
+	got, err := s.repo.GetUserById(ctx, 0)
+	s.NoError(err)
+	s.Equal("buda", got.Nickname)
+	s.Empty(s.repo.GetDeadLetters(ctx))
+}
+
+func (s *RepositoryMapTestSuite) TestRepositoryMap_WithTxPanic() {
+	ctx := context.Background()
+	s.Panics(func() {
+		_ = s.repo.WithTx(ctx, func(tx app.Repository) error {
+			tx.AddUser(ctx, &users.User{ID: 0, Nickname: "buda"})
+			panic("boom")
+		})
+	})
+	s.Zero(s.repo.CountUsers(ctx))
+
+	// замок отпущен и после паники
+	s.NoError(s.repo.WithTx(ctx, func(app.Repository) error { return nil }))
+}
+
+func (s *RepositoryMapTestSuite) TestRepositoryMap_WithTxSavepoint() {
+	ctx := context.Background()
+	failure := fmt.Errorf("failure")
+	err := s.repo.WithTx(ctx, func(tx app.Repository) error {
+		tx.AddUser(ctx, &users.User{ID: 0, Nickname: "outer"})
+		inner := tx.WithTx(ctx, func(tx app.Repository) error {
+			tx.AddUser(ctx, &users.User{ID: 1, Nickname: "inner"})
+			return failure
+		})
+		s.ErrorIs(inner, failure)
+		return nil
+	})
+	s.NoError(err)
+
+	_, err = s.repo.GetUserById(ctx, 0)
+	s.NoError(err)
+	_, err = s.repo.GetUserById(ctx, 1)
+	s.ErrorIs(err, app.IncorrectUserId)
+	s.Equal(int64(1), s.repo.GetUsersPrimaryKey(ctx))
+}
+
+func (s *RepositoryMapTestSuite) TestRepositoryMap_ConcurrentCreate() {
+	ctx := context.Background()
+	service := app.NewApp(s.repo)
+	user, err := service.CreateUser(ctx, "buda", "buda@phystech.edu")
+	s.Require().NoError(err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.CreateAd(ctx, "Ad", "text", user.ID)
+			s.NoError(err)
+		}()
+	}
+	wg.Wait()
+
+	total, _ := s.repo.CountAds(ctx)
+	s.Equal(50, total, "ID объявлений не должны повторяться")
+}
+
+func (s *RepositoryMapTestSuite) TestRepositoryMap_Webhooks() {
+	webhook1 := outbox.Webhook{ID: s.repo.GetWebhooksPrimaryKey(context.Background()), URL: "http://localhost/1", Secret: "secret"}
+	s.repo.AddWebhook(context.Background(), &webhook1)
+	webhook2 := outbox.Webhook{ID: s.repo.GetWebhooksPrimaryKey(context.Background()), URL: "http://localhost/2", Secret: "secret"}
+	s.repo.AddWebhook(context.Background(), &webhook2)
+
+	s.Equal([]outbox.Webhook{webhook1, webhook2}, s.repo.GetWebhooks(context.Background()))
+
+	s.True(s.repo.DeleteWebhook(context.Background(), webhook1.ID))
+	s.False(s.repo.DeleteWebhook(context.Background(), webhook1.ID))
+	s.Equal([]outbox.Webhook{webhook2}, s.repo.GetWebhooks(context.Background()))
+
+	letter := outbox.DeadLetter{Event: outbox.Event{ID: 1, Type: outbox.AdCreated}, WebhookID: webhook2.ID, Attempts: 5}
+	s.repo.AddDeadLetter(context.Background(), &letter)
+	s.Equal([]outbox.DeadLetter{letter}, s.repo.GetDeadLetters(context.Background()))
+}
+
+func (s *RepositoryMapTestSuite) TestRepositoryMap_Counts() {
+	s.repo.AddAd(context.Background(), &ads.Ad{ID: 1, Title: "Ad 1", Text: "Ad 1 description", AuthorID: 1, Published: true})
+	s.repo.AddAd(context.Background(), &ads.Ad{ID: 2, Title: "Ad 2", Text: "Ad 2 description", AuthorID: 1, Published: false})
+	s.repo.AddUser(context.Background(), &users.User{ID: 1, Nickname: "nickname 1", Email: "email 1"})
+
+	total, published := s.repo.CountAds(context.Background())
+	s.Equal(2, total)
+	s.Equal(1, published)
+	s.Equal(1, s.repo.CountUsers(context.Background()))
+}
+
 // test for checking speed processing
+func (s *RepositoryMapTestSuite) TestRepositoryMap_Ping() {
+	s.NoError(s.repo.Ping(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.ErrorIs(s.repo.Ping(ctx), context.Canceled)
+}
+
 func BenchmarkRepoRun(b *testing.B) {
 	b.Run("Get ad by id", BenchmarkGetAdById)
 	b.Run("Get ads By title", BenchmarkGetAdsByTitle)
+	b.Run("Get ads with filters", BenchmarkGetAdsWithFilters)
+	b.Run("Get ads page", BenchmarkGetAdsPage)
+}
+
+// BenchmarkGetAdsWithFilters сравнивает выборку по индексам с полным перебором на 100 тысячах объявлений
+func BenchmarkGetAdsWithFilters(b *testing.B) {
+	repo := New()
+	addIndexedAds(repo, 100_000)
+	dictAds := repo.(*repositoryMap).dictAds
+
+	for _, bc := range []struct {
+		name    string
+		filters map[string]any
+	}{
+		{"author", map[string]any{"user_id": int64(7)}},
+		{"author and published", map[string]any{"user_id": int64(7), "published": true}},
+		{"date", map[string]any{"date_creating": "2023-04-05"}},
+		{"published", map[string]any{"published": false}},
+	} {
+		b.Run(bc.name+"/full scan", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = selectAdsFullScan(dictAds, bc.filters)
+			}
+		})
+		b.Run(bc.name+"/index", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = repo.GetAds(context.Background(), bc.filters)
+			}
+		})
+	}
+}
+
+// BenchmarkGetAdsPage читает одну страницу выгрузки из середины 100 тысяч объявлений
+func BenchmarkGetAdsPage(b *testing.B) {
+	repo := New()
+	addIndexedAds(repo, 100_000)
+
+	for _, bc := range []struct {
+		name    string
+		filters map[string]any
+	}{
+		{"author", map[string]any{"user_id": int64(7)}},
+		{"published", map[string]any{"published": false}},
+		{"unfiltered", map[string]any{"unknown": 1}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = repo.GetAdsPage(context.Background(), bc.filters, 50_000, 500)
+			}
+		})
+	}
 }
 
 func BenchmarkGetAdById(b *testing.B) {
 	repo := New()
 	ad := &ads.Ad{ID: 1, Title: "Test ad", Text: "Test text", AuthorID: 1}
-	repo.AddAd(ad)
+	repo.AddAd(context.Background(), ad)
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		_, _ = repo.GetAdById(1)
+		_, _ = repo.GetAdById(context.Background(), 1)
 	}
 }
 
@@ -263,12 +798,12 @@ func BenchmarkGetAdsByTitle(b *testing.B) {
 	repo := New()
 	ad1 := &ads.Ad{ID: 1, Title: "Test ad 1", Text: "Test text 1", AuthorID: 1}
 	ad2 := &ads.Ad{ID: 2, Title: "Test ad 2", Text: "Test text 2", AuthorID: 1}
-	repo.AddAd(ad1)
-	repo.AddAd(ad2)
+	repo.AddAd(context.Background(), ad1)
+	repo.AddAd(context.Background(), ad2)
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		_ = repo.GetAdsByTitle("Test ad")
+		_ = repo.GetAdsByTitle(context.Background(), "Test ad")
 	}
 }
 
@@ -279,8 +814,8 @@ func FuzzGetAdById(f *testing.F) {
 	// Test for correct ID.
 	f.Fuzz(func(t *testing.T, id int64) {
 		expect := ads.Ad{ID: id, Title: "Ad 1", Text: "Ad 1 description", AuthorID: 1, Published: true}
-		repo.AddAd(&expect)
-		got, err := repo.GetAdById(id)
+		repo.AddAd(context.Background(), &expect)
+		got, err := repo.GetAdById(context.Background(), id)
 
 		assert.NoError(t, err)
 		assert.Equal(t, got, expect)
@@ -294,13 +829,13 @@ type TestGetAd struct {
 
 func (s *RepositoryMapTestSuite) TestRepositoryMap_GetAdsTable() {
 	expectedAd1 := ads.Ad{ID: 1, Title: "Ad 1", Text: "Ad 1 description", AuthorID: 1, Published: true}
-	s.repo.AddAd(&expectedAd1)
+	s.repo.AddAd(context.Background(), &expectedAd1)
 
 	expectedAd2 := ads.Ad{ID: 2, Title: "Ad 2", Text: "Ad 2 description", AuthorID: 2, Published: true}
-	s.repo.AddAd(&expectedAd2)
+	s.repo.AddAd(context.Background(), &expectedAd2)
 
 	expectedAd3 := ads.Ad{ID: 3, Title: "Ad 3", Text: "Ad 3 description", AuthorID: 3, Published: false}
-	s.repo.AddAd(&expectedAd3)
+	s.repo.AddAd(context.Background(), &expectedAd3)
 
 	tests := []TestGetAd{
 		{1, expectedAd1},
@@ -311,7 +846,7 @@ func (s *RepositoryMapTestSuite) TestRepositoryMap_GetAdsTable() {
 	for _, test := range tests {
 		test := test // create a new variable for each test case to avoid variable shadowing
 		s.Run(fmt.Sprintf("Test case %d", test.Id), func() {
-			ad, err := s.repo.GetAdById(test.Id)
+			ad, err := s.repo.GetAdById(context.Background(), test.Id)
 			s.NoError(err)
 			s.Equal(ad, test.ExpectAd)
 		})
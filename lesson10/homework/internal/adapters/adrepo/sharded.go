@@ -0,0 +1,320 @@
+package adrepo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"homework10/internal/ads"
+	"homework10/internal/app"
+	"homework10/internal/outbox"
+	"homework10/internal/users"
+)
+
+// shardedRepository делит объявления и пользователей по ID между шардами - обычными repositoryMap
+// со своим замком, так что записи в разные шарды не ждут друг друга. Запросы по всем шардам читают
+// шарды по очереди, каждый под своим замком; транзакции берут замки только тех шардов, к которым обращаются.
+// ID объявлений и пользователей выдают атомарные счётчики, общие для всех шардов.
+// Вебхуки и dead letters живут в нулевом шарде, outbox - в шарде объявления, токены - в шарде пользователя
+type shardedRepository struct {
+	shards []*repositoryMap
+	ids    *shardedIDs
+	tx     *shardedTx // не nil у транзакции: шарды берутся через неё
+}
+
+// shardedIDs - следующие ID; каждый AddAd и AddUser увеличивает свой счётчик
+type shardedIDs struct {
+	ads   atomic.Int64
+	users atomic.Int64
+}
+
+// NewSharded создаёт репозиторий из shards шардов
+func NewSharded(shards int) app.Repository {
+	if shards < 1 {
+		shards = 1
+	}
+	repo := &shardedRepository{shards: make([]*repositoryMap, shards), ids: &shardedIDs{}}
+	for idx := range repo.shards {
+		repo.shards[idx] = newRepositoryMap(int64(shards), int64(idx))
+	}
+	return repo
+}
+
+func (repo *shardedRepository) shardIndex(id int64) int {
+	idx := id % int64(len(repo.shards))
+	if idx < 0 {
+		idx += int64(len(repo.shards))
+	}
+	return int(idx)
+}
+
+func (repo *shardedRepository) shard(id int64) *repositoryMap {
+	return repo.at(repo.shardIndex(id))
+}
+
+// at - шард idx; в транзакции - его представление под замком транзакции
+func (repo *shardedRepository) at(idx int) *repositoryMap {
+	if repo.tx != nil {
+		return repo.tx.acquire(idx)
+	}
+	return repo.shards[idx]
+}
+
+// exclusive вызывает fn в транзакции под замками всех шардов: так проверяется то, что должно
+// выполняться во всём репозитории сразу, например уникальность email
+func (repo *shardedRepository) exclusive(ctx context.Context, fn func(tx *shardedRepository) error) error {
+	return repo.WithTx(ctx, func(tx app.Repository) error {
+		view := tx.(*shardedRepository)
+		view.tx.lockAll()
+		return fn(view)
+	})
+}
+
+func (repo *shardedRepository) GetAdById(ctx context.Context, id int64) (ads.Ad, error) {
+	return repo.shard(id).GetAdById(ctx, id)
+}
+
+func (repo *shardedRepository) AddAd(ctx context.Context, ad *ads.Ad) {
+	repo.shard(ad.ID).AddAd(ctx, ad)
+	if repo.tx != nil {
+		repo.tx.ads.add(ad.ID)
+	} else {
+		repo.ids.ads.Add(1)
+	}
+}
+
+// GetAdsPrimaryKey в транзакции сразу забирает ID из счётчика: см. txIDs
+func (repo *shardedRepository) GetAdsPrimaryKey(_ context.Context) int64 {
+	if repo.tx != nil {
+		return repo.tx.ads.next()
+	}
+	return repo.ids.ads.Load()
+}
+
+func (repo *shardedRepository) ChangeAd(ctx context.Context, ad *ads.Ad) bool {
+	return repo.shard(ad.ID).ChangeAd(ctx, ad)
+}
+
+// ChangeAds, как и у repositoryMap, сначала применяет change ко всем копиям и только потом пишет.
+// Замки шардов объявлений берутся заранее, по возрастанию номеров
+func (repo *shardedRepository) ChangeAds(ctx context.Context, ids []int64, change func(ad *ads.Ad) error) (changed []ads.Ad, err error) {
+	err = repo.WithTx(ctx, func(tx app.Repository) error {
+		view := tx.(*shardedRepository)
+		idxs := make([]int, 0, len(ids))
+		for _, id := range ids {
+			idxs = append(idxs, view.shardIndex(id))
+		}
+		view.tx.lock(idxs)
+
+		changed = make([]ads.Ad, 0, len(ids))
+		for _, id := range ids {
+			ad, ok := view.shard(id).dictAds[id]
+			if !ok {
+				return fmt.Errorf("ad %d: %w", id, app.IncorrectAdId)
+			}
+			if err := change(&ad); err != nil {
+				return fmt.Errorf("ad %d: %w", id, err)
+			}
+			changed = append(changed, ad)
+		}
+
+		for _, ad := range changed {
+			view.shard(ad.ID).changeAd(ad)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+func (repo *shardedRepository) GetAds(ctx context.Context, filters map[string]any) []ads.Ad {
+	var list []ads.Ad
+	for idx := range repo.shards {
+		list = append(list, repo.at(idx).GetAds(ctx, filters)...)
+	}
+	return list
+}
+
+func (repo *shardedRepository) GetAdsByTitle(ctx context.Context, pattern string) []ads.Ad {
+	var list []ads.Ad
+	for idx := range repo.shards {
+		list = append(list, repo.at(idx).GetAdsByTitle(ctx, pattern)...)
+	}
+	return list
+}
+
+// GetAdsPage берёт до limit объявлений из каждого шарда и оставляет limit первых по ID
+func (repo *shardedRepository) GetAdsPage(ctx context.Context, filters map[string]any, afterID int64, limit int) []ads.Ad {
+	var page []ads.Ad
+	for idx := range repo.shards {
+		page = append(page, repo.at(idx).GetAdsPage(ctx, filters, afterID, limit)...)
+	}
+	sort.Slice(page, func(i, j int) bool {
+		return page[i].ID < page[j].ID
+	})
+	if len(page) > limit {
+		page = page[:limit]
+	}
+	return page
+}
+
+func (repo *shardedRepository) GetUserById(ctx context.Context, id int64) (users.User, error) {
+	return repo.shard(id).GetUserById(ctx, id)
+}
+
+func (repo *shardedRepository) GetUserByEmail(ctx context.Context, email string) (users.User, error) {
+	for idx := range repo.shards {
+		if user, err := repo.at(idx).GetUserByEmail(ctx, email); err == nil {
+			return user, nil
+		}
+	}
+	return users.User{}, app.UserNotFound
+}
+
+// userConflict проверяет уникальность email и ника во всех шардах, а не только в шарде пользователя
+func userConflict(shards []*repositoryMap, user users.User) error {
+	for _, shard := range shards {
+		if err := shard.userIndex.conflict(user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddUser и ChangeUser берут замки всех шардов: email и ник должны быть уникальны во всём репозитории
+func (repo *shardedRepository) AddUser(ctx context.Context, user *users.User) error {
+	return repo.exclusive(ctx, func(tx *shardedRepository) error {
+		if err := userConflict(tx.tx.views, *user); err != nil {
+			return err
+		}
+		if err := tx.shard(user.ID).AddUser(ctx, user); err != nil {
+			return err
+		}
+		tx.tx.users.add(user.ID)
+		return nil
+	})
+}
+
+func (repo *shardedRepository) ChangeUser(ctx context.Context, user *users.User) (ok bool, err error) {
+	err = repo.exclusive(ctx, func(tx *shardedRepository) error {
+		shard := tx.shard(user.ID)
+		if _, found := shard.dictUsers[user.ID]; !found {
+			return nil
+		}
+		if err := userConflict(tx.tx.views, *user); err != nil {
+			return err
+		}
+		var err error
+		ok, err = shard.ChangeUser(ctx, user)
+		return err
+	})
+	return ok, err
+}
+
+func (repo *shardedRepository) TouchUser(ctx context.Context, id int64, at time.Time) {
+	repo.shard(id).TouchUser(ctx, id, at)
+}
+
+func (repo *shardedRepository) GetUsersPrimaryKey(_ context.Context) int64 {
+	if repo.tx != nil {
+		return repo.tx.users.next()
+	}
+	return repo.ids.users.Load()
+}
+
+func (repo *shardedRepository) DeleteAd(ctx context.Context, adId int64) {
+	repo.shard(adId).DeleteAd(ctx, adId)
+}
+
+func (repo *shardedRepository) DeleteUser(ctx context.Context, userId int64) {
+	repo.shard(userId).DeleteUser(ctx, userId)
+}
+
+// AddToken кладёт токен в шард пользователя: там же его удалит DeleteUser
+func (repo *shardedRepository) AddToken(ctx context.Context, token users.Token) {
+	repo.shard(token.UserID).AddToken(ctx, token)
+}
+
+// TakeToken ищет токен во всех шардах: по хэшу не узнать, чей он
+func (repo *shardedRepository) TakeToken(ctx context.Context, hash string) (users.Token, bool) {
+	for idx := range repo.shards {
+		if token, ok := repo.at(idx).TakeToken(ctx, hash); ok {
+			return token, true
+		}
+	}
+	return users.Token{}, false
+}
+
+// GetOutboxEvents сливает outbox шардов по времени создания. Порядок событий одного объявления
+// сохраняется: они всегда в одном шарде
+func (repo *shardedRepository) GetOutboxEvents(ctx context.Context, limit int) []outbox.Event {
+	var events []outbox.Event
+	for idx := range repo.shards {
+		events = append(events, repo.at(idx).GetOutboxEvents(ctx, limit)...)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].CreatedAt.Before(events[j].CreatedAt)
+	})
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+	return events
+}
+
+// DeleteOutboxEvent находит шард по ID события: шард idx выдаёт ID, равные idx по модулю числа шардов
+func (repo *shardedRepository) DeleteOutboxEvent(ctx context.Context, id int64) {
+	repo.shard(id).DeleteOutboxEvent(ctx, id)
+}
+
+func (repo *shardedRepository) AddWebhook(ctx context.Context, webhook *outbox.Webhook) {
+	repo.at(0).AddWebhook(ctx, webhook)
+}
+
+func (repo *shardedRepository) GetWebhooksPrimaryKey(ctx context.Context) int64 {
+	return repo.at(0).GetWebhooksPrimaryKey(ctx)
+}
+
+func (repo *shardedRepository) GetWebhooks(ctx context.Context) []outbox.Webhook {
+	return repo.at(0).GetWebhooks(ctx)
+}
+
+func (repo *shardedRepository) DeleteWebhook(ctx context.Context, id int64) bool {
+	return repo.at(0).DeleteWebhook(ctx, id)
+}
+
+func (repo *shardedRepository) AddDeadLetter(ctx context.Context, letter *outbox.DeadLetter) {
+	repo.at(0).AddDeadLetter(ctx, letter)
+}
+
+func (repo *shardedRepository) GetDeadLetters(ctx context.Context) []outbox.DeadLetter {
+	return repo.at(0).GetDeadLetters(ctx)
+}
+
+func (repo *shardedRepository) CountAds(ctx context.Context) (total int, published int) {
+	for idx := range repo.shards {
+		t, p := repo.at(idx).CountAds(ctx)
+		total += t
+		published += p
+	}
+	return total, published
+}
+
+func (repo *shardedRepository) CountUsers(ctx context.Context) (count int) {
+	for idx := range repo.shards {
+		count += repo.at(idx).CountUsers(ctx)
+	}
+	return count
+}
+
+func (repo *shardedRepository) Ping(ctx context.Context) error {
+	for idx := range repo.shards {
+		if err := repo.at(idx).Ping(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
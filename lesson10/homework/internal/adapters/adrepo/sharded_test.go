@@ -0,0 +1,206 @@
+package adrepo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"homework10/internal/ads"
+	"homework10/internal/app"
+	"homework10/internal/logging"
+	"homework10/internal/users"
+)
+
+// ShardedRepositoryTestSuite прогоняет все тесты repositoryMap на шардированном репозитории
+type ShardedRepositoryTestSuite struct {
+	RepositoryMapTestSuite
+}
+
+func (s *ShardedRepositoryTestSuite) SetupTest() {
+	s.repo = NewSharded(4)
+}
+
+func TestShardedRepoRun(t *testing.T) {
+	suite.Run(t, new(ShardedRepositoryTestSuite))
+}
+
+func (s *ShardedRepositoryTestSuite) TestSharded_RollbackAcrossShards() {
+	ctx := context.Background()
+	for id := int64(0); id < 8; id++ {
+		s.repo.AddAd(ctx, &ads.Ad{ID: id, Title: fmt.Sprintf("Ad %d", id), AuthorID: 1})
+	}
+	s.repo.AddUser(ctx, &users.User{ID: 1, Nickname: "buda"})
+
+	failure := fmt.Errorf("failure")
+	err := s.repo.WithTx(ctx, func(tx app.Repository) error {
+		for id := int64(0); id < 8; id++ {
+			tx.DeleteAd(ctx, id)
+		}
+		tx.AddAd(ctx, &ads.Ad{ID: tx.GetAdsPrimaryKey(ctx), Title: "new", AuthorID: 1})
+		tx.DeleteUser(ctx, 1)
+		return failure
+	})
+	s.ErrorIs(err, failure)
+
+	total, _ := s.repo.CountAds(ctx)
+	s.Equal(8, total)
+	s.Equal(int64(8), s.repo.GetAdsPrimaryKey(ctx))
+	s.Len(s.repo.GetAdsByTitle(ctx, "Ad"), 8)
+	_, err = s.repo.GetUserById(ctx, 1)
+	s.NoError(err)
+	s.Len(s.repo.GetOutboxEvents(ctx, 0), 8)
+}
+
+func (s *ShardedRepositoryTestSuite) TestSharded_ChangeAdsAcrossShards() {
+	ctx := context.Background()
+	for id := int64(0); id < 4; id++ {
+		s.repo.AddAd(ctx, &ads.Ad{ID: id, Title: "Ad", AuthorID: id % 2})
+	}
+
+	_, err := s.repo.ChangeAds(ctx, []int64{0, 1, 2, 3}, func(ad *ads.Ad) error {
+		if ad.AuthorID != 0 {
+			return app.IncorrectUserId
+		}
+		ad.Published = true
+		return nil
+	})
+	s.ErrorIs(err, app.IncorrectUserId)
+	_, published := s.repo.CountAds(ctx)
+	s.Zero(published)
+
+	changed, err := s.repo.ChangeAds(ctx, []int64{2, 0}, func(ad *ads.Ad) error {
+		ad.Published = true
+		return nil
+	})
+	s.NoError(err)
+	s.Equal([]int64{2, 0}, []int64{changed[0].ID, changed[1].ID})
+	s.Equal([]int64{0, 2}, adIDs(s.repo.GetAds(ctx, nil)))
+}
+
+func (s *ShardedRepositoryTestSuite) TestSharded_OutboxEventIDs() {
+	ctx := context.Background()
+	for id := int64(0); id < 10; id++ {
+		s.repo.AddAd(ctx, &ads.Ad{ID: id, Title: "Ad", AuthorID: 1})
+	}
+
+	events := s.repo.GetOutboxEvents(ctx, 0)
+	s.Len(events, 10)
+	seen := make(map[int64]bool)
+	for _, event := range events {
+		s.False(seen[event.ID], "ID событий разных шардов не должны совпадать")
+		seen[event.ID] = true
+	}
+	s.Len(s.repo.GetOutboxEvents(ctx, 3), 3)
+
+	for _, event := range events {
+		s.repo.DeleteOutboxEvent(ctx, event.ID)
+	}
+	s.Empty(s.repo.GetOutboxEvents(ctx, 0))
+}
+
+func (s *ShardedRepositoryTestSuite) TestSharded_TxRetriesOnConflict() {
+	ctx := context.Background()
+	for id := int64(0); id < 4; id++ {
+		s.repo.AddAd(ctx, &ads.Ad{ID: id, Title: "Ad", AuthorID: 1})
+	}
+	shard1 := s.repo.(*shardedRepository).shards[1].lock
+
+	calls := 0
+	err := s.repo.WithTx(ctx, func(tx app.Repository) error {
+		calls++
+		if calls == 1 {
+			// шард 1 занят другой записью, пока транзакция держит шард 3
+			shard1.Lock()
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				shard1.Unlock()
+			}()
+		}
+		ad, err := tx.GetAdById(ctx, 3)
+		s.Require().NoError(err)
+		ad.Published = true
+		tx.ChangeAd(ctx, &ad)
+		tx.DeleteAd(ctx, 1)
+		return nil
+	})
+	s.NoError(err)
+	s.Equal(2, calls)
+
+	total, published := s.repo.CountAds(ctx)
+	s.Equal(3, total)
+	s.Equal(1, published)
+	s.Len(s.repo.GetOutboxEvents(ctx, 0), 6)
+}
+
+func (s *ShardedRepositoryTestSuite) TestSharded_TxReservesIDs() {
+	ctx := context.Background()
+	err := s.repo.WithTx(ctx, func(tx app.Repository) error {
+		ad := ads.Ad{ID: tx.GetAdsPrimaryKey(ctx), Title: "first", AuthorID: 1}
+		s.Equal(ad.ID, tx.GetAdsPrimaryKey(ctx))
+
+		// выданный ID занят, хотя объявление ещё не добавлено, а замков у транзакции пока нет
+		done := make(chan error)
+		go func() {
+			done <- s.repo.WithTx(ctx, func(tx app.Repository) error {
+				tx.AddAd(ctx, &ads.Ad{ID: tx.GetAdsPrimaryKey(ctx), Title: "second", AuthorID: 1})
+				return nil
+			})
+		}()
+		s.Require().NoError(<-done)
+
+		tx.AddAd(ctx, &ad)
+		return nil
+	})
+	s.NoError(err)
+
+	s.Equal(int64(2), s.repo.GetAdsPrimaryKey(ctx))
+	first, err := s.repo.GetAdById(ctx, 0)
+	s.NoError(err)
+	s.Equal("first", first.Title)
+	second, err := s.repo.GetAdById(ctx, 1)
+	s.NoError(err)
+	s.Equal("second", second.Title)
+}
+
+// BenchmarkParallelWrites показывает, как пропускная способность записей через app.App растёт с числом шардов:
+// go test -run xxx -bench ParallelWrites -cpu 1,2,4,8 ./internal/adapters/adrepo
+func BenchmarkParallelWrites(b *testing.B) {
+	const adsCount, usersCount = 100_000, 100
+	ctx := logging.WithLogger(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	for _, bc := range []struct {
+		name string
+		repo func() app.Repository
+	}{
+		{"single lock", New},
+		{"sharded", func() app.Repository { return NewSharded(4 * runtime.GOMAXPROCS(0)) }},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			repo := bc.repo()
+			for id := int64(0); id < usersCount; id++ {
+				_ = repo.AddUser(ctx, &users.User{ID: id, Nickname: fmt.Sprintf("user%d", id), Email: fmt.Sprintf("user%d@phystech.edu", id)})
+			}
+			addIndexedAds(repo, adsCount)
+			service := app.NewApp(repo)
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				rnd := rand.New(rand.NewSource(rand.Int63()))
+				for pb.Next() {
+					if rnd.Intn(2) == 0 {
+						id := rnd.Int63n(adsCount)
+						_, _ = service.UpdateAd(ctx, id, id%usersCount, fmt.Sprintf("Ad %d", id), "text")
+					} else {
+						_, _ = service.CreateAd(ctx, fmt.Sprintf("New %d", rnd.Int63()), "text", rnd.Int63n(usersCount))
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package adrepo
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"homework10/internal/app"
+)
+
+// shardConflict - паника, которой транзакция прерывает fn, когда замок шарда нельзя взять без риска
+// взаимной блокировки. WithTx откатывает транзакцию и выполняет fn заново, заранее взяв замки
+// всех шардов, к которым она обращалась
+type shardConflict struct{}
+
+// shardedTx - транзакция шардированного репозитория. Замок шарда берётся при первом обращении к нему:
+// шард с номером больше уже взятых ждёт замка, как при блокировке по порядку номеров, а шард с меньшим
+// номером берётся только если свободен. Так транзакции в разных шардах не ждут друг друга
+// и не могут заблокировать одна другую
+type shardedTx struct {
+	shards  []*repositoryMap
+	journal *journal
+	views   []*repositoryMap // nil - замок шарда ещё не взят
+	opened  []savepoint      // состояние шарда на момент взятия замка
+	maxHeld int
+	blocked int // шард, на котором случился shardConflict
+
+	ads   txIDs
+	users txIDs
+}
+
+func newShardedTx(repo *shardedRepository) *shardedTx {
+	return &shardedTx{
+		shards:  repo.shards,
+		journal: &journal{},
+		views:   make([]*repositoryMap, len(repo.shards)),
+		opened:  make([]savepoint, len(repo.shards)),
+		maxHeld: -1,
+		ads:     newTxIDs(&repo.ids.ads),
+		users:   newTxIDs(&repo.ids.users),
+	}
+}
+
+// acquire возвращает представление шарда idx, при первом обращении беря его замок
+func (tx *shardedTx) acquire(idx int) *repositoryMap {
+	if view := tx.views[idx]; view != nil {
+		return view
+	}
+
+	lock := tx.shards[idx].lock.(*sync.Mutex)
+	if idx > tx.maxHeld {
+		lock.Lock()
+	} else if !lock.TryLock() {
+		tx.blocked = idx
+		panic(shardConflict{})
+	}
+	view := tx.shards[idx].view(tx.journal)
+	tx.views[idx], tx.opened[idx] = view, view.savepoint()
+	tx.maxHeld = max(tx.maxHeld, idx)
+	return view
+}
+
+// lock берёт замки шардов idxs по возрастанию номеров: если других замков ещё нет, конфликта не будет
+func (tx *shardedTx) lock(idxs []int) {
+	idxs = slices.Clone(idxs)
+	slices.Sort(idxs)
+	for _, idx := range idxs {
+		tx.acquire(idx)
+	}
+}
+
+func (tx *shardedTx) lockAll() {
+	for idx := range tx.shards {
+		tx.acquire(idx)
+	}
+}
+
+// touched - шарды, к которым обращалась транзакция, вместе с тем, чей замок она не смогла взять
+func (tx *shardedTx) touched() []int {
+	idxs := []int{tx.blocked}
+	for idx, view := range tx.views {
+		if view != nil {
+			idxs = append(idxs, idx)
+		}
+	}
+	return idxs
+}
+
+func (tx *shardedTx) release() {
+	for idx, view := range tx.views {
+		if view != nil {
+			tx.shards[idx].lock.Unlock()
+		}
+	}
+}
+
+// shardedSavepoint - точка отката транзакции. Шарды, замки которых взяты позже,
+// откатываются к состоянию на момент взятия замка
+type shardedSavepoint struct {
+	undo   int
+	shards []*savepoint
+	ads    txIDsSavepoint
+	users  txIDsSavepoint
+}
+
+func (tx *shardedTx) savepoint() shardedSavepoint {
+	sp := shardedSavepoint{
+		undo:   len(tx.journal.undo),
+		shards: make([]*savepoint, len(tx.views)),
+		ads:    tx.ads.savepoint(),
+		users:  tx.users.savepoint(),
+	}
+	for idx, view := range tx.views {
+		if view != nil {
+			shardSp := view.savepoint()
+			sp.shards[idx] = &shardSp
+		}
+	}
+	return sp
+}
+
+// rollback откатывает журнал один раз: он общий у всех шардов, так что у всех точек undo одинаковый
+func (tx *shardedTx) rollback(sp shardedSavepoint) {
+	for idx, view := range tx.views {
+		if view == nil {
+			continue
+		}
+		shardSp := tx.opened[idx]
+		if sp.shards[idx] != nil {
+			shardSp = *sp.shards[idx]
+		}
+		shardSp.undo = sp.undo
+		view.rollback(shardSp)
+	}
+	tx.ads.rollback(sp.ads)
+	tx.users.rollback(sp.users)
+}
+
+// run выполняет fn в транзакции и отпускает замки; conflict - fn прервана из-за shardConflict
+// и уже откачена
+func (tx *shardedTx) run(repo *shardedRepository, fn func(tx app.Repository) error) (err error, conflict bool) {
+	defer tx.release()
+
+	sp := tx.savepoint()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.rollback(sp)
+			if _, ok := r.(shardConflict); ok {
+				conflict = true
+				return
+			}
+			panic(r)
+		}
+		if err != nil {
+			tx.rollback(sp)
+		}
+	}()
+	return fn(&shardedRepository{shards: repo.shards, ids: repo.ids, tx: tx}), false
+}
+
+// WithTx пишет в шарды через представления с общим журналом отката, так что транзакция откатывается
+// целиком, в каких бы шардах она ни писала. Замки берутся только у шардов, к которым обращается fn.
+// При конфликте замков fn выполняется заново, поэтому она не должна делать ничего, кроме записей через tx.
+// Каждый повтор заранее берёт на шард больше, так что повторов не больше, чем шардов.
+// WithTx на самой транзакции - точка сохранения, как у repositoryMap
+func (repo *shardedRepository) WithTx(_ context.Context, fn func(tx app.Repository) error) (err error) {
+	if repo.tx != nil {
+		sp := repo.tx.savepoint()
+		defer func() {
+			if r := recover(); r != nil {
+				repo.tx.rollback(sp)
+				panic(r)
+			}
+			if err != nil {
+				repo.tx.rollback(sp)
+			}
+		}()
+		return fn(repo)
+	}
+
+	var locked []int
+	for {
+		tx := newShardedTx(repo)
+		tx.lock(locked)
+		err, conflict := tx.run(repo, fn)
+		if !conflict {
+			return err
+		}
+		locked = tx.touched()
+	}
+}
+
+// txIDs - ID, которые транзакция взяла из общего счётчика. GetAdsPrimaryKey в транзакции сразу забирает
+// ID из счётчика, чтобы параллельная транзакция в других шардах не получила тот же, и до AddAd
+// возвращает его же. При откате счётчик возвращается назад, только если взятые ID идут подряд
+// и никто не брал ID после них; иначе они пропадают
+type txIDs struct {
+	counter  *atomic.Int64
+	runStart int64 // начало последнего непрерывного отрезка взятых ID
+	last     int64 // значение счётчика после последнего взятия, -1 - ID ещё не брались
+	reserved int64 // ID от next, ещё не занятый AddAd; -1 - нет
+}
+
+type txIDsSavepoint struct {
+	last     int64
+	reserved int64
+}
+
+func newTxIDs(counter *atomic.Int64) txIDs {
+	return txIDs{counter: counter, last: -1, reserved: -1}
+}
+
+func (ids *txIDs) take() int64 {
+	id := ids.counter.Add(1) - 1
+	if id != ids.last {
+		ids.runStart = id
+	}
+	ids.last = id + 1
+	return id
+}
+
+// next - ID для нового объекта
+func (ids *txIDs) next() int64 {
+	if ids.reserved < 0 {
+		ids.reserved = ids.take()
+	}
+	return ids.reserved
+}
+
+// add учитывает добавленный объект: его ID - выданный next или взятый мимо счётчика
+func (ids *txIDs) add(id int64) {
+	if id == ids.reserved {
+		ids.reserved = -1
+		return
+	}
+	ids.take()
+}
+
+func (ids *txIDs) savepoint() txIDsSavepoint {
+	return txIDsSavepoint{last: ids.last, reserved: ids.reserved}
+}
+
+func (ids *txIDs) rollback(sp txIDsSavepoint) {
+	ids.reserved = sp.reserved
+	if ids.last < 0 {
+		return
+	}
+	to := max(ids.runStart, sp.last)
+	if to < ids.last && ids.counter.CompareAndSwap(ids.last, to) {
+		ids.last = to
+	}
+}
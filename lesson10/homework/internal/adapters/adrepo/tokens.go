@@ -0,0 +1,67 @@
+package adrepo
+
+import (
+	"context"
+
+	"homework10/internal/users"
+)
+
+// tokenKey - у пользователя не больше одного токена на каждое назначение
+type tokenKey struct {
+	userID  int64
+	purpose users.Purpose
+}
+
+// tokenTable - токены по хэшу и хэш действующего токена по пользователю и назначению
+type tokenTable struct {
+	byHash map[string]users.Token
+	byUser map[tokenKey]string
+}
+
+func newTokenTable() tokenTable {
+	return tokenTable{byHash: make(map[string]users.Token), byUser: make(map[tokenKey]string)}
+}
+
+func (repo *repositoryMap) AddToken(_ context.Context, token users.Token) {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	key := tokenKey{userID: token.UserID, purpose: token.Purpose}
+	if old, ok := repo.tokens.byUser[key]; ok {
+		repo.deleteToken(old)
+	}
+	rememberEntry(repo, repo.tokens.byHash, token.Hash)
+	rememberEntry(repo, repo.tokens.byUser, key)
+	repo.tokens.byHash[token.Hash] = token
+	repo.tokens.byUser[key] = token.Hash
+}
+
+func (repo *repositoryMap) TakeToken(_ context.Context, hash string) (users.Token, bool) {
+	repo.lock.Lock()
+	defer repo.lock.Unlock()
+
+	token, ok := repo.tokens.byHash[hash]
+	if ok {
+		repo.deleteToken(hash)
+	}
+	return token, ok
+}
+
+// deleteUserTokens удаляет все токены пользователя; вызывается только под repo.lock
+func (repo *repositoryMap) deleteUserTokens(userID int64) {
+	for _, purpose := range []users.Purpose{users.PurposeVerifyEmail, users.PurposeResetPassword} {
+		if hash, ok := repo.tokens.byUser[tokenKey{userID: userID, purpose: purpose}]; ok {
+			repo.deleteToken(hash)
+		}
+	}
+}
+
+// вызывается только под repo.lock
+func (repo *repositoryMap) deleteToken(hash string) {
+	token := repo.tokens.byHash[hash]
+	key := tokenKey{userID: token.UserID, purpose: token.Purpose}
+	rememberEntry(repo, repo.tokens.byHash, hash)
+	rememberEntry(repo, repo.tokens.byUser, key)
+	delete(repo.tokens.byHash, hash)
+	delete(repo.tokens.byUser, key)
+}
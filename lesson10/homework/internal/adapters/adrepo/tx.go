@@ -0,0 +1,172 @@
+package adrepo
+
+import (
+	"context"
+	"slices"
+
+	"homework10/internal/app"
+)
+
+// noLock - замок транзакции: репозиторий и так заблокирован на всё время WithTx
+type noLock struct{}
+
+func (noLock) Lock()   {}
+func (noLock) Unlock() {}
+
+// journal - журнал отката транзакции: каждое изменение таблиц кладёт сюда функцию, которая его отменяет.
+// Счётчики и добавления в конец outbox и dead letters откатываются по savepoint
+type journal struct {
+	undo []func()
+}
+
+// savepoint - состояние, к которому откатывается транзакция или вложенный WithTx
+type savepoint struct {
+	undo int
+
+	counterAds      int64
+	counterUsers    int64
+	counterEvents   int64
+	counterWebhooks int64
+
+	outboxEvents int
+	deadLetters  int
+}
+
+// WithTx держит замок репозитория всё время fn, а изменения пишет прямо в таблицы, запоминая в журнале,
+// как их отменить. Откат стоит столько, сколько изменений успела сделать fn, а не весь репозиторий.
+// WithTx на самой транзакции - точка сохранения: ошибка откатывает только то, что сделано внутри неё
+func (repo *repositoryMap) WithTx(_ context.Context, fn func(tx app.Repository) error) (err error) {
+	tx := repo
+	if repo.journal == nil {
+		repo.lock.Lock()
+		defer repo.lock.Unlock()
+		tx = repo.view(&journal{})
+	}
+
+	sp := tx.savepoint()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.rollback(sp)
+			panic(r)
+		}
+		if err != nil {
+			tx.rollback(sp)
+		}
+	}()
+	return fn(tx)
+}
+
+// view - те же таблицы без своего замка: вызывающий уже держит repo.lock.
+// С непустым journal изменения через view можно откатить
+func (repo *repositoryMap) view(j *journal) *repositoryMap {
+	return &repositoryMap{tables: repo.tables, lock: noLock{}, journal: j}
+}
+
+func (repo *repositoryMap) savepoint() savepoint {
+	return savepoint{
+		undo:            len(repo.journal.undo),
+		counterAds:      repo.counterAds,
+		counterUsers:    repo.counterUsers,
+		counterEvents:   repo.counterEvents,
+		counterWebhooks: repo.counterWebhooks,
+		outboxEvents:    len(repo.outboxEvents),
+		deadLetters:     len(repo.deadLetters),
+	}
+}
+
+func (repo *repositoryMap) rollback(sp savepoint) {
+	undo := repo.journal.undo
+	for idx := len(undo) - 1; idx >= sp.undo; idx-- {
+		undo[idx]()
+	}
+	repo.journal.undo = undo[:sp.undo]
+
+	repo.counterAds = sp.counterAds
+	repo.counterUsers = sp.counterUsers
+	repo.counterEvents = sp.counterEvents
+	repo.counterWebhooks = sp.counterWebhooks
+	repo.outboxEvents = repo.outboxEvents[:sp.outboxEvents]
+	repo.deadLetters = repo.deadLetters[:sp.deadLetters]
+}
+
+// record кладёт отмену изменения в журнал; вне транзакции ничего не делает
+func (repo *repositoryMap) record(undo func()) {
+	if repo.journal != nil {
+		repo.journal.undo = append(repo.journal.undo, undo)
+	}
+}
+
+// rememberEntry запоминает значение key в m, чтобы при откате вернуть его или удалить ключ
+func rememberEntry[K comparable, V any](repo *repositoryMap, m map[K]V, key K) {
+	if repo.journal == nil {
+		return
+	}
+	old, ok := m[key]
+	repo.record(func() {
+		if ok {
+			m[key] = old
+		} else {
+			delete(m, key)
+		}
+	})
+}
+
+// rememberAd запоминает объявление, его место в индексах и список объявлений с его названием.
+// Список копируется: ChangeAd и DeleteAd меняют его элементы на месте
+func (repo *repositoryMap) rememberAd(id int64, title string) {
+	if repo.journal == nil {
+		return
+	}
+	ad, existed := repo.dictAds[id]
+	repo.record(func() {
+		if current, ok := repo.dictAds[id]; ok {
+			repo.index.remove(current)
+		}
+		if existed {
+			repo.dictAds[id] = ad
+			repo.index.add(ad)
+		} else {
+			delete(repo.dictAds, id)
+		}
+	})
+
+	old, ok := repo.dictAdsByTitle[title]
+	old = slices.Clone(old)
+	repo.record(func() {
+		if ok {
+			repo.dictAdsByTitle[title] = old
+		} else {
+			delete(repo.dictAdsByTitle, title)
+		}
+	})
+}
+
+// rememberUser запоминает пользователя вместе с его email и ником в уникальных индексах
+func (repo *repositoryMap) rememberUser(id int64) {
+	if repo.journal == nil {
+		return
+	}
+	user, existed := repo.dictUsers[id]
+	repo.record(func() {
+		if current, ok := repo.dictUsers[id]; ok {
+			repo.userIndex.remove(current)
+		}
+		if existed {
+			repo.dictUsers[id] = user
+			repo.userIndex.add(user)
+		} else {
+			delete(repo.dictUsers, id)
+		}
+	})
+}
+
+// rememberOutbox запоминает копию outbox перед удалением события из середины
+func (repo *repositoryMap) rememberOutbox() {
+	if repo.journal == nil {
+		return
+	}
+	old := slices.Clone(repo.outboxEvents)
+	repo.record(func() {
+		repo.outboxEvents = old
+	})
+}
@@ -0,0 +1,46 @@
+package adrepo
+
+import (
+	"homework10/internal/app"
+	"homework10/internal/users"
+)
+
+// userIndex - уникальные индексы пользователей по нормализованным email и нику.
+// Пустые значения не индексируются: их не пропускает валидация приложения
+type userIndex struct {
+	byEmail    map[string]int64
+	byNickname map[string]int64
+}
+
+func newUserIndex() userIndex {
+	return userIndex{byEmail: make(map[string]int64), byNickname: make(map[string]int64)}
+}
+
+// conflict возвращает EmailTaken или NicknameTaken, если email или ник занят другим пользователем
+func (ix *userIndex) conflict(user users.User) error {
+	if id, ok := ix.byEmail[users.NormalizeEmail(user.Email)]; ok && id != user.ID {
+		return app.EmailTaken
+	}
+	if id, ok := ix.byNickname[users.NicknameKey(user.Nickname)]; ok && id != user.ID {
+		return app.NicknameTaken
+	}
+	return nil
+}
+
+func (ix *userIndex) add(user users.User) {
+	if email := users.NormalizeEmail(user.Email); email != "" {
+		ix.byEmail[email] = user.ID
+	}
+	if nickname := users.NicknameKey(user.Nickname); nickname != "" {
+		ix.byNickname[nickname] = user.ID
+	}
+}
+
+func (ix *userIndex) remove(user users.User) {
+	if email := users.NormalizeEmail(user.Email); ix.byEmail[email] == user.ID {
+		delete(ix.byEmail, email)
+	}
+	if nickname := users.NicknameKey(user.Nickname); ix.byNickname[nickname] == user.ID {
+		delete(ix.byNickname, nickname)
+	}
+}
@@ -0,0 +1,302 @@
+// Package adsio читает и пишет объявления построчно в CSV и NDJSON для импорта и экспорта.
+// Колонки экспорта - надмножество колонок импорта, поэтому выгрузку можно загрузить обратно
+package adsio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strconv"
+	"time"
+
+	"homework10/internal/ads"
+)
+
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// MaxLineSize - предел длины одной строки NDJSON, чтобы одна строка не заняла всю память
+const MaxLineSize = 1 << 20
+
+// ErrUnsupportedFormat - формат не CSV и не NDJSON
+var ErrUnsupportedFormat = errors.New("unsupported format, use csv or ndjson")
+
+// колонки CSV в порядке экспорта; импорт читает title, text и user_id, остальные пропускает
+var columns = []string{"id", "title", "text", "user_id", "published", "date_creating", "date_update"}
+
+// FormatFromContentType определяет формат по заголовку Content-Type
+func FormatFromContentType(contentType string) (string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", ErrUnsupportedFormat
+	}
+	switch mediaType {
+	case "text/csv":
+		return FormatCSV, nil
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return FormatNDJSON, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// ContentType - заголовок Content-Type для выгрузки в формате format
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// Row - объявление из файла импорта
+type Row struct {
+	Line   int // номер строки в файле, с единицы
+	Title  string
+	Text   string
+	UserID int64
+}
+
+// RowError - строка, которую не удалось разобрать. Чтение после неё можно продолжать
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// Reader читает строки импорта по одной. Read возвращает *RowError для испорченной строки
+// и io.EOF в конце файла; любая другая ошибка - сбой чтения, после которого продолжать нельзя
+type Reader interface {
+	Read() (Row, error)
+}
+
+func NewReader(format string, r io.Reader) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r), nil
+	case FormatNDJSON:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), MaxLineSize)
+		return &ndjsonReader{scanner: scanner}, nil
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+type csvReader struct {
+	reader *csv.Reader
+	index  map[string]int // колонка -> позиция, из первой строки файла
+	err    error          // ошибка заголовка: без него строки не разобрать
+}
+
+func newCSVReader(r io.Reader) *csvReader {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	return &csvReader{reader: reader}
+}
+
+func (c *csvReader) Read() (Row, error) {
+	if c.err != nil {
+		return Row{}, c.err
+	}
+	if c.index == nil {
+		if c.err = c.readHeader(); c.err != nil {
+			return Row{}, c.err
+		}
+	}
+
+	record, err := c.reader.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return Row{}, &RowError{Line: parseErr.StartLine, Err: parseErr.Err}
+	}
+	if err != nil {
+		return Row{}, err
+	}
+
+	line, _ := c.reader.FieldPos(0)
+	row := Row{Line: line}
+	if len(record) < c.width() {
+		return row, &RowError{Line: line, Err: fmt.Errorf("expected %d fields, got %d", c.width(), len(record))}
+	}
+	row.Title = record[c.index["title"]]
+	row.Text = record[c.index["text"]]
+	row.UserID, err = strconv.ParseInt(record[c.index["user_id"]], 10, 64)
+	if err != nil {
+		return row, &RowError{Line: line, Err: fmt.Errorf("user_id %q is not a number", record[c.index["user_id"]])}
+	}
+	return row, nil
+}
+
+func (c *csvReader) readHeader() error {
+	header, err := c.reader.Read()
+	if errors.Is(err, io.EOF) {
+		return io.EOF
+	}
+	if err != nil {
+		return fmt.Errorf("csv header: %w", err)
+	}
+
+	index := make(map[string]int, len(header))
+	for idx, name := range header {
+		index[name] = idx
+	}
+	for _, name := range []string{"title", "text", "user_id"} {
+		if _, ok := index[name]; !ok {
+			return fmt.Errorf("csv header: column %s is required", name)
+		}
+	}
+	c.index = index
+	return nil
+}
+
+// width - сколько полей должно быть в строке, чтобы в ней нашлись все нужные колонки
+func (c *csvReader) width() int {
+	width := 0
+	for _, name := range []string{"title", "text", "user_id"} {
+		if c.index[name]+1 > width {
+			width = c.index[name] + 1
+		}
+	}
+	return width
+}
+
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// ndjsonRow - строка NDJSON; лишние поля (id, published и т.д. из выгрузки) пропускаются
+type ndjsonRow struct {
+	Title  *string `json:"title"`
+	Text   *string `json:"text"`
+	UserID *int64  `json:"user_id"`
+}
+
+func (n *ndjsonReader) Read() (Row, error) {
+	for n.scanner.Scan() {
+		n.line++
+		data := bytes.TrimSpace(n.scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		row := Row{Line: n.line}
+		var raw ndjsonRow
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return row, &RowError{Line: n.line, Err: err}
+		}
+		if raw.UserID == nil {
+			return row, &RowError{Line: n.line, Err: errors.New("user_id is required")}
+		}
+		row.UserID = *raw.UserID
+		if raw.Title != nil {
+			row.Title = *raw.Title
+		}
+		if raw.Text != nil {
+			row.Text = *raw.Text
+		}
+		return row, nil
+	}
+	if err := n.scanner.Err(); err != nil {
+		return Row{}, fmt.Errorf("line %d: %w", n.line+1, err)
+	}
+	return Row{}, io.EOF
+}
+
+// Writer пишет объявления в поток выгрузки. Flush нужно вызвать в конце
+type Writer interface {
+	Write(ad ads.Ad) error
+	Flush() error
+}
+
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{writer: csv.NewWriter(w)}, nil
+	case FormatNDJSON:
+		buffered := bufio.NewWriter(w)
+		return &ndjsonWriter{buffered: buffered, encoder: json.NewEncoder(buffered)}, nil
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+	header bool
+}
+
+func (c *csvWriter) Write(ad ads.Ad) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	return c.writer.Write([]string{
+		strconv.FormatInt(ad.ID, 10),
+		ad.Title,
+		ad.Text,
+		strconv.FormatInt(ad.AuthorID, 10),
+		strconv.FormatBool(ad.Published),
+		ad.DateCreating.Format(time.RFC3339Nano),
+		ad.DateUpdate.Format(time.RFC3339Nano),
+	})
+}
+
+// Flush пишет заголовок и для пустой выгрузки, чтобы файл оставался корректным CSV
+func (c *csvWriter) Flush() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+func (c *csvWriter) writeHeader() error {
+	if c.header {
+		return nil
+	}
+	c.header = true
+	return c.writer.Write(columns)
+}
+
+type ndjsonWriter struct {
+	buffered *bufio.Writer
+	encoder  *json.Encoder
+}
+
+type ndjsonAd struct {
+	ID           int64     `json:"id"`
+	Title        string    `json:"title"`
+	Text         string    `json:"text"`
+	UserID       int64     `json:"user_id"`
+	Published    bool      `json:"published"`
+	DateCreating time.Time `json:"date_creating"`
+	DateUpdate   time.Time `json:"date_update"`
+}
+
+func (n *ndjsonWriter) Write(ad ads.Ad) error {
+	return n.encoder.Encode(ndjsonAd{
+		ID:           ad.ID,
+		Title:        ad.Title,
+		Text:         ad.Text,
+		UserID:       ad.AuthorID,
+		Published:    ad.Published,
+		DateCreating: ad.DateCreating,
+		DateUpdate:   ad.DateUpdate,
+	})
+}
+
+func (n *ndjsonWriter) Flush() error {
+	return n.buffered.Flush()
+}
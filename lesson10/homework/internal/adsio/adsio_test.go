@@ -0,0 +1,163 @@
+package adsio
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"homework10/internal/ads"
+)
+
+// readAll читает все строки; испорченные строки возвращаются отдельно
+func readAll(t *testing.T, format string, input string) ([]Row, []*RowError, error) {
+	reader, err := NewReader(format, strings.NewReader(input))
+	require.NoError(t, err)
+
+	var rows []Row
+	var rowErrs []*RowError
+	for {
+		row, err := reader.Read()
+		var rowErr *RowError
+		switch {
+		case errors.Is(err, io.EOF):
+			return rows, rowErrs, nil
+		case errors.As(err, &rowErr):
+			rowErrs = append(rowErrs, rowErr)
+		case err != nil:
+			return rows, rowErrs, err
+		default:
+			rows = append(rows, row)
+		}
+	}
+}
+
+func TestFormatFromContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		format      string
+		err         error
+	}{
+		{"text/csv", FormatCSV, nil},
+		{"text/csv; charset=utf-8", FormatCSV, nil},
+		{"application/x-ndjson", FormatNDJSON, nil},
+		{"application/ndjson", FormatNDJSON, nil},
+		{"application/json", "", ErrUnsupportedFormat},
+		{"", "", ErrUnsupportedFormat},
+	}
+	for _, test := range tests {
+		t.Run(test.contentType, func(t *testing.T) {
+			format, err := FormatFromContentType(test.contentType)
+			assert.Equal(t, test.format, format)
+			assert.ErrorIs(t, err, test.err)
+		})
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	input := "user_id,extra,title,text\n" +
+		"1,x,hello,world\n" +
+		"two,x,bad,user\n" +
+		"3,x\n" +
+		"4,x,\"multi\nline\",text\n" +
+		"5,x,\"broken\"quote,text\n" +
+		"6,x,last,row\n"
+
+	rows, rowErrs, err := readAll(t, FormatCSV, input)
+	require.NoError(t, err)
+	assert.Equal(t, []Row{
+		{Line: 2, Title: "hello", Text: "world", UserID: 1},
+		{Line: 5, Title: "multi\nline", Text: "text", UserID: 4},
+		{Line: 8, Title: "last", Text: "row", UserID: 6},
+	}, rows)
+
+	lines := make([]int, 0, len(rowErrs))
+	for _, rowErr := range rowErrs {
+		lines = append(lines, rowErr.Line)
+	}
+	assert.Equal(t, []int{3, 4, 7}, lines)
+}
+
+func TestCSVReader_Header(t *testing.T) {
+	_, _, err := readAll(t, FormatCSV, "title,text\nhello,world\n")
+	assert.ErrorContains(t, err, "column user_id is required")
+
+	rows, _, err := readAll(t, FormatCSV, "")
+	assert.NoError(t, err)
+	assert.Empty(t, rows)
+}
+
+func TestNDJSONReader(t *testing.T) {
+	input := `{"title":"hello","text":"world","user_id":1}
+
+{"title":"bad json"
+{"title":"no user","text":"text"}
+{"id":7,"title":"from export","text":"text","user_id":2,"published":true}
+`
+	rows, rowErrs, err := readAll(t, FormatNDJSON, input)
+	require.NoError(t, err)
+	assert.Equal(t, []Row{
+		{Line: 1, Title: "hello", Text: "world", UserID: 1},
+		{Line: 5, Title: "from export", Text: "text", UserID: 2},
+	}, rows)
+	require.Len(t, rowErrs, 2)
+	assert.Equal(t, 3, rowErrs[0].Line)
+	assert.Equal(t, 4, rowErrs[1].Line)
+}
+
+func TestNDJSONReader_LineTooLong(t *testing.T) {
+	input := `{"title":"` + strings.Repeat("a", MaxLineSize) + `","text":"text","user_id":1}` + "\n"
+	_, _, err := readAll(t, FormatNDJSON, input)
+	assert.ErrorIs(t, err, bufio.ErrTooLong)
+}
+
+func TestWriter_RoundTrip(t *testing.T) {
+	created := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	list := []ads.Ad{
+		{ID: 1, Title: "hello, world", Text: "multi\nline", AuthorID: 3, Published: true, DateCreating: created, DateUpdate: created},
+		{ID: 2, Title: `"quoted"`, Text: "text", AuthorID: 4, DateCreating: created, DateUpdate: created},
+	}
+
+	for _, format := range []string{FormatCSV, FormatNDJSON} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			writer, err := NewWriter(format, &buf)
+			require.NoError(t, err)
+			for _, ad := range list {
+				require.NoError(t, writer.Write(ad))
+			}
+			require.NoError(t, writer.Flush())
+
+			rows, rowErrs, err := readAll(t, format, buf.String())
+			require.NoError(t, err)
+			assert.Empty(t, rowErrs)
+			require.Len(t, rows, len(list))
+			for idx, ad := range list {
+				assert.Equal(t, ad.Title, rows[idx].Title)
+				assert.Equal(t, ad.Text, rows[idx].Text)
+				assert.Equal(t, ad.AuthorID, rows[idx].UserID)
+			}
+		})
+	}
+}
+
+func TestCSVWriter_EmptyExportHasHeader(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewWriter(FormatCSV, &buf)
+	require.NoError(t, err)
+	require.NoError(t, writer.Flush())
+	assert.Equal(t, "id,title,text,user_id,published,date_creating,date_update\n", buf.String())
+}
+
+func TestUnsupportedFormat(t *testing.T) {
+	_, err := NewReader("xml", strings.NewReader(""))
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+	_, err = NewWriter("xml", io.Discard)
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
@@ -0,0 +1,224 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"homework10/internal/logging"
+	"homework10/internal/mailer"
+	"homework10/internal/users"
+)
+
+const (
+	DefaultVerifyEmailTTL   = 24 * time.Hour
+	DefaultResetPasswordTTL = time.Hour
+)
+
+// password - новый пароль для проверки тегами; 72 байта - больше bcrypt не принимает
+type password struct {
+	Password string `validate:"min:8;max:72"`
+}
+
+// RequestEmailVerification заново отправляет письмо для подтверждения email.
+// Токен из прежнего письма после этого не действует
+func (a *appRepo) RequestEmailVerification(ctx context.Context, userId int64) error {
+	var user users.User
+	var token string
+	err := a.repository.WithTx(ctx, func(tx Repository) error {
+		var err error
+		user, err = tx.GetUserById(ctx, userId)
+		if err != nil {
+			return userNotFound(err)
+		}
+		if user.EmailVerified {
+			return EmailAlreadyVerified
+		}
+
+		token, err = a.issueToken(ctx, tx, user, users.PurposeVerifyEmail)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return a.sendVerification(ctx, user, token)
+}
+
+// ConfirmEmail подтверждает email по токену из письма. Токен одноразовый
+func (a *appRepo) ConfirmEmail(ctx context.Context, token string) (*users.User, error) {
+	var user users.User
+	err := a.repository.WithTx(ctx, func(tx Repository) error {
+		var err error
+		user, err = a.takeToken(ctx, tx, token, users.PurposeVerifyEmail)
+		if err != nil {
+			return err
+		}
+
+		user.EmailVerified = true
+		user.LastSeenAt = a.now().UTC()
+		_, err = tx.ChangeUser(ctx, &user)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("email verified", slog.Int64("user_id", user.ID))
+	return &user, nil
+}
+
+// RequestPasswordReset отправляет письмо со сбросом пароля. Если пользователя с таким email нет,
+// письмо не отправляется, но ошибки тоже нет: по ответу нельзя узнать, зарегистрирован ли адрес
+func (a *appRepo) RequestPasswordReset(ctx context.Context, email string) error {
+	var user users.User
+	var token string
+	err := a.repository.WithTx(ctx, func(tx Repository) error {
+		var err error
+		user, err = tx.GetUserByEmail(ctx, users.NormalizeEmail(email))
+		if errors.Is(err, UserNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		token, err = a.issueToken(ctx, tx, user, users.PurposeResetPassword)
+		return err
+	})
+	if err != nil || token == "" {
+		return err
+	}
+
+	err = a.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Someone asked to reset the password for %s.\n\nToken: %s\n\n"+
+			"The token expires in %s. If it was not you, ignore this email.", user.Nickname, token, a.resetPasswordTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("send password reset email: %w", err)
+	}
+	return nil
+}
+
+// checkPassword проверяет текущий пароль пользователя; пока пароль не задан через сброс, проверка не проходит
+func checkPassword(user users.User, password string) error {
+	if user.PasswordHash == "" || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return WrongPassword
+	}
+	return nil
+}
+
+// ResetPassword задаёт новый пароль по токену из письма. Письмо пришло на email пользователя,
+// поэтому email заодно считается подтверждённым. Токен одноразовый
+func (a *appRepo) ResetPassword(ctx context.Context, token string, newPassword string) error {
+	if err := validate(password{Password: newPassword}); err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	var user users.User
+	err = a.repository.WithTx(ctx, func(tx Repository) error {
+		var err error
+		user, err = a.takeToken(ctx, tx, token, users.PurposeResetPassword)
+		if err != nil {
+			return err
+		}
+
+		user.PasswordHash = string(hash)
+		user.EmailVerified = true
+		user.LastSeenAt = a.now().UTC()
+		_, err = tx.ChangeUser(ctx, &user)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("password reset", slog.Int64("user_id", user.ID))
+	return nil
+}
+
+// issueToken создаёт токен для user и сохраняет через tx его хэш. Сам токен уходит только в письмо
+func (a *appRepo) issueToken(ctx context.Context, tx Repository, user users.User, purpose users.Purpose) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	ttl := a.verifyEmailTTL
+	if purpose == users.PurposeResetPassword {
+		ttl = a.resetPasswordTTL
+	}
+	tx.AddToken(ctx, users.Token{
+		Hash:      hashToken(token),
+		UserID:    user.ID,
+		Email:     user.Email,
+		Purpose:   purpose,
+		ExpiresAt: a.now().Add(ttl),
+	})
+	return token, nil
+}
+
+// takeToken гасит токен и возвращает его пользователя. Неизвестный, просроченный, выданный
+// для другого или на прежний email токен - InvalidToken; ошибка откатывает транзакцию,
+// так что токен для другого назначения не сгорает
+func (a *appRepo) takeToken(ctx context.Context, tx Repository, token string, purpose users.Purpose) (users.User, error) {
+	stored, ok := tx.TakeToken(ctx, hashToken(token))
+	if !ok || stored.Purpose != purpose || !a.now().Before(stored.ExpiresAt) {
+		return users.User{}, InvalidToken
+	}
+	user, err := tx.GetUserById(ctx, stored.UserID)
+	if err != nil || user.Email != stored.Email {
+		return users.User{}, InvalidToken
+	}
+	return user, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (a *appRepo) sendVerification(ctx context.Context, user users.User, token string) error {
+	err := a.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf("Hi, %s!\n\nConfirm your email to publish ads.\n\nToken: %s\n\n"+
+			"The token expires in %s.", user.Nickname, token, a.verifyEmailTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("send verification email: %w", err)
+	}
+	return nil
+}
+
+// sendVerificationOrWarn - для писем после записи: пользователь уже сохранён, и ошибка почты
+// не должна выглядеть как ошибка запроса
+func (a *appRepo) sendVerificationOrWarn(ctx context.Context, user users.User, token string) {
+	if err := a.sendVerification(ctx, user, token); err != nil {
+		logging.FromContext(ctx).Warn("verification email not sent", slog.Int64("user_id", user.ID), slog.Any("error", err))
+	}
+}
+
+// canPublish - публиковать объявления может только автор с подтверждённым email
+func canPublish(ctx context.Context, tx Repository, userId int64) error {
+	user, err := tx.GetUserById(ctx, userId)
+	if err != nil {
+		return IncorrectUserId
+	}
+	if !user.EmailVerified {
+		return EmailNotVerified
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package app_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/app"
+	"homework10/internal/mailer"
+)
+
+// mailToken достаёт токен из последнего письма на адрес to
+func mailToken(t *testing.T, capture *mailer.Capture, to string) string {
+	t.Helper()
+	msg, ok := capture.Last(to)
+	require.True(t, ok, "no email to %s", to)
+	for _, line := range strings.Split(msg.Body, "\n") {
+		if token, ok := strings.CutPrefix(line, "Token: "); ok {
+			return token
+		}
+	}
+	require.Fail(t, "no token in email", msg.Body)
+	return ""
+}
+
+func newAccountApp(opts ...app.Option) (app.App, *mailer.Capture) {
+	capture := mailer.NewCapture()
+	return app.NewApp(adrepo.New(), append([]app.Option{app.WithMailer(capture)}, opts...)...), capture
+}
+
+func TestEmailVerification(t *testing.T) {
+	ctx := context.Background()
+	service, capture := newAccountApp()
+
+	user, err := service.CreateUser(ctx, "buda", "Buda@phystech.edu")
+	require.NoError(t, err)
+	assert.False(t, user.EmailVerified)
+	ad, err := service.CreateAd(ctx, "title", "text", user.ID)
+	require.NoError(t, err)
+
+	_, err = service.ChangeAdStatus(ctx, ad.ID, user.ID, true)
+	assert.ErrorIs(t, err, app.EmailNotVerified)
+	_, err = service.BatchChangeAdStatus(ctx, []int64{ad.ID}, user.ID, true)
+	assert.ErrorIs(t, err, app.EmailNotVerified)
+
+	token := mailToken(t, capture, "buda@phystech.edu")
+	verified, err := service.ConfirmEmail(ctx, token)
+	require.NoError(t, err)
+	assert.True(t, verified.EmailVerified)
+	_, err = service.ConfirmEmail(ctx, token)
+	assert.ErrorIs(t, err, app.InvalidToken, "токен одноразовый")
+
+	_, err = service.ChangeAdStatus(ctx, ad.ID, user.ID, true)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, service.RequestEmailVerification(ctx, user.ID), app.EmailAlreadyVerified)
+}
+
+func TestEmailVerification_Resend(t *testing.T) {
+	ctx := context.Background()
+	service, capture := newAccountApp()
+	user, err := service.CreateUser(ctx, "buda", "buda@phystech.edu")
+	require.NoError(t, err)
+	first := mailToken(t, capture, user.Email)
+
+	require.NoError(t, service.RequestEmailVerification(ctx, user.ID))
+	second := mailToken(t, capture, user.Email)
+	assert.NotEqual(t, first, second)
+
+	_, err = service.ConfirmEmail(ctx, first)
+	assert.ErrorIs(t, err, app.InvalidToken, "действует только последний токен")
+	_, err = service.ConfirmEmail(ctx, second)
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, service.RequestEmailVerification(ctx, 42), app.UserNotFound)
+}
+
+func TestEmailVerification_Expired(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	service, capture := newAccountApp(app.WithTokenTTL(time.Hour, 0), app.WithClock(func() time.Time { return now }))
+	user, err := service.CreateUser(ctx, "buda", "buda@phystech.edu")
+	require.NoError(t, err)
+
+	now = now.Add(time.Hour)
+	_, err = service.ConfirmEmail(ctx, mailToken(t, capture, user.Email))
+	assert.ErrorIs(t, err, app.InvalidToken)
+}
+
+func TestEmailVerification_EmailChanged(t *testing.T) {
+	ctx := context.Background()
+	service, capture := newAccountApp()
+	user, err := service.CreateUser(ctx, "buda", "buda@phystech.edu")
+	require.NoError(t, err)
+	_, err = service.ConfirmEmail(ctx, mailToken(t, capture, user.Email))
+	require.NoError(t, err)
+
+	// смена ника не трогает подтверждение и не требует пароля
+	user, err = service.UpdateUser(ctx, user.ID, "og buda", "buda@phystech.edu", "")
+	require.NoError(t, err)
+	assert.True(t, user.EmailVerified)
+
+	// email меняется только с текущим паролем, а пока пароль не задан - не меняется вовсе
+	_, err = service.UpdateUser(ctx, user.ID, "og buda", "og.buda@phystech.edu", "")
+	assert.ErrorIs(t, err, app.WrongPassword)
+	setPassword(t, service, capture, user.Email, "buda password")
+	_, err = service.UpdateUser(ctx, user.ID, "og buda", "og.buda@phystech.edu", "wrong password")
+	assert.ErrorIs(t, err, app.WrongPassword)
+	_, ok := capture.Last("og.buda@phystech.edu")
+	assert.False(t, ok, "без пароля письмо на новый адрес не уходит")
+
+	// смена email снимает подтверждение
+	user, err = service.UpdateUser(ctx, user.ID, "og buda", "og.buda@phystech.edu", "buda password")
+	require.NoError(t, err)
+	assert.False(t, user.EmailVerified)
+
+	user, err = service.ConfirmEmail(ctx, mailToken(t, capture, "og.buda@phystech.edu"))
+	require.NoError(t, err)
+	assert.True(t, user.EmailVerified)
+}
+
+func TestPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	service, capture := newAccountApp()
+	user, err := service.CreateUser(ctx, "buda", "buda@phystech.edu")
+	require.NoError(t, err)
+	verifyToken := mailToken(t, capture, user.Email)
+
+	require.NoError(t, service.RequestPasswordReset(ctx, "nobody@phystech.edu"))
+	assert.Len(t, capture.Messages(), 1, "на незарегистрированный адрес письмо не уходит")
+
+	require.NoError(t, service.RequestPasswordReset(ctx, " BUDA@phystech.edu"))
+	resetToken := mailToken(t, capture, user.Email)
+
+	// токен подтверждения email не сбрасывает пароль и после попытки остаётся действующим
+	assert.ErrorIs(t, service.ResetPassword(ctx, verifyToken, "new password"), app.InvalidToken)
+
+	err = service.ResetPassword(ctx, resetToken, "short")
+	var fields *app.InvalidFieldsError
+	require.ErrorAs(t, err, &fields)
+	assert.Equal(t, []app.FieldViolation{{Field: "password", Description: "should have length at least 8"}}, fields.Violations)
+
+	require.NoError(t, service.ResetPassword(ctx, resetToken, "new password"))
+	assert.ErrorIs(t, service.ResetPassword(ctx, resetToken, "other password"), app.InvalidToken)
+
+	got, err := service.GetUser(ctx, user.ID)
+	require.NoError(t, err)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(got.PasswordHash), []byte("new password")))
+	assert.True(t, got.EmailVerified, "письмо со сбросом пришло на email пользователя")
+
+	_, err = service.ConfirmEmail(ctx, verifyToken)
+	assert.NoError(t, err)
+}
+
+func TestPasswordReset_Expired(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	service, capture := newAccountApp(app.WithClock(func() time.Time { return now }))
+	user, err := service.CreateUser(ctx, "buda", "buda@phystech.edu")
+	require.NoError(t, err)
+	require.NoError(t, service.RequestPasswordReset(ctx, user.Email))
+
+	now = now.Add(app.DefaultResetPasswordTTL)
+	assert.ErrorIs(t, service.ResetPassword(ctx, mailToken(t, capture, user.Email), "new password"), app.InvalidToken)
+}
+
+type failingMailer struct{}
+
+func (failingMailer) Send(context.Context, mailer.Message) error {
+	return errors.New("smtp is down")
+}
+
+func TestEmailVerification_MailerDown(t *testing.T) {
+	ctx := context.Background()
+	service := app.NewApp(adrepo.New(), app.WithMailer(failingMailer{}))
+
+	// пользователь создаётся и без письма, а явный запрос письма сообщает об ошибке
+	user, err := service.CreateUser(ctx, "buda", "buda@phystech.edu")
+	require.NoError(t, err)
+	err = service.RequestEmailVerification(ctx, user.ID)
+	assert.ErrorContains(t, err, "smtp is down")
+	assert.Equal(t, app.KindInternal, app.KindOf(err))
+}
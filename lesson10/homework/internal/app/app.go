@@ -1,176 +1,464 @@
 package app
 
 import (
+	"context"
 	"errors"
-	"github.com/dubter/Validator"
 	"homework10/internal/ads"
+	"homework10/internal/logging"
+	"homework10/internal/mailer"
+	"homework10/internal/outbox"
 	"homework10/internal/users"
+	"log/slog"
+	"net/mail"
+	"net/url"
 	"time"
 )
 
-// some errors
-
-var IncorrectUserId = errors.New("incorrect user id")
-var ValidateError = errors.New("validation error")
-var IncorrectAdId = errors.New("id is not found")
+// Stats - агрегаты по репозиторию для метрик
+type Stats struct {
+	Ads          int
+	PublishedAds int
+	Users        int
+}
 
 type App interface {
-	CreateAd(title string, text string, userId int64) (*ads.Ad, error)
-	ChangeAdStatus(adId int64, userId int64, published bool) (*ads.Ad, error)
-	UpdateAd(adId int64, userId int64, title string, text string) (*ads.Ad, error)
-	DeleteAd(adId int64, userId int64) error
-
-	GetAd(id int64) (*ads.Ad, error)
-	GetListAds(filters map[string]any) []ads.Ad
-	GetListAdsByTitle(pattern string) []ads.Ad
-
-	CreateUser(nickname string, email string) (*users.User, error)
-	UpdateUser(userId int64, nickname string, email string) (*users.User, error)
-	DeleteUser(userId int64) error
-	GetUser(userId int64) (*users.User, error)
+	CreateAd(ctx context.Context, title string, text string, userId int64) (*ads.Ad, error)
+	ChangeAdStatus(ctx context.Context, adId int64, userId int64, published bool) (*ads.Ad, error)
+	UpdateAd(ctx context.Context, adId int64, userId int64, title string, text string) (*ads.Ad, error)
+	DeleteAd(ctx context.Context, adId int64, userId int64) error
+
+	GetAd(ctx context.Context, id int64) (*ads.Ad, error)
+	GetListAds(ctx context.Context, filters map[string]any) []ads.Ad
+	GetListAdsByTitle(ctx context.Context, pattern string) []ads.Ad
+	BatchGetAds(ctx context.Context, ids []int64) (found []ads.Ad, missing []int64, err error)
+	BatchChangeAdStatus(ctx context.Context, adIds []int64, userId int64, published bool) ([]ads.Ad, error)
+	ImportAds(ctx context.Context, next func() (ImportRow, error)) (ImportReport, error)
+	ExportAds(ctx context.Context, filters map[string]any, fn func(ad ads.Ad) error) error
+
+	CreateUser(ctx context.Context, nickname string, email string) (*users.User, error)
+	UpdateUser(ctx context.Context, userId int64, nickname string, email string, password string) (*users.User, error)
+	DeleteUser(ctx context.Context, userId int64) error
+	GetUser(ctx context.Context, userId int64) (*users.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*users.User, error)
+	UpdateProfile(ctx context.Context, userId int64, password string, profile users.Profile) (*users.User, error)
+	GetUserAds(ctx context.Context, userId int64) ([]ads.Ad, error)
+	BatchGetUsers(ctx context.Context, ids []int64) (found []users.User, missing []int64, err error)
+
+	RequestEmailVerification(ctx context.Context, userId int64) error
+	ConfirmEmail(ctx context.Context, token string) (*users.User, error)
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token string, password string) error
+
+	CreateWebhook(ctx context.Context, url string, secret string) (*outbox.Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookId int64) error
+	GetWebhooks(ctx context.Context) []outbox.Webhook
+	GetDeadLetters(ctx context.Context) []outbox.DeadLetter
+
+	GetStats(ctx context.Context) Stats
 }
 
 type Repository interface {
-	GetAdById(id int64) (ads.Ad, error)
-	AddAd(ad *ads.Ad)
-	GetAdsPrimaryKey() int64
-	ChangeAd(ad *ads.Ad) bool
+	GetAdById(ctx context.Context, id int64) (ads.Ad, error)
+	AddAd(ctx context.Context, ad *ads.Ad)
+	GetAdsPrimaryKey(ctx context.Context) int64
+	ChangeAd(ctx context.Context, ad *ads.Ad) bool
+	// ChangeAds применяет change к объявлениям ids в одной транзакции: если какого-то объявления нет
+	// (IncorrectAdId) или change вернула ошибку, не сохраняется ни одно. Возвращает изменённые
+	// объявления в порядке ids
+	ChangeAds(ctx context.Context, ids []int64, change func(ad *ads.Ad) error) ([]ads.Ad, error)
+
+	GetAds(ctx context.Context, filters map[string]any) []ads.Ad
+	GetAdsByTitle(ctx context.Context, pattern string) []ads.Ad
+	// GetAdsPage возвращает до limit объявлений с ID больше afterID по тем же фильтрам, что GetAds,
+	// по возрастанию ID. ExportAds читает так всю таблицу, поэтому страница не должна требовать
+	// выборки всех подходящих объявлений
+	GetAdsPage(ctx context.Context, filters map[string]any, afterID int64, limit int) []ads.Ad
+
+	GetUserById(ctx context.Context, id int64) (users.User, error)
+	// GetUserByEmail ищет пользователя по нормализованному email; UserNotFound, если такого нет
+	GetUserByEmail(ctx context.Context, email string) (users.User, error)
+	// AddUser и ChangeUser не сохраняют пользователя и возвращают EmailTaken или NicknameTaken,
+	// если email или ник (без учёта регистра) уже занят другим пользователем.
+	// ChangeUser возвращает ok == false, если пользователя нет или он не сохранён
+	AddUser(ctx context.Context, user *users.User) error
+	ChangeUser(ctx context.Context, user *users.User) (ok bool, err error)
+	// TouchUser меняет только LastSeenAt пользователя; если пользователя нет, ничего не делает
+	TouchUser(ctx context.Context, id int64, at time.Time)
+	GetUsersPrimaryKey(ctx context.Context) int64
+	DeleteAd(ctx context.Context, adId int64)
+	// DeleteUser удаляет и токены пользователя
+	DeleteUser(ctx context.Context, uerId int64)
+
+	// AddToken сохраняет токен и удаляет прежний токен пользователя с тем же назначением:
+	// действует только токен из последнего письма
+	AddToken(ctx context.Context, token users.Token)
+	// TakeToken удаляет токен с хэшем hash и возвращает его; ok == false, если такого нет
+	TakeToken(ctx context.Context, hash string) (token users.Token, ok bool)
+
+	// AddAd, ChangeAd и DeleteAd в той же записи кладут событие в outbox
+	GetOutboxEvents(ctx context.Context, limit int) []outbox.Event
+	DeleteOutboxEvent(ctx context.Context, id int64)
+
+	AddWebhook(ctx context.Context, webhook *outbox.Webhook)
+	GetWebhooksPrimaryKey(ctx context.Context) int64
+	GetWebhooks(ctx context.Context) []outbox.Webhook
+	DeleteWebhook(ctx context.Context, id int64) bool
+	AddDeadLetter(ctx context.Context, letter *outbox.DeadLetter)
+	GetDeadLetters(ctx context.Context) []outbox.DeadLetter
+
+	CountAds(ctx context.Context) (total int, published int)
+	CountUsers(ctx context.Context) int
+	// Ping проверяет, что хранилище отвечает; на нём держится проверка готовности сервиса
+	Ping(ctx context.Context) error
+
+	// WithTx выполняет fn как одну транзакцию: если fn вернула ошибку или запаниковала,
+	// всё, что она изменила через tx, откатывается. Внутри fn репозиторий доступен только через tx,
+	// и tx нельзя передавать в другие горутины или использовать после возврата из fn
+	WithTx(ctx context.Context, fn func(tx Repository) error) error
+}
 
-	GetAds(filters map[string]any) []ads.Ad
-	GetAdsByTitle(pattern string) []ads.Ad
+type Option func(*appRepo)
 
-	GetUserById(id int64) (users.User, error)
-	AddUser(user *users.User)
-	ChangeUser(user *users.User) bool
-	GetUsersPrimaryKey() int64
-	DeleteAd(adId int64)
-	DeleteUser(uerId int64)
+// WithMailer задаёт, через что уходят письма с токенами. По умолчанию письма пишутся в лог
+func WithMailer(m mailer.Mailer) Option {
+	return func(a *appRepo) {
+		a.mailer = m
+	}
 }
 
-func NewApp(repo Repository) App {
-	return &appRepo{repo}
+// WithPrivateWebhookTargets разрешает регистрировать вебхуки на loopback, частные и link-local адреса.
+// Нужно для локальной разработки и тестов, в остальных случаях такие адреса отклоняются
+func WithPrivateWebhookTargets(allow bool) Option {
+	return func(a *appRepo) {
+		a.privateWebhookTargets = allow
+	}
 }
 
-type appRepo struct {
-	repository Repository
+// WithTokenTTL задаёт, сколько действуют токены подтверждения email и сброса пароля.
+// Неположительное значение оставляет срок по умолчанию
+func WithTokenTTL(verifyEmail, resetPassword time.Duration) Option {
+	return func(a *appRepo) {
+		if verifyEmail > 0 {
+			a.verifyEmailTTL = verifyEmail
+		}
+		if resetPassword > 0 {
+			a.resetPasswordTTL = resetPassword
+		}
+	}
 }
 
-func (a *appRepo) CreateAd(title string, text string, userId int64) (*ads.Ad, error) {
-	if _, err := a.repository.GetUserById(userId); err != nil {
-		return nil, IncorrectUserId
+func NewApp(repo Repository, opts ...Option) App {
+	a := &appRepo{
+		repository:       repo,
+		mailer:           mailer.Log{},
+		verifyEmailTTL:   DefaultVerifyEmailTTL,
+		resetPasswordTTL: DefaultResetPasswordTTL,
+		now:              time.Now,
 	}
-	now := time.Now().UTC()
-	ad := ads.Ad{ID: a.repository.GetAdsPrimaryKey(), Title: title, Text: text, AuthorID: userId, DateCreating: now, DateUpdate: now, Published: false}
-	if Validator.Validate(ad) != nil {
-		return nil, ValidateError
+	for _, opt := range opts {
+		opt(a)
 	}
-	a.repository.AddAd(&ad)
-	return &ad, nil
+	return a
 }
 
-func (a *appRepo) CreateUser(nickname string, email string) (*users.User, error) {
-	user := users.User{ID: a.repository.GetUsersPrimaryKey(), Nickname: nickname, Email: email}
-	if Validator.Validate(user) != nil {
-		return nil, ValidateError
-	}
+type appRepo struct {
+	repository Repository
 
-	a.repository.AddUser(&user)
-	return &user, nil
+	mailer           mailer.Mailer
+	verifyEmailTTL   time.Duration
+	resetPasswordTTL time.Duration
+	now              func() time.Time // часы для сроков токенов, даты регистрации и LastSeenAt
+
+	privateWebhookTargets bool
 }
 
-func (a *appRepo) ChangeAdStatus(adId int64, userId int64, published bool) (*ads.Ad, error) {
-	ad, err := a.repository.GetAdById(adId)
+func (a *appRepo) CreateAd(ctx context.Context, title string, text string, userId int64) (*ads.Ad, error) {
+	var ad ads.Ad
+	err := a.repository.WithTx(ctx, func(tx Repository) error {
+		if _, err := tx.GetUserById(ctx, userId); err != nil {
+			return IncorrectUserId
+		}
+		now := time.Now().UTC()
+		ad = ads.Ad{ID: tx.GetAdsPrimaryKey(ctx), Title: title, Text: text, AuthorID: userId, DateCreating: now, DateUpdate: now, Published: false}
+		if err := validate(ad); err != nil {
+			return err
+		}
+		tx.AddAd(ctx, &ad)
+		tx.TouchUser(ctx, userId, a.now().UTC())
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	ad.Published = published
-	ad.DateUpdate = time.Now().UTC()
-	if userId != ad.AuthorID {
-		return nil, IncorrectUserId
-	}
-	if Validator.Validate(ad) != nil {
-		return nil, ValidateError
-	}
-
-	a.repository.ChangeAd(&ad)
+	logging.FromContext(ctx).Info("ad created", slog.Int64("ad_id", ad.ID), slog.Int64("user_id", userId))
 	return &ad, nil
 }
 
-func (a *appRepo) UpdateAd(adId int64, userId int64, title string, text string) (*ads.Ad, error) {
-	ad, err := a.repository.GetAdById(adId)
+// CreateUser сразу отправляет письмо для подтверждения email. Если письмо не ушло, пользователь
+// всё равно создан и может запросить письмо ещё раз через RequestEmailVerification
+func (a *appRepo) CreateUser(ctx context.Context, nickname string, email string) (*users.User, error) {
+	var user users.User
+	var token string
+	err := a.repository.WithTx(ctx, func(tx Repository) error {
+		now := a.now().UTC()
+		user = users.User{ID: tx.GetUsersPrimaryKey(ctx), Nickname: nickname, Email: email, CreatedAt: now, LastSeenAt: now}
+		extra := normalizeUser(&user)
+		if err := validate(user, extra...); err != nil {
+			return err
+		}
+		if err := tx.AddUser(ctx, &user); err != nil {
+			return err
+		}
+
+		var err error
+		token, err = a.issueToken(ctx, tx, user, users.PurposeVerifyEmail)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
+	logging.FromContext(ctx).Info("user created", slog.Int64("user_id", user.ID), slog.String("email", user.Email))
+	a.sendVerificationOrWarn(ctx, user, token)
+	return &user, nil
+}
 
-	ad.Text = text
-	ad.Title = title
-	ad.DateUpdate = time.Now().UTC()
-	if userId != ad.AuthorID {
-		return nil, IncorrectUserId
-	}
-	if Validator.Validate(ad) != nil {
-		return nil, ValidateError
+func (a *appRepo) ChangeAdStatus(ctx context.Context, adId int64, userId int64, published bool) (*ads.Ad, error) {
+	ad, err := a.changeAd(ctx, adId, userId, func(ad *ads.Ad) {
+		ad.Published = published
+	})
+	if err != nil {
+		return nil, err
 	}
+	logging.FromContext(ctx).Info("ad status changed", slog.Int64("ad_id", ad.ID), slog.Bool("published", published))
+	return ad, nil
+}
 
-	a.repository.ChangeAd(&ad)
+func (a *appRepo) UpdateAd(ctx context.Context, adId int64, userId int64, title string, text string) (*ads.Ad, error) {
+	return a.changeAd(ctx, adId, userId, func(ad *ads.Ad) {
+		ad.Text = text
+		ad.Title = title
+	})
+}
+
+// changeAd читает объявление, проверяет автора, применяет change и сохраняет - одной транзакцией,
+// чтобы параллельное изменение не потерялось между чтением и записью
+func (a *appRepo) changeAd(ctx context.Context, adId int64, userId int64, change func(ad *ads.Ad)) (*ads.Ad, error) {
+	var ad ads.Ad
+	err := a.repository.WithTx(ctx, func(tx Repository) error {
+		var err error
+		ad, err = tx.GetAdById(ctx, adId)
+		if err != nil {
+			return err
+		}
+
+		wasPublished := ad.Published
+		change(&ad)
+		ad.DateUpdate = time.Now().UTC()
+		if userId != ad.AuthorID {
+			return IncorrectUserId
+		}
+		if ad.Published && !wasPublished {
+			if err := canPublish(ctx, tx, userId); err != nil {
+				return err
+			}
+		}
+		if err := validate(ad); err != nil {
+			return err
+		}
+
+		tx.ChangeAd(ctx, &ad)
+		tx.TouchUser(ctx, userId, a.now().UTC())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 	return &ad, nil
 }
 
-func (a *appRepo) GetAd(id int64) (*ads.Ad, error) {
-	ad, err := a.repository.GetAdById(id)
-	return &ad, err
+func (a *appRepo) GetAd(ctx context.Context, id int64) (*ads.Ad, error) {
+	ad, err := a.repository.GetAdById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &ad, nil
 }
 
-func (a *appRepo) GetListAds(filters map[string]any) []ads.Ad {
-	return a.repository.GetAds(filters)
+func (a *appRepo) GetListAds(ctx context.Context, filters map[string]any) []ads.Ad {
+	return a.repository.GetAds(ctx, filters)
 }
 
-func (a *appRepo) GetListAdsByTitle(pattern string) []ads.Ad {
-	return a.repository.GetAdsByTitle(pattern)
+func (a *appRepo) GetListAdsByTitle(ctx context.Context, pattern string) []ads.Ad {
+	return a.repository.GetAdsByTitle(ctx, pattern)
 }
 
-func (a *appRepo) UpdateUser(userId int64, nickname string, email string) (*users.User, error) {
-	user, err := a.repository.GetUserById(userId)
+// UpdateUser при смене email снимает подтверждение и отправляет письмо на новый адрес.
+// Email меняется только с текущим паролем, как и профиль: иначе кто угодно мог бы
+// перевести аккаунт на свой адрес и сбросить пароль через него
+func (a *appRepo) UpdateUser(ctx context.Context, userId int64, nickname string, email string, password string) (*users.User, error) {
+	var user users.User
+	var token string
+	err := a.repository.WithTx(ctx, func(tx Repository) error {
+		var err error
+		user, err = tx.GetUserById(ctx, userId)
+		if err != nil {
+			return err
+		}
+
+		oldEmail := user.Email
+		user.Nickname = nickname
+		user.Email = email
+		user.LastSeenAt = a.now().UTC()
+		extra := normalizeUser(&user)
+		if err := validate(user, extra...); err != nil {
+			return err
+		}
+
+		// новый адрес надо подтвердить заново
+		if user.Email != oldEmail {
+			if err := checkPassword(user, password); err != nil {
+				return err
+			}
+			user.EmailVerified = false
+			if token, err = a.issueToken(ctx, tx, user, users.PurposeVerifyEmail); err != nil {
+				return err
+			}
+		}
+		_, err = tx.ChangeUser(ctx, &user)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	user.Nickname = nickname
-	user.Email = email
-	if Validator.Validate(user) != nil {
-		return nil, ValidateError
+	if token != "" {
+		a.sendVerificationOrWarn(ctx, user, token)
 	}
+	return &user, nil
+}
 
-	a.repository.ChangeUser(&user)
+func (a *appRepo) GetUser(ctx context.Context, userId int64) (*users.User, error) {
+	user, err := a.repository.GetUserById(ctx, userId)
+	if err != nil {
+		return nil, userNotFound(err)
+	}
 	return &user, nil
 }
 
-func (a *appRepo) GetUser(userId int64) (*users.User, error) {
-	user, err := a.repository.GetUserById(userId)
-	return &user, err
+// GetUserByEmail ищет пользователя по email без учёта регистра и пробелов по краям
+func (a *appRepo) GetUserByEmail(ctx context.Context, email string) (*users.User, error) {
+	user, err := a.repository.GetUserByEmail(ctx, users.NormalizeEmail(email))
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
 }
 
-func (a *appRepo) DeleteUser(userId int64) error {
-	_, err := a.repository.GetUserById(userId)
+// DeleteUser удаляет пользователя вместе со всеми его объявлениями одной транзакцией
+func (a *appRepo) DeleteUser(ctx context.Context, userId int64) error {
+	deletedAds := 0
+	err := a.repository.WithTx(ctx, func(tx Repository) error {
+		if _, err := tx.GetUserById(ctx, userId); err != nil {
+			return userNotFound(err)
+		}
+
+		for _, ad := range tx.GetAds(ctx, map[string]any{"user_id": userId}) {
+			tx.DeleteAd(ctx, ad.ID)
+			deletedAds++
+		}
+		tx.DeleteUser(ctx, userId)
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-
-	a.repository.DeleteUser(userId)
+	logging.FromContext(ctx).Info("user deleted", slog.Int64("user_id", userId), slog.Int("ads_deleted", deletedAds))
 	return nil
 }
 
-func (a *appRepo) DeleteAd(adId int64, userId int64) error {
-	ad, err := a.repository.GetAdById(adId)
+func (a *appRepo) DeleteAd(ctx context.Context, adId int64, userId int64) error {
+	err := a.repository.WithTx(ctx, func(tx Repository) error {
+		ad, err := tx.GetAdById(ctx, adId)
+		if err != nil {
+			return err
+		}
+
+		if ad.AuthorID != userId {
+			return IncorrectUserId
+		}
+
+		tx.DeleteAd(ctx, adId)
+		tx.TouchUser(ctx, userId, a.now().UTC())
+		return nil
+	})
 	if err != nil {
 		return err
 	}
+	logging.FromContext(ctx).Info("ad deleted", slog.Int64("ad_id", adId), slog.Int64("user_id", userId))
+	return nil
+}
+
+func (a *appRepo) CreateWebhook(ctx context.Context, rawURL string, secret string) (*outbox.Webhook, error) {
+	var extra []FieldViolation
+	if u, err := url.Parse(rawURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		extra = append(extra, FieldViolation{Field: "url", Description: "should be an absolute http or https URL"})
+	} else if !a.privateWebhookTargets && !outbox.PublicHost(u.Hostname()) {
+		extra = append(extra, FieldViolation{Field: "url", Description: "should not point to a loopback, private or link-local address"})
+	}
+
+	var webhook outbox.Webhook
+	err := a.repository.WithTx(ctx, func(tx Repository) error {
+		webhook = outbox.Webhook{ID: tx.GetWebhooksPrimaryKey(ctx), URL: rawURL, Secret: secret}
+		if err := validate(webhook, extra...); err != nil {
+			return err
+		}
+		tx.AddWebhook(ctx, &webhook)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
 
-	if ad.AuthorID != userId {
-		return IncorrectUserId
+func (a *appRepo) DeleteWebhook(ctx context.Context, webhookId int64) error {
+	if !a.repository.DeleteWebhook(ctx, webhookId) {
+		return IncorrectWebhookId
 	}
+	return nil
+}
+
+func (a *appRepo) GetWebhooks(ctx context.Context) []outbox.Webhook {
+	return a.repository.GetWebhooks(ctx)
+}
 
-	a.repository.DeleteAd(adId)
+func (a *appRepo) GetDeadLetters(ctx context.Context) []outbox.DeadLetter {
+	return a.repository.GetDeadLetters(ctx)
+}
+
+func (a *appRepo) GetStats(ctx context.Context) Stats {
+	adsCount, publishedCount := a.repository.CountAds(ctx)
+	return Stats{Ads: adsCount, PublishedAds: publishedCount, Users: a.repository.CountUsers(ctx)}
+}
+
+// normalizeUser приводит ник и email к виду, в котором они хранятся, и проверяет формат email.
+// Пустой email не проверяется: его уже не пропустит тег validate
+func normalizeUser(user *users.User) []FieldViolation {
+	user.Nickname = users.NormalizeNickname(user.Nickname)
+	user.Email = users.NormalizeEmail(user.Email)
+	if user.Email == "" {
+		return nil
+	}
+	if addr, err := mail.ParseAddress(user.Email); err != nil || addr.Address != user.Email {
+		return []FieldViolation{{Field: "email", Description: "should be a valid email address"}}
+	}
 	return nil
 }
+
+// userNotFound: репозиторий сообщает об отсутствии пользователя через IncorrectUserId,
+// но там, где пользователь - сам объект запроса, это «не найден», а не «запрещено»
+func userNotFound(err error) error {
+	if errors.Is(err, IncorrectUserId) {
+		return UserNotFound
+	}
+	return err
+}
@@ -1,11 +1,17 @@
-package app
+package app_test
 
 import (
+	"context"
+	"errors"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/bcrypt"
 	"homework10/internal/ads"
+	"homework10/internal/app"
 	"homework10/internal/app/mocks"
+	"homework10/internal/outbox"
 	"homework10/internal/users"
+	"io"
 	"testing"
 	"time"
 )
@@ -13,6 +19,16 @@ import (
 const dateFormat string = "2006-01-02"
 const one int64 = 1
 
+// author - автор объявлений с подтверждённым email: только такой может публиковать
+var author = users.User{ID: one, Nickname: "author", Email: "author@mail.ru", EmailVerified: true}
+
+// clockAt - часы приложения, которые всегда показывают at: по ним ставятся даты регистрации и LastSeenAt
+func clockAt(at time.Time) app.Option {
+	return app.WithClock(func() time.Time { return at })
+}
+
+var registered = time.Date(2023, time.May, 1, 12, 0, 0, 0, time.UTC)
+
 type AppRepoTestSuite struct {
 	suite.Suite
 	repo mocks.Repository
@@ -20,6 +36,16 @@ type AppRepoTestSuite struct {
 
 func (s *AppRepoTestSuite) SetupTest() {
 	s.repo = mocks.Repository{}
+	runTxOn(&s.repo)
+}
+
+// runTxOn настраивает WithTx мока так, что транзакция идёт через сам мок.
+// TouchUser сопровождает каждую запись от имени пользователя, поэтому разрешён всегда
+func runTxOn(repo *mocks.Repository) {
+	repo.On("WithTx", mock.Anything, mock.Anything).Return(func(_ context.Context, fn func(app.Repository) error) error {
+		return fn(repo)
+	})
+	repo.On("TouchUser", mock.Anything, mock.Anything, mock.Anything).Maybe()
 }
 
 func TestRepoRun(t *testing.T) {
@@ -29,12 +55,12 @@ func TestRepoRun(t *testing.T) {
 func (s *AppRepoTestSuite) TestAppRepo_CreateAd() {
 	now := time.Now().UTC()
 	expect := ads.Ad{ID: one, Title: "ad 1", Text: "text 1", AuthorID: one, Published: false, DateCreating: now, DateUpdate: now}
-	s.repo.On("GetUserById", one).Return(users.User{}, nil)
-	s.repo.On("GetAdsPrimaryKey").Return(one)
-	s.repo.On("AddAd", mock.AnythingOfType("*ads.Ad"))
+	s.repo.On("GetUserById", mock.Anything, one).Return(users.User{}, nil)
+	s.repo.On("GetAdsPrimaryKey", mock.Anything, mock.Anything).Return(one)
+	s.repo.On("AddAd", mock.Anything, mock.AnythingOfType("*ads.Ad"))
 
-	service := NewApp(&s.repo)
-	got, err := service.CreateAd(expect.Title, expect.Text, expect.AuthorID)
+	service := app.NewApp(&s.repo)
+	got, err := service.CreateAd(context.Background(), expect.Title, expect.Text, expect.AuthorID)
 	s.NoError(err)
 
 	expect.DateCreating = CutTime(expect.DateCreating)
@@ -48,12 +74,13 @@ func (s *AppRepoTestSuite) TestAppRepo_CreateAd() {
 func (s *AppRepoTestSuite) TestAppRepo_ChangeAdStatus() {
 	now := time.Now().UTC()
 	expect := ads.Ad{ID: one, Title: "ad 1", Text: "text 1", AuthorID: one, Published: false, DateCreating: now, DateUpdate: now}
-	s.repo.On("GetAdById", one).Return(expect, nil)
-	s.repo.On("ChangeAd", mock.AnythingOfType("*ads.Ad")).Return(true)
+	s.repo.On("GetAdById", mock.Anything, one).Return(expect, nil)
+	s.repo.On("GetUserById", mock.Anything, one).Return(author, nil)
+	s.repo.On("ChangeAd", mock.Anything, mock.AnythingOfType("*ads.Ad")).Return(true)
 
-	service := NewApp(&s.repo)
+	service := app.NewApp(&s.repo)
 	expect.Published = true
-	got, err := service.ChangeAdStatus(expect.ID, expect.AuthorID, expect.Published)
+	got, err := service.ChangeAdStatus(context.Background(), expect.ID, expect.AuthorID, expect.Published)
 	s.NoError(err)
 
 	got.DateUpdate = CutTime(got.DateUpdate)
@@ -65,13 +92,13 @@ func (s *AppRepoTestSuite) TestAppRepo_ChangeAdStatus() {
 func (s *AppRepoTestSuite) TestAppRepo_UpdateAd() {
 	now := time.Now().UTC()
 	expect := ads.Ad{ID: one, Title: "ad 1", Text: "text 1", AuthorID: one, Published: false, DateCreating: now, DateUpdate: now}
-	s.repo.On("GetAdById", one).Return(expect, nil)
-	s.repo.On("ChangeAd", mock.AnythingOfType("*ads.Ad")).Return(true)
+	s.repo.On("GetAdById", mock.Anything, one).Return(expect, nil)
+	s.repo.On("ChangeAd", mock.Anything, mock.AnythingOfType("*ads.Ad")).Return(true)
 
-	service := NewApp(&s.repo)
+	service := app.NewApp(&s.repo)
 	expect.Text = "text 2"
 	expect.Title = "ad 2"
-	got, err := service.UpdateAd(expect.ID, expect.AuthorID, expect.Title, expect.Text)
+	got, err := service.UpdateAd(context.Background(), expect.ID, expect.AuthorID, expect.Title, expect.Text)
 	s.NoError(err)
 
 	got.DateUpdate = CutTime(got.DateUpdate)
@@ -83,43 +110,43 @@ func (s *AppRepoTestSuite) TestAppRepo_UpdateAd() {
 func (s *AppRepoTestSuite) TestAppRepo_DeleteAd() {
 	now := time.Now().UTC()
 	expect := ads.Ad{ID: one, Title: "ad 1", Text: "text 1", AuthorID: one, Published: false, DateCreating: now, DateUpdate: now}
-	s.repo.On("GetAdById", one).Return(expect, nil)
-	s.repo.On("DeleteAd", one)
+	s.repo.On("GetAdById", mock.Anything, one).Return(expect, nil)
+	s.repo.On("DeleteAd", mock.Anything, one)
 
-	service := NewApp(&s.repo)
-	err := service.DeleteAd(expect.ID, expect.AuthorID)
+	service := app.NewApp(&s.repo)
+	err := service.DeleteAd(context.Background(), expect.ID, expect.AuthorID)
 	s.NoError(err)
 }
 
 func (s *AppRepoTestSuite) TestAppRepo_DeleteAdIncorrectAdId() {
 	now := time.Now().UTC()
 	expect := ads.Ad{ID: one, Title: "ad 1", Text: "text 1", AuthorID: one, Published: false, DateCreating: now, DateUpdate: now}
-	s.repo.On("GetAdById", one).Return(expect, IncorrectAdId)
-	s.repo.On("DeleteAd", one)
+	s.repo.On("GetAdById", mock.Anything, one).Return(expect, app.IncorrectAdId)
+	s.repo.On("DeleteAd", mock.Anything, one)
 
-	service := NewApp(&s.repo)
-	err := service.DeleteAd(expect.ID, expect.AuthorID)
-	s.ErrorIs(err, IncorrectAdId)
+	service := app.NewApp(&s.repo)
+	err := service.DeleteAd(context.Background(), expect.ID, expect.AuthorID)
+	s.ErrorIs(err, app.IncorrectAdId)
 }
 
 func (s *AppRepoTestSuite) TestAppRepo_DeleteAdIncorrectUserId() {
 	now := time.Now().UTC()
 	expect := ads.Ad{ID: one, Title: "ad 1", Text: "text 1", AuthorID: one, Published: false, DateCreating: now, DateUpdate: now}
-	s.repo.On("GetAdById", one).Return(expect, nil)
-	s.repo.On("DeleteAd", one)
+	s.repo.On("GetAdById", mock.Anything, one).Return(expect, nil)
+	s.repo.On("DeleteAd", mock.Anything, one)
 
-	service := NewApp(&s.repo)
-	err := service.DeleteAd(expect.ID, int64(2))
-	s.ErrorIs(err, IncorrectUserId)
+	service := app.NewApp(&s.repo)
+	err := service.DeleteAd(context.Background(), expect.ID, int64(2))
+	s.ErrorIs(err, app.IncorrectUserId)
 }
 
 func (s *AppRepoTestSuite) TestAppRepo_GetAd() {
 	now := time.Now().UTC()
 	expect := ads.Ad{ID: one, Title: "ad 1", Text: "text 1", AuthorID: one, Published: false, DateCreating: now, DateUpdate: now}
-	s.repo.On("GetAdById", one).Return(expect, nil)
+	s.repo.On("GetAdById", mock.Anything, one).Return(expect, nil)
 
-	service := NewApp(&s.repo)
-	got, err := service.GetAd(expect.ID)
+	service := app.NewApp(&s.repo)
+	got, err := service.GetAd(context.Background(), expect.ID)
 	s.NoError(err)
 	s.Equal(expect, *got)
 }
@@ -135,10 +162,10 @@ func (s *AppRepoTestSuite) TestAppRepo_GetListAds() {
 	}
 
 	expectedList := []ads.Ad{expect1, expect2}
-	s.repo.On("GetAds", filters).Return(expectedList, nil)
+	s.repo.On("GetAds", mock.Anything, filters).Return(expectedList, nil)
 
-	service := NewApp(&s.repo)
-	gotList := service.GetListAds(filters)
+	service := app.NewApp(&s.repo)
+	gotList := service.GetListAds(context.Background(), filters)
 	s.Equal(gotList, expectedList)
 }
 
@@ -149,10 +176,10 @@ func (s *AppRepoTestSuite) TestAppRepo_GetListAdsByTitle() {
 	pattern := "ad"
 
 	expectedList := []ads.Ad{expect1, expect2}
-	s.repo.On("GetAdsByTitle", pattern).Return(expectedList, nil)
+	s.repo.On("GetAdsByTitle", mock.Anything, pattern).Return(expectedList, nil)
 
-	service := NewApp(&s.repo)
-	gotList := service.GetListAdsByTitle(pattern)
+	service := app.NewApp(&s.repo)
+	gotList := service.GetListAdsByTitle(context.Background(), pattern)
 	s.Equal(gotList, expectedList)
 }
 
@@ -163,194 +190,495 @@ func CutTime(t time.Time) time.Time {
 }
 
 func (s *AppRepoTestSuite) TestAppRepo_CreateUser() {
-	expect := users.User{ID: one, Nickname: "nickname 1", Email: "email"}
+	expect := users.User{ID: one, Nickname: "nickname 1", Email: "email@mail.ru", CreatedAt: registered, LastSeenAt: registered}
 
-	s.repo.On("GetUsersPrimaryKey").Return(one)
-	s.repo.On("AddUser", mock.AnythingOfType("*users.User"))
+	s.repo.On("GetUsersPrimaryKey", mock.Anything, mock.Anything).Return(one)
+	s.repo.On("AddUser", mock.Anything, mock.AnythingOfType("*users.User")).Return(nil)
+	s.repo.On("AddToken", mock.Anything, mock.AnythingOfType("users.Token"))
 
-	service := NewApp(&s.repo)
-	got, err := service.CreateUser(expect.Nickname, expect.Email)
+	service := app.NewApp(&s.repo, clockAt(registered))
+	got, err := service.CreateUser(context.Background(), expect.Nickname, expect.Email)
 	s.NoError(err)
 	s.Equal(*got, expect)
 }
 
+func (s *AppRepoTestSuite) TestAppRepo_CreateUserNormalizes() {
+	s.repo.On("GetUsersPrimaryKey", mock.Anything, mock.Anything).Return(one)
+	s.repo.On("AddUser", mock.Anything, mock.AnythingOfType("*users.User")).Return(nil)
+	s.repo.On("AddToken", mock.Anything, mock.AnythingOfType("users.Token"))
+
+	service := app.NewApp(&s.repo, clockAt(registered))
+	got, err := service.CreateUser(context.Background(), " nickname 1 ", " Email@Mail.RU ")
+	s.NoError(err)
+	s.Equal(users.User{ID: one, Nickname: "nickname 1", Email: "email@mail.ru", CreatedAt: registered, LastSeenAt: registered}, *got)
+}
+
+func (s *AppRepoTestSuite) TestAppRepo_CreateUserEmailTaken() {
+	s.repo.On("GetUsersPrimaryKey", mock.Anything, mock.Anything).Return(one)
+	s.repo.On("AddUser", mock.Anything, mock.AnythingOfType("*users.User")).Return(app.EmailTaken)
+
+	service := app.NewApp(&s.repo)
+	_, err := service.CreateUser(context.Background(), "nickname 1", "email@mail.ru")
+	s.ErrorIs(err, app.EmailTaken)
+	s.Equal(app.KindConflict, app.KindOf(err))
+}
+
+func (s *AppRepoTestSuite) TestAppRepo_GetUserByEmail() {
+	expect := users.User{ID: one, Nickname: "nickname 1", Email: "email@mail.ru"}
+	s.repo.On("GetUserByEmail", mock.Anything, "email@mail.ru").Return(expect, nil)
+
+	service := app.NewApp(&s.repo)
+	got, err := service.GetUserByEmail(context.Background(), " EMAIL@mail.ru")
+	s.NoError(err)
+	s.Equal(expect, *got)
+}
+
 func (s *AppRepoTestSuite) TestAppRepo_UpdateUser() {
-	expect := users.User{ID: one, Nickname: "nickname 1", Email: "email 1"}
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	s.Require().NoError(err)
+	expect := users.User{ID: one, Nickname: "nickname 1", Email: "email1@mail.ru", PasswordHash: string(hash), CreatedAt: registered, LastSeenAt: registered}
 
-	s.repo.On("GetUserById", one).Return(expect, nil)
-	s.repo.On("ChangeUser", mock.AnythingOfType("*users.User")).Return(true)
+	s.repo.On("GetUserById", mock.Anything, one).Return(expect, nil)
+	s.repo.On("ChangeUser", mock.Anything, mock.AnythingOfType("*users.User")).Return(true, nil)
+	s.repo.On("AddToken", mock.Anything, mock.AnythingOfType("users.Token"))
 
-	service := NewApp(&s.repo)
+	lastSeen := registered.Add(time.Hour)
+	service := app.NewApp(&s.repo, clockAt(lastSeen))
 	expect.Nickname = "nickname 2"
-	expect.Email = "email 2"
-	got, err := service.UpdateUser(expect.ID, expect.Nickname, expect.Email)
+	expect.Email = "email2@mail.ru"
+	expect.LastSeenAt = lastSeen
+	got, err := service.UpdateUser(context.Background(), expect.ID, expect.Nickname, expect.Email, "password")
 	s.NoError(err)
 	s.Equal(*got, expect)
 }
 
+func (s *AppRepoTestSuite) TestAppRepo_UpdateUserWrongPassword() {
+	expect := users.User{ID: one, Nickname: "nickname 1", Email: "email1@mail.ru"}
+
+	s.repo.On("GetUserById", mock.Anything, one).Return(expect, nil)
+
+	service := app.NewApp(&s.repo)
+	_, err := service.UpdateUser(context.Background(), expect.ID, expect.Nickname, "email2@mail.ru", "password")
+	s.ErrorIs(err, app.WrongPassword)
+	s.repo.AssertNotCalled(s.T(), "ChangeUser", mock.Anything, mock.Anything)
+}
+
 func (s *AppRepoTestSuite) TestAppRepo_UpdateUserIncorrectAdId() {
-	expect := users.User{ID: one, Nickname: "nickname 1", Email: "email 1"}
+	expect := users.User{ID: one, Nickname: "nickname 1", Email: "email1@mail.ru"}
 
-	s.repo.On("GetUserById", one).Return(expect, IncorrectAdId)
-	s.repo.On("ChangeUser", mock.AnythingOfType("*users.User")).Return(true)
+	s.repo.On("GetUserById", mock.Anything, one).Return(expect, app.IncorrectAdId)
+	s.repo.On("ChangeUser", mock.Anything, mock.AnythingOfType("*users.User")).Return(true, nil)
 
-	service := NewApp(&s.repo)
+	service := app.NewApp(&s.repo)
 	expect.Nickname = "nickname 2"
-	expect.Email = "email 2"
-	_, err := service.UpdateUser(expect.ID, expect.Nickname, expect.Email)
-	s.ErrorIs(err, IncorrectAdId)
+	expect.Email = "email2@mail.ru"
+	_, err := service.UpdateUser(context.Background(), expect.ID, expect.Nickname, expect.Email, "")
+	s.ErrorIs(err, app.IncorrectAdId)
 }
 
 func (s *AppRepoTestSuite) TestAppRepo_UpdateUserValidationErr() {
-	expect := users.User{ID: one, Nickname: "nickname 1", Email: "email 1"}
+	expect := users.User{ID: one, Nickname: "nickname 1", Email: "email1@mail.ru"}
 
-	s.repo.On("GetUserById", one).Return(expect, nil)
-	s.repo.On("ChangeUser", mock.AnythingOfType("*users.User")).Return(true)
+	s.repo.On("GetUserById", mock.Anything, one).Return(expect, nil)
+	s.repo.On("ChangeUser", mock.Anything, mock.AnythingOfType("*users.User")).Return(true, nil)
 
-	service := NewApp(&s.repo)
+	service := app.NewApp(&s.repo)
 	expect.Nickname = ""
-	expect.Email = "email 2"
-	_, err := service.UpdateUser(expect.ID, expect.Nickname, expect.Email)
-	s.ErrorIs(err, ValidateError)
+	expect.Email = "email2@mail.ru"
+	_, err := service.UpdateUser(context.Background(), expect.ID, expect.Nickname, expect.Email, "")
+	s.ErrorIs(err, app.ValidateError)
 }
 
 func (s *AppRepoTestSuite) TestAppRepo_GetUser() {
-	expect := users.User{ID: one, Nickname: "nickname 1", Email: "email 1"}
+	expect := users.User{ID: one, Nickname: "nickname 1", Email: "email1@mail.ru"}
 
-	s.repo.On("GetUserById", one).Return(expect, nil)
+	s.repo.On("GetUserById", mock.Anything, one).Return(expect, nil)
 
-	service := NewApp(&s.repo)
-	got, err := service.GetUser(expect.ID)
+	service := app.NewApp(&s.repo)
+	got, err := service.GetUser(context.Background(), expect.ID)
 	s.NoError(err)
 	s.Equal(*got, expect)
 }
 
 func (s *AppRepoTestSuite) TestAppRepo_DeleteUser() {
-	expect := users.User{ID: one, Nickname: "nickname 1", Email: "email 1"}
+	expect := users.User{ID: one, Nickname: "nickname 1", Email: "email1@mail.ru"}
 
-	s.repo.On("GetUserById", expect.ID).Return(expect, nil)
-	s.repo.On("DeleteUser", expect.ID)
+	s.repo.On("GetUserById", mock.Anything, expect.ID).Return(expect, nil)
+	s.repo.On("GetAds", mock.Anything, map[string]any{"user_id": expect.ID}).Return([]ads.Ad{{ID: 3}, {ID: 5}})
+	s.repo.On("DeleteAd", mock.Anything, mock.Anything)
+	s.repo.On("DeleteUser", mock.Anything, expect.ID)
 
-	service := NewApp(&s.repo)
-	err := service.DeleteUser(expect.ID)
+	service := app.NewApp(&s.repo)
+	err := service.DeleteUser(context.Background(), expect.ID)
 	s.NoError(err)
+	s.repo.AssertCalled(s.T(), "DeleteAd", mock.Anything, int64(3))
+	s.repo.AssertCalled(s.T(), "DeleteAd", mock.Anything, int64(5))
+	s.repo.AssertCalled(s.T(), "DeleteUser", mock.Anything, expect.ID)
 }
 
-func (s *AppRepoTestSuite) TestAppRepo_DeleteUserIncorrectUserId() {
-	expect := users.User{ID: one, Nickname: "nickname 1", Email: "email 1"}
+func (s *AppRepoTestSuite) TestAppRepo_DeleteUserNotFound() {
+	expect := users.User{ID: one, Nickname: "nickname 1", Email: "email1@mail.ru"}
+
+	s.repo.On("GetUserById", mock.Anything, expect.ID).Return(expect, app.IncorrectUserId)
+	s.repo.On("DeleteUser", mock.Anything, expect.ID)
+
+	service := app.NewApp(&s.repo)
+	err := service.DeleteUser(context.Background(), expect.ID)
+	s.ErrorIs(err, app.UserNotFound)
+}
 
-	s.repo.On("GetUserById", expect.ID).Return(expect, IncorrectUserId)
-	s.repo.On("DeleteUser", expect.ID)
+func (s *AppRepoTestSuite) TestAppRepo_GetUserNotFound() {
+	s.repo.On("GetUserById", mock.Anything, one).Return(users.User{}, app.IncorrectUserId)
 
-	service := NewApp(&s.repo)
-	err := service.DeleteUser(expect.ID)
-	s.ErrorIs(err, IncorrectUserId)
+	service := app.NewApp(&s.repo)
+	got, err := service.GetUser(context.Background(), one)
+	s.ErrorIs(err, app.UserNotFound)
+	s.Nil(got)
+}
+
+func (s *AppRepoTestSuite) TestAppRepo_GetAdIncorrectAdId() {
+	s.repo.On("GetAdById", mock.Anything, one).Return(ads.Ad{}, app.IncorrectAdId)
+
+	service := app.NewApp(&s.repo)
+	got, err := service.GetAd(context.Background(), one)
+	s.ErrorIs(err, app.IncorrectAdId)
+	s.Nil(got)
 }
 
 // tests which return errors
 func (s *AppRepoTestSuite) TestAppRepo_CreateAdIncorrectUserId() {
 	now := time.Now().UTC()
 	expect := ads.Ad{ID: one, Title: "ad 1", Text: "text 1", AuthorID: one, Published: false, DateCreating: now, DateUpdate: now}
-	s.repo.On("GetUserById", one).Return(users.User{}, IncorrectUserId)
-	s.repo.On("GetAdsPrimaryKey").Return(one)
-	s.repo.On("AddAd", mock.AnythingOfType("*ads.Ad"))
+	s.repo.On("GetUserById", mock.Anything, one).Return(users.User{}, app.IncorrectUserId)
+	s.repo.On("GetAdsPrimaryKey", mock.Anything, mock.Anything).Return(one)
+	s.repo.On("AddAd", mock.Anything, mock.AnythingOfType("*ads.Ad"))
 
-	service := NewApp(&s.repo)
-	_, err := service.CreateAd(expect.Title, expect.Text, expect.AuthorID)
-	s.ErrorIs(err, IncorrectUserId)
+	service := app.NewApp(&s.repo)
+	_, err := service.CreateAd(context.Background(), expect.Title, expect.Text, expect.AuthorID)
+	s.ErrorIs(err, app.IncorrectUserId)
 }
 
 func (s *AppRepoTestSuite) TestAppRepo_CreateAdValidationErr() {
 	now := time.Now().UTC()
 	expect := ads.Ad{ID: one, Title: "", Text: "text 1", AuthorID: one, Published: false, DateCreating: now, DateUpdate: now}
-	s.repo.On("GetUserById", one).Return(users.User{}, nil)
-	s.repo.On("GetAdsPrimaryKey").Return(one)
-	s.repo.On("AddAd", mock.AnythingOfType("*ads.Ad"))
+	s.repo.On("GetUserById", mock.Anything, one).Return(users.User{}, nil)
+	s.repo.On("GetAdsPrimaryKey", mock.Anything, mock.Anything).Return(one)
+	s.repo.On("AddAd", mock.Anything, mock.AnythingOfType("*ads.Ad"))
 
-	service := NewApp(&s.repo)
-	_, err := service.CreateAd(expect.Title, expect.Text, expect.AuthorID)
-	s.ErrorIs(err, ValidateError)
+	service := app.NewApp(&s.repo)
+	_, err := service.CreateAd(context.Background(), expect.Title, expect.Text, expect.AuthorID)
+	s.ErrorIs(err, app.ValidateError)
 }
 
 func (s *AppRepoTestSuite) TestAppRepo_CreateUserValidationErr() {
-	expect := users.User{ID: one, Nickname: "", Email: "email"}
+	expect := users.User{ID: one, Nickname: "", Email: "email@mail.ru"}
 
-	s.repo.On("GetUsersPrimaryKey").Return(one)
-	s.repo.On("AddUser", mock.AnythingOfType("*users.User"))
+	s.repo.On("GetUsersPrimaryKey", mock.Anything, mock.Anything).Return(one)
+	s.repo.On("AddUser", mock.Anything, mock.AnythingOfType("*users.User")).Return(nil)
+	s.repo.On("AddToken", mock.Anything, mock.AnythingOfType("users.Token"))
 
-	service := NewApp(&s.repo)
-	_, err := service.CreateUser(expect.Nickname, expect.Email)
-	s.ErrorIs(err, ValidateError)
+	service := app.NewApp(&s.repo)
+	_, err := service.CreateUser(context.Background(), expect.Nickname, expect.Email)
+	s.ErrorIs(err, app.ValidateError)
 }
 
 func (s *AppRepoTestSuite) TestAppRepo_ChangeAdStatusValidationErr() {
 	now := time.Now().UTC()
 	expect := ads.Ad{ID: one, Title: "", Text: "text 1", AuthorID: one, Published: false, DateCreating: now, DateUpdate: now}
-	s.repo.On("GetAdById", one).Return(expect, nil)
-	s.repo.On("ChangeAd", mock.AnythingOfType("*ads.Ad")).Return(true)
+	s.repo.On("GetAdById", mock.Anything, one).Return(expect, nil)
+	s.repo.On("GetUserById", mock.Anything, one).Return(author, nil)
+	s.repo.On("ChangeAd", mock.Anything, mock.AnythingOfType("*ads.Ad")).Return(true)
 
-	service := NewApp(&s.repo)
+	service := app.NewApp(&s.repo)
 	expect.Published = true
-	_, err := service.ChangeAdStatus(expect.ID, expect.AuthorID, expect.Published)
-	s.ErrorIs(err, ValidateError)
+	_, err := service.ChangeAdStatus(context.Background(), expect.ID, expect.AuthorID, expect.Published)
+	s.ErrorIs(err, app.ValidateError)
 }
 
 func (s *AppRepoTestSuite) TestAppRepo_ChangeAdStatusIncorrectUserId() {
 	now := time.Now().UTC()
 	expect := ads.Ad{ID: one, Title: "title", Text: "text 1", AuthorID: one, Published: false, DateCreating: now, DateUpdate: now}
-	s.repo.On("GetAdById", one).Return(expect, nil)
-	s.repo.On("ChangeAd", mock.AnythingOfType("*ads.Ad")).Return(true)
+	s.repo.On("GetAdById", mock.Anything, one).Return(expect, nil)
+	s.repo.On("ChangeAd", mock.Anything, mock.AnythingOfType("*ads.Ad")).Return(true)
 
-	service := NewApp(&s.repo)
+	service := app.NewApp(&s.repo)
 	expect.Published = true
-	_, err := service.ChangeAdStatus(expect.ID, int64(2), expect.Published)
-	s.ErrorIs(err, IncorrectUserId)
+	_, err := service.ChangeAdStatus(context.Background(), expect.ID, int64(2), expect.Published)
+	s.ErrorIs(err, app.IncorrectUserId)
 }
 
 func (s *AppRepoTestSuite) TestAppRepo_ChangeAdStatusIncorrectAdId() {
 	now := time.Now().UTC()
 	expect := ads.Ad{ID: one, Title: "title", Text: "text 1", AuthorID: one, Published: false, DateCreating: now, DateUpdate: now}
-	s.repo.On("GetAdById", one).Return(expect, IncorrectAdId)
-	s.repo.On("ChangeAd", mock.AnythingOfType("*ads.Ad")).Return(true)
+	s.repo.On("GetAdById", mock.Anything, one).Return(expect, app.IncorrectAdId)
+	s.repo.On("ChangeAd", mock.Anything, mock.AnythingOfType("*ads.Ad")).Return(true)
 
-	service := NewApp(&s.repo)
+	service := app.NewApp(&s.repo)
 	expect.Published = true
-	_, err := service.ChangeAdStatus(expect.ID, expect.AuthorID, expect.Published)
-	s.ErrorIs(err, IncorrectAdId)
+	_, err := service.ChangeAdStatus(context.Background(), expect.ID, expect.AuthorID, expect.Published)
+	s.ErrorIs(err, app.IncorrectAdId)
 }
 
 func (s *AppRepoTestSuite) TestAppRepo_UpdateAdIncorrectAdId() {
 	now := time.Now().UTC()
 	expect := ads.Ad{ID: one, Title: "title", Text: "text 1", AuthorID: one, Published: false, DateCreating: now, DateUpdate: now}
-	s.repo.On("GetAdById", one).Return(expect, IncorrectAdId)
+	s.repo.On("GetAdById", mock.Anything, one).Return(expect, app.IncorrectAdId)
 
-	service := NewApp(&s.repo)
+	service := app.NewApp(&s.repo)
 	expect.Title = "new title"
 	expect.Text = "new text"
-	_, err := service.UpdateAd(expect.ID, expect.AuthorID, expect.Title, expect.Text)
-	s.ErrorIs(err, IncorrectAdId)
+	_, err := service.UpdateAd(context.Background(), expect.ID, expect.AuthorID, expect.Title, expect.Text)
+	s.ErrorIs(err, app.IncorrectAdId)
 }
 
 func (s *AppRepoTestSuite) TestAppRepo_UpdateAdValidationErr() {
 	now := time.Now().UTC()
 	expect := ads.Ad{ID: one, Title: "title", Text: "text 1", AuthorID: one, Published: false, DateCreating: now, DateUpdate: now}
-	s.repo.On("GetAdById", one).Return(expect, nil)
+	s.repo.On("GetAdById", mock.Anything, one).Return(expect, nil)
 
-	service := NewApp(&s.repo)
+	service := app.NewApp(&s.repo)
 	expect.Title = ""
-	_, err := service.UpdateAd(expect.ID, expect.AuthorID, expect.Title, expect.Text)
-	s.ErrorIs(err, ValidateError)
+	_, err := service.UpdateAd(context.Background(), expect.ID, expect.AuthorID, expect.Title, expect.Text)
+	s.ErrorIs(err, app.ValidateError)
 }
 
 func (s *AppRepoTestSuite) TestAppRepo_UpdateAdIncorrectUserId() {
 	now := time.Now().UTC()
 	expect := ads.Ad{ID: one, Title: "title", Text: "text 1", AuthorID: one, Published: false, DateCreating: now, DateUpdate: now}
-	s.repo.On("GetAdById", one).Return(expect, nil)
-	s.repo.On("ChangeAd", mock.AnythingOfType("*ads.Ad")).Return(true)
+	s.repo.On("GetAdById", mock.Anything, one).Return(expect, nil)
+	s.repo.On("ChangeAd", mock.Anything, mock.AnythingOfType("*ads.Ad")).Return(true)
 
-	service := NewApp(&s.repo)
+	service := app.NewApp(&s.repo)
 	expect.Title = "new title"
 	expect.Title = "new text"
-	_, err := service.UpdateAd(expect.ID, int64(2), expect.Title, expect.Text)
-	s.ErrorIs(err, IncorrectUserId)
+	_, err := service.UpdateAd(context.Background(), expect.ID, int64(2), expect.Title, expect.Text)
+	s.ErrorIs(err, app.IncorrectUserId)
+}
+
+func (s *AppRepoTestSuite) TestAppRepo_CreateWebhook() {
+	expect := outbox.Webhook{ID: one, URL: "https://example.com/hook", Secret: "secret"}
+	s.repo.On("GetWebhooksPrimaryKey", mock.Anything, mock.Anything).Return(one)
+	s.repo.On("AddWebhook", mock.Anything, mock.AnythingOfType("*outbox.Webhook"))
+
+	service := app.NewApp(&s.repo)
+	got, err := service.CreateWebhook(context.Background(), expect.URL, expect.Secret)
+	s.NoError(err)
+	s.Equal(expect, *got)
+}
+
+func (s *AppRepoTestSuite) TestAppRepo_CreateWebhookValidationErr() {
+	s.repo.On("GetWebhooksPrimaryKey", mock.Anything, mock.Anything).Return(one)
+
+	service := app.NewApp(&s.repo)
+	for _, rawURL := range []string{"", "ftp://example.com", "not a url", "http://",
+		"http://localhost:8080/hook", "http://127.0.0.1/hook", "http://10.0.0.5/hook", "http://[::1]/hook",
+		"http://169.254.169.254/latest/meta-data", "http://[::ffff:192.168.0.1]/hook"} {
+		_, err := service.CreateWebhook(context.Background(), rawURL, "secret")
+		s.ErrorIs(err, app.ValidateError, rawURL)
+	}
+
+	_, err := service.CreateWebhook(context.Background(), "https://example.com/hook", "")
+	s.ErrorIs(err, app.ValidateError)
+}
+
+func (s *AppRepoTestSuite) TestAppRepo_CreateWebhookPrivateTargetAllowed() {
+	s.repo.On("GetWebhooksPrimaryKey", mock.Anything, mock.Anything).Return(one)
+	s.repo.On("AddWebhook", mock.Anything, mock.AnythingOfType("*outbox.Webhook"))
+
+	service := app.NewApp(&s.repo, app.WithPrivateWebhookTargets(true))
+	_, err := service.CreateWebhook(context.Background(), "http://127.0.0.1:8080/hook", "secret")
+	s.NoError(err)
+}
+
+func (s *AppRepoTestSuite) TestAppRepo_DeleteWebhook() {
+	s.repo.On("DeleteWebhook", mock.Anything, one).Return(true)
+	s.repo.On("DeleteWebhook", mock.Anything, int64(2)).Return(false)
+
+	service := app.NewApp(&s.repo)
+	s.NoError(service.DeleteWebhook(context.Background(), one))
+	s.ErrorIs(service.DeleteWebhook(context.Background(), 2), app.IncorrectWebhookId)
+}
+
+func (s *AppRepoTestSuite) TestAppRepo_GetStats() {
+	s.repo.On("CountAds", mock.Anything, mock.Anything).Return(5, 2)
+	s.repo.On("CountUsers", mock.Anything, mock.Anything).Return(3)
+
+	service := app.NewApp(&s.repo)
+	s.Equal(app.Stats{Ads: 5, PublishedAds: 2, Users: 3}, service.GetStats(context.Background()))
+}
+
+func (s *AppRepoTestSuite) TestAppRepo_ImportAds() {
+	s.repo.On("GetUserById", mock.Anything, one).Return(users.User{}, nil)
+	s.repo.On("GetUserById", mock.Anything, int64(2)).Return(users.User{}, app.IncorrectUserId)
+	s.repo.On("GetAdsPrimaryKey", mock.Anything, mock.Anything).Return(one)
+	s.repo.On("AddAd", mock.Anything, mock.AnythingOfType("*ads.Ad"))
+
+	parseErr := errors.New("bad row")
+	rows := []app.ImportRow{
+		{Line: 2, Title: "ad 1", Text: "text 1", UserID: one},
+		{Line: 3, Err: parseErr},
+		{Line: 4, Title: "", Text: "text 3", UserID: one},
+		{Line: 5, Title: "ad 4", Text: "text 4", UserID: 2},
+		{Line: 6, Title: "ad 5", Text: "text 5", UserID: one},
+	}
+
+	service := app.NewApp(&s.repo)
+	report, err := service.ImportAds(context.Background(), func() (app.ImportRow, error) {
+		if len(rows) == 0 {
+			return app.ImportRow{}, io.EOF
+		}
+		row := rows[0]
+		rows = rows[1:]
+		return row, nil
+	})
+	s.NoError(err)
+	s.Equal(2, report.Imported)
+	s.Equal(3, report.Failed)
+	s.Require().Len(report.Failures, 3)
+	s.Equal(3, report.Failures[0].Line)
+	s.ErrorIs(report.Failures[0].Err, parseErr)
+	s.Equal(4, report.Failures[1].Line)
+	s.ErrorIs(report.Failures[1].Err, app.ValidateError)
+	s.Equal(5, report.Failures[2].Line)
+	s.ErrorIs(report.Failures[2].Err, app.IncorrectUserId)
+	s.repo.AssertNumberOfCalls(s.T(), "AddAd", 2)
+}
+
+func (s *AppRepoTestSuite) TestAppRepo_ImportAdsReadErr() {
+	s.repo.On("GetUserById", mock.Anything, one).Return(users.User{}, nil)
+	s.repo.On("GetAdsPrimaryKey", mock.Anything, mock.Anything).Return(one)
+	s.repo.On("AddAd", mock.Anything, mock.AnythingOfType("*ads.Ad"))
+
+	readErr := errors.New("connection reset")
+	calls := 0
+	service := app.NewApp(&s.repo)
+	report, err := service.ImportAds(context.Background(), func() (app.ImportRow, error) {
+		calls++
+		if calls > 1 {
+			return app.ImportRow{}, readErr
+		}
+		return app.ImportRow{Line: 1, Title: "ad 1", Text: "text 1", UserID: one}, nil
+	})
+	s.ErrorIs(err, readErr)
+	s.Equal(1, report.Imported)
+}
+
+func (s *AppRepoTestSuite) TestAppRepo_ImportAdsTooManyRows() {
+	parseErr := errors.New("bad row")
+	line := 0
+	service := app.NewApp(&s.repo)
+	report, err := service.ImportAds(context.Background(), func() (app.ImportRow, error) {
+		line++
+		return app.ImportRow{Line: line, Err: parseErr}, nil
+	})
+	s.ErrorIs(err, app.TooManyImportRows)
+	s.Equal(app.MaxImportRows, report.Failed)
+	s.Equal(app.MaxImportRows+1, line, "строка сверх лимита прочитана, но не обработана")
+}
+
+func (s *AppRepoTestSuite) TestAppRepo_ExportAds() {
+	filters := map[string]any{"published": true}
+	full := make([]ads.Ad, app.ExportPageSize)
+	for idx := range full {
+		full[idx] = ads.Ad{ID: int64(idx)}
+	}
+	s.repo.On("GetAdsPage", mock.Anything, filters, int64(-1), app.ExportPageSize).Return(full)
+	s.repo.On("GetAdsPage", mock.Anything, filters, int64(app.ExportPageSize-1), app.ExportPageSize).Return([]ads.Ad{{ID: app.ExportPageSize}})
+
+	service := app.NewApp(&s.repo)
+	var got []int64
+	err := service.ExportAds(context.Background(), filters, func(ad ads.Ad) error {
+		got = append(got, ad.ID)
+		return nil
+	})
+	s.NoError(err)
+	s.Len(got, app.ExportPageSize+1)
+	s.Equal(int64(app.ExportPageSize), got[len(got)-1])
+	s.repo.AssertNumberOfCalls(s.T(), "GetAdsPage", 2)
+
+	stop := errors.New("client gone")
+	err = service.ExportAds(context.Background(), filters, func(ads.Ad) error {
+		return stop
+	})
+	s.ErrorIs(err, stop)
+}
+
+func (s *AppRepoTestSuite) TestAppRepo_BatchGetAds() {
+	s.repo.On("GetAdById", mock.Anything, one).Return(ads.Ad{ID: one, Title: "ad 1"}, nil)
+	s.repo.On("GetAdById", mock.Anything, int64(2)).Return(ads.Ad{}, app.IncorrectAdId)
+	s.repo.On("GetAdById", mock.Anything, int64(3)).Return(ads.Ad{ID: 3, Title: "ad 3"}, nil)
+
+	service := app.NewApp(&s.repo)
+	found, missing, err := service.BatchGetAds(context.Background(), []int64{3, 2, one, 3})
+	s.NoError(err)
+	s.Equal([]ads.Ad{{ID: 3, Title: "ad 3"}, {ID: one, Title: "ad 1"}}, found)
+	s.Equal([]int64{2}, missing)
+	s.repo.AssertNumberOfCalls(s.T(), "GetAdById", 3)
+}
+
+func (s *AppRepoTestSuite) TestAppRepo_BatchGetUsers() {
+	s.repo.On("GetUserById", mock.Anything, one).Return(users.User{ID: one, Nickname: "buda"}, nil)
+	s.repo.On("GetUserById", mock.Anything, int64(2)).Return(users.User{}, app.IncorrectUserId)
+
+	service := app.NewApp(&s.repo)
+	found, missing, err := service.BatchGetUsers(context.Background(), []int64{2, one})
+	s.NoError(err)
+	s.Equal([]users.User{{ID: one, Nickname: "buda"}}, found)
+	s.Equal([]int64{2}, missing)
+}
+
+func (s *AppRepoTestSuite) TestAppRepo_BatchTooLarge() {
+	ids := make([]int64, app.MaxBatchSize+1)
+	for idx := range ids {
+		ids[idx] = int64(idx)
+	}
+
+	service := app.NewApp(&s.repo)
+	_, _, err := service.BatchGetAds(context.Background(), ids)
+	s.ErrorIs(err, app.ValidateError)
+	_, _, err = service.BatchGetUsers(context.Background(), ids)
+	s.ErrorIs(err, app.ValidateError)
+	_, err = service.BatchChangeAdStatus(context.Background(), ids, one, true)
+	s.ErrorIs(err, app.ValidateError)
+	s.repo.AssertNotCalled(s.T(), "GetAdById", mock.Anything, mock.Anything)
+	s.repo.AssertNotCalled(s.T(), "ChangeAds", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// changeAds ведёт себя как транзакция репозитория над stored
+func changeAds(stored ...ads.Ad) func(context.Context, []int64, func(*ads.Ad) error) ([]ads.Ad, error) {
+	return func(_ context.Context, _ []int64, change func(*ads.Ad) error) ([]ads.Ad, error) {
+		var changed []ads.Ad
+		for _, ad := range stored {
+			if err := change(&ad); err != nil {
+				return nil, err
+			}
+			changed = append(changed, ad)
+		}
+		return changed, nil
+	}
+}
+
+func (s *AppRepoTestSuite) TestAppRepo_BatchChangeAdStatus() {
+	s.repo.On("GetUserById", mock.Anything, one).Return(author, nil)
+	s.repo.On("ChangeAds", mock.Anything, []int64{one, 2}, mock.Anything).
+		Return(changeAds(ads.Ad{ID: one, Title: "ad 1", Text: "text 1", AuthorID: one}, ads.Ad{ID: 2, Title: "ad 2", Text: "text 2", AuthorID: one}))
+
+	service := app.NewApp(&s.repo)
+	list, err := service.BatchChangeAdStatus(context.Background(), []int64{one, 2, one}, one, true)
+	s.NoError(err)
+	s.Require().Len(list, 2)
+	for _, ad := range list {
+		s.True(ad.Published)
+		s.False(ad.DateUpdate.IsZero())
+	}
+}
+
+func (s *AppRepoTestSuite) TestAppRepo_BatchChangeAdStatusForeignAd() {
+	s.repo.On("GetUserById", mock.Anything, one).Return(author, nil)
+	s.repo.On("ChangeAds", mock.Anything, []int64{one, 2}, mock.Anything).
+		Return(changeAds(ads.Ad{ID: one, Title: "ad 1", Text: "text 1", AuthorID: one}, ads.Ad{ID: 2, Title: "ad 2", Text: "text 2", AuthorID: 2}))
+
+	service := app.NewApp(&s.repo)
+	_, err := service.BatchChangeAdStatus(context.Background(), []int64{one, 2}, one, true)
+	s.ErrorIs(err, app.IncorrectUserId)
 }
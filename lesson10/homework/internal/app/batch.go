@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"homework10/internal/ads"
+	"homework10/internal/logging"
+	"homework10/internal/users"
+)
+
+// MaxBatchSize - сколько ID принимает один пакетный запрос
+const MaxBatchSize = 100
+
+// batchIDs проверяет размер пакета и убирает повторы, сохраняя порядок запроса
+func batchIDs(ids []int64) ([]int64, error) {
+	if len(ids) > MaxBatchSize {
+		return nil, &InvalidFieldsError{Violations: []FieldViolation{
+			{Field: "ids", Description: fmt.Sprintf("should contain at most %d ids", MaxBatchSize)},
+		}}
+	}
+
+	seen := make(map[int64]bool, len(ids))
+	unique := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique, nil
+}
+
+// BatchGetAds возвращает найденные объявления в порядке запроса и ID, которых нет
+func (a *appRepo) BatchGetAds(ctx context.Context, ids []int64) ([]ads.Ad, []int64, error) {
+	ids, err := batchIDs(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	found := make([]ads.Ad, 0, len(ids))
+	missing := make([]int64, 0)
+	for _, id := range ids {
+		ad, err := a.repository.GetAdById(ctx, id)
+		switch {
+		case errors.Is(err, IncorrectAdId):
+			missing = append(missing, id)
+		case err != nil:
+			return nil, nil, err
+		default:
+			found = append(found, ad)
+		}
+	}
+	return found, missing, nil
+}
+
+// BatchGetUsers возвращает найденных пользователей в порядке запроса и ID, которых нет
+func (a *appRepo) BatchGetUsers(ctx context.Context, ids []int64) ([]users.User, []int64, error) {
+	ids, err := batchIDs(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	found := make([]users.User, 0, len(ids))
+	missing := make([]int64, 0)
+	for _, id := range ids {
+		user, err := a.repository.GetUserById(ctx, id)
+		switch {
+		case errors.Is(err, IncorrectUserId):
+			missing = append(missing, id)
+		case err != nil:
+			return nil, nil, err
+		default:
+			found = append(found, user)
+		}
+	}
+	return found, missing, nil
+}
+
+// BatchChangeAdStatus публикует или снимает с публикации несколько объявлений пользователя.
+// Всё или ничего: если хоть одно объявление не найдено или принадлежит другому автору,
+// не меняется ни одно. Публиковать может только автор с подтверждённым email
+func (a *appRepo) BatchChangeAdStatus(ctx context.Context, adIds []int64, userId int64, published bool) ([]ads.Ad, error) {
+	adIds, err := batchIDs(adIds)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	var changed []ads.Ad
+	err = a.repository.WithTx(ctx, func(tx Repository) error {
+		if published {
+			if err := canPublish(ctx, tx, userId); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		changed, err = tx.ChangeAds(ctx, adIds, func(ad *ads.Ad) error {
+			if ad.AuthorID != userId {
+				return IncorrectUserId
+			}
+			ad.Published = published
+			ad.DateUpdate = now
+			return validate(*ad)
+		})
+		if err != nil {
+			return err
+		}
+		tx.TouchUser(ctx, userId, a.now().UTC())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("ads status changed", slog.Int("ads", len(changed)), slog.Bool("published", published))
+	return changed, nil
+}
@@ -0,0 +1,71 @@
+package app
+
+import "errors"
+
+// Kind - категория доменной ошибки. По ней порты выбирают HTTP-статус и gRPC-код
+type Kind uint8
+
+const (
+	KindInternal  Kind = iota // ошибка вне каталога
+	KindNotFound              // сущность не найдена
+	KindForbidden             // действие запрещено пользователю
+	KindInvalid               // данные не прошли валидацию
+	KindConflict              // состояние не позволяет выполнить действие
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindNotFound:
+		return "not found"
+	case KindForbidden:
+		return "forbidden"
+	case KindInvalid:
+		return "invalid"
+	case KindConflict:
+		return "conflict"
+	default:
+		return "internal"
+	}
+}
+
+// Error - ошибка из каталога приложения
+type Error struct {
+	Kind    Kind
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// каталог ошибок приложения
+
+var (
+	IncorrectUserId      error = &Error{Kind: KindForbidden, Message: "incorrect user id"}
+	ValidateError        error = &Error{Kind: KindInvalid, Message: "validation error"}
+	IncorrectAdId        error = &Error{Kind: KindNotFound, Message: "id is not found"}
+	IncorrectWebhookId   error = &Error{Kind: KindNotFound, Message: "webhook is not found"}
+	UserNotFound         error = &Error{Kind: KindNotFound, Message: "user is not found"}
+	EmailTaken           error = &Error{Kind: KindConflict, Message: "email is already taken"}
+	NicknameTaken        error = &Error{Kind: KindConflict, Message: "nickname is already taken"}
+	EmailNotVerified     error = &Error{Kind: KindForbidden, Message: "email is not verified"}
+	EmailAlreadyVerified error = &Error{Kind: KindConflict, Message: "email is already verified"}
+	InvalidToken         error = &Error{Kind: KindInvalid, Message: "token is invalid or expired"}
+	WrongPassword        error = &Error{Kind: KindForbidden, Message: "password is wrong or not set"}
+	TooManyImportRows    error = &Error{Kind: KindInvalid, Message: "import has too many rows"}
+)
+
+// Catalogue - все ошибки каталога, чтобы порты могли проверить, что каждая переводится в свой код
+var Catalogue = []error{
+	IncorrectUserId, ValidateError, IncorrectAdId, IncorrectWebhookId, UserNotFound, EmailTaken, NicknameTaken,
+	EmailNotVerified, EmailAlreadyVerified, InvalidToken, WrongPassword, TooManyImportRows,
+}
+
+// KindOf возвращает категорию ошибки; всё, что не из каталога, считается внутренней ошибкой
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return KindInternal
+}
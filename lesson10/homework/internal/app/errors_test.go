@@ -0,0 +1,48 @@
+package app_test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"homework10/internal/app"
+)
+
+// каждая ошибка из errors.go должна попасть в Catalogue: по нему errmap проверяет коды транспорта
+func TestCatalogueIsComplete(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "errors.go", nil, 0)
+	require.NoError(t, err)
+
+	inCatalogue := make(map[string]bool, len(app.Catalogue))
+	for _, e := range app.Catalogue {
+		inCatalogue[e.Error()] = true
+	}
+
+	declared := 0
+	ast.Inspect(file, func(n ast.Node) bool {
+		lit, ok := n.(*ast.CompositeLit)
+		if !ok || lit.Type == nil {
+			return true
+		}
+		if ident, ok := lit.Type.(*ast.Ident); !ok || ident.Name != "Error" {
+			return true
+		}
+		for _, elt := range lit.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok || kv.Key.(*ast.Ident).Name != "Message" {
+				continue
+			}
+			message, err := strconv.Unquote(kv.Value.(*ast.BasicLit).Value)
+			require.NoError(t, err)
+			assert.True(t, inCatalogue[message], "%q is not in app.Catalogue", message)
+			declared++
+		}
+		return true
+	})
+	assert.Equal(t, declared, len(app.Catalogue))
+}
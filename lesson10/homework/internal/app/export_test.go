@@ -0,0 +1,13 @@
+package app
+
+import "time"
+
+// FieldName открывает fieldName для тестов в пакете app_test
+var FieldName = fieldName
+
+// WithClock подменяет часы, по которым истекают токены и отмечается активность пользователей
+func WithClock(now func() time.Time) Option {
+	return func(a *appRepo) {
+		a.now = now
+	}
+}
@@ -0,0 +1,107 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+
+	"homework10/internal/ads"
+	"homework10/internal/logging"
+)
+
+const (
+	// ExportPageSize - сколько объявлений ExportAds забирает из репозитория за раз
+	ExportPageSize = 500
+	// MaxImportFailures - сколько ошибок строк попадает в отчёт; остальные только считаются
+	MaxImportFailures = 1000
+	// MaxImportRows - сколько строк принимает один импорт; больший файл нужно делить на части
+	MaxImportRows = 10000
+)
+
+// ImportRow - объявление из потока импорта. Err - строку не удалось разобрать,
+// она попадёт в отчёт без попытки создать объявление
+type ImportRow struct {
+	Line   int // номер строки или сообщения, с единицы
+	Title  string
+	Text   string
+	UserID int64
+	Err    error
+}
+
+// ImportFailure - строка, из которой не получилось объявление
+type ImportFailure struct {
+	Line int
+	Err  error
+}
+
+// ImportReport - итог импорта
+type ImportReport struct {
+	Imported int
+	Failed   int
+	Failures []ImportFailure // первые MaxImportFailures ошибок
+}
+
+func (r *ImportReport) fail(line int, err error) {
+	r.Failed++
+	if len(r.Failures) < MaxImportFailures {
+		r.Failures = append(r.Failures, ImportFailure{Line: line, Err: err})
+	}
+}
+
+// ImportAds создаёт объявления из строк, которые отдаёт next, пока тот не вернёт io.EOF.
+// Каждая строка проверяется как в CreateAd; ошибка строки попадает в отчёт и не прерывает импорт.
+// Ошибка next, кроме io.EOF, прерывает импорт: отчёт тогда описывает уже обработанные строки.
+// Строка сверх MaxImportRows прерывает импорт с ошибкой TooManyImportRows
+func (a *appRepo) ImportAds(ctx context.Context, next func() (ImportRow, error)) (ImportReport, error) {
+	var report ImportReport
+	defer func() {
+		logging.FromContext(ctx).Info("ads imported", slog.Int("imported", report.Imported), slog.Int("failed", report.Failed))
+	}()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		row, err := next()
+		if errors.Is(err, io.EOF) {
+			return report, nil
+		}
+		if err != nil {
+			return report, err
+		}
+		if report.Imported+report.Failed >= MaxImportRows {
+			return report, TooManyImportRows
+		}
+
+		if row.Err == nil {
+			_, row.Err = a.CreateAd(ctx, row.Title, row.Text, row.UserID)
+		}
+		if row.Err != nil {
+			report.fail(row.Line, row.Err)
+			continue
+		}
+		report.Imported++
+	}
+}
+
+// ExportAds передаёт в fn объявления по тем же фильтрам, что GetListAds, по возрастанию ID.
+// Репозиторий читается страницами по ExportPageSize, так что выгрузка не держит в памяти все объявления
+func (a *appRepo) ExportAds(ctx context.Context, filters map[string]any, fn func(ad ads.Ad) error) error {
+	afterID := int64(-1)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		page := a.repository.GetAdsPage(ctx, filters, afterID, ExportPageSize)
+		for _, ad := range page {
+			if err := fn(ad); err != nil {
+				return err
+			}
+		}
+		if len(page) < ExportPageSize {
+			return nil
+		}
+		afterID = page[len(page)-1].ID
+	}
+}
@@ -4,9 +4,16 @@ package mocks
 
 import (
 	ads "homework10/internal/ads"
+	app "homework10/internal/app"
+
+	context "context"
 
 	mock "github.com/stretchr/testify/mock"
 
+	outbox "homework10/internal/outbox"
+
+	time "time"
+
 	users "homework10/internal/users"
 )
 
@@ -15,23 +22,47 @@ type Repository struct {
 	mock.Mock
 }
 
-// AddAd provides a mock function with given fields: ad
-func (_m *Repository) AddAd(ad *ads.Ad) {
-	_m.Called(ad)
+// AddAd provides a mock function with given fields: ctx, ad
+func (_m *Repository) AddAd(ctx context.Context, ad *ads.Ad) {
+	_m.Called(ctx, ad)
+}
+
+// AddDeadLetter provides a mock function with given fields: ctx, letter
+func (_m *Repository) AddDeadLetter(ctx context.Context, letter *outbox.DeadLetter) {
+	_m.Called(ctx, letter)
+}
+
+// AddToken provides a mock function with given fields: ctx, token
+func (_m *Repository) AddToken(ctx context.Context, token users.Token) {
+	_m.Called(ctx, token)
+}
+
+// AddUser provides a mock function with given fields: ctx, user
+func (_m *Repository) AddUser(ctx context.Context, user *users.User) error {
+	ret := _m.Called(ctx, user)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *users.User) error); ok {
+		r0 = rf(ctx, user)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
 }
 
-// AddUser provides a mock function with given fields: user
-func (_m *Repository) AddUser(user *users.User) {
-	_m.Called(user)
+// AddWebhook provides a mock function with given fields: ctx, webhook
+func (_m *Repository) AddWebhook(ctx context.Context, webhook *outbox.Webhook) {
+	_m.Called(ctx, webhook)
 }
 
-// ChangeAd provides a mock function with given fields: ad
-func (_m *Repository) ChangeAd(ad *ads.Ad) bool {
-	ret := _m.Called(ad)
+// ChangeAd provides a mock function with given fields: ctx, ad
+func (_m *Repository) ChangeAd(ctx context.Context, ad *ads.Ad) bool {
+	ret := _m.Called(ctx, ad)
 
 	var r0 bool
-	if rf, ok := ret.Get(0).(func(*ads.Ad) bool); ok {
-		r0 = rf(ad)
+	if rf, ok := ret.Get(0).(func(context.Context, *ads.Ad) bool); ok {
+		r0 = rf(ctx, ad)
 	} else {
 		r0 = ret.Get(0).(bool)
 	}
@@ -39,47 +70,140 @@ func (_m *Repository) ChangeAd(ad *ads.Ad) bool {
 	return r0
 }
 
-// ChangeUser provides a mock function with given fields: user
-func (_m *Repository) ChangeUser(user *users.User) bool {
-	ret := _m.Called(user)
+// ChangeAds provides a mock function with given fields: ctx, ids, change
+func (_m *Repository) ChangeAds(ctx context.Context, ids []int64, change func(*ads.Ad) error) ([]ads.Ad, error) {
+	ret := _m.Called(ctx, ids, change)
+
+	var r0 []ads.Ad
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int64, func(*ads.Ad) error) ([]ads.Ad, error)); ok {
+		return rf(ctx, ids, change)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int64, func(*ads.Ad) error) []ads.Ad); ok {
+		r0 = rf(ctx, ids, change)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ads.Ad)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int64, func(*ads.Ad) error) error); ok {
+		r1 = rf(ctx, ids, change)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ChangeUser provides a mock function with given fields: ctx, user
+func (_m *Repository) ChangeUser(ctx context.Context, user *users.User) (bool, error) {
+	ret := _m.Called(ctx, user)
 
 	var r0 bool
-	if rf, ok := ret.Get(0).(func(*users.User) bool); ok {
-		r0 = rf(user)
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *users.User) (bool, error)); ok {
+		return rf(ctx, user)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *users.User) bool); ok {
+		r0 = rf(ctx, user)
 	} else {
 		r0 = ret.Get(0).(bool)
 	}
 
+	if rf, ok := ret.Get(1).(func(context.Context, *users.User) error); ok {
+		r1 = rf(ctx, user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountAds provides a mock function with given fields: ctx
+func (_m *Repository) CountAds(ctx context.Context) (int, int) {
+	ret := _m.Called(ctx)
+
+	var r0 int
+	var r1 int
+	if rf, ok := ret.Get(0).(func(context.Context) (int, int)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) int); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) int); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Get(1).(int)
+	}
+
+	return r0, r1
+}
+
+// CountUsers provides a mock function with given fields: ctx
+func (_m *Repository) CountUsers(ctx context.Context) int {
+	ret := _m.Called(ctx)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(context.Context) int); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
 	return r0
 }
 
-// DeleteAd provides a mock function with given fields: adId
-func (_m *Repository) DeleteAd(adId int64) {
-	_m.Called(adId)
+// DeleteAd provides a mock function with given fields: ctx, adId
+func (_m *Repository) DeleteAd(ctx context.Context, adId int64) {
+	_m.Called(ctx, adId)
+}
+
+// DeleteOutboxEvent provides a mock function with given fields: ctx, id
+func (_m *Repository) DeleteOutboxEvent(ctx context.Context, id int64) {
+	_m.Called(ctx, id)
 }
 
-// DeleteUser provides a mock function with given fields: uerId
-func (_m *Repository) DeleteUser(uerId int64) {
-	_m.Called(uerId)
+// DeleteUser provides a mock function with given fields: ctx, uerId
+func (_m *Repository) DeleteUser(ctx context.Context, uerId int64) {
+	_m.Called(ctx, uerId)
 }
 
-// GetAdById provides a mock function with given fields: id
-func (_m *Repository) GetAdById(id int64) (ads.Ad, error) {
-	ret := _m.Called(id)
+// DeleteWebhook provides a mock function with given fields: ctx, id
+func (_m *Repository) DeleteWebhook(ctx context.Context, id int64) bool {
+	ret := _m.Called(ctx, id)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(context.Context, int64) bool); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// GetAdById provides a mock function with given fields: ctx, id
+func (_m *Repository) GetAdById(ctx context.Context, id int64) (ads.Ad, error) {
+	ret := _m.Called(ctx, id)
 
 	var r0 ads.Ad
 	var r1 error
-	if rf, ok := ret.Get(0).(func(int64) (ads.Ad, error)); ok {
-		return rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (ads.Ad, error)); ok {
+		return rf(ctx, id)
 	}
-	if rf, ok := ret.Get(0).(func(int64) ads.Ad); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ads.Ad); ok {
+		r0 = rf(ctx, id)
 	} else {
 		r0 = ret.Get(0).(ads.Ad)
 	}
 
-	if rf, ok := ret.Get(1).(func(int64) error); ok {
-		r1 = rf(id)
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -87,13 +211,13 @@ func (_m *Repository) GetAdById(id int64) (ads.Ad, error) {
 	return r0, r1
 }
 
-// GetAds provides a mock function with given fields: filters
-func (_m *Repository) GetAds(filters map[string]interface{}) []ads.Ad {
-	ret := _m.Called(filters)
+// GetAds provides a mock function with given fields: ctx, filters
+func (_m *Repository) GetAds(ctx context.Context, filters map[string]interface{}) []ads.Ad {
+	ret := _m.Called(ctx, filters)
 
 	var r0 []ads.Ad
-	if rf, ok := ret.Get(0).(func(map[string]interface{}) []ads.Ad); ok {
-		r0 = rf(filters)
+	if rf, ok := ret.Get(0).(func(context.Context, map[string]interface{}) []ads.Ad); ok {
+		r0 = rf(ctx, filters)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]ads.Ad)
@@ -103,13 +227,13 @@ func (_m *Repository) GetAds(filters map[string]interface{}) []ads.Ad {
 	return r0
 }
 
-// GetAdsByTitle provides a mock function with given fields: pattern
-func (_m *Repository) GetAdsByTitle(pattern string) []ads.Ad {
-	ret := _m.Called(pattern)
+// GetAdsByTitle provides a mock function with given fields: ctx, pattern
+func (_m *Repository) GetAdsByTitle(ctx context.Context, pattern string) []ads.Ad {
+	ret := _m.Called(ctx, pattern)
 
 	var r0 []ads.Ad
-	if rf, ok := ret.Get(0).(func(string) []ads.Ad); ok {
-		r0 = rf(pattern)
+	if rf, ok := ret.Get(0).(func(context.Context, string) []ads.Ad); ok {
+		r0 = rf(ctx, pattern)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]ads.Ad)
@@ -119,13 +243,29 @@ func (_m *Repository) GetAdsByTitle(pattern string) []ads.Ad {
 	return r0
 }
 
-// GetAdsPrimaryKey provides a mock function with given fields:
-func (_m *Repository) GetAdsPrimaryKey() int64 {
-	ret := _m.Called()
+// GetAdsPage provides a mock function with given fields: ctx, filters, afterID, limit
+func (_m *Repository) GetAdsPage(ctx context.Context, filters map[string]interface{}, afterID int64, limit int) []ads.Ad {
+	ret := _m.Called(ctx, filters, afterID, limit)
+
+	var r0 []ads.Ad
+	if rf, ok := ret.Get(0).(func(context.Context, map[string]interface{}, int64, int) []ads.Ad); ok {
+		r0 = rf(ctx, filters, afterID, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ads.Ad)
+		}
+	}
+
+	return r0
+}
+
+// GetAdsPrimaryKey provides a mock function with given fields: ctx
+func (_m *Repository) GetAdsPrimaryKey(ctx context.Context) int64 {
+	ret := _m.Called(ctx)
 
 	var r0 int64
-	if rf, ok := ret.Get(0).(func() int64); ok {
-		r0 = rf()
+	if rf, ok := ret.Get(0).(func(context.Context) int64); ok {
+		r0 = rf(ctx)
 	} else {
 		r0 = ret.Get(0).(int64)
 	}
@@ -133,23 +273,55 @@ func (_m *Repository) GetAdsPrimaryKey() int64 {
 	return r0
 }
 
-// GetUserById provides a mock function with given fields: id
-func (_m *Repository) GetUserById(id int64) (users.User, error) {
-	ret := _m.Called(id)
+// GetDeadLetters provides a mock function with given fields: ctx
+func (_m *Repository) GetDeadLetters(ctx context.Context) []outbox.DeadLetter {
+	ret := _m.Called(ctx)
+
+	var r0 []outbox.DeadLetter
+	if rf, ok := ret.Get(0).(func(context.Context) []outbox.DeadLetter); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]outbox.DeadLetter)
+		}
+	}
+
+	return r0
+}
+
+// GetOutboxEvents provides a mock function with given fields: ctx, limit
+func (_m *Repository) GetOutboxEvents(ctx context.Context, limit int) []outbox.Event {
+	ret := _m.Called(ctx, limit)
+
+	var r0 []outbox.Event
+	if rf, ok := ret.Get(0).(func(context.Context, int) []outbox.Event); ok {
+		r0 = rf(ctx, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]outbox.Event)
+		}
+	}
+
+	return r0
+}
+
+// GetUserByEmail provides a mock function with given fields: ctx, email
+func (_m *Repository) GetUserByEmail(ctx context.Context, email string) (users.User, error) {
+	ret := _m.Called(ctx, email)
 
 	var r0 users.User
 	var r1 error
-	if rf, ok := ret.Get(0).(func(int64) (users.User, error)); ok {
-		return rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, string) (users.User, error)); ok {
+		return rf(ctx, email)
 	}
-	if rf, ok := ret.Get(0).(func(int64) users.User); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, string) users.User); ok {
+		r0 = rf(ctx, email)
 	} else {
 		r0 = ret.Get(0).(users.User)
 	}
 
-	if rf, ok := ret.Get(1).(func(int64) error); ok {
-		r1 = rf(id)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, email)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -157,13 +329,37 @@ func (_m *Repository) GetUserById(id int64) (users.User, error) {
 	return r0, r1
 }
 
-// GetUsersPrimaryKey provides a mock function with given fields:
-func (_m *Repository) GetUsersPrimaryKey() int64 {
-	ret := _m.Called()
+// GetUserById provides a mock function with given fields: ctx, id
+func (_m *Repository) GetUserById(ctx context.Context, id int64) (users.User, error) {
+	ret := _m.Called(ctx, id)
+
+	var r0 users.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (users.User, error)); ok {
+		return rf(ctx, id)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) users.User); ok {
+		r0 = rf(ctx, id)
+	} else {
+		r0 = ret.Get(0).(users.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUsersPrimaryKey provides a mock function with given fields: ctx
+func (_m *Repository) GetUsersPrimaryKey(ctx context.Context) int64 {
+	ret := _m.Called(ctx)
 
 	var r0 int64
-	if rf, ok := ret.Get(0).(func() int64); ok {
-		r0 = rf()
+	if rf, ok := ret.Get(0).(func(context.Context) int64); ok {
+		r0 = rf(ctx)
 	} else {
 		r0 = ret.Get(0).(int64)
 	}
@@ -171,6 +367,93 @@ func (_m *Repository) GetUsersPrimaryKey() int64 {
 	return r0
 }
 
+// GetWebhooks provides a mock function with given fields: ctx
+func (_m *Repository) GetWebhooks(ctx context.Context) []outbox.Webhook {
+	ret := _m.Called(ctx)
+
+	var r0 []outbox.Webhook
+	if rf, ok := ret.Get(0).(func(context.Context) []outbox.Webhook); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]outbox.Webhook)
+		}
+	}
+
+	return r0
+}
+
+// GetWebhooksPrimaryKey provides a mock function with given fields: ctx
+func (_m *Repository) GetWebhooksPrimaryKey(ctx context.Context) int64 {
+	ret := _m.Called(ctx)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context) int64); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	return r0
+}
+
+// Ping provides a mock function with given fields: ctx
+func (_m *Repository) Ping(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// TakeToken provides a mock function with given fields: ctx, hash
+func (_m *Repository) TakeToken(ctx context.Context, hash string) (users.Token, bool) {
+	ret := _m.Called(ctx, hash)
+
+	var r0 users.Token
+	var r1 bool
+	if rf, ok := ret.Get(0).(func(context.Context, string) (users.Token, bool)); ok {
+		return rf(ctx, hash)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) users.Token); ok {
+		r0 = rf(ctx, hash)
+	} else {
+		r0 = ret.Get(0).(users.Token)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) bool); ok {
+		r1 = rf(ctx, hash)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	return r0, r1
+}
+
+// TouchUser provides a mock function with given fields: ctx, id, at
+func (_m *Repository) TouchUser(ctx context.Context, id int64, at time.Time) {
+	_m.Called(ctx, id, at)
+}
+
+// WithTx provides a mock function with given fields: ctx, fn
+func (_m *Repository) WithTx(ctx context.Context, fn func(app.Repository) error) error {
+	ret := _m.Called(ctx, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, func(app.Repository) error) error); ok {
+		r0 = rf(ctx, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 type mockConstructorTestingTNewRepository interface {
 	mock.TestingT
 	Cleanup(func())
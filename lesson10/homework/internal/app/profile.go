@@ -0,0 +1,83 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"homework10/internal/ads"
+	"homework10/internal/logging"
+	"homework10/internal/users"
+)
+
+// phonePattern - номер в формате E.164: плюс, код страны и до 15 цифр всего
+var phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// UpdateProfile заменяет поля профиля целиком: пустое поле очищает значение.
+// Профиль меняет только владелец, поэтому запрос подтверждается паролем пользователя;
+// пока пароль не задан через сброс, профиль не меняется
+func (a *appRepo) UpdateProfile(ctx context.Context, userId int64, password string, profile users.Profile) (*users.User, error) {
+	extra := normalizeProfile(&profile)
+	if err := validate(profile, extra...); err != nil {
+		return nil, err
+	}
+
+	var user users.User
+	err := a.repository.WithTx(ctx, func(tx Repository) error {
+		var err error
+		user, err = tx.GetUserById(ctx, userId)
+		if err != nil {
+			return userNotFound(err)
+		}
+		if err := checkPassword(user, password); err != nil {
+			return err
+		}
+
+		user.Profile = profile
+		user.LastSeenAt = a.now().UTC()
+		_, err = tx.ChangeUser(ctx, &user)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("profile updated", slog.Int64("user_id", user.ID))
+	return &user, nil
+}
+
+// GetUserAds возвращает опубликованные объявления пользователя, от новых к старым
+func (a *appRepo) GetUserAds(ctx context.Context, userId int64) ([]ads.Ad, error) {
+	if _, err := a.repository.GetUserById(ctx, userId); err != nil {
+		return nil, userNotFound(err)
+	}
+
+	list := a.repository.GetAds(ctx, map[string]any{"user_id": userId, "published": true})
+	slices.SortFunc(list, func(x, y ads.Ad) int {
+		return cmp.Compare(y.ID, x.ID)
+	})
+	return list, nil
+}
+
+// normalizeProfile убирает пробелы по краям полей, приводит телефон к виду +79991234567
+// и проверяет форматы телефона и ссылки на аватар. Пустые поля не проверяются
+func normalizeProfile(profile *users.Profile) []FieldViolation {
+	profile.DisplayName = strings.TrimSpace(profile.DisplayName)
+	profile.City = strings.TrimSpace(profile.City)
+	profile.AvatarURL = strings.TrimSpace(profile.AvatarURL)
+	profile.Phone = users.NormalizePhone(profile.Phone)
+
+	var violations []FieldViolation
+	if profile.Phone != "" && !phonePattern.MatchString(profile.Phone) {
+		violations = append(violations, FieldViolation{Field: "phone", Description: "should be a phone number in international format, like +79991234567"})
+	}
+	if profile.AvatarURL != "" {
+		if u, err := url.Parse(profile.AvatarURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			violations = append(violations, FieldViolation{Field: "avatar_url", Description: "should be an absolute http or https URL"})
+		}
+	}
+	return violations
+}
@@ -0,0 +1,122 @@
+package app_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"homework10/internal/app"
+	"homework10/internal/mailer"
+	"homework10/internal/users"
+)
+
+// setPassword задаёт пароль пользователю через письмо со сбросом пароля
+func setPassword(t *testing.T, service app.App, capture *mailer.Capture, email string, password string) {
+	t.Helper()
+	ctx := context.Background()
+	require.NoError(t, service.RequestPasswordReset(ctx, email))
+	require.NoError(t, service.ResetPassword(ctx, mailToken(t, capture, email), password))
+}
+
+func TestUpdateProfile(t *testing.T) {
+	ctx := context.Background()
+	now := registered
+	service, capture := newAccountApp(app.WithClock(func() time.Time { return now }))
+	user, err := service.CreateUser(ctx, "buda", "buda@phystech.edu")
+	require.NoError(t, err)
+	assert.Equal(t, registered, user.CreatedAt)
+
+	_, err = service.UpdateProfile(ctx, user.ID, "", users.Profile{City: "Moscow"})
+	assert.ErrorIs(t, err, app.WrongPassword, "пока пароль не задан, профиль не меняется")
+	setPassword(t, service, capture, user.Email, "long enough")
+	_, err = service.UpdateProfile(ctx, user.ID, "wrong password", users.Profile{City: "Moscow"})
+	assert.ErrorIs(t, err, app.WrongPassword)
+
+	now = now.Add(time.Hour)
+	user, err = service.UpdateProfile(ctx, user.ID, "long enough", users.Profile{
+		DisplayName: " Buda ",
+		Phone:       "+7 (999) 123-45-67",
+		City:        "Moscow",
+		AvatarURL:   "https://example.com/buda.png",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, users.Profile{DisplayName: "Buda", Phone: "+79991234567", City: "Moscow", AvatarURL: "https://example.com/buda.png"}, user.Profile)
+	assert.Equal(t, registered, user.CreatedAt)
+	assert.Equal(t, now, user.LastSeenAt)
+
+	// профиль заменяется целиком
+	user, err = service.UpdateProfile(ctx, user.ID, "long enough", users.Profile{City: "Kazan"})
+	require.NoError(t, err)
+	assert.Equal(t, users.Profile{City: "Kazan"}, user.Profile)
+
+	_, err = service.UpdateProfile(ctx, 42, "long enough", users.Profile{})
+	assert.ErrorIs(t, err, app.UserNotFound)
+}
+
+func TestUpdateProfile_Invalid(t *testing.T) {
+	ctx := context.Background()
+	service, capture := newAccountApp()
+	user, err := service.CreateUser(ctx, "buda", "buda@phystech.edu")
+	require.NoError(t, err)
+	setPassword(t, service, capture, user.Email, "long enough")
+
+	_, err = service.UpdateProfile(ctx, user.ID, "long enough", users.Profile{
+		DisplayName: strings.Repeat("a", 61),
+		Phone:       "8 999 123-45-67",
+		AvatarURL:   "/avatars/buda.png",
+	})
+	var fields *app.InvalidFieldsError
+	require.ErrorAs(t, err, &fields)
+	var violated []string
+	for _, v := range fields.Violations {
+		violated = append(violated, v.Field)
+	}
+	assert.ElementsMatch(t, []string{"display_name", "phone", "avatar_url"}, violated)
+
+	got, err := service.GetUser(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Zero(t, got.Profile, "невалидный профиль не сохраняется")
+}
+
+func TestGetUserAds(t *testing.T) {
+	ctx := context.Background()
+	service, capture := newAccountApp()
+	buda, err := service.CreateUser(ctx, "buda", "buda@phystech.edu")
+	require.NoError(t, err)
+	mayot, err := service.CreateUser(ctx, "mayot", "mayot@phystech.edu")
+	require.NoError(t, err)
+
+	// publish создаёт объявление и публикует его, подтвердив email автора
+	publish := func(user *users.User, title string) int64 {
+		if !user.EmailVerified {
+			_, err := service.ConfirmEmail(ctx, mailToken(t, capture, user.Email))
+			require.NoError(t, err)
+			user.EmailVerified = true
+		}
+		ad, err := service.CreateAd(ctx, title, "text", user.ID)
+		require.NoError(t, err)
+		_, err = service.ChangeAdStatus(ctx, ad.ID, user.ID, true)
+		require.NoError(t, err)
+		return ad.ID
+	}
+	first := publish(buda, "first")
+	_, err = service.CreateAd(ctx, "draft", "text", buda.ID)
+	require.NoError(t, err)
+	publish(mayot, "other")
+	second := publish(buda, "second")
+
+	list, err := service.GetUserAds(ctx, buda.ID)
+	require.NoError(t, err)
+	ids := make([]int64, 0, len(list))
+	for _, ad := range list {
+		ids = append(ids, ad.ID)
+	}
+	assert.Equal(t, []int64{second, first}, ids)
+
+	_, err = service.GetUserAds(ctx, 42)
+	assert.ErrorIs(t, err, app.UserNotFound)
+}
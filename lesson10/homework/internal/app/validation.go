@@ -0,0 +1,71 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/dubter/Validator"
+)
+
+// FieldViolation - нарушение правила валидации в одном поле
+type FieldViolation struct {
+	Field       string // имя поля в API: title, nickname, url
+	Description string
+}
+
+// InvalidFieldsError перечисляет поля, не прошедшие валидацию.
+// errors.Is(err, ValidateError) для неё истинно, поэтому старые проверки продолжают работать
+type InvalidFieldsError struct {
+	Violations []FieldViolation
+}
+
+func (e *InvalidFieldsError) Error() string {
+	parts := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		parts = append(parts, v.Field+" "+v.Description)
+	}
+	return ValidateError.Error() + ": " + strings.Join(parts, "; ")
+}
+
+func (e *InvalidFieldsError) Unwrap() error {
+	return ValidateError
+}
+
+// validate проверяет теги validate у сущности. Дополнительные нарушения (например, формат URL)
+// попадают в ту же ошибку, чтобы клиент сразу увидел все неверные поля
+func validate(entity any, extra ...FieldViolation) error {
+	var violations []FieldViolation
+
+	err := Validator.Validate(entity)
+	var errs Validator.ValidationErrors
+	switch {
+	case errors.As(err, &errs):
+		for _, e := range errs {
+			violations = append(violations, FieldViolation{Field: fieldName(e.Field), Description: e.Error})
+		}
+	case err != nil:
+		return fmt.Errorf("%w: %s", ValidateError, err.Error())
+	}
+
+	violations = append(violations, extra...)
+	if len(violations) == 0 {
+		return nil
+	}
+	return &InvalidFieldsError{Violations: violations}
+}
+
+// fieldName переводит имя поля Go в snake_case, как поля называются в API: AuthorID -> author_id
+func fieldName(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for idx, r := range runes {
+		if idx > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[idx-1]) ||
+			(idx+1 < len(runes) && unicode.IsLower(runes[idx+1]))) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,93 @@
+package app_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"homework10/internal/app"
+	"homework10/internal/app/mocks"
+	"homework10/internal/users"
+)
+
+func TestValidationViolations(t *testing.T) {
+	repo := &mocks.Repository{}
+	runTxOn(repo)
+	repo.On("GetUserById", mock.Anything, one).Return(users.User{}, nil)
+	repo.On("GetAdsPrimaryKey", mock.Anything).Return(one)
+	repo.On("GetUsersPrimaryKey", mock.Anything).Return(one)
+	repo.On("GetWebhooksPrimaryKey", mock.Anything).Return(one)
+	service := app.NewApp(repo)
+
+	tests := []struct {
+		name   string
+		call   func() error
+		expect []app.FieldViolation
+	}{
+		{
+			name: "ad with empty title and long text",
+			call: func() error {
+				_, err := service.CreateAd(context.Background(), "", strings.Repeat("a", 500), one)
+				return err
+			},
+			expect: []app.FieldViolation{
+				{Field: "title", Description: "should have length at least 1"},
+				{Field: "text", Description: "should have length at most 499"},
+			},
+		},
+		{
+			name: "user with long nickname",
+			call: func() error {
+				_, err := service.CreateUser(context.Background(), strings.Repeat("a", 31), "email@mail.ru")
+				return err
+			},
+			expect: []app.FieldViolation{{Field: "nickname", Description: "should have length at most 30"}},
+		},
+		{
+			name: "user with invalid email",
+			call: func() error {
+				_, err := service.CreateUser(context.Background(), "buda", "Buda <buda@mail.ru>")
+				return err
+			},
+			expect: []app.FieldViolation{{Field: "email", Description: "should be a valid email address"}},
+		},
+		{
+			name: "webhook with ftp url and no secret",
+			call: func() error {
+				_, err := service.CreateWebhook(context.Background(), "ftp://example.com", "")
+				return err
+			},
+			expect: []app.FieldViolation{
+				{Field: "secret", Description: "should have length at least 1"},
+				{Field: "url", Description: "should be an absolute http or https URL"},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.call()
+			assert.ErrorIs(t, err, app.ValidateError)
+
+			var fields *app.InvalidFieldsError
+			if assert.ErrorAs(t, err, &fields) {
+				assert.Equal(t, tc.expect, fields.Violations)
+			}
+		})
+	}
+}
+
+func TestFieldName(t *testing.T) {
+	tests := map[string]string{
+		"Title":        "title",
+		"AuthorID":     "author_id",
+		"DateCreating": "date_creating",
+		"URL":          "url",
+		"HTTPStatus":   "http_status",
+	}
+	for in, expect := range tests {
+		assert.Equal(t, expect, app.FieldName(in), in)
+	}
+}
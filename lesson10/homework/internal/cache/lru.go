@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Counters - статистика одного кэша с момента создания
+type Counters struct {
+	Hits      uint64
+	Misses    uint64
+	Loads     uint64 // чтения из репозитория; меньше Misses, когда singleflight склеил одновременные промахи
+	Evictions uint64
+	Entries   int
+}
+
+type entry[V any] struct {
+	key     int64
+	value   V
+	expires time.Time
+}
+
+// fill - загрузка значения из репозитория. Если ключ инвалидировали, пока она шла,
+// прочитанное значение могло устареть и в кэш не кладётся
+type fill struct {
+	stale bool
+}
+
+// lru - кэш на size значений с временем жизни ttl; при переполнении вытесняется самое давно читанное
+type lru[V any] struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	group singleflight.Group
+
+	mu       sync.Mutex
+	items    map[int64]*list.Element
+	order    *list.List // в начале - последнее прочитанное
+	pending  map[int64]*fill
+	counters Counters
+}
+
+func newLRU[V any](size int, ttl time.Duration) *lru[V] {
+	return &lru[V]{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		items:   make(map[int64]*list.Element, size),
+		order:   list.New(),
+		pending: make(map[int64]*fill),
+	}
+}
+
+// get отдаёт значение из кэша, а при промахе читает его через load. Одновременные промахи
+// по одному ключу делят одну загрузку. Ошибки load не кэшируются
+func (c *lru[V]) get(ctx context.Context, key int64, load func(ctx context.Context, key int64) (V, error)) (V, error) {
+	if value, ok := c.lookup(key); ok {
+		return value, nil
+	}
+
+	res, err, _ := c.group.Do(strconv.FormatInt(key, 10), func() (any, error) {
+		f := c.begin(key)
+		value, err := load(ctx, key)
+		c.finish(key, f, value, err == nil)
+		return value, err
+	})
+	value, _ := res.(V)
+	return value, err
+}
+
+func (c *lru[V]) lookup(key int64) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		e := elem.Value.(*entry[V])
+		if c.now().Before(e.expires) {
+			c.order.MoveToFront(elem)
+			c.counters.Hits++
+			return e.value, true
+		}
+		c.remove(elem)
+	}
+	c.counters.Misses++
+	var zero V
+	return zero, false
+}
+
+func (c *lru[V]) begin(key int64) *fill {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f := &fill{}
+	c.pending[key] = f
+	c.counters.Loads++
+	return f
+}
+
+func (c *lru[V]) finish(key int64, f *fill, value V, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pending[key] == f {
+		delete(c.pending, key)
+	}
+	if !ok || f.stale {
+		return
+	}
+
+	if elem, ok := c.items[key]; ok {
+		c.remove(elem)
+	}
+	c.items[key] = c.order.PushFront(&entry[V]{key: key, value: value, expires: c.now().Add(c.ttl)})
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+		c.counters.Evictions++
+	}
+}
+
+// invalidate выкидывает ключ из кэша и не даёт идущей загрузке положить в него старое значение.
+// Вызывается после записи в репозиторий: следующее чтение пойдёт в репозиторий за новым значением,
+// а не присоединится к загрузке, начатой до записи
+func (c *lru[V]) invalidate(key int64) {
+	c.mu.Lock()
+	if elem, ok := c.items[key]; ok {
+		c.remove(elem)
+	}
+	if f, ok := c.pending[key]; ok {
+		f.stale = true
+	}
+	c.mu.Unlock()
+
+	c.group.Forget(strconv.FormatInt(key, 10))
+}
+
+func (c *lru[V]) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*entry[V]).key)
+}
+
+func (c *lru[V]) stats() Counters {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counters := c.counters
+	counters.Entries = c.order.Len()
+	return counters
+}
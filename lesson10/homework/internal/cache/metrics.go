@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "adservice"
+
+type statsCollector struct {
+	r         *Repository
+	hits      *prometheus.Desc
+	misses    *prometheus.Desc
+	loads     *prometheus.Desc
+	evictions *prometheus.Desc
+	entries   *prometheus.Desc
+}
+
+// NewStatsCollector отдаёт статистику кэша с меткой kind: ad или user
+func NewStatsCollector(r *Repository) prometheus.Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(namespace+"_repository_cache_"+name, help, []string{"kind"}, nil)
+	}
+	return &statsCollector{
+		r:         r,
+		hits:      desc("hits_total", "Reads served from the repository cache."),
+		misses:    desc("misses_total", "Reads not found in the repository cache."),
+		loads:     desc("loads_total", "Repository reads made by the cache on misses."),
+		evictions: desc("evictions_total", "Entries evicted from the repository cache to fit its size."),
+		entries:   desc("entries", "Entries in the repository cache."),
+	}
+}
+
+func (c *statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.loads
+	ch <- c.evictions
+	ch <- c.entries
+}
+
+func (c *statsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.r.Stats()
+	for _, kind := range []struct {
+		name     string
+		counters Counters
+	}{{"ad", stats.Ads}, {"user", stats.Users}} {
+		ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(kind.counters.Hits), kind.name)
+		ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(kind.counters.Misses), kind.name)
+		ch <- prometheus.MustNewConstMetric(c.loads, prometheus.CounterValue, float64(kind.counters.Loads), kind.name)
+		ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(kind.counters.Evictions), kind.name)
+		ch <- prometheus.MustNewConstMetric(c.entries, prometheus.GaugeValue, float64(kind.counters.Entries), kind.name)
+	}
+}
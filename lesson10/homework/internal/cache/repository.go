@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"homework10/internal/ads"
+	"homework10/internal/app"
+	"homework10/internal/users"
+)
+
+const (
+	DefaultSize = 10000
+	DefaultTTL  = time.Minute
+)
+
+type Config struct {
+	// Size - сколько объявлений и сколько пользователей держит кэш, отдельно для каждого
+	Size int
+	// TTL ограничивает, как долго кэш отдаёт значение, изменённое в обход него
+	TTL time.Duration
+}
+
+// Stats - статистика кэшей объявлений и пользователей
+type Stats struct {
+	Ads   Counters
+	Users Counters
+}
+
+// Repository кэширует GetAdById и GetUserById поверх любого app.Repository.
+// Остальные методы идут в репозиторий напрямую; записи объявлений и пользователей
+// выкидывают их из кэша
+type Repository struct {
+	app.Repository
+
+	ads   *lru[ads.Ad]
+	users *lru[users.User]
+}
+
+func NewRepository(next app.Repository, cfg Config) *Repository {
+	if cfg.Size <= 0 {
+		cfg.Size = DefaultSize
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultTTL
+	}
+	return &Repository{
+		Repository: next,
+		ads:        newLRU[ads.Ad](cfg.Size, cfg.TTL),
+		users:      newLRU[users.User](cfg.Size, cfg.TTL),
+	}
+}
+
+func (r *Repository) Stats() Stats {
+	return Stats{Ads: r.ads.stats(), Users: r.users.stats()}
+}
+
+func (r *Repository) GetAdById(ctx context.Context, id int64) (ads.Ad, error) {
+	return r.ads.get(ctx, id, r.Repository.GetAdById)
+}
+
+func (r *Repository) GetUserById(ctx context.Context, id int64) (users.User, error) {
+	return r.users.get(ctx, id, r.Repository.GetUserById)
+}
+
+func (r *Repository) ChangeAd(ctx context.Context, ad *ads.Ad) bool {
+	ok := r.Repository.ChangeAd(ctx, ad)
+	r.ads.invalidate(ad.ID)
+	return ok
+}
+
+func (r *Repository) ChangeAds(ctx context.Context, ids []int64, change func(ad *ads.Ad) error) ([]ads.Ad, error) {
+	list, err := r.Repository.ChangeAds(ctx, ids, change)
+	for _, id := range ids {
+		r.ads.invalidate(id)
+	}
+	return list, err
+}
+
+func (r *Repository) DeleteAd(ctx context.Context, adId int64) {
+	r.Repository.DeleteAd(ctx, adId)
+	r.ads.invalidate(adId)
+}
+
+func (r *Repository) ChangeUser(ctx context.Context, user *users.User) (bool, error) {
+	ok, err := r.Repository.ChangeUser(ctx, user)
+	r.users.invalidate(user.ID)
+	return ok, err
+}
+
+func (r *Repository) TouchUser(ctx context.Context, id int64, at time.Time) {
+	r.Repository.TouchUser(ctx, id, at)
+	r.users.invalidate(id)
+}
+
+func (r *Repository) DeleteUser(ctx context.Context, userId int64) {
+	r.Repository.DeleteUser(ctx, userId)
+	r.users.invalidate(userId)
+}
+
+// WithTx не пускает транзакцию в кэш: чтения через tx идут в репозиторий, иначе в кэш попали бы
+// незакоммиченные значения. Записанные ключи выкидываются из кэша после конца транзакции,
+// когда новые значения уже видны остальным (или откачены)
+func (r *Repository) WithTx(ctx context.Context, fn func(tx app.Repository) error) error {
+	written := &written{}
+	defer func() {
+		for _, id := range written.ads {
+			r.ads.invalidate(id)
+		}
+		for _, id := range written.users {
+			r.users.invalidate(id)
+		}
+	}()
+
+	return r.Repository.WithTx(ctx, func(tx app.Repository) error {
+		return fn(&txRepository{Repository: tx, written: written})
+	})
+}
+
+// written - ключи, записанные в транзакции, в том числе во вложенных WithTx
+type written struct {
+	ads   []int64
+	users []int64
+}
+
+type txRepository struct {
+	app.Repository
+
+	written *written
+}
+
+func (t *txRepository) ChangeAd(ctx context.Context, ad *ads.Ad) bool {
+	t.written.ads = append(t.written.ads, ad.ID)
+	return t.Repository.ChangeAd(ctx, ad)
+}
+
+func (t *txRepository) ChangeAds(ctx context.Context, ids []int64, change func(ad *ads.Ad) error) ([]ads.Ad, error) {
+	t.written.ads = append(t.written.ads, ids...)
+	return t.Repository.ChangeAds(ctx, ids, change)
+}
+
+func (t *txRepository) DeleteAd(ctx context.Context, adId int64) {
+	t.written.ads = append(t.written.ads, adId)
+	t.Repository.DeleteAd(ctx, adId)
+}
+
+func (t *txRepository) ChangeUser(ctx context.Context, user *users.User) (bool, error) {
+	t.written.users = append(t.written.users, user.ID)
+	return t.Repository.ChangeUser(ctx, user)
+}
+
+func (t *txRepository) TouchUser(ctx context.Context, id int64, at time.Time) {
+	t.written.users = append(t.written.users, id)
+	t.Repository.TouchUser(ctx, id, at)
+}
+
+func (t *txRepository) DeleteUser(ctx context.Context, userId int64) {
+	t.written.users = append(t.written.users, userId)
+	t.Repository.DeleteUser(ctx, userId)
+}
+
+func (t *txRepository) WithTx(ctx context.Context, fn func(tx app.Repository) error) error {
+	return t.Repository.WithTx(ctx, func(tx app.Repository) error {
+		return fn(&txRepository{Repository: tx, written: t.written})
+	})
+}
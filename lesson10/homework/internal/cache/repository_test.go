@@ -0,0 +1,277 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/ads"
+	"homework10/internal/app"
+	"homework10/internal/users"
+)
+
+// slowRepo считает чтения объявлений и, если задан gate, держит их уже прочитанными до закрытия gate
+type slowRepo struct {
+	app.Repository
+
+	loads   atomic.Int64
+	read    chan struct{}
+	gate    chan struct{}
+	gateSet atomic.Bool
+}
+
+func (s *slowRepo) GetAdById(ctx context.Context, id int64) (ads.Ad, error) {
+	s.loads.Add(1)
+	ad, err := s.Repository.GetAdById(ctx, id)
+	if s.gateSet.Load() {
+		s.read <- struct{}{}
+		<-s.gate
+	}
+	return ad, err
+}
+
+func (s *slowRepo) hold() {
+	s.read = make(chan struct{}, 100)
+	s.gate = make(chan struct{})
+	s.gateSet.Store(true)
+}
+
+func (s *slowRepo) release() {
+	s.gateSet.Store(false)
+	close(s.gate)
+}
+
+func newTestRepository(cfg Config, adsCount int) (*Repository, *slowRepo) {
+	ctx := context.Background()
+	backend := &slowRepo{Repository: adrepo.New()}
+	for id := int64(0); id < int64(adsCount); id++ {
+		backend.AddAd(ctx, &ads.Ad{ID: id, Title: "title", Text: "text", AuthorID: 1})
+	}
+	backend.AddUser(ctx, &users.User{ID: 0, Nickname: "nick", Email: "nick@mail.ru"})
+	return NewRepository(backend, cfg), backend
+}
+
+func TestRepository_HitAndMiss(t *testing.T) {
+	ctx := context.Background()
+	r, backend := newTestRepository(Config{}, 1)
+
+	for i := 0; i < 3; i++ {
+		ad, err := r.GetAdById(ctx, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, "title", ad.Title)
+	}
+	_, err := r.GetAdById(ctx, 42)
+	assert.ErrorIs(t, err, app.IncorrectAdId)
+	_, err = r.GetAdById(ctx, 42)
+	assert.ErrorIs(t, err, app.IncorrectAdId)
+
+	assert.Equal(t, int64(3), backend.loads.Load())
+	assert.Equal(t, Counters{Hits: 2, Misses: 3, Loads: 3, Entries: 1}, r.Stats().Ads)
+}
+
+func TestRepository_Invalidate(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRepository(Config{}, 2)
+
+	ad, _ := r.GetAdById(ctx, 0)
+	ad.Title = "changed"
+	r.ChangeAd(ctx, &ad)
+	ad, err := r.GetAdById(ctx, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "changed", ad.Title)
+
+	_, _ = r.GetAdById(ctx, 1)
+	r.DeleteAd(ctx, 1)
+	_, err = r.GetAdById(ctx, 1)
+	assert.ErrorIs(t, err, app.IncorrectAdId)
+
+	_, _ = r.GetAdById(ctx, 0)
+	_, err = r.ChangeAds(ctx, []int64{0}, func(ad *ads.Ad) error {
+		ad.Published = true
+		return nil
+	})
+	assert.NoError(t, err)
+	ad, _ = r.GetAdById(ctx, 0)
+	assert.True(t, ad.Published)
+
+	user, _ := r.GetUserById(ctx, 0)
+	user.Nickname = "renamed"
+	r.ChangeUser(ctx, &user)
+	user, _ = r.GetUserById(ctx, 0)
+	assert.Equal(t, "renamed", user.Nickname)
+
+	r.DeleteUser(ctx, 0)
+	_, err = r.GetUserById(ctx, 0)
+	assert.ErrorIs(t, err, app.IncorrectUserId)
+}
+
+func TestRepository_TTL(t *testing.T) {
+	ctx := context.Background()
+	r, backend := newTestRepository(Config{TTL: time.Minute}, 1)
+	now := time.Now()
+	r.ads.now = func() time.Time { return now }
+
+	_, _ = r.GetAdById(ctx, 0)
+	now = now.Add(59 * time.Second)
+	_, _ = r.GetAdById(ctx, 0)
+	assert.Equal(t, int64(1), backend.loads.Load())
+
+	now = now.Add(time.Second)
+	_, _ = r.GetAdById(ctx, 0)
+	assert.Equal(t, int64(2), backend.loads.Load())
+}
+
+func TestRepository_Eviction(t *testing.T) {
+	ctx := context.Background()
+	r, backend := newTestRepository(Config{Size: 2}, 3)
+
+	_, _ = r.GetAdById(ctx, 0)
+	_, _ = r.GetAdById(ctx, 1)
+	_, _ = r.GetAdById(ctx, 0) // 1 теперь самое давно читанное
+	_, _ = r.GetAdById(ctx, 2)
+	assert.Equal(t, int64(3), backend.loads.Load())
+
+	_, _ = r.GetAdById(ctx, 0)
+	_, _ = r.GetAdById(ctx, 2)
+	assert.Equal(t, int64(3), backend.loads.Load())
+	_, _ = r.GetAdById(ctx, 1)
+	assert.Equal(t, int64(4), backend.loads.Load())
+
+	stats := r.Stats().Ads
+	assert.Equal(t, uint64(2), stats.Evictions)
+	assert.Equal(t, 2, stats.Entries)
+}
+
+func TestRepository_Singleflight(t *testing.T) {
+	ctx := context.Background()
+	r, backend := newTestRepository(Config{}, 1)
+	backend.hold()
+
+	const readers = 20
+	var wg sync.WaitGroup
+	wg.Add(readers)
+	for i := 0; i < readers; i++ {
+		go func() {
+			defer wg.Done()
+			ad, err := r.GetAdById(ctx, 0)
+			assert.NoError(t, err)
+			assert.Equal(t, "title", ad.Title)
+		}()
+	}
+
+	<-backend.read
+	// ждём, пока остальные читатели промахнутся и встанут за идущей загрузкой
+	assert.Eventually(t, func() bool { return r.Stats().Ads.Misses == readers }, time.Second, time.Millisecond)
+	backend.release()
+	wg.Wait()
+
+	assert.Equal(t, int64(1), backend.loads.Load())
+}
+
+func TestRepository_WriteDuringLoad(t *testing.T) {
+	ctx := context.Background()
+	r, backend := newTestRepository(Config{}, 1)
+	backend.hold()
+
+	done := make(chan ads.Ad)
+	go func() {
+		ad, _ := r.GetAdById(ctx, 0)
+		done <- ad
+	}()
+	<-backend.read
+
+	// загрузка уже прочитала старое объявление, а запись завершилась раньше неё
+	ad := ads.Ad{ID: 0, Title: "changed", Text: "text", AuthorID: 1}
+	r.ChangeAd(ctx, &ad)
+	backend.release()
+	assert.Equal(t, "title", (<-done).Title)
+
+	got, err := r.GetAdById(ctx, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "changed", got.Title)
+}
+
+func TestRepository_WithTx(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRepository(Config{}, 1)
+	_, _ = r.GetAdById(ctx, 0)
+
+	errRollback := errors.New("rollback")
+	err := r.WithTx(ctx, func(tx app.Repository) error {
+		tx.ChangeAd(ctx, &ads.Ad{ID: 0, Title: "uncommitted", Text: "text", AuthorID: 1})
+		// чтение внутри транзакции видит её изменения, но не попадает в кэш
+		ad, err := tx.GetAdById(ctx, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, "uncommitted", ad.Title)
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+	ad, _ := r.GetAdById(ctx, 0)
+	assert.Equal(t, "title", ad.Title)
+
+	err = r.WithTx(ctx, func(tx app.Repository) error {
+		return tx.WithTx(ctx, func(tx app.Repository) error {
+			tx.ChangeAd(ctx, &ads.Ad{ID: 0, Title: "committed", Text: "text", AuthorID: 1})
+			return nil
+		})
+	})
+	assert.NoError(t, err)
+	ad, _ = r.GetAdById(ctx, 0)
+	assert.Equal(t, "committed", ad.Title)
+}
+
+func TestRepository_ConcurrentWriters(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRepository(Config{Size: 4}, 8)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(2)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				id := int64((w + i) % 8)
+				r.ChangeAd(ctx, &ads.Ad{ID: id, Title: "title", Text: "text", AuthorID: int64(i)})
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 400; i++ {
+				_, _ = r.GetAdById(ctx, int64(i%8))
+			}
+		}()
+	}
+	wg.Wait()
+
+	// после записей кэш не должен отдавать ничего, кроме последнего записанного
+	for id := int64(0); id < 8; id++ {
+		cached, err := r.GetAdById(ctx, id)
+		assert.NoError(t, err)
+		stored, err := r.Repository.GetAdById(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, stored, cached)
+	}
+}
+
+func TestStatsCollector(t *testing.T) {
+	ctx := context.Background()
+	r, _ := newTestRepository(Config{}, 1)
+	_, _ = r.GetAdById(ctx, 0)
+	_, _ = r.GetAdById(ctx, 0)
+
+	expected := `
+# HELP adservice_repository_cache_hits_total Reads served from the repository cache.
+# TYPE adservice_repository_cache_hits_total counter
+adservice_repository_cache_hits_total{kind="ad"} 1
+adservice_repository_cache_hits_total{kind="user"} 0
+`
+	assert.NoError(t, testutil.CollectAndCompare(NewStatsCollector(r), strings.NewReader(expected), "adservice_repository_cache_hits_total"))
+}
@@ -0,0 +1,282 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/mail"
+	"time"
+
+	"homework10/internal/tracing"
+)
+
+const (
+	RepositoryMemory  = "memory"
+	RepositorySharded = "sharded"
+)
+
+const (
+	ListenDual   = "dual"
+	ListenSingle = "single"
+)
+
+const (
+	MailerLog  = "log"
+	MailerSMTP = "smtp"
+)
+
+// Config - все настройки сервиса. Ключ в YAML-файле задаёт и имя флага, и имя переменной окружения:
+// rate.read.rps -> --rate-read-rps и ADS_RATE_READ_RPS
+type Config struct {
+	ListenMode  string            `yaml:"listen_mode" usage:"dual: http and grpc on their own ports, single: both on http.addr"`
+	HTTP        HTTPConfig        `yaml:"http"`
+	GRPC        GRPCConfig        `yaml:"grpc"`
+	Gateway     GatewayConfig     `yaml:"gateway"`
+	Shutdown    ShutdownConfig    `yaml:"shutdown"`
+	Repository  RepositoryConfig  `yaml:"repository"`
+	Cache       CacheConfig       `yaml:"cache"`
+	Log         LogConfig         `yaml:"log"`
+	Rate        RateConfig        `yaml:"rate"`
+	Trace       TraceConfig       `yaml:"trace"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	Health      HealthConfig      `yaml:"health"`
+	Webhooks    WebhooksConfig    `yaml:"webhooks"`
+	Mail        MailConfig        `yaml:"mail"`
+	Admin       AdminConfig       `yaml:"admin"`
+}
+
+type HTTPConfig struct {
+	Addr              string        `yaml:"addr" usage:"http server listen address"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" usage:"time to read request headers"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" usage:"time to read the whole request"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" usage:"time to write the response"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" usage:"keep-alive connection idle time"`
+	TLS               TLSConfig     `yaml:"tls"`
+}
+
+type GRPCConfig struct {
+	Addr string    `yaml:"addr" usage:"grpc server listen address"`
+	TLS  TLSConfig `yaml:"tls"`
+}
+
+// GatewayConfig - REST API, собранный grpc-gateway из service.proto. Пустой адрес выключает шлюз.
+// Таймауты и TLS шлюз берёт из http
+type GatewayConfig struct {
+	Addr string `yaml:"addr" usage:"rest gateway listen address, empty disables the gateway"`
+}
+
+// TLSConfig - пустой CertFile означает plaintext. Файлы перечитываются при изменении
+type TLSConfig struct {
+	CertFile     string `yaml:"cert_file" usage:"PEM certificate, enables TLS"`
+	KeyFile      string `yaml:"key_file" usage:"PEM private key of the certificate"`
+	ClientCAFile string `yaml:"client_ca_file" usage:"PEM CA bundle, requires clients to present a certificate signed by it (mutual TLS)"`
+}
+
+type ShutdownConfig struct {
+	Timeout    time.Duration `yaml:"timeout" usage:"how long to wait for in-flight requests on shutdown"`
+	DrainDelay time.Duration `yaml:"drain_delay" usage:"how long to report not ready before stopping the servers"`
+}
+
+type RepositoryConfig struct {
+	Type   string `yaml:"type" usage:"repository implementation: memory or sharded"`
+	Shards int    `yaml:"shards" usage:"number of shards of the sharded repository"`
+}
+
+// CacheConfig - кэш GetAdById и GetUserById перед репозиторием. Нулевой size выключает кэш
+type CacheConfig struct {
+	Size int           `yaml:"size" usage:"ads and users kept in the repository cache, 0 disables the cache"`
+	TTL  time.Duration `yaml:"ttl" usage:"how long a cached ad or user is served before it is read again"`
+}
+
+type LogConfig struct {
+	Level string `yaml:"level" usage:"log level: debug, info, warn or error"`
+}
+
+type LimitConfig struct {
+	RPS   float64 `yaml:"rps" usage:"requests per second per client, 0 disables the limit"`
+	Burst int     `yaml:"burst" usage:"requests burst per client"`
+}
+
+type RateConfig struct {
+	Read  LimitConfig `yaml:"read"`
+	Write LimitConfig `yaml:"write"`
+}
+
+type TraceConfig struct {
+	Exporter string `yaml:"exporter" usage:"where to export traces: none or stdout"`
+}
+
+type IdempotencyConfig struct {
+	TTL time.Duration `yaml:"ttl" usage:"how long responses to Idempotency-Key requests are kept"`
+}
+
+type HealthConfig struct {
+	Timeout time.Duration `yaml:"timeout" usage:"timeout of a single readiness check"`
+}
+
+type WebhooksConfig struct {
+	PollInterval time.Duration `yaml:"poll_interval" usage:"how often the outbox is checked for new events"`
+	BatchSize    int           `yaml:"batch_size" usage:"events taken from the outbox at once"`
+	MaxAttempts  int           `yaml:"max_attempts" usage:"delivery attempts before an event goes to dead letters"`
+	BaseBackoff  time.Duration `yaml:"base_backoff" usage:"delay before the second delivery attempt"`
+	MaxBackoff   time.Duration `yaml:"max_backoff" usage:"maximum delay between delivery attempts"`
+	// для локальной разработки: подписчик обычно слушает на localhost
+	AllowPrivateTargets bool `yaml:"allow_private_targets" usage:"allow webhooks to loopback, private and link-local addresses"`
+}
+
+// MailConfig - письма с токенами подтверждения email и сброса пароля
+type MailConfig struct {
+	Mailer           string        `yaml:"mailer" usage:"how emails are sent: log (written to the log with their tokens, for local runs) or smtp"`
+	SMTP             SMTPConfig    `yaml:"smtp"`
+	VerifyEmailTTL   time.Duration `yaml:"verify_email_ttl" usage:"how long an email verification token is valid"`
+	ResetPasswordTTL time.Duration `yaml:"reset_password_ttl" usage:"how long a password reset token is valid"`
+}
+
+type SMTPConfig struct {
+	Addr     string        `yaml:"addr" usage:"smtp server address"`
+	Username string        `yaml:"username" usage:"smtp user, empty sends emails without authentication"`
+	Password string        `yaml:"password" usage:"smtp password" secret:"true"`
+	From     string        `yaml:"from" usage:"sender address of the emails"`
+	Timeout  time.Duration `yaml:"timeout" usage:"time to send one email"`
+}
+
+// AdminConfig - служебный API (/admin/..., поиск пользователя по email). Запросы к нему подписываются токеном
+// в заголовке Authorization: Bearer; без токена служебный API закрыт
+type AdminConfig struct {
+	Token string `yaml:"token" usage:"bearer token of the admin API and of the lookup by email, empty disables them" secret:"true"`
+}
+
+func Default() Config {
+	return Config{
+		ListenMode: ListenDual,
+		HTTP: HTTPConfig{
+			Addr:              ":18080",
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+		},
+		GRPC:        GRPCConfig{Addr: ":50054"},
+		Shutdown:    ShutdownConfig{Timeout: 30 * time.Second, DrainDelay: 5 * time.Second},
+		Repository:  RepositoryConfig{Type: RepositoryMemory, Shards: 16},
+		Cache:       CacheConfig{Size: 10000, TTL: time.Minute},
+		Log:         LogConfig{Level: "info"},
+		Rate:        RateConfig{Read: LimitConfig{RPS: 50, Burst: 100}, Write: LimitConfig{RPS: 5, Burst: 10}},
+		Trace:       TraceConfig{Exporter: tracing.ExporterNone},
+		Idempotency: IdempotencyConfig{TTL: 24 * time.Hour},
+		Health:      HealthConfig{Timeout: 2 * time.Second},
+		Webhooks: WebhooksConfig{
+			PollInterval: time.Second,
+			BatchSize:    100,
+			MaxAttempts:  5,
+			BaseBackoff:  500 * time.Millisecond,
+			MaxBackoff:   30 * time.Second,
+		},
+		Mail: MailConfig{
+			Mailer:           MailerLog,
+			SMTP:             SMTPConfig{Timeout: 10 * time.Second},
+			VerifyEmailTTL:   24 * time.Hour,
+			ResetPasswordTTL: time.Hour,
+		},
+	}
+}
+
+// LogLevel возвращает уровень логирования; значение уже проверено в Validate
+func (c *Config) LogLevel() slog.Level {
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(c.Log.Level))
+	return level
+}
+
+// Validate проверяет все настройки сразу и возвращает полный список проблем
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	for _, addr := range []struct {
+		key   string
+		value string
+	}{{"http.addr", c.HTTP.Addr}, {"grpc.addr", c.GRPC.Addr}} {
+		_, _, err := net.SplitHostPort(addr.value)
+		check(err == nil, "%s: invalid address %q", addr.key, addr.value)
+	}
+	if c.Gateway.Addr != "" {
+		_, _, err := net.SplitHostPort(c.Gateway.Addr)
+		check(err == nil, "gateway.addr: invalid address %q", c.Gateway.Addr)
+		check(c.Gateway.Addr != c.HTTP.Addr, "http.addr and gateway.addr must differ")
+		check(c.ListenMode != ListenDual || c.Gateway.Addr != c.GRPC.Addr, "grpc.addr and gateway.addr must differ")
+	}
+	switch c.ListenMode {
+	case ListenDual:
+		check(c.HTTP.Addr != c.GRPC.Addr, "http.addr and grpc.addr must differ")
+	case ListenSingle:
+		// grpc.addr не используется; TLS на общем порту настраивается через http.tls
+		check(c.GRPC.TLS == TLSConfig{}, "grpc.tls is not used in single listen mode, configure http.tls instead")
+	default:
+		check(false, "listen_mode: unknown mode %q", c.ListenMode)
+	}
+	for _, tls := range []struct {
+		key   string
+		value TLSConfig
+	}{{"http.tls", c.HTTP.TLS}, {"grpc.tls", c.GRPC.TLS}} {
+		check((tls.value.CertFile == "") == (tls.value.KeyFile == ""), "%s: cert_file and key_file must be set together", tls.key)
+		check(tls.value.ClientCAFile == "" || tls.value.CertFile != "", "%s: client_ca_file requires cert_file", tls.key)
+	}
+	for _, timeout := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"http.read_header_timeout", c.HTTP.ReadHeaderTimeout},
+		{"http.read_timeout", c.HTTP.ReadTimeout},
+		{"http.write_timeout", c.HTTP.WriteTimeout},
+		{"http.idle_timeout", c.HTTP.IdleTimeout},
+		{"shutdown.timeout", c.Shutdown.Timeout},
+		{"idempotency.ttl", c.Idempotency.TTL},
+		{"health.timeout", c.Health.Timeout},
+		{"webhooks.poll_interval", c.Webhooks.PollInterval},
+		{"webhooks.base_backoff", c.Webhooks.BaseBackoff},
+		{"mail.verify_email_ttl", c.Mail.VerifyEmailTTL},
+		{"mail.reset_password_ttl", c.Mail.ResetPasswordTTL},
+	} {
+		check(timeout.value > 0, "%s must be positive", timeout.key)
+	}
+	check(c.Shutdown.DrainDelay >= 0, "shutdown.drain_delay must not be negative")
+	check(c.Webhooks.MaxBackoff >= c.Webhooks.BaseBackoff, "webhooks.max_backoff must not be less than webhooks.base_backoff")
+	check(c.Webhooks.BatchSize > 0, "webhooks.batch_size must be positive")
+	check(c.Webhooks.MaxAttempts > 0, "webhooks.max_attempts must be positive")
+	check(c.Cache.Size >= 0, "cache.size must not be negative")
+	check(c.Cache.Size == 0 || c.Cache.TTL > 0, "cache.ttl must be positive")
+
+	switch c.Mail.Mailer {
+	case MailerLog:
+	case MailerSMTP:
+		_, _, err := net.SplitHostPort(c.Mail.SMTP.Addr)
+		check(err == nil, "mail.smtp.addr: invalid address %q", c.Mail.SMTP.Addr)
+		_, err = mail.ParseAddress(c.Mail.SMTP.From)
+		check(err == nil, "mail.smtp.from: invalid address %q", c.Mail.SMTP.From)
+		check(c.Mail.SMTP.Timeout > 0, "mail.smtp.timeout must be positive")
+	default:
+		check(false, "mail.mailer: unknown mailer %q", c.Mail.Mailer)
+	}
+
+	check(c.Repository.Type == RepositoryMemory || c.Repository.Type == RepositorySharded, "repository.type: unknown repository %q", c.Repository.Type)
+	check(c.Repository.Type != RepositorySharded || c.Repository.Shards > 0, "repository.shards must be positive")
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.Log.Level)) == nil, "log.level: unknown level %q", c.Log.Level)
+	check(c.Trace.Exporter == tracing.ExporterNone || c.Trace.Exporter == tracing.ExporterStdout, "trace.exporter: unknown exporter %q", c.Trace.Exporter)
+
+	for _, limit := range []struct {
+		key   string
+		value LimitConfig
+	}{{"rate.read", c.Rate.Read}, {"rate.write", c.Rate.Write}} {
+		check(limit.value.RPS >= 0, "%s.rps must not be negative", limit.key)
+		check(limit.value.RPS == 0 || limit.value.Burst > 0, "%s.burst must be positive", limit.key)
+	}
+
+	return errors.Join(errs...)
+}
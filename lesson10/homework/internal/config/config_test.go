@@ -0,0 +1,161 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func env(values map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := values[key]
+		return value, ok
+	}
+}
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, printConfig, err := Load(nil, env(nil), &bytes.Buffer{})
+	assert.NoError(t, err)
+	assert.False(t, printConfig)
+	assert.Equal(t, Default(), cfg)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeFile(t, `
+http:
+  addr: ":1000"
+  read_timeout: 5s
+grpc:
+  addr: ":2000"
+rate:
+  write:
+    rps: 1
+`)
+
+	cfg, _, err := Load(
+		[]string{"--config", path, "--http-addr", ":3000"},
+		env(map[string]string{"ADS_HTTP_ADDR": ":4000", "ADS_GRPC_ADDR": ":5000", "ADS_LOG_LEVEL": "debug", "ADS_WEBHOOKS_ALLOW_PRIVATE_TARGETS": "true"}),
+		&bytes.Buffer{},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, ":3000", cfg.HTTP.Addr)                // флаг важнее окружения
+	assert.Equal(t, ":5000", cfg.GRPC.Addr)                // окружение важнее файла
+	assert.Equal(t, 5*time.Second, cfg.HTTP.ReadTimeout)   // файл важнее значений по умолчанию
+	assert.Equal(t, 30*time.Second, cfg.HTTP.WriteTimeout) // значение по умолчанию
+	assert.Equal(t, float64(1), cfg.Rate.Write.RPS)        // вложенные ключи файла
+	assert.Equal(t, Default().Rate.Write.Burst, cfg.Rate.Write.Burst)
+	assert.Equal(t, "debug", cfg.Log.Level)
+	assert.True(t, cfg.Webhooks.AllowPrivateTargets)
+}
+
+func TestLoad_SingleListenMode(t *testing.T) {
+	// в режиме одного порта grpc.addr не используется и может совпадать с http.addr
+	cfg, _, err := Load([]string{"--listen-mode", "single", "--grpc-addr", ":18080"}, env(nil), &bytes.Buffer{})
+	assert.NoError(t, err)
+	assert.Equal(t, ListenSingle, cfg.ListenMode)
+}
+
+func TestLoad_ConfigPathFromEnv(t *testing.T) {
+	path := writeFile(t, "trace:\n  exporter: stdout\n")
+	cfg, _, err := Load(nil, env(map[string]string{"ADS_CONFIG": path}), &bytes.Buffer{})
+	assert.NoError(t, err)
+	assert.Equal(t, "stdout", cfg.Trace.Exporter)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		file string
+	}{
+		{name: "unknown key in file", file: "http:\n  port: 80\n"},
+		{name: "bad duration in env", env: map[string]string{"ADS_SHUTDOWN_TIMEOUT": "soon"}},
+		{name: "bad number in flag", args: []string{"--rate-read-burst", "many"}},
+		{name: "bad bool in env", env: map[string]string{"ADS_WEBHOOKS_ALLOW_PRIVATE_TARGETS": "maybe"}},
+		{name: "unknown flag", args: []string{"--port", "80"}},
+		{name: "positional argument", args: []string{"serve"}},
+		{name: "missing file", args: []string{"--config", "/does/not/exist.yaml"}},
+		{name: "same addresses", args: []string{"--grpc-addr", ":18080"}},
+		{name: "gateway on grpc address", args: []string{"--gateway-addr", ":50054"}},
+		{name: "bad gateway address", env: map[string]string{"ADS_GATEWAY_ADDR": "gateway"}},
+		{name: "unknown repository", env: map[string]string{"ADS_REPOSITORY_TYPE": "postgres"}},
+		{name: "no shards", args: []string{"--repository-type", "sharded", "--repository-shards", "0"}},
+		{name: "negative cache size", args: []string{"--cache-size", "-1"}},
+		{name: "zero cache ttl", args: []string{"--cache-ttl", "0s"}},
+		{name: "unknown log level", args: []string{"--log-level", "loud"}},
+		{name: "unknown exporter", args: []string{"--trace-exporter", "jaeger"}},
+		{name: "zero timeout", args: []string{"--http-read-timeout", "0s"}},
+		{name: "negative rate", args: []string{"--rate-write-rps", "-1"}},
+		{name: "bad address", args: []string{"--http-addr", "localhost"}},
+		{name: "unknown listen mode", args: []string{"--listen-mode", "triple"}},
+		{name: "grpc tls in single mode", args: []string{"--listen-mode", "single", "--grpc-tls-cert-file", "s.pem", "--grpc-tls-key-file", "k.pem"}},
+		{name: "tls cert without key", args: []string{"--grpc-tls-cert-file", "server.pem"}},
+		{name: "client ca without cert", env: map[string]string{"ADS_GRPC_TLS_CLIENT_CA_FILE": "ca.pem"}},
+		{name: "unknown mailer", args: []string{"--mail-mailer", "carrier-pigeon"}},
+		{name: "smtp without address", args: []string{"--mail-mailer", "smtp", "--mail-smtp-from", "noreply@example.com"}},
+		{name: "smtp with bad sender", args: []string{"--mail-mailer", "smtp", "--mail-smtp-addr", "localhost:25", "--mail-smtp-from", "noreply"}},
+		{name: "zero token ttl", env: map[string]string{"ADS_MAIL_RESET_PASSWORD_TTL": "0s"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			args := tc.args
+			if tc.file != "" {
+				args = append(args, "--config", writeFile(t, tc.file))
+			}
+			_, _, err := Load(args, env(tc.env), &bytes.Buffer{})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoad_Help(t *testing.T) {
+	var out bytes.Buffer
+	_, _, err := Load([]string{"--help"}, env(nil), &out)
+	assert.ErrorIs(t, err, flag.ErrHelp)
+	assert.Contains(t, out.String(), "-rate-read-rps")
+	assert.Contains(t, out.String(), "env ADS_RATE_READ_RPS, default 50")
+}
+
+func TestWrite_RoundTrip(t *testing.T) {
+	cfg := Default()
+	cfg.HTTP.Addr = ":9000"
+	cfg.Webhooks.BaseBackoff = 250 * time.Millisecond
+
+	var buf bytes.Buffer
+	assert.NoError(t, cfg.Write(&buf))
+	assert.Contains(t, buf.String(), "base_backoff: 250ms")
+
+	loaded, printConfig, err := Load([]string{"--config", writeFile(t, buf.String()), "--print-config"}, env(nil), &bytes.Buffer{})
+	assert.NoError(t, err)
+	assert.True(t, printConfig)
+	assert.Equal(t, cfg, loaded)
+}
+
+func TestWrite_RedactsSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.Mail.SMTP.Username = "mailer"
+	cfg.Mail.SMTP.Password = "smtp-secret"
+	cfg.Admin.Token = "admin-secret"
+
+	var buf bytes.Buffer
+	assert.NoError(t, cfg.Write(&buf))
+	assert.NotContains(t, buf.String(), "smtp-secret")
+	assert.NotContains(t, buf.String(), "admin-secret")
+	assert.Contains(t, buf.String(), "password: '[REDACTED]'")
+	assert.Contains(t, buf.String(), "token: '[REDACTED]'")
+	assert.Contains(t, buf.String(), "username: mailer")
+	assert.Equal(t, "smtp-secret", cfg.Mail.SMTP.Password, "Write не меняет саму конфигурацию")
+}
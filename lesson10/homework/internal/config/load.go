@@ -0,0 +1,181 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix - префикс переменных окружения, ADS_CONFIG задаёт путь к файлу
+const EnvPrefix = "ADS_"
+
+// redacted заменяет в выводе Write значения настроек с тегом secret
+const redacted = "[REDACTED]"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setting - одна настройка, доступная из файла, окружения и флагов
+type setting struct {
+	key    string // http.read_timeout
+	flag   string // http-read-timeout
+	env    string // ADS_HTTP_READ_TIMEOUT
+	usage  string
+	secret bool // пароль или токен: Write его не печатает
+	value  reflect.Value
+}
+
+func settings(cfg *Config) []setting {
+	var out []setting
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		for idx := 0; idx < v.NumField(); idx++ {
+			field := v.Type().Field(idx)
+			key := prefix + field.Tag.Get("yaml")
+			if field.Type.Kind() == reflect.Struct {
+				walk(v.Field(idx), key+".")
+				continue
+			}
+			out = append(out, setting{
+				key:    key,
+				flag:   strings.NewReplacer(".", "-", "_", "-").Replace(key),
+				env:    EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_")),
+				usage:  field.Tag.Get("usage"),
+				secret: field.Tag.Get("secret") == "true",
+				value:  v.Field(idx),
+			})
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem(), "")
+	return out
+}
+
+func (s setting) set(raw string) error {
+	switch {
+	case s.value.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		s.value.SetInt(int64(d))
+	case s.value.Kind() == reflect.String:
+		s.value.SetString(raw)
+	case s.value.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		s.value.SetInt(int64(n))
+	case s.value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		s.value.SetBool(b)
+	case s.value.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		s.value.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", s.value.Type())
+	}
+	return nil
+}
+
+// Load собирает конфигурацию с приоритетом: флаги > переменные окружения > файл > значения по умолчанию.
+// Путь к файлу задаётся флагом --config или переменной ADS_CONFIG. Второе значение сообщает,
+// что передан --print-config. Ошибка flag.ErrHelp означает, что был запрошен --help
+func Load(args []string, lookupEnv func(string) (string, bool), output io.Writer) (Config, bool, error) {
+	cfg := Default()
+	all := settings(&cfg)
+
+	fs := flag.NewFlagSet("adservice", flag.ContinueOnError)
+	fs.SetOutput(output)
+	path := fs.String("config", "", "path to a YAML config file (env "+EnvPrefix+"CONFIG)")
+	printConfig := fs.Bool("print-config", false, "print the effective config with passwords and tokens redacted and exit")
+	flags := make(map[string]string)
+	for _, s := range all {
+		name := s.flag
+		fs.Func(name, fmt.Sprintf("%s (env %s, default %v)", s.usage, s.env, s.value.Interface()), func(raw string) error {
+			flags[name] = raw
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, false, err
+	}
+	if fs.NArg() > 0 {
+		return cfg, false, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	if *path == "" {
+		*path, _ = lookupEnv(EnvPrefix + "CONFIG")
+	}
+	if *path != "" {
+		if err := readFile(*path, &cfg); err != nil {
+			return cfg, false, err
+		}
+	}
+
+	for _, s := range all {
+		if raw, ok := lookupEnv(s.env); ok {
+			if err := s.set(raw); err != nil {
+				return cfg, false, fmt.Errorf("env %s: %w", s.env, err)
+			}
+		}
+	}
+	for _, s := range all {
+		if raw, ok := flags[s.flag]; ok {
+			if err := s.set(raw); err != nil {
+				return cfg, false, fmt.Errorf("flag --%s: %w", s.flag, err)
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return cfg, false, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, *printConfig, nil
+}
+
+func readFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Write печатает конфигурацию в том же формате, в котором её читает Load. Заданные пароли и токены
+// заменяются на [REDACTED]: вывод --print-config часто попадает в журналы и тикеты
+func (c *Config) Write(w io.Writer) error {
+	masked := *c
+	for _, s := range settings(&masked) {
+		if s.secret && !s.value.IsZero() {
+			s.value.SetString(redacted)
+		}
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&masked); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
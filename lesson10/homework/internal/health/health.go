@@ -0,0 +1,125 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const DefaultTimeout = 2 * time.Second
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+var ErrShuttingDown = errors.New("shutting down")
+
+// Check проверяет одну зависимость сервиса, nil означает, что она готова
+type Check func(ctx context.Context) error
+
+// Result - итог проверки готовности: общий статус и статус каждой проверки ("ok" или текст ошибки)
+type Result struct {
+	Status string
+	Checks map[string]string
+}
+
+func (r Result) Ready() bool {
+	return r.Status == StatusOK
+}
+
+// Checker собирает проверки готовности. Живость (liveness) от проверок не зависит:
+// если процесс может ответить, он жив
+type Checker struct {
+	mu           sync.RWMutex
+	checks       map[string]Check
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+}
+
+// New создаёт Checker без проверок; timeout <= 0 заменяется на DefaultTimeout
+func New(timeout time.Duration) *Checker {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Checker{checks: make(map[string]Check), timeout: timeout}
+}
+
+// Register добавляет (или заменяет) проверку с именем name
+func (c *Checker) Register(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check
+}
+
+// Shutdown переводит сервис в состояние "не готов" до конца жизни процесса,
+// чтобы балансировщик перестал присылать запросы ещё до остановки серверов
+func (c *Checker) Shutdown() {
+	c.shuttingDown.Store(true)
+}
+
+func (c *Checker) ShuttingDown() bool {
+	return c.shuttingDown.Load()
+}
+
+// Check параллельно запускает все проверки, каждую не дольше timeout
+func (c *Checker) Check(ctx context.Context) Result {
+	c.mu.RLock()
+	names := make([]string, 0, len(c.checks))
+	for name := range c.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := make([]Check, len(names))
+	for idx, name := range names {
+		checks[idx] = c.checks[name]
+	}
+	c.mu.RUnlock()
+
+	errs := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for idx := range checks {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			errs[idx] = c.run(ctx, checks[idx])
+		}(idx)
+	}
+	wg.Wait()
+
+	result := Result{Status: StatusOK, Checks: make(map[string]string, len(names))}
+	for idx, name := range names {
+		if errs[idx] != nil {
+			result.Status = StatusUnavailable
+			result.Checks[name] = errs[idx].Error()
+		} else {
+			result.Checks[name] = StatusOK
+		}
+	}
+	if c.ShuttingDown() {
+		result.Status = StatusUnavailable
+		result.Checks["shutdown"] = ErrShuttingDown.Error()
+	}
+	return result
+}
+
+// run не даёт зависшей проверке задержать ответ дольше timeout
+func (c *Checker) run(ctx context.Context, check Check) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- check(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
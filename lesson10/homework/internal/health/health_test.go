@@ -0,0 +1,50 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker(t *testing.T) {
+	checker := New(10 * time.Millisecond)
+	assert.Equal(t, Result{Status: StatusOK, Checks: map[string]string{}}, checker.Check(context.Background()))
+
+	checker.Register("repository", func(ctx context.Context) error { return nil })
+	assert.Equal(t, Result{Status: StatusOK, Checks: map[string]string{"repository": StatusOK}}, checker.Check(context.Background()))
+
+	checker.Register("dispatcher", func(ctx context.Context) error { return errors.New("not running") })
+	result := checker.Check(context.Background())
+	assert.False(t, result.Ready())
+	assert.Equal(t, map[string]string{"repository": StatusOK, "dispatcher": "not running"}, result.Checks)
+}
+
+func TestChecker_Timeout(t *testing.T) {
+	checker := New(10 * time.Millisecond)
+	stuck := make(chan struct{})
+	defer close(stuck)
+	checker.Register("stuck", func(ctx context.Context) error {
+		<-stuck
+		return nil
+	})
+
+	start := time.Now()
+	result := checker.Check(context.Background())
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, StatusUnavailable, result.Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), result.Checks["stuck"])
+}
+
+func TestChecker_Shutdown(t *testing.T) {
+	checker := New(0)
+	checker.Register("repository", func(ctx context.Context) error { return nil })
+	assert.True(t, checker.Check(context.Background()).Ready())
+
+	checker.Shutdown()
+	result := checker.Check(context.Background())
+	assert.False(t, result.Ready())
+	assert.Equal(t, map[string]string{"repository": StatusOK, "shutdown": ErrShuttingDown.Error()}, result.Checks)
+}
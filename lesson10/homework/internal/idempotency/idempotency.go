@@ -0,0 +1,98 @@
+package idempotency
+
+import (
+	"sync"
+	"time"
+)
+
+const DefaultTTL = 24 * time.Hour
+
+// Record - сохранённый ответ на первый запрос с данным ключом
+type Record struct {
+	RequestHash string
+	StatusCode  int
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+type Store interface {
+	Get(key string) (Record, bool)
+	Save(key string, record Record)
+	// Lock не даёт параллельно выполнить два запроса с одним ключом
+	Lock(key string) (unlock func())
+}
+
+const sweepInterval = time.Minute
+
+type memoryStore struct {
+	ttl       time.Duration
+	records   map[string]Record
+	locks     map[string]*keyLock
+	lastSweep time.Time
+	mu        sync.Mutex
+}
+
+type keyLock struct {
+	mu      sync.Mutex
+	waiters int
+}
+
+func NewMemoryStore(ttl time.Duration) Store {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &memoryStore{ttl: ttl, records: make(map[string]Record), locks: make(map[string]*keyLock)}
+}
+
+func (s *memoryStore) Get(key string) (Record, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[key]
+	if ok && time.Now().After(record.ExpiresAt) {
+		delete(s.records, key)
+		return Record{}, false
+	}
+	return record, ok
+}
+
+func (s *memoryStore) Save(key string, record Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > sweepInterval {
+		for k, r := range s.records {
+			if now.After(r.ExpiresAt) {
+				delete(s.records, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	record.ExpiresAt = now.Add(s.ttl)
+	s.records[key] = record
+}
+
+func (s *memoryStore) Lock(key string) func() {
+	s.mu.Lock()
+	l, ok := s.locks[key]
+	if !ok {
+		l = &keyLock{}
+		s.locks[key] = l
+	}
+	l.waiters++
+	s.mu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+
+		s.mu.Lock()
+		l.waiters--
+		if l.waiters == 0 {
+			delete(s.locks, key)
+		}
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,65 @@
+package idempotency
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore_SaveGet(t *testing.T) {
+	store := NewMemoryStore(time.Hour)
+
+	_, ok := store.Get("key")
+	assert.False(t, ok)
+
+	store.Save("key", Record{RequestHash: "hash", StatusCode: 200, Body: []byte("body")})
+	got, ok := store.Get("key")
+	assert.True(t, ok)
+	assert.Equal(t, "hash", got.RequestHash)
+	assert.Equal(t, 200, got.StatusCode)
+	assert.Equal(t, []byte("body"), got.Body)
+}
+
+func TestMemoryStore_Expiration(t *testing.T) {
+	store := NewMemoryStore(10 * time.Millisecond)
+	store.Save("key", Record{RequestHash: "hash"})
+
+	time.Sleep(20 * time.Millisecond)
+	_, ok := store.Get("key")
+	assert.False(t, ok)
+}
+
+func TestMemoryStore_Lock(t *testing.T) {
+	store := NewMemoryStore(time.Hour)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	inside, maxInside := 0, 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := store.Lock("key")
+			defer unlock()
+
+			mu.Lock()
+			inside++
+			if inside > maxInside {
+				maxInside = inside
+			}
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			inside--
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, maxInside)
+	assert.Empty(t, store.(*memoryStore).locks)
+}
@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew_RedactsSensitiveFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo)
+
+	logger.Info("user created", slog.Int64("user_id", 1), slog.String("email", "buda@phystech.edu"), slog.String("Password", "qwerty"))
+
+	var record map[string]any
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "user created", record["msg"])
+	assert.Equal(t, float64(1), record["user_id"])
+	assert.Equal(t, redacted, record["email"])
+	assert.Equal(t, redacted, record["Password"])
+	assert.NotContains(t, buf.String(), "phystech")
+}
+
+func TestNew_Level(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelWarn)
+
+	logger.Info("skipped")
+	assert.Empty(t, buf.String())
+	logger.Warn("written")
+	assert.Contains(t, buf.String(), "written")
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Equal(t, slog.Default(), FromContext(context.Background()))
+
+	logger := New(&bytes.Buffer{}, slog.LevelInfo)
+	ctx := WithLogger(context.Background(), logger)
+	assert.Equal(t, logger, FromContext(ctx))
+
+	assert.Empty(t, RequestIDFromContext(ctx))
+	assert.Equal(t, "abc", RequestIDFromContext(WithRequestID(ctx, "abc")))
+}
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		requestID string
+		valid     bool
+	}{
+		{"", false},
+		{NewRequestID(), true},
+		{"0b6f1c8e-4c1d-4a5e-9f5e-1f2e3d4c5b6a", true},
+		{"id with spaces", false},
+		{"id\nwith\nnewlines", false},
+		{string(bytes.Repeat([]byte("a"), 129)), false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.valid, ValidRequestID(test.requestID), test.requestID)
+	}
+}
@@ -0,0 +1,67 @@
+package mailer
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"homework10/internal/logging"
+)
+
+// Message - письмо одному получателю
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer отправляет письма. Send возвращает ошибку, если письмо не принято к отправке
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Capture складывает письма в память вместо отправки - для тестов
+type Capture struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewCapture() *Capture {
+	return &Capture{}
+}
+
+func (c *Capture) Send(_ context.Context, msg Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, msg)
+	return nil
+}
+
+// Messages возвращает копию всех отправленных писем по порядку отправки
+func (c *Capture) Messages() []Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Message(nil), c.messages...)
+}
+
+// Last возвращает последнее письмо на адрес to
+func (c *Capture) Last(to string) (Message, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for idx := len(c.messages) - 1; idx >= 0; idx-- {
+		if c.messages[idx].To == to {
+			return c.messages[idx], true
+		}
+	}
+	return Message{}, false
+}
+
+// Log пишет письма в лог вместо отправки - для локального запуска без SMTP-сервера.
+// В лог попадают и токены из писем, поэтому в продакшене он не годится
+type Log struct{}
+
+func (Log) Send(ctx context.Context, msg Message) error {
+	logging.FromContext(ctx).Info("mail",
+		slog.String("to", msg.To), slog.String("subject", msg.Subject), slog.String("body", msg.Body))
+	return nil
+}
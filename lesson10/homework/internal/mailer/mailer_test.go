@@ -0,0 +1,113 @@
+package mailer
+
+import (
+	"context"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// smtpSession - что фейковый SMTP-сервер получил за одно соединение
+type smtpSession struct {
+	from, to string
+	data     string
+}
+
+// serveSMTP принимает одно соединение и отвечает на команды, которые шлёт net/smtp, без STARTTLS и AUTH
+func serveSMTP(t *testing.T) (addr string, session <-chan smtpSession) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	done := make(chan smtpSession, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		var s smtpSession
+		_ = tp.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch cmd {
+			case "EHLO", "HELO":
+				_ = tp.PrintfLine("250 localhost")
+			case "MAIL":
+				s.from = line
+				_ = tp.PrintfLine("250 OK")
+			case "RCPT":
+				s.to = line
+				_ = tp.PrintfLine("250 OK")
+			case "DATA":
+				_ = tp.PrintfLine("354 go ahead")
+				lines, _ := tp.ReadDotLines()
+				s.data = strings.Join(lines, "\n")
+				_ = tp.PrintfLine("250 OK")
+			case "QUIT":
+				_ = tp.PrintfLine("221 bye")
+				done <- s
+				return
+			default:
+				_ = tp.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+	return ln.Addr().String(), done
+}
+
+func TestSMTP_Send(t *testing.T) {
+	addr, session := serveSMTP(t)
+	m := NewSMTP(SMTPConfig{Addr: addr, From: "noreply@example.com"})
+
+	err := m.Send(context.Background(), Message{To: "buda@phystech.edu", Subject: "Подтвердите email", Body: "line 1\nline 2"})
+	require.NoError(t, err)
+
+	s := <-session
+	assert.Equal(t, "MAIL FROM:<noreply@example.com>", strings.SplitN(s.from, " BODY", 2)[0])
+	assert.Equal(t, "RCPT TO:<buda@phystech.edu>", s.to)
+	assert.Contains(t, s.data, "To: <buda@phystech.edu>\n")
+	assert.Contains(t, s.data, "Subject: =?utf-8?q?")
+	assert.True(t, strings.HasSuffix(s.data, "\n\nline 1\nline 2"), s.data)
+}
+
+func TestSMTP_RejectsHeaderInjection(t *testing.T) {
+	m := NewSMTP(SMTPConfig{Addr: "127.0.0.1:1", From: "noreply@example.com"})
+	err := m.Send(context.Background(), Message{To: "buda@phystech.edu\r\nBcc: all@example.com", Subject: "hi"})
+	assert.ErrorContains(t, err, "line break")
+}
+
+func TestSMTP_DialError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	err = NewSMTP(SMTPConfig{Addr: addr}).Send(context.Background(), Message{To: "buda@phystech.edu"})
+	assert.ErrorContains(t, err, "mailer: dial")
+}
+
+func TestCapture(t *testing.T) {
+	c := NewCapture()
+	ctx := context.Background()
+	_ = c.Send(ctx, Message{To: "buda@phystech.edu", Subject: "first"})
+	_ = c.Send(ctx, Message{To: "mayot@phystech.edu", Subject: "other"})
+	_ = c.Send(ctx, Message{To: "buda@phystech.edu", Subject: "second"})
+
+	last, ok := c.Last("buda@phystech.edu")
+	assert.True(t, ok)
+	assert.Equal(t, "second", last.Subject)
+	_, ok = c.Last("nobody@phystech.edu")
+	assert.False(t, ok)
+	assert.Len(t, c.Messages(), 3)
+}
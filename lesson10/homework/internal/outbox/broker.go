@@ -0,0 +1,70 @@
+package outbox
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultBrokerBuffer - сколько событий может отстать подписчик, прежде чем его отключат
+const DefaultBrokerBuffer = 64
+
+// Broker раздаёт события outbox подписчикам внутри процесса (WatchAds, /ads/events).
+// Диспетчер публикует каждое событие до рассылки вебхуков, поэтому подписчики видят
+// те же события, что и вебхуки, и с той же гарантией «хотя бы один раз»
+type Broker struct {
+	mu     sync.Mutex
+	subs   map[chan Event]struct{}
+	buffer int
+}
+
+func NewBroker(buffer int) *Broker {
+	if buffer <= 0 {
+		buffer = DefaultBrokerBuffer
+	}
+	return &Broker{subs: make(map[chan Event]struct{}), buffer: buffer}
+}
+
+// Subscribe возвращает канал новых событий. Канал закрывается, когда отменён ctx или когда
+// подписчик не успевает его разбирать: во втором случае ctx.Err() после закрытия равен nil
+func (b *Broker) Subscribe(ctx context.Context) <-chan Event {
+	ch := make(chan Event, b.buffer)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.unsubscribe(ch)
+	}()
+	return ch
+}
+
+// Publish отправляет событие всем подписчикам, не дожидаясь их
+func (b *Broker) Publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- event:
+		default:
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribers - число активных подписчиков
+func (b *Broker) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+func (b *Broker) unsubscribe(ch chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[ch]; ok {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
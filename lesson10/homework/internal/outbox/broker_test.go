@@ -0,0 +1,54 @@
+package outbox
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBrokerFanOut(t *testing.T) {
+	broker := NewBroker(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first, second := broker.Subscribe(ctx), broker.Subscribe(ctx)
+	broker.Publish(Event{ID: 1, Type: AdCreated})
+
+	assert.Equal(t, int64(1), (<-first).ID)
+	assert.Equal(t, int64(1), (<-second).ID)
+}
+
+func TestBrokerUnsubscribeOnCancel(t *testing.T) {
+	broker := NewBroker(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	events := broker.Subscribe(ctx)
+	require.Equal(t, 1, broker.Subscribers())
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("channel was not closed after cancel")
+	}
+	assert.Equal(t, 0, broker.Subscribers())
+}
+
+func TestBrokerDropsSlowSubscriber(t *testing.T) {
+	broker := NewBroker(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := broker.Subscribe(ctx)
+
+	broker.Publish(Event{ID: 1})
+	broker.Publish(Event{ID: 2})
+
+	assert.Equal(t, int64(1), (<-events).ID)
+	_, ok := <-events
+	assert.False(t, ok)
+	assert.NoError(t, ctx.Err())
+	assert.Equal(t, 0, broker.Subscribers())
+}
@@ -0,0 +1,257 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"homework10/internal/ads"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Signature"
+	EventTypeHeader = "X-Event-Type"
+	EventIdHeader   = "X-Event-Id"
+)
+
+type Config struct {
+	// Client по умолчанию соединяется только с публичными адресами, см. AllowPrivateTargets
+	Client       *http.Client
+	PollInterval time.Duration // как часто диспетчер проверяет outbox
+	BatchSize    int           // сколько событий забирается за один проход
+	MaxAttempts  int           // попыток доставки одного события одному подписчику
+	BaseBackoff  time.Duration // задержка перед второй попыткой, дальше удваивается
+	MaxBackoff   time.Duration
+	Broker       *Broker // если задан, каждое событие публикуется и подписчикам внутри процесса
+	// AllowPrivateTargets разрешает доставку на loopback, частные и link-local адреса:
+	// для локальной разработки и тестов. Действует, только если Client не задан
+	AllowPrivateTargets bool
+	Logger              *slog.Logger // по умолчанию slog.Default()
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Client:       publicClient(DefaultTimeout),
+		PollInterval: time.Second,
+		BatchSize:    100,
+		MaxAttempts:  5,
+		BaseBackoff:  500 * time.Millisecond,
+		MaxBackoff:   30 * time.Second,
+	}
+}
+
+type Dispatcher struct {
+	store   Store
+	cfg     Config
+	running atomic.Bool
+}
+
+func NewDispatcher(store Store, cfg Config) *Dispatcher {
+	def := DefaultConfig()
+	if cfg.Client == nil {
+		cfg.Client = def.Client
+		if cfg.AllowPrivateTargets {
+			cfg.Client = &http.Client{Timeout: DefaultTimeout}
+		}
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = def.PollInterval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = def.BatchSize
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = def.MaxAttempts
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = def.BaseBackoff
+	}
+	if cfg.MaxBackoff < cfg.BaseBackoff {
+		cfg.MaxBackoff = cfg.BaseBackoff
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Dispatcher{store: store, cfg: cfg}
+}
+
+type adPayload struct {
+	ID           int64     `json:"id"`
+	Title        string    `json:"title"`
+	Text         string    `json:"text"`
+	AuthorID     int64     `json:"author_id"`
+	Published    bool      `json:"published"`
+	DateUpdate   time.Time `json:"date_update"`
+	DateCreating time.Time `json:"date_creating"`
+}
+
+type eventPayload struct {
+	ID        int64     `json:"id"`
+	Type      EventType `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Ad        adPayload `json:"ad"`
+}
+
+func newAdPayload(ad ads.Ad) adPayload {
+	return adPayload{
+		ID:           ad.ID,
+		Title:        ad.Title,
+		Text:         ad.Text,
+		AuthorID:     ad.AuthorID,
+		Published:    ad.Published,
+		DateUpdate:   ad.DateUpdate,
+		DateCreating: ad.DateCreating,
+	}
+}
+
+// Sign возвращает значение заголовка X-Signature для тела запроса
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Run разбирает outbox, пока не будет отменён ctx
+func (d *Dispatcher) Run(ctx context.Context) error {
+	d.running.Store(true)
+	defer d.running.Store(false)
+
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.Flush(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Running сообщает, работает ли сейчас цикл Run
+func (d *Dispatcher) Running() bool {
+	return d.running.Load()
+}
+
+// Flush доставляет накопившиеся события всем подписчикам. Событие удаляется из outbox,
+// когда каждый подписчик либо получил его, либо оно попало в dead-letter список.
+// Подписчики получают пачку событий параллельно, каждый - по порядку: подписчик, который
+// не отвечает и ждёт повторов, не задерживает доставку остальным
+func (d *Dispatcher) Flush(ctx context.Context) {
+	for {
+		events := d.store.GetOutboxEvents(ctx, d.cfg.BatchSize)
+		if len(events) == 0 {
+			return
+		}
+
+		if d.cfg.Broker != nil {
+			for _, event := range events {
+				d.cfg.Broker.Publish(event)
+			}
+		}
+		var wg sync.WaitGroup
+		for _, webhook := range d.store.GetWebhooks(ctx) {
+			wg.Add(1)
+			go func(webhook Webhook) {
+				defer wg.Done()
+				d.deliverAll(ctx, webhook, events)
+			}(webhook)
+		}
+		wg.Wait()
+		if ctx.Err() != nil {
+			return
+		}
+
+		for _, event := range events {
+			d.store.DeleteOutboxEvent(ctx, event.ID)
+		}
+	}
+}
+
+// deliverAll доставляет события одному подписчику по порядку; то, что не удалось доставить,
+// уходит в dead letters
+func (d *Dispatcher) deliverAll(ctx context.Context, webhook Webhook, events []Event) {
+	for _, event := range events {
+		attempts, err := d.deliver(ctx, webhook, event)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			d.cfg.Logger.Warn("event moved to dead letters", slog.Int64("webhook_id", webhook.ID), slog.Int64("event_id", event.ID), slog.String("error", err.Error()))
+			d.store.AddDeadLetter(ctx, &DeadLetter{
+				Event:     event,
+				WebhookID: webhook.ID,
+				Attempts:  attempts,
+				LastError: err.Error(),
+				FailedAt:  time.Now().UTC(),
+			})
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, webhook Webhook, event Event) (int, error) {
+	body, err := json.Marshal(eventPayload{
+		ID:        event.ID,
+		Type:      event.Type,
+		CreatedAt: event.CreatedAt,
+		Ad:        newAdPayload(event.Ad),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	backoff := d.cfg.BaseBackoff
+	attempt := 0
+	for {
+		attempt++
+		err = d.post(ctx, webhook, event, body)
+		if err == nil || attempt >= d.cfg.MaxAttempts {
+			return attempt, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > d.cfg.MaxBackoff {
+			backoff = d.cfg.MaxBackoff
+		}
+	}
+}
+
+func (d *Dispatcher) post(ctx context.Context, webhook Webhook, event Event, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, body))
+	req.Header.Set(EventTypeHeader, string(event.Type))
+	req.Header.Set(EventIdHeader, strconv.FormatInt(event.ID, 10))
+
+	resp, err := d.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code: %s", resp.Status)
+	}
+	return nil
+}
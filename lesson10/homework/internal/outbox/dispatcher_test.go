@@ -0,0 +1,260 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/suite"
+	"homework10/internal/ads"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type memoryStore struct {
+	mu          sync.Mutex
+	events      []Event
+	webhooks    []Webhook
+	deadLetters []DeadLetter
+}
+
+func (m *memoryStore) GetOutboxEvents(_ context.Context, limit int) []Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if limit > len(m.events) {
+		limit = len(m.events)
+	}
+	return append([]Event(nil), m.events[:limit]...)
+}
+
+func (m *memoryStore) DeleteOutboxEvent(_ context.Context, id int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for idx := range m.events {
+		if m.events[idx].ID == id {
+			m.events = append(m.events[:idx], m.events[idx+1:]...)
+			return
+		}
+	}
+}
+
+func (m *memoryStore) GetWebhooks(_ context.Context) []Webhook {
+	return m.webhooks
+}
+
+func (m *memoryStore) AddDeadLetter(_ context.Context, letter *DeadLetter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deadLetters = append(m.deadLetters, *letter)
+}
+
+type DispatcherTestSuite struct {
+	suite.Suite
+	store *memoryStore
+	cfg   Config
+}
+
+func (s *DispatcherTestSuite) SetupTest() {
+	s.store = &memoryStore{}
+	s.cfg = Config{PollInterval: 10 * time.Millisecond, MaxAttempts: 3, BaseBackoff: time.Millisecond, MaxBackoff: 4 * time.Millisecond, AllowPrivateTargets: true}
+}
+
+func TestDispatcherRun(t *testing.T) {
+	suite.Run(t, new(DispatcherTestSuite))
+}
+
+func (s *DispatcherTestSuite) TestDispatcher_DeliversSignedPayload() {
+	var received []eventPayload
+	var mu sync.Mutex
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		s.NoError(err)
+		s.Equal(Sign("secret", body), r.Header.Get(SignatureHeader))
+		s.Equal(string(AdCreated), r.Header.Get(EventTypeHeader))
+
+		var payload eventPayload
+		s.NoError(json.Unmarshal(body, &payload))
+		mu.Lock()
+		received = append(received, payload)
+		mu.Unlock()
+	}))
+	defer receiver.Close()
+
+	s.store.webhooks = []Webhook{{ID: 0, URL: receiver.URL, Secret: "secret"}}
+	s.store.events = []Event{
+		{ID: 0, Type: AdCreated, Ad: ads.Ad{ID: 0, Title: "hello", Text: "world", AuthorID: 1}},
+		{ID: 1, Type: AdCreated, Ad: ads.Ad{ID: 1, Title: "foo", Text: "bar", AuthorID: 1}},
+	}
+
+	NewDispatcher(s.store, s.cfg).Flush(context.Background())
+
+	s.Len(received, 2)
+	s.Equal(int64(0), received[0].ID)
+	s.Equal("hello", received[0].Ad.Title)
+	s.Equal(int64(1), received[1].Ad.ID)
+	s.Empty(s.store.events)
+	s.Empty(s.store.deadLetters)
+}
+
+func (s *DispatcherTestSuite) TestDispatcher_RetriesWithBackoff() {
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	s.store.webhooks = []Webhook{{ID: 0, URL: receiver.URL, Secret: "secret"}}
+	s.store.events = []Event{{ID: 0, Type: AdUpdated}}
+
+	NewDispatcher(s.store, s.cfg).Flush(context.Background())
+
+	s.Equal(int32(3), atomic.LoadInt32(&calls))
+	s.Empty(s.store.events)
+	s.Empty(s.store.deadLetters)
+}
+
+func (s *DispatcherTestSuite) TestDispatcher_DeadLetter() {
+	var calls int32
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	var delivered int32
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&delivered, 1)
+	}))
+	defer healthy.Close()
+
+	s.store.webhooks = []Webhook{{ID: 0, URL: failing.URL, Secret: "a"}, {ID: 1, URL: healthy.URL, Secret: "b"}}
+	s.store.events = []Event{{ID: 7, Type: AdDeleted}}
+	var logs bytes.Buffer
+	s.cfg.Logger = slog.New(slog.NewTextHandler(&logs, nil))
+
+	NewDispatcher(s.store, s.cfg).Flush(context.Background())
+
+	s.Equal(int32(s.cfg.MaxAttempts), atomic.LoadInt32(&calls))
+	s.Equal(int32(1), atomic.LoadInt32(&delivered))
+	s.Empty(s.store.events)
+	s.Len(s.store.deadLetters, 1)
+	s.Equal(int64(7), s.store.deadLetters[0].Event.ID)
+	s.Equal(int64(0), s.store.deadLetters[0].WebhookID)
+	s.Equal(s.cfg.MaxAttempts, s.store.deadLetters[0].Attempts)
+	s.Contains(logs.String(), "event moved to dead letters")
+}
+
+func (s *DispatcherTestSuite) TestDispatcher_SlowWebhookDoesNotBlockOthers() {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	var delivered int32
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&delivered, 1)
+	}))
+	defer healthy.Close()
+
+	s.store.webhooks = []Webhook{{ID: 0, URL: slow.URL, Secret: "a"}, {ID: 1, URL: healthy.URL, Secret: "b"}}
+	s.store.events = []Event{{ID: 0, Type: AdCreated}, {ID: 1, Type: AdUpdated}, {ID: 2, Type: AdDeleted}}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		NewDispatcher(s.store, s.cfg).Flush(ctx)
+	}()
+
+	// первый подписчик ещё не ответил на первое событие, а второй уже получил все
+	s.Eventually(func() bool { return atomic.LoadInt32(&delivered) == 3 }, time.Second, time.Millisecond)
+	cancel()
+	<-done
+}
+
+func (s *DispatcherTestSuite) TestDispatcher_RejectsPrivateTargets() {
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer receiver.Close()
+
+	s.store.webhooks = []Webhook{{ID: 0, URL: receiver.URL, Secret: "secret"}}
+	s.store.events = []Event{{ID: 0, Type: AdCreated}}
+	s.cfg.AllowPrivateTargets = false
+
+	NewDispatcher(s.store, s.cfg).Flush(context.Background())
+
+	s.Zero(atomic.LoadInt32(&calls), "loopback-адрес не должен получать вебхуки")
+	s.Len(s.store.deadLetters, 1)
+	s.Contains(s.store.deadLetters[0].LastError, ErrPrivateTarget.Error())
+}
+
+func TestPublicHost(t *testing.T) {
+	for host, public := range map[string]bool{
+		"example.com":     true,
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"localhost":       false,
+		"api.localhost.":  false,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"0.0.0.0":         false,
+		"::1":             false,
+		"fe80::1":         false,
+		"fd00::1":         false,
+		"::ffff:10.0.0.1": false,
+		"224.0.0.1":       false,
+	} {
+		if got := PublicHost(host); got != public {
+			t.Errorf("PublicHost(%q) = %v, want %v", host, got, public)
+		}
+	}
+}
+
+func (s *DispatcherTestSuite) TestDispatcher_RunStopsOnCancel() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	dispatcher := NewDispatcher(s.store, s.cfg)
+	go func() {
+		done <- dispatcher.Run(ctx)
+	}()
+
+	s.Eventually(dispatcher.Running, time.Second, time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		s.NoError(err)
+		s.False(dispatcher.Running())
+	case <-time.After(time.Second):
+		s.Fail("dispatcher did not stop")
+	}
+}
+
+func (s *DispatcherTestSuite) TestDispatcher_PublishesToBroker() {
+	broker := NewBroker(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := broker.Subscribe(ctx)
+
+	s.store.events = []Event{{ID: 0, Type: AdCreated, Ad: ads.Ad{ID: 0, Title: "hello"}}}
+	s.cfg.Broker = broker
+	NewDispatcher(s.store, s.cfg).Flush(context.Background())
+
+	event := <-events
+	s.Equal(AdCreated, event.Type)
+	s.Equal("hello", event.Ad.Title)
+	s.Empty(s.store.events)
+}
@@ -0,0 +1,48 @@
+package outbox
+
+import (
+	"context"
+	"homework10/internal/ads"
+	"time"
+)
+
+type EventType string
+
+const (
+	AdCreated       EventType = "ad.created"
+	AdUpdated       EventType = "ad.updated"
+	AdStatusChanged EventType = "ad.status_changed"
+	AdDeleted       EventType = "ad.deleted"
+)
+
+// Event - доменное событие, записанное в outbox вместе с изменением объявления
+type Event struct {
+	ID        int64
+	Type      EventType
+	Ad        ads.Ad
+	CreatedAt time.Time
+}
+
+// Webhook - подписка на события, Secret используется для подписи тела запроса (HMAC-SHA256)
+type Webhook struct {
+	ID     int64
+	URL    string `validate:"min:1;max:2048"`
+	Secret string `validate:"min:1;max:256"`
+}
+
+// DeadLetter - событие, которое так и не удалось доставить подписчику
+type DeadLetter struct {
+	Event     Event
+	WebhookID int64
+	Attempts  int
+	LastError string
+	FailedAt  time.Time
+}
+
+// Store - часть репозитория, которая нужна диспетчеру
+type Store interface {
+	GetOutboxEvents(ctx context.Context, limit int) []Event
+	DeleteOutboxEvent(ctx context.Context, id int64)
+	GetWebhooks(ctx context.Context) []Webhook
+	AddDeadLetter(ctx context.Context, letter *DeadLetter)
+}
@@ -0,0 +1,59 @@
+package outbox
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultTimeout - сколько ждать ответа подписчика на одну попытку доставки
+const DefaultTimeout = 10 * time.Second
+
+var ErrPrivateTarget = errors.New("webhook target is a loopback, private or link-local address")
+
+// PublicAddr сообщает, можно ли слать вебхуки на адрес. Loopback, частные, link-local,
+// multicast и неуказанные адреса закрыты: иначе через подписку можно достучаться до внутренних сервисов
+func PublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate()
+}
+
+// PublicHost проверяет хост из адреса подписки при регистрации. IP-адрес проверяется сразу,
+// имя - только на localhost: куда оно ведёт, проверяется при каждой доставке, уже после резолва
+func PublicHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return PublicAddr(addr)
+	}
+	return true
+}
+
+// publicClient - HTTP-клиент, который соединяется только с публичными адресами.
+// Адрес проверяется при установке соединения, поэтому имя, которое резолвится во внутреннюю сеть,
+// и редирект туда тоже не пройдут. Прокси не используется: через него проверка бы не работала
+func publicClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !PublicAddr(addrPort.Addr()) {
+				return ErrPrivateTarget
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -0,0 +1,110 @@
+// Package errmap переводит ошибки приложения в коды транспорта.
+// HTTP (gin и REST-шлюз) и gRPC берут коды из одной таблицы, поэтому одна и та же
+// ошибка приложения отдаётся в обоих портах одинаково
+package errmap
+
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+
+	"homework10/internal/app"
+)
+
+// InternalMessage отдаётся клиенту вместо текста ошибок вне каталога приложения
+const InternalMessage = "internal error"
+
+type code struct {
+	http int
+	grpc codes.Code
+}
+
+var kinds = map[app.Kind]code{
+	app.KindInternal:  {http.StatusInternalServerError, codes.Internal},
+	app.KindNotFound:  {http.StatusNotFound, codes.NotFound},
+	app.KindForbidden: {http.StatusForbidden, codes.PermissionDenied},
+	app.KindInvalid:   {http.StatusBadRequest, codes.InvalidArgument},
+	app.KindConflict:  {http.StatusConflict, codes.AlreadyExists},
+}
+
+// exceptions - ошибки, которым не подходит код их категории. Подтверждённый email - не занятый
+// объект, а состояние, в котором действие бессмысленно: для gRPC это FailedPrecondition, а не AlreadyExists
+var exceptions = []struct {
+	err  error
+	code code
+}{
+	{app.EmailAlreadyVerified, code{http.StatusConflict, codes.FailedPrecondition}},
+}
+
+func codeOf(err error) code {
+	for _, e := range exceptions {
+		if errors.Is(err, e.err) {
+			return e.code
+		}
+	}
+	return kinds[app.KindOf(err)]
+}
+
+// HTTPStatus возвращает HTTP-статус для ошибки приложения
+func HTTPStatus(err error) int {
+	return codeOf(err).http
+}
+
+// GRPCCode возвращает gRPC-код для ошибки приложения
+func GRPCCode(err error) codes.Code {
+	return codeOf(err).grpc
+}
+
+// HTTPStatusFromCode возвращает HTTP-статус, соответствующий gRPC-коду из таблицы.
+// ok == false, если код не соответствует ни одной категории ошибок приложения
+func HTTPStatusFromCode(c codes.Code) (status int, ok bool) {
+	for _, e := range exceptions {
+		if e.code.grpc == c {
+			return e.code.http, true
+		}
+	}
+	kind, ok := KindFromCode(c)
+	if !ok {
+		return 0, false
+	}
+	return kinds[kind].http, true
+}
+
+// KindFromCode - обратный поиск по таблице: категория ошибки приложения по gRPC-коду
+func KindFromCode(c codes.Code) (app.Kind, bool) {
+	for kind, k := range kinds {
+		if k.grpc == c {
+			return kind, true
+		}
+	}
+	for _, e := range exceptions {
+		if e.code.grpc == c {
+			return app.KindOf(e.err), true
+		}
+	}
+	return 0, false
+}
+
+// KindFromHTTPStatus - обратный поиск по таблице: категория ошибки приложения по HTTP-статусу
+func KindFromHTTPStatus(status int) (app.Kind, bool) {
+	for kind, k := range kinds {
+		if k.http == status {
+			return kind, true
+		}
+	}
+	return 0, false
+}
+
+// GRPCCodeOfKind возвращает gRPC-код категории ошибки приложения
+func GRPCCodeOfKind(kind app.Kind) codes.Code {
+	return kinds[kind].grpc
+}
+
+// Message возвращает текст ошибки для клиента. Текст внутренних ошибок наружу не отдаётся
+func Message(err error) string {
+	if app.KindOf(err) == app.KindInternal {
+		return InternalMessage
+	}
+	return err.Error()
+}
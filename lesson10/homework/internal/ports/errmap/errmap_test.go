@@ -0,0 +1,93 @@
+package errmap
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+
+	"homework10/internal/app"
+)
+
+// catalogue - коды всех ошибок из app.Catalogue: новая ошибка без строки здесь роняет TestMapping
+var catalogue = map[error]struct {
+	http int
+	grpc codes.Code
+}{
+	app.IncorrectUserId:      {http.StatusForbidden, codes.PermissionDenied},
+	app.ValidateError:        {http.StatusBadRequest, codes.InvalidArgument},
+	app.IncorrectAdId:        {http.StatusNotFound, codes.NotFound},
+	app.IncorrectWebhookId:   {http.StatusNotFound, codes.NotFound},
+	app.UserNotFound:         {http.StatusNotFound, codes.NotFound},
+	app.EmailTaken:           {http.StatusConflict, codes.AlreadyExists},
+	app.NicknameTaken:        {http.StatusConflict, codes.AlreadyExists},
+	app.EmailNotVerified:     {http.StatusForbidden, codes.PermissionDenied},
+	app.EmailAlreadyVerified: {http.StatusConflict, codes.FailedPrecondition},
+	app.InvalidToken:         {http.StatusBadRequest, codes.InvalidArgument},
+	app.WrongPassword:        {http.StatusForbidden, codes.PermissionDenied},
+	app.TooManyImportRows:    {http.StatusBadRequest, codes.InvalidArgument},
+}
+
+func TestMapping(t *testing.T) {
+	type testCase struct {
+		name     string
+		err      error
+		http     int
+		grpc     codes.Code
+		internal bool
+	}
+	var tests []testCase
+	for _, err := range app.Catalogue {
+		want, ok := catalogue[err]
+		assert.True(t, ok, "no expected codes for %q", err)
+		tests = append(tests, testCase{name: err.Error(), err: err, http: want.http, grpc: want.grpc})
+	}
+	tests = append(tests, []testCase{
+		{name: "field violations", err: &app.InvalidFieldsError{Violations: []app.FieldViolation{{Field: "title", Description: "is empty"}}}, http: http.StatusBadRequest, grpc: codes.InvalidArgument},
+		{name: "conflict", err: &app.Error{Kind: app.KindConflict, Message: "email is taken"}, http: http.StatusConflict, grpc: codes.AlreadyExists},
+		{name: "wrapped", err: fmt.Errorf("update ad 7: %w", app.IncorrectAdId), http: http.StatusNotFound, grpc: codes.NotFound},
+		{name: "wrapped exception", err: fmt.Errorf("user 7: %w", app.EmailAlreadyVerified), http: http.StatusConflict, grpc: codes.FailedPrecondition},
+		{name: "unknown", err: errors.New("disk is on fire"), http: http.StatusInternalServerError, grpc: codes.Internal, internal: true},
+	}...)
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.http, HTTPStatus(tc.err))
+			assert.Equal(t, tc.grpc, GRPCCode(tc.err))
+
+			// REST-шлюз получает от gRPC-сервиса только код: по нему он должен прийти к тому же статусу
+			status, ok := HTTPStatusFromCode(GRPCCode(tc.err))
+			assert.True(t, ok)
+			assert.Equal(t, HTTPStatus(tc.err), status)
+
+			// клиенты восстанавливают категорию ошибки по коду любого из транспортов
+			kind, ok := KindFromHTTPStatus(HTTPStatus(tc.err))
+			assert.True(t, ok)
+			assert.Equal(t, app.KindOf(tc.err), kind)
+			kind, ok = KindFromCode(GRPCCode(tc.err))
+			assert.True(t, ok)
+			assert.Equal(t, app.KindOf(tc.err), kind)
+
+			if tc.internal {
+				assert.Equal(t, InternalMessage, Message(tc.err))
+			} else {
+				assert.Equal(t, tc.err.Error(), Message(tc.err))
+			}
+		})
+	}
+}
+
+func TestEveryKindIsMapped(t *testing.T) {
+	for k := app.KindInternal; k <= app.KindConflict; k++ {
+		_, ok := kinds[k]
+		assert.True(t, ok, k.String())
+	}
+}
+
+func TestHTTPStatusFromUnknownCode(t *testing.T) {
+	_, ok := HTTPStatusFromCode(codes.Unavailable)
+	assert.False(t, ok)
+}
@@ -0,0 +1,73 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/encoding/protojson"
+	protobuf "google.golang.org/protobuf/proto"
+
+	"homework10/internal/app"
+	grpcPort "homework10/internal/ports/grpc"
+	"homework10/internal/ports/grpc/proto"
+	"homework10/internal/ports/httpgin"
+)
+
+// NewHandler отдаёт REST API под /api/v1, собранный grpc-gateway по аннотациям google.api.http
+// в service.proto. Запросы уходят прямо в реализацию AdService, без сетевого вызова и без middleware,
+// поэтому наружу он отдаётся только через NewHTTPServer.
+// Ответы и ошибки завёрнуты так же, как у gin: {"data": ..., "error": ...}
+func NewHandler(a app.App) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &envelope{JSONPb: runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{EmitUnpopulated: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		}}),
+		runtime.WithErrorHandler(errorHandler),
+		runtime.SetQueryParameterParser(queryParser{}),
+	)
+	if err := proto.RegisterAdServiceHandlerServer(context.Background(), mux, grpcPort.NewService(a)); err != nil {
+		return nil, err
+	}
+	return mux, nil
+}
+
+// Routes перечисляет маршруты шлюза по аннотациям google.api.http в service.proto
+func Routes() []httpgin.GatewayRoute {
+	var routes []httpgin.GatewayRoute
+	methods := proto.File_service_proto.Services().ByName("AdService").Methods()
+	for idx := 0; idx < methods.Len(); idx++ {
+		rule, ok := protobuf.GetExtension(methods.Get(idx).Options(), annotations.E_Http).(*annotations.HttpRule)
+		if !ok || rule == nil {
+			continue
+		}
+		for _, binding := range append([]*annotations.HttpRule{rule}, rule.GetAdditionalBindings()...) {
+			switch pattern := binding.GetPattern().(type) {
+			case *annotations.HttpRule_Get:
+				routes = append(routes, httpgin.GatewayRoute{Method: http.MethodGet, Path: pattern.Get})
+			case *annotations.HttpRule_Post:
+				routes = append(routes, httpgin.GatewayRoute{Method: http.MethodPost, Path: pattern.Post})
+			case *annotations.HttpRule_Put:
+				routes = append(routes, httpgin.GatewayRoute{Method: http.MethodPut, Path: pattern.Put})
+			case *annotations.HttpRule_Delete:
+				routes = append(routes, httpgin.GatewayRoute{Method: http.MethodDelete, Path: pattern.Delete})
+			case *annotations.HttpRule_Patch:
+				routes = append(routes, httpgin.GatewayRoute{Method: http.MethodPatch, Path: pattern.Patch})
+			}
+		}
+	}
+	return routes
+}
+
+// NewHTTPServer - отдельный HTTP-сервер для шлюза. Он собирается так же, как сервер gin, с теми же опциями:
+// запросы проходят те же middleware (лимиты, Idempotency-Key, токен служебных маршрутов, журнал, метрики,
+// трассировку) и только затем попадают в шлюз. Реестр метрик шлюзу нужен свой
+func NewHTTPServer(port string, a app.App, opts ...httpgin.Option) (*http.Server, error) {
+	handler, err := NewHandler(a)
+	if err != nil {
+		return nil, err
+	}
+	return httpgin.NewHTTPServer(port, a, append(opts, httpgin.WithGateway(handler, Routes()))...), nil
+}
@@ -0,0 +1,178 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/utilities"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"homework10/internal/ports/errmap"
+	"homework10/internal/ports/problem"
+)
+
+// response повторяет обёртку ответов gin
+type response struct {
+	Data  json.RawMessage `json:"data"`
+	Error *string         `json:"error"`
+}
+
+// envelope кладёт ответ метода в поле data. Пустой ответ (удаление) превращается в "success"
+type envelope struct {
+	runtime.JSONPb
+}
+
+func (e *envelope) Marshal(v interface{}) ([]byte, error) {
+	if _, ok := v.(*emptypb.Empty); ok {
+		return json.Marshal(response{Data: json.RawMessage(`"success"`)})
+	}
+
+	data, err := e.JSONPb.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if desc := descriptorOf(v); desc != nil {
+		var tree any
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&tree); err != nil {
+			return nil, err
+		}
+		if data, err = json.Marshal(int64AsNumbers(desc, tree)); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(response{Data: data})
+}
+
+// descriptorOf находит описание сообщения для ответа метода или для списка из response_body
+func descriptorOf(v interface{}) protoreflect.MessageDescriptor {
+	if msg, ok := v.(protobuf.Message); ok {
+		return msg.ProtoReflect().Descriptor()
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice {
+		return nil
+	}
+	if msg, ok := reflect.Zero(rv.Type().Elem()).Interface().(protobuf.Message); ok {
+		return msg.ProtoReflect().Descriptor()
+	}
+	return nil
+}
+
+// int64AsNumbers возвращает 64-битным целым вид чисел: protojson пишет их строками,
+// а клиенты REST API (и ответы gin) ждут "id": 1, а не "id": "1"
+func int64AsNumbers(desc protoreflect.MessageDescriptor, tree any) any {
+	if list, ok := tree.([]any); ok {
+		for idx := range list {
+			list[idx] = int64AsNumbers(desc, list[idx])
+		}
+		return list
+	}
+	obj, ok := tree.(map[string]any)
+	if !ok {
+		return tree
+	}
+
+	fields := desc.Fields()
+	for idx := 0; idx < fields.Len(); idx++ {
+		field := fields.Get(idx)
+		value, ok := obj[field.JSONName()]
+		if !ok || field.IsMap() {
+			continue
+		}
+		if list, ok := value.([]any); ok && field.IsList() {
+			for idx := range list {
+				list[idx] = fieldAsNumber(field, list[idx])
+			}
+			continue
+		}
+		obj[field.JSONName()] = fieldAsNumber(field, value)
+	}
+	return obj
+}
+
+func fieldAsNumber(field protoreflect.FieldDescriptor, value any) any {
+	switch field.Kind() {
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if s, ok := value.(string); ok {
+			return json.Number(s)
+		}
+	case protoreflect.MessageKind:
+		return int64AsNumbers(field.Message(), value)
+	}
+	return value
+}
+
+// errorHandler отвечает кодом HTTP, соответствующим коду gRPC, и текстом ошибки в поле error.
+// Ошибки валидации с errdetails.BadRequest отдаются как problem+json, так же как в gin
+func errorHandler(_ context.Context, _ *runtime.ServeMux, _ runtime.Marshaler, w http.ResponseWriter, _ *http.Request, err error) {
+	code := http.StatusInternalServerError
+	var httpErr *runtime.HTTPStatusError
+	if errors.As(err, &httpErr) {
+		code = httpErr.HTTPStatus
+		err = httpErr.Err
+	}
+	s := status.Convert(err)
+	if httpErr == nil {
+		// коды ошибок приложения переводятся по той же таблице, что и в gin; остальные (например,
+		// ошибки разбора запроса самим шлюзом) - по умолчанию grpc-gateway
+		var ok bool
+		if code, ok = errmap.HTTPStatusFromCode(s.Code()); !ok {
+			code = runtime.HTTPStatusFromCode(s.Code())
+		}
+	}
+
+	for _, detail := range s.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			params := make([]problem.InvalidParam, 0, len(badRequest.GetFieldViolations()))
+			for _, v := range badRequest.GetFieldViolations() {
+				params = append(params, problem.InvalidParam{Name: v.GetField(), Reason: v.GetDescription()})
+			}
+			problem.Write(w, problem.Validation(s.Message(), params))
+			return
+		}
+	}
+
+	message := s.Message()
+	body, _ := json.Marshal(response{Data: json.RawMessage("null"), Error: &message})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(body)
+}
+
+// dateLayouts - форматы date_creating, которые принимает gin: значение time.Time, выведенное через %v,
+// и просто дата. Фильтр всё равно сравнивает только день
+var dateLayouts = []string{
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	"2006-01-02",
+}
+
+// queryParser приводит date_creating к RFC 3339, которого ждёт google.protobuf.Timestamp
+type queryParser struct {
+	runtime.DefaultQueryParser
+}
+
+func (p queryParser) Parse(msg protobuf.Message, values url.Values, filter *utilities.DoubleArray) error {
+	if raw := values.Get("date_creating"); raw != "" {
+		for _, layout := range dateLayouts {
+			if date, err := time.Parse(layout, raw); err == nil {
+				values.Set("date_creating", date.Format(time.RFC3339Nano))
+				break
+			}
+		}
+	}
+	return p.DefaultQueryParser.Parse(msg, values, filter)
+}
@@ -0,0 +1,40 @@
+package grpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"homework10/internal/ports/grpc/proto"
+)
+
+var ErrUnauthenticated = status.New(codes.Unauthenticated, "admin token is missing or wrong")
+
+// adminMethods - служебные методы: поиск по email раскрывает, зарегистрирован ли адрес,
+// поэтому он доступен только внутренним вызовам с токеном администратора
+var adminMethods = map[string]bool{
+	proto.AdService_GetUserByEmail_FullMethodName: true,
+}
+
+// AdminInterceptor пускает к служебным методам только вызовы с метаданными authorization: Bearer <token>.
+// Пустой token закрывает служебные методы целиком
+func AdminInterceptor(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !adminMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		for _, value := range md.Get("authorization") {
+			got, ok := strings.CutPrefix(value, "Bearer ")
+			if ok && token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1 {
+				return handler(ctx, req)
+			}
+		}
+		return nil, ErrUnauthenticated.Err()
+	}
+}
@@ -4,65 +4,90 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"homework10/internal/app"
+	"homework10/internal/outbox"
+	"homework10/internal/ports/errmap"
 	"homework10/internal/ports/grpc/proto"
+	"homework10/internal/users"
 )
 
 var ErrValidate = status.New(codes.InvalidArgument, "validation error")
 var ErrIncorrectUserId = status.New(codes.PermissionDenied, "incorrect user id")
 var ErrIncorrectAdId = status.New(codes.NotFound, "id is not found")
 var OkStatus = status.New(codes.OK, "success")
+var ErrWatchDisabled = status.New(codes.Unimplemented, "ad events are not enabled on this server")
+var ErrWatchTooSlow = status.New(codes.ResourceExhausted, "watcher is too slow, resubscribe")
+
+// validationError дополняет ErrValidate списком неверных полей (errdetails.BadRequest)
+func validationError(err error) error {
+	var fields *app.InvalidFieldsError
+	if !errors.As(err, &fields) {
+		return ErrValidate.Err()
+	}
 
-type AdService struct {
-	a app.App
+	details := &errdetails.BadRequest{}
+	for _, v := range fields.Violations {
+		details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Description,
+		})
+	}
+	st, detailsErr := ErrValidate.WithDetails(details)
+	if detailsErr != nil {
+		return ErrValidate.Err()
+	}
+	return st.Err()
 }
 
-func NewService(a app.App) *AdService {
-	return &AdService{a}
+// appError переводит ошибку приложения в gRPC-статус по общей с HTTP таблице errmap
+func appError(err error) error {
+	if errors.Is(err, app.ValidateError) {
+		return validationError(err)
+	}
+	return status.Error(errmap.GRPCCode(err), errmap.Message(err))
 }
 
-func (service *AdService) CreateAd(_ context.Context, req *proto.CreateAdRequest) (*proto.AdResponse, error) {
-	ad, ok := service.a.CreateAd(req.GetTitle(), req.GetText(), req.GetUserId())
+type AdService struct {
+	a      app.App
+	events *outbox.Broker
+}
 
-	if errors.Is(ok, app.ValidateError) {
-		return nil, ErrValidate.Err()
-	}
+func NewService(a app.App) *AdService {
+	return &AdService{a: a}
+}
 
-	if errors.Is(ok, app.IncorrectUserId) {
-		return nil, ErrIncorrectUserId.Err()
+func (service *AdService) CreateAd(ctx context.Context, req *proto.CreateAdRequest) (*proto.AdResponse, error) {
+	ad, ok := service.a.CreateAd(ctx, req.GetTitle(), req.GetText(), req.GetUserId())
+	if ok != nil {
+		return nil, appError(ok)
 	}
 
 	return AdSuccessResponse(ad), OkStatus.Err()
 }
 
-func (service *AdService) ChangeAdStatus(_ context.Context, req *proto.ChangeAdStatusRequest) (*proto.AdResponse, error) {
-	ad, ok := service.a.ChangeAdStatus(req.GetAdId(), req.GetUserId(), req.GetPublished())
-
-	if errors.Is(ok, app.IncorrectUserId) {
-		return nil, ErrIncorrectUserId.Err()
+func (service *AdService) ChangeAdStatus(ctx context.Context, req *proto.ChangeAdStatusRequest) (*proto.AdResponse, error) {
+	ad, ok := service.a.ChangeAdStatus(ctx, req.GetAdId(), req.GetUserId(), req.GetPublished())
+	if ok != nil {
+		return nil, appError(ok)
 	}
 
 	return AdSuccessResponse(ad), OkStatus.Err()
 }
 
-func (service *AdService) UpdateAd(_ context.Context, req *proto.UpdateAdRequest) (*proto.AdResponse, error) {
-	ad, ok := service.a.UpdateAd(req.GetAdId(), req.GetUserId(), req.GetTitle(), req.GetText())
-
-	if errors.Is(ok, app.ValidateError) {
-		return nil, ErrValidate.Err()
-	}
-
-	if errors.Is(ok, app.IncorrectUserId) {
-		return nil, ErrIncorrectUserId.Err()
+func (service *AdService) UpdateAd(ctx context.Context, req *proto.UpdateAdRequest) (*proto.AdResponse, error) {
+	ad, ok := service.a.UpdateAd(ctx, req.GetAdId(), req.GetUserId(), req.GetTitle(), req.GetText())
+	if ok != nil {
+		return nil, appError(ok)
 	}
 
 	return AdSuccessResponse(ad), OkStatus.Err()
 }
 
-func (service *AdService) ListAdsWithFilter(_ context.Context, req *proto.GetListAdsWithFilterRequest) (*proto.ListAdResponse, error) {
+func (service *AdService) ListAdsWithFilter(ctx context.Context, req *proto.GetListAdsWithFilterRequest) (*proto.ListAdResponse, error) {
 	filters := make(map[string]any)
 
 	if req.UserId != nil {
@@ -77,79 +102,206 @@ func (service *AdService) ListAdsWithFilter(_ context.Context, req *proto.GetLis
 		filters["published"] = *req.Published
 	}
 
-	list := service.a.GetListAds(filters)
+	list := service.a.GetListAds(ctx, filters)
 	return AdsSuccessResponse(list), OkStatus.Err()
 }
 
-func (service *AdService) ListAdsByTitle(_ context.Context, req *proto.GetListAdsByTitleRequest) (*proto.ListAdResponse, error) {
-	list := service.a.GetListAdsByTitle(req.GetTitle())
+func (service *AdService) ListAdsByTitle(ctx context.Context, req *proto.GetListAdsByTitleRequest) (*proto.ListAdResponse, error) {
+	list := service.a.GetListAdsByTitle(ctx, req.GetTitle())
 	return AdsSuccessResponse(list), OkStatus.Err()
 }
 
-func (service *AdService) CreateUser(_ context.Context, req *proto.CreateUserRequest) (*proto.UserResponse, error) {
-	user, ok := service.a.CreateUser(req.GetNickname(), req.GetEmail())
+func (service *AdService) CreateUser(ctx context.Context, req *proto.CreateUserRequest) (*proto.UserResponse, error) {
+	user, ok := service.a.CreateUser(ctx, req.GetNickname(), req.GetEmail())
+	if ok != nil {
+		return nil, appError(ok)
+	}
 
-	if errors.Is(ok, app.ValidateError) {
-		return nil, ErrValidate.Err()
+	return UserSuccessResponse(user), OkStatus.Err()
+}
+
+func (service *AdService) UpdateUser(ctx context.Context, req *proto.UpdateUserRequest) (*proto.UserResponse, error) {
+	user, ok := service.a.UpdateUser(ctx, req.GetUserId(), req.GetNickname(), req.GetEmail(), req.GetPassword())
+	if ok != nil {
+		return nil, appError(ok)
 	}
 
 	return UserSuccessResponse(user), OkStatus.Err()
 }
 
-func (service *AdService) UpdateUser(_ context.Context, req *proto.UpdateUserRequest) (*proto.UserResponse, error) {
-	user, ok := service.a.UpdateUser(req.GetUserId(), req.GetNickname(), req.GetEmail())
+func (service *AdService) GetUser(ctx context.Context, req *proto.GetUserRequest) (*proto.UserResponse, error) {
+	user, ok := service.a.GetUser(ctx, req.GetId())
+	if ok != nil {
+		return nil, appError(ok)
+	}
+
+	return PublicUserResponse(user), OkStatus.Err()
+}
 
-	if errors.Is(ok, app.IncorrectUserId) {
-		return nil, ErrIncorrectUserId.Err()
+func (service *AdService) GetUserByEmail(ctx context.Context, req *proto.GetUserByEmailRequest) (*proto.UserResponse, error) {
+	user, err := service.a.GetUserByEmail(ctx, req.GetEmail())
+	if err != nil {
+		return nil, appError(err)
 	}
 
-	if errors.Is(ok, app.ValidateError) {
-		return nil, ErrValidate.Err()
+	return PublicUserResponse(user), OkStatus.Err()
+}
+
+func (service *AdService) UpdateProfile(ctx context.Context, req *proto.UpdateProfileRequest) (*proto.UserResponse, error) {
+	user, err := service.a.UpdateProfile(ctx, req.GetUserId(), req.GetPassword(), users.Profile{
+		DisplayName: req.GetDisplayName(),
+		Phone:       req.GetPhone(),
+		City:        req.GetCity(),
+		AvatarURL:   req.GetAvatarUrl(),
+	})
+	if err != nil {
+		return nil, appError(err)
 	}
 
-	return UserSuccessResponse(user), OkStatus.Err()
+	return ProfileResponse(user), OkStatus.Err()
 }
 
-func (service *AdService) GetUser(_ context.Context, req *proto.GetUserRequest) (*proto.UserResponse, error) {
-	user, ok := service.a.GetUser(req.GetId())
+func (service *AdService) ListUserAds(ctx context.Context, req *proto.ListUserAdsRequest) (*proto.ListAdResponse, error) {
+	list, err := service.a.GetUserAds(ctx, req.GetUserId())
+	if err != nil {
+		return nil, appError(err)
+	}
+
+	return AdsSuccessResponse(list), OkStatus.Err()
+}
 
-	if errors.Is(ok, app.IncorrectUserId) {
-		return nil, ErrIncorrectUserId.Err()
+func (service *AdService) RequestEmailVerification(ctx context.Context, req *proto.RequestEmailVerificationRequest) (*emptypb.Empty, error) {
+	err := service.a.RequestEmailVerification(ctx, req.GetUserId())
+	if err != nil {
+		return nil, appError(err)
 	}
 
-	return UserSuccessResponse(user), OkStatus.Err()
+	return new(emptypb.Empty), OkStatus.Err()
 }
 
-func (service *AdService) DeleteUser(_ context.Context, req *proto.DeleteUserRequest) (*emptypb.Empty, error) {
-	ok := service.a.DeleteUser(req.GetId())
+func (service *AdService) ConfirmEmail(ctx context.Context, req *proto.ConfirmEmailRequest) (*proto.UserResponse, error) {
+	user, err := service.a.ConfirmEmail(ctx, req.GetToken())
+	if err != nil {
+		return nil, appError(err)
+	}
 
-	if errors.Is(ok, app.IncorrectUserId) {
-		return nil, ErrIncorrectUserId.Err()
+	return UserSuccessResponse(user), OkStatus.Err()
+}
+
+func (service *AdService) RequestPasswordReset(ctx context.Context, req *proto.RequestPasswordResetRequest) (*emptypb.Empty, error) {
+	err := service.a.RequestPasswordReset(ctx, req.GetEmail())
+	if err != nil {
+		return nil, appError(err)
 	}
 
 	return new(emptypb.Empty), OkStatus.Err()
 }
 
-func (service *AdService) DeleteAd(_ context.Context, req *proto.DeleteAdRequest) (*emptypb.Empty, error) {
-	ok := service.a.DeleteAd(req.GetAdId(), req.GetUserId())
-
-	if errors.Is(ok, app.IncorrectUserId) {
-		return nil, ErrIncorrectUserId.Err()
+func (service *AdService) ResetPassword(ctx context.Context, req *proto.ResetPasswordRequest) (*emptypb.Empty, error) {
+	err := service.a.ResetPassword(ctx, req.GetToken(), req.GetPassword())
+	if err != nil {
+		return nil, appError(err)
 	}
 
-	if errors.Is(ok, app.IncorrectAdId) {
-		return nil, ErrIncorrectAdId.Err()
+	return new(emptypb.Empty), OkStatus.Err()
+}
+
+func (service *AdService) DeleteUser(ctx context.Context, req *proto.DeleteUserRequest) (*emptypb.Empty, error) {
+	ok := service.a.DeleteUser(ctx, req.GetId())
+	if ok != nil {
+		return nil, appError(ok)
 	}
 
 	return new(emptypb.Empty), OkStatus.Err()
 }
 
-func (service *AdService) GetAd(_ context.Context, req *proto.GetAdRequest) (*proto.AdResponse, error) {
-	ad, ok := service.a.GetAd(req.GetAdId())
+func (service *AdService) DeleteAd(ctx context.Context, req *proto.DeleteAdRequest) (*emptypb.Empty, error) {
+	ok := service.a.DeleteAd(ctx, req.GetAdId(), req.GetUserId())
+	if ok != nil {
+		return nil, appError(ok)
+	}
+
+	return new(emptypb.Empty), OkStatus.Err()
+}
 
-	if errors.Is(ok, app.IncorrectAdId) {
-		return nil, ErrIncorrectAdId.Err()
+func (service *AdService) GetAd(ctx context.Context, req *proto.GetAdRequest) (*proto.AdResponse, error) {
+	ad, ok := service.a.GetAd(ctx, req.GetAdId())
+	if ok != nil {
+		return nil, appError(ok)
 	}
 
 	return AdSuccessResponse(ad), OkStatus.Err()
 }
+
+// WatchAds пересылает события объявлений, пока клиент не отпишется. Если клиент отстаёт
+// больше чем на буфер брокера, поток завершается с ErrWatchTooSlow
+func (service *AdService) WatchAds(req *proto.WatchAdsRequest, stream proto.AdService_WatchAdsServer) error {
+	if service.events == nil {
+		return ErrWatchDisabled.Err()
+	}
+
+	ctx := stream.Context()
+	for event := range service.events.Subscribe(ctx) {
+		if req.UserId != nil && event.Ad.AuthorID != req.GetUserId() {
+			continue
+		}
+		if err := stream.Send(AdEventResponse(event)); err != nil {
+			return err
+		}
+	}
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	return ErrWatchTooSlow.Err()
+}
+
+// ImportAds создаёт объявления из потока и отвечает отчётом, когда клиент закроет поток.
+// Поток длиннее app.MaxImportRows обрывается с InvalidArgument
+func (service *AdService) ImportAds(stream proto.AdService_ImportAdsServer) error {
+	row := 0
+	report, err := service.a.ImportAds(stream.Context(), func() (app.ImportRow, error) {
+		req, err := stream.Recv()
+		if err != nil {
+			return app.ImportRow{}, err
+		}
+		row++
+		return app.ImportRow{Line: row, Title: req.GetTitle(), Text: req.GetText(), UserID: req.GetUserId()}, nil
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		if app.KindOf(err) != app.KindInternal {
+			return appError(err)
+		}
+		return status.FromContextError(err).Err()
+	}
+	return stream.SendAndClose(ImportReportResponse(report))
+}
+
+func (service *AdService) BatchGetAds(ctx context.Context, req *proto.BatchGetRequest) (*proto.BatchGetAdsResponse, error) {
+	found, missing, err := service.a.BatchGetAds(ctx, req.GetIds())
+	if err != nil {
+		return nil, appError(err)
+	}
+
+	return BatchGetAdsResponse(found, missing), OkStatus.Err()
+}
+
+func (service *AdService) BatchGetUsers(ctx context.Context, req *proto.BatchGetRequest) (*proto.BatchGetUsersResponse, error) {
+	found, missing, err := service.a.BatchGetUsers(ctx, req.GetIds())
+	if err != nil {
+		return nil, appError(err)
+	}
+
+	return BatchGetUsersResponse(found, missing), OkStatus.Err()
+}
+
+func (service *AdService) BatchChangeAdStatus(ctx context.Context, req *proto.BatchChangeAdStatusRequest) (*proto.ListAdResponse, error) {
+	list, err := service.a.BatchChangeAdStatus(ctx, req.GetAdIds(), req.GetUserId(), req.GetPublished())
+	if err != nil {
+		return nil, appError(err)
+	}
+
+	return AdsSuccessResponse(list), OkStatus.Err()
+}
@@ -2,11 +2,18 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 	"homework10/internal/ads"
 	"homework10/internal/app"
+	"homework10/internal/ports/errmap"
 	"homework10/internal/ports/grpc/proto"
 	"homework10/internal/ports/httpgin/mocks"
 	"homework10/internal/users"
@@ -29,7 +36,7 @@ func TestRepoRun(t *testing.T) {
 func (s *AdServiceTestSuite) TestAdService_CreateAd() {
 	request := &proto.CreateAdRequest{Title: "title 1", Text: "text 1", UserId: 1}
 	expect := &ads.Ad{ID: 1, Title: "title 1", Text: "text 1", AuthorID: 1}
-	s.app.On("CreateAd", request.Title, request.Text, request.UserId).Return(expect, nil)
+	s.app.On("CreateAd", mock.Anything, request.Title, request.Text, request.UserId).Return(expect, nil)
 
 	service := NewService(&s.app)
 	response, err := service.CreateAd(context.TODO(), request)
@@ -40,17 +47,34 @@ func (s *AdServiceTestSuite) TestAdService_CreateAd() {
 func (s *AdServiceTestSuite) TestAdService_CreateAdValidationErr() {
 	request := &proto.CreateAdRequest{Title: "", Text: "text 1", UserId: 1}
 	expect := &ads.Ad{ID: 1, Title: "", Text: "text 1", AuthorID: 1}
-	s.app.On("CreateAd", request.Title, request.Text, request.UserId).Return(expect, app.ValidateError)
+	s.app.On("CreateAd", mock.Anything, request.Title, request.Text, request.UserId).Return(expect, app.ValidateError)
 
 	service := NewService(&s.app)
 	_, err := service.CreateAd(context.TODO(), request)
 	s.ErrorIs(err, ErrValidate.Err())
 }
 
+func (s *AdServiceTestSuite) TestAdService_CreateAdFieldViolations() {
+	request := &proto.CreateAdRequest{Title: "", Text: "text 1", UserId: 1}
+	violations := &app.InvalidFieldsError{Violations: []app.FieldViolation{{Field: "title", Description: "should have length at least 1"}}}
+	s.app.On("CreateAd", mock.Anything, request.Title, request.Text, request.UserId).Return(nil, violations)
+
+	service := NewService(&s.app)
+	_, err := service.CreateAd(context.TODO(), request)
+	st := status.Convert(err)
+	s.Equal(codes.InvalidArgument, st.Code())
+	s.Require().Len(st.Details(), 1)
+	badRequest, ok := st.Details()[0].(*errdetails.BadRequest)
+	s.Require().True(ok)
+	s.Require().Len(badRequest.GetFieldViolations(), 1)
+	s.Equal("title", badRequest.GetFieldViolations()[0].GetField())
+	s.Equal("should have length at least 1", badRequest.GetFieldViolations()[0].GetDescription())
+}
+
 func (s *AdServiceTestSuite) TestAdService_CreateAdIncorrectUserId() {
 	request := &proto.CreateAdRequest{Title: "title", Text: "text 1", UserId: 3}
 	expect := &ads.Ad{ID: 1, Title: "title", Text: "text 1", AuthorID: 3}
-	s.app.On("CreateAd", request.Title, request.Text, request.UserId).Return(expect, app.IncorrectUserId)
+	s.app.On("CreateAd", mock.Anything, request.Title, request.Text, request.UserId).Return(expect, app.IncorrectUserId)
 
 	service := NewService(&s.app)
 	_, err := service.CreateAd(context.TODO(), request)
@@ -60,7 +84,7 @@ func (s *AdServiceTestSuite) TestAdService_CreateAdIncorrectUserId() {
 func (s *AdServiceTestSuite) TestAdService_ChangeAdStatus() {
 	request := &proto.ChangeAdStatusRequest{AdId: 1, Published: true, UserId: 1}
 	expect := &ads.Ad{ID: 1, Title: "ad 1", Text: "text 1", AuthorID: 1, Published: true}
-	s.app.On("ChangeAdStatus", request.AdId, request.UserId, request.Published).Return(expect, nil)
+	s.app.On("ChangeAdStatus", mock.Anything, request.AdId, request.UserId, request.Published).Return(expect, nil)
 
 	service := NewService(&s.app)
 	response, err := service.ChangeAdStatus(context.TODO(), request)
@@ -71,7 +95,7 @@ func (s *AdServiceTestSuite) TestAdService_ChangeAdStatus() {
 func (s *AdServiceTestSuite) TestAdService_ChangeAdStatusIncorrectUserId() {
 	request := &proto.ChangeAdStatusRequest{AdId: 1, Published: true, UserId: 10}
 	expect := &ads.Ad{ID: 1, Title: "ad 1", Text: "text 1", AuthorID: 10, Published: true}
-	s.app.On("ChangeAdStatus", request.AdId, request.UserId, request.Published).Return(expect, app.IncorrectUserId)
+	s.app.On("ChangeAdStatus", mock.Anything, request.AdId, request.UserId, request.Published).Return(expect, app.IncorrectUserId)
 
 	service := NewService(&s.app)
 	_, err := service.ChangeAdStatus(context.TODO(), request)
@@ -81,7 +105,7 @@ func (s *AdServiceTestSuite) TestAdService_ChangeAdStatusIncorrectUserId() {
 func (s *AdServiceTestSuite) TestAdService_UpdateAd() {
 	request := &proto.UpdateAdRequest{AdId: 1, Title: "updated ad", UserId: 1, Text: "updated text"}
 	expect := &ads.Ad{ID: 1, Title: "updated ad", Text: "updated text", AuthorID: 1, Published: false}
-	s.app.On("UpdateAd", request.AdId, request.UserId, request.Title, request.Text).Return(expect, nil)
+	s.app.On("UpdateAd", mock.Anything, request.AdId, request.UserId, request.Title, request.Text).Return(expect, nil)
 
 	service := NewService(&s.app)
 	response, err := service.UpdateAd(context.TODO(), request)
@@ -92,7 +116,7 @@ func (s *AdServiceTestSuite) TestAdService_UpdateAd() {
 func (s *AdServiceTestSuite) TestAdService_UpdateAdValidationErr() {
 	request := &proto.UpdateAdRequest{AdId: 1, Title: "", UserId: 1, Text: "updated text"}
 	expect := &ads.Ad{ID: 1, Title: "updated ad", Text: "", AuthorID: 1, Published: false}
-	s.app.On("UpdateAd", request.AdId, request.UserId, request.Title, request.Text).Return(expect, app.ValidateError)
+	s.app.On("UpdateAd", mock.Anything, request.AdId, request.UserId, request.Title, request.Text).Return(expect, app.ValidateError)
 
 	service := NewService(&s.app)
 	_, err := service.UpdateAd(context.TODO(), request)
@@ -102,7 +126,7 @@ func (s *AdServiceTestSuite) TestAdService_UpdateAdValidationErr() {
 func (s *AdServiceTestSuite) TestAdService_UpdateAdIncorrectUserId() {
 	request := &proto.UpdateAdRequest{AdId: 1, Title: "", UserId: 1, Text: "updated text"}
 	expect := &ads.Ad{ID: 1, Title: "updated ad", Text: "", AuthorID: 1, Published: false}
-	s.app.On("UpdateAd", request.AdId, request.UserId, request.Title, request.Text).Return(expect, app.IncorrectUserId)
+	s.app.On("UpdateAd", mock.Anything, request.AdId, request.UserId, request.Title, request.Text).Return(expect, app.IncorrectUserId)
 
 	service := NewService(&s.app)
 	_, err := service.UpdateAd(context.TODO(), request)
@@ -120,7 +144,7 @@ func (s *AdServiceTestSuite) TestAdService_ListAdsWithFilter() {
 	adsList := []ads.Ad{expect1, expect2}
 
 	filters := map[string]any{"user_id": *request.UserId, "published": *request.Published, "date_creating": fmt.Sprint((*request.DateCreating).AsTime().UTC())}
-	s.app.On("GetListAds", filters).Return(adsList, nil)
+	s.app.On("GetListAds", mock.Anything, filters).Return(adsList, nil)
 
 	service := NewService(&s.app)
 	response, err := service.ListAdsWithFilter(context.TODO(), request)
@@ -136,7 +160,7 @@ func (s *AdServiceTestSuite) TestAdService_ListAdsByTitle() {
 	title := expect1.Title
 	request := &proto.GetListAdsByTitleRequest{Title: title}
 
-	s.app.On("GetListAdsByTitle", title).Return(adsList, nil)
+	s.app.On("GetListAdsByTitle", mock.Anything, title).Return(adsList, nil)
 
 	service := NewService(&s.app)
 	response, err := service.ListAdsByTitle(context.TODO(), request)
@@ -148,7 +172,7 @@ func (s *AdServiceTestSuite) TestAdService_CreateUser() {
 	expect := &users.User{ID: 1, Nickname: "nickname", Email: "email"}
 	request := &proto.CreateUserRequest{Nickname: expect.Nickname, Email: expect.Email}
 
-	s.app.On("CreateUser", request.Nickname, request.Email).Return(expect, nil)
+	s.app.On("CreateUser", mock.Anything, request.Nickname, request.Email).Return(expect, nil)
 
 	service := NewService(&s.app)
 	response, err := service.CreateUser(context.TODO(), request)
@@ -160,7 +184,7 @@ func (s *AdServiceTestSuite) TestAdService_CreateUserValidationErr() {
 	expect := &users.User{ID: 1, Nickname: "", Email: "email"}
 	request := &proto.CreateUserRequest{Nickname: expect.Nickname, Email: expect.Email}
 
-	s.app.On("CreateUser", request.Nickname, request.Email).Return(expect, app.ValidateError)
+	s.app.On("CreateUser", mock.Anything, request.Nickname, request.Email).Return(expect, app.ValidateError)
 
 	service := NewService(&s.app)
 	_, err := service.CreateUser(context.TODO(), request)
@@ -169,9 +193,9 @@ func (s *AdServiceTestSuite) TestAdService_CreateUserValidationErr() {
 
 func (s *AdServiceTestSuite) TestAdService_UpdateUser() {
 	expect := &users.User{ID: 1, Nickname: "nickname", Email: "email"}
-	request := &proto.UpdateUserRequest{UserId: expect.ID, Nickname: expect.Nickname, Email: expect.Email}
+	request := &proto.UpdateUserRequest{UserId: expect.ID, Nickname: expect.Nickname, Email: expect.Email, Password: "password"}
 
-	s.app.On("UpdateUser", request.UserId, request.Nickname, request.Email).Return(expect, nil)
+	s.app.On("UpdateUser", mock.Anything, request.UserId, request.Nickname, request.Email, request.Password).Return(expect, nil)
 
 	service := NewService(&s.app)
 	response, err := service.UpdateUser(context.TODO(), request)
@@ -181,9 +205,9 @@ func (s *AdServiceTestSuite) TestAdService_UpdateUser() {
 
 func (s *AdServiceTestSuite) TestAdService_UpdateUserIncorrectUserId() {
 	expect := &users.User{ID: 10, Nickname: "nickname", Email: "email"}
-	request := &proto.UpdateUserRequest{UserId: expect.ID, Nickname: expect.Nickname, Email: expect.Email}
+	request := &proto.UpdateUserRequest{UserId: expect.ID, Nickname: expect.Nickname, Email: expect.Email, Password: "password"}
 
-	s.app.On("UpdateUser", request.UserId, request.Nickname, request.Email).Return(expect, app.IncorrectUserId)
+	s.app.On("UpdateUser", mock.Anything, request.UserId, request.Nickname, request.Email, request.Password).Return(expect, app.IncorrectUserId)
 
 	service := NewService(&s.app)
 	_, err := service.UpdateUser(context.TODO(), request)
@@ -192,9 +216,9 @@ func (s *AdServiceTestSuite) TestAdService_UpdateUserIncorrectUserId() {
 
 func (s *AdServiceTestSuite) TestAdService_UpdateUserValidationErr() {
 	expect := &users.User{ID: 10, Nickname: "", Email: "email"}
-	request := &proto.UpdateUserRequest{UserId: expect.ID, Nickname: expect.Nickname, Email: expect.Email}
+	request := &proto.UpdateUserRequest{UserId: expect.ID, Nickname: expect.Nickname, Email: expect.Email, Password: "password"}
 
-	s.app.On("UpdateUser", request.UserId, request.Nickname, request.Email).Return(expect, app.ValidateError)
+	s.app.On("UpdateUser", mock.Anything, request.UserId, request.Nickname, request.Email, request.Password).Return(expect, app.ValidateError)
 
 	service := NewService(&s.app)
 	_, err := service.UpdateUser(context.TODO(), request)
@@ -205,19 +229,26 @@ func (s *AdServiceTestSuite) TestAdService_GetUser() {
 	expect := &users.User{ID: 1, Nickname: "nickname", Email: "email"}
 	request := &proto.GetUserRequest{Id: expect.ID}
 
-	s.app.On("GetUser", request.Id).Return(expect, nil)
+	s.app.On("GetUser", mock.Anything, request.Id).Return(expect, nil)
 
 	service := NewService(&s.app)
 	response, err := service.GetUser(context.TODO(), request)
 	s.NoError(err)
-	s.Equal(response, UserSuccessResponse(expect))
+	s.Equal(response, PublicUserResponse(expect))
+	s.Nil(response.Email)
+
+	// x-user-id клиент выбирает сам, поэтому email он не открывает
+	ctx := metadata.NewIncomingContext(context.TODO(), metadata.Pairs("x-user-id", "1"))
+	response, err = service.GetUser(ctx, request)
+	s.NoError(err)
+	s.Nil(response.Email)
 }
 
 func (s *AdServiceTestSuite) TestAdService_GetUserIncorrectUserId() {
 	expect := &users.User{ID: 1, Nickname: "nickname", Email: "email"}
 	request := &proto.GetUserRequest{Id: expect.ID}
 
-	s.app.On("GetUser", request.Id).Return(expect, app.IncorrectUserId)
+	s.app.On("GetUser", mock.Anything, request.Id).Return(expect, app.IncorrectUserId)
 
 	service := NewService(&s.app)
 	_, err := service.GetUser(context.TODO(), request)
@@ -227,7 +258,7 @@ func (s *AdServiceTestSuite) TestAdService_GetUserIncorrectUserId() {
 func (s *AdServiceTestSuite) TestAdService_DeleteUser() {
 	request := &proto.DeleteUserRequest{Id: 1}
 
-	s.app.On("DeleteUser", request.Id).Return(nil)
+	s.app.On("DeleteUser", mock.Anything, request.Id).Return(nil)
 
 	service := NewService(&s.app)
 	_, err := service.DeleteUser(context.TODO(), request)
@@ -237,16 +268,53 @@ func (s *AdServiceTestSuite) TestAdService_DeleteUser() {
 func (s *AdServiceTestSuite) TestAdService_DeleteUserIncorrectUserId() {
 	request := &proto.DeleteUserRequest{Id: 10}
 
-	s.app.On("DeleteUser", request.Id).Return(app.IncorrectUserId)
+	s.app.On("DeleteUser", mock.Anything, request.Id).Return(app.IncorrectUserId)
 
 	service := NewService(&s.app)
 	_, err := service.DeleteUser(context.TODO(), request)
 	s.ErrorIs(err, ErrIncorrectUserId.Err())
 }
 
+func (s *AdServiceTestSuite) TestAdService_GetUserNotFound() {
+	request := &proto.GetUserRequest{Id: 10}
+	s.app.On("GetUser", mock.Anything, request.Id).Return(nil, app.UserNotFound)
+
+	service := NewService(&s.app)
+	_, err := service.GetUser(context.TODO(), request)
+	s.Equal(codes.NotFound, status.Code(err))
+}
+
+func (s *AdServiceTestSuite) TestAdService_UpdateProfile() {
+	profile := users.Profile{DisplayName: "Buda", Phone: "+79991234567"}
+	expect := &users.User{ID: 1, Nickname: "nickname", Email: "email", Profile: profile}
+	request := &proto.UpdateProfileRequest{UserId: expect.ID, Password: "password", DisplayName: profile.DisplayName, Phone: profile.Phone}
+
+	s.app.On("UpdateProfile", mock.Anything, expect.ID, "password", profile).Return(expect, nil)
+
+	service := NewService(&s.app)
+	response, err := service.UpdateProfile(context.TODO(), request)
+	s.NoError(err)
+	s.Equal(response, ProfileResponse(expect))
+	s.Equal("+79991234567", response.GetPhone())
+}
+
+func (s *AdServiceTestSuite) TestAdService_ListUserAds() {
+	adsList := []ads.Ad{{ID: 2, Title: "title 2", AuthorID: 1, Published: true}, {ID: 1, Title: "title 1", AuthorID: 1, Published: true}}
+	s.app.On("GetUserAds", mock.Anything, int64(1)).Return(adsList, nil)
+	s.app.On("GetUserAds", mock.Anything, int64(2)).Return(nil, app.UserNotFound)
+
+	service := NewService(&s.app)
+	response, err := service.ListUserAds(context.TODO(), &proto.ListUserAdsRequest{UserId: 1})
+	s.NoError(err)
+	s.Equal(response, AdsSuccessResponse(adsList))
+
+	_, err = service.ListUserAds(context.TODO(), &proto.ListUserAdsRequest{UserId: 2})
+	s.Equal(codes.NotFound, status.Code(err))
+}
+
 func (s *AdServiceTestSuite) TestAdService_DeleteAd() {
 	request := &proto.DeleteAdRequest{AdId: 1, UserId: 1}
-	s.app.On("DeleteAd", request.AdId, request.UserId).Return(nil)
+	s.app.On("DeleteAd", mock.Anything, request.AdId, request.UserId).Return(nil)
 
 	service := NewService(&s.app)
 	_, err := service.DeleteAd(context.TODO(), request)
@@ -255,7 +323,7 @@ func (s *AdServiceTestSuite) TestAdService_DeleteAd() {
 
 func (s *AdServiceTestSuite) TestAdService_DeleteAdIncorrectUserId() {
 	request := &proto.DeleteAdRequest{AdId: 1, UserId: 10}
-	s.app.On("DeleteAd", request.AdId, request.UserId).Return(app.IncorrectUserId)
+	s.app.On("DeleteAd", mock.Anything, request.AdId, request.UserId).Return(app.IncorrectUserId)
 
 	service := NewService(&s.app)
 	_, err := service.DeleteAd(context.TODO(), request)
@@ -264,7 +332,7 @@ func (s *AdServiceTestSuite) TestAdService_DeleteAdIncorrectUserId() {
 
 func (s *AdServiceTestSuite) TestAdService_DeleteAdIncorrectAdId() {
 	request := &proto.DeleteAdRequest{AdId: 10, UserId: 1}
-	s.app.On("DeleteAd", request.AdId, request.UserId).Return(app.IncorrectAdId)
+	s.app.On("DeleteAd", mock.Anything, request.AdId, request.UserId).Return(app.IncorrectAdId)
 
 	service := NewService(&s.app)
 	_, err := service.DeleteAd(context.TODO(), request)
@@ -275,7 +343,7 @@ func (s *AdServiceTestSuite) TestAdService_GetAd() {
 	request := &proto.GetAdRequest{AdId: 10}
 	expect := &ads.Ad{ID: 1, Title: "title", Text: "text 1", AuthorID: 3}
 
-	s.app.On("GetAd", request.AdId).Return(expect, nil)
+	s.app.On("GetAd", mock.Anything, request.AdId).Return(expect, nil)
 
 	service := NewService(&s.app)
 	response, err := service.GetAd(context.TODO(), request)
@@ -287,9 +355,28 @@ func (s *AdServiceTestSuite) TestAdService_GetAdIncorrectAdId() {
 	request := &proto.GetAdRequest{AdId: 10}
 	expect := &ads.Ad{ID: 1, Title: "title", Text: "text 1", AuthorID: 3}
 
-	s.app.On("GetAd", request.AdId).Return(expect, app.IncorrectAdId)
+	s.app.On("GetAd", mock.Anything, request.AdId).Return(expect, app.IncorrectAdId)
 
 	service := NewService(&s.app)
 	_, err := service.GetAd(context.TODO(), request)
 	s.ErrorIs(err, ErrIncorrectAdId.Err())
 }
+
+func (s *AdServiceTestSuite) TestAdService_ChangeAdStatusIncorrectAdId() {
+	request := &proto.ChangeAdStatusRequest{AdId: 10, UserId: 1, Published: true}
+	s.app.On("ChangeAdStatus", mock.Anything, request.AdId, request.UserId, request.Published).Return(nil, app.IncorrectAdId)
+
+	service := NewService(&s.app)
+	_, err := service.ChangeAdStatus(context.TODO(), request)
+	s.ErrorIs(err, ErrIncorrectAdId.Err())
+}
+
+func (s *AdServiceTestSuite) TestAdService_GetAdInternalError() {
+	request := &proto.GetAdRequest{AdId: 1}
+	s.app.On("GetAd", mock.Anything, request.AdId).Return(nil, errors.New("storage is unavailable"))
+
+	service := NewService(&s.app)
+	_, err := service.GetAd(context.TODO(), request)
+	s.Equal(codes.Internal, status.Code(err))
+	s.Equal(errmap.InternalMessage, status.Convert(err).Message())
+}
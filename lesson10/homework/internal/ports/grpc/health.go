@@ -0,0 +1,74 @@
+package grpc
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"homework10/internal/health"
+	"homework10/internal/ports/grpc/proto"
+)
+
+// как часто Watch перепроверяет готовность
+const healthWatchInterval = time.Second
+
+var ErrUnknownService = status.New(codes.NotFound, "unknown service")
+
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+	checker *health.Checker
+}
+
+// NewHealthServer реализует стандартный grpc.health.v1.Health поверх проверок checker.
+// Пустое имя сервиса и ad.AdService означают одно и то же - весь сервер
+func NewHealthServer(checker *health.Checker) healthpb.HealthServer {
+	return &healthServer{checker: checker}
+}
+
+func (h *healthServer) status(ctx context.Context, service string) healthpb.HealthCheckResponse_ServingStatus {
+	if service != "" && service != proto.AdService_ServiceDesc.ServiceName {
+		return healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+	}
+	if !h.checker.Check(ctx).Ready() {
+		return healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	return healthpb.HealthCheckResponse_SERVING
+}
+
+func (h *healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	servingStatus := h.status(ctx, req.Service)
+	if servingStatus == healthpb.HealthCheckResponse_SERVICE_UNKNOWN {
+		return nil, ErrUnknownService.Err()
+	}
+	return &healthpb.HealthCheckResponse{Status: servingStatus}, nil
+}
+
+// Watch присылает статус при каждом его изменении. Когда сервер начинает останавливаться,
+// клиент получает NOT_SERVING и поток закрывается, иначе GracefulStop ждал бы его вечно
+func (h *healthServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	ticker := time.NewTicker(healthWatchInterval)
+	defer ticker.Stop()
+
+	last := healthpb.HealthCheckResponse_UNKNOWN
+	for {
+		servingStatus := h.status(stream.Context(), req.Service)
+		if servingStatus != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: servingStatus}); err != nil {
+				return err
+			}
+			last = servingStatus
+		}
+		if h.checker.ShuttingDown() {
+			return nil
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-ticker.C:
+		}
+	}
+}
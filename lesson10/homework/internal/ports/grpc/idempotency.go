@@ -0,0 +1,89 @@
+package grpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"homework10/internal/idempotency"
+	"homework10/internal/ports/grpc/proto"
+)
+
+const (
+	IdempotencyKeyMetadata      = "idempotency-key"
+	IdempotencyReplayedMetadata = "idempotent-replayed"
+)
+
+var ErrIdempotencyKeyReused = status.New(codes.FailedPrecondition, "idempotency key was already used with a different request")
+
+// методы, для которых поддерживается ключ идемпотентности
+var idempotentMethods = map[string]bool{
+	proto.AdService_CreateAd_FullMethodName:   true,
+	proto.AdService_CreateUser_FullMethodName: true,
+}
+
+// IdempotencyInterceptor сохраняет успешный ответ на запрос с метаданными idempotency-key
+// и отдаёт его повторно на ретраи с тем же ключом и телом
+func IdempotencyInterceptor(store idempotency.Store) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !idempotentMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		keys := md.Get(IdempotencyKeyMetadata)
+		if len(keys) == 0 || keys[0] == "" {
+			return handler(ctx, req)
+		}
+
+		msg, ok := req.(protobuf.Message)
+		if !ok {
+			return handler(ctx, req)
+		}
+		body, err := protobuf.MarshalOptions{Deterministic: true}.Marshal(msg)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+
+		hash := sha256.Sum256(body)
+		requestHash := hex.EncodeToString(hash[:])
+		storeKey := "grpc:" + info.FullMethod + ":" + keys[0]
+
+		unlock := store.Lock(storeKey)
+		defer unlock()
+
+		if record, ok := store.Get(storeKey); ok {
+			if record.RequestHash != requestHash {
+				return nil, ErrIdempotencyKeyReused.Err()
+			}
+
+			var stored anypb.Any
+			if err := protobuf.Unmarshal(record.Body, &stored); err != nil {
+				return nil, status.Error(codes.Internal, err.Error())
+			}
+			_ = grpc.SetHeader(ctx, metadata.Pairs(IdempotencyReplayedMetadata, "true"))
+			return stored.UnmarshalNew()
+		}
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, err
+		}
+
+		if respMsg, ok := resp.(protobuf.Message); ok {
+			if stored, errAny := anypb.New(respMsg); errAny == nil {
+				if data, errMarshal := protobuf.Marshal(stored); errMarshal == nil {
+					store.Save(storeKey, idempotency.Record{RequestHash: requestHash, Body: data})
+				}
+			}
+		}
+		return resp, nil
+	}
+}
@@ -2,25 +2,90 @@ package loggers
 
 import (
 	"context"
+	"log/slog"
+	"time"
+
+	grpcmiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
-	"log"
+
+	"homework10/internal/logging"
 )
 
-func Logger(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
-	log.Printf("Received request: %v", req)
-	resp, err = handler(ctx, req)
-	log.Printf("Sent response: %v", resp)
+// Logger берёт x-request-id из метаданных (или генерирует новый), возвращает его в заголовках ответа
+// и кладёт в контекст логгер с этим идентификатором
+func Logger(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	start := time.Now()
+	callCtx, logger := withRequestLogger(ctx, func(md metadata.MD) { _ = grpc.SetHeader(ctx, md) })
+
+	resp, err = handler(callCtx, req)
+
+	logCall(callCtx, logger, info.FullMethod, err, start)
 	return resp, err
 }
 
-func PanicInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
-	defer func() {
-		if r := recover(); r != nil {
-			log.Printf("PANIC: %v", r)
-			err = status.Errorf(codes.Internal, "Internal server error")
+// StreamLogger - Logger для потоковых методов; запись в журнал появляется, когда поток закрыт
+func StreamLogger(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	wrapped := grpcmiddleware.WrapServerStream(stream)
+	var logger *slog.Logger
+	wrapped.WrappedContext, logger = withRequestLogger(stream.Context(), func(md metadata.MD) { _ = stream.SetHeader(md) })
+
+	err := handler(srv, wrapped)
+
+	logCall(wrapped.WrappedContext, logger, info.FullMethod, err, start)
+	return err
+}
+
+func withRequestLogger(ctx context.Context, setHeader func(metadata.MD)) (context.Context, *slog.Logger) {
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(logging.RequestIDMetadata); len(values) > 0 {
+			requestID = values[0]
 		}
-	}()
+	}
+	if !logging.ValidRequestID(requestID) {
+		requestID = logging.NewRequestID()
+	}
+	setHeader(metadata.Pairs(logging.RequestIDMetadata, requestID))
+
+	logger := logging.FromContext(ctx).With(slog.String("request_id", requestID))
+	return logging.WithLogger(logging.WithRequestID(ctx, requestID), logger), logger
+}
+
+func logCall(ctx context.Context, logger *slog.Logger, method string, err error, start time.Time) {
+	code := status.Code(err)
+	level := slog.LevelInfo
+	switch code {
+	case codes.OK:
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		level = slog.LevelError
+	default:
+		level = slog.LevelWarn
+	}
+
+	logger.LogAttrs(ctx, level, "grpc request",
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("latency", time.Since(start)),
+	)
+}
+
+func PanicInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer recoverPanic(ctx, &err)
 	return handler(ctx, req)
 }
+
+func PanicStreamInterceptor(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer recoverPanic(stream.Context(), &err)
+	return handler(srv, stream)
+}
+
+func recoverPanic(ctx context.Context, err *error) {
+	if r := recover(); r != nil {
+		logging.FromContext(ctx).Error("panic occurred", slog.Any("panic", r))
+		*err = status.Errorf(codes.Internal, "Internal server error")
+	}
+}
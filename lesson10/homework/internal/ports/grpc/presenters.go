@@ -1,8 +1,12 @@
 package grpc
 
 import (
+	"errors"
 	"google.golang.org/protobuf/types/known/timestamppb"
 	"homework10/internal/ads"
+	"homework10/internal/app"
+	"homework10/internal/outbox"
+	"homework10/internal/ports/errmap"
 	"homework10/internal/ports/grpc/proto"
 	"homework10/internal/users"
 )
@@ -19,11 +23,32 @@ func AdSuccessResponse(ad *ads.Ad) *proto.AdResponse {
 	}
 }
 
+// UserSuccessResponse - ответ на запрос, в котором клиент сам прислал email пользователя
+// (создание, изменение) или доказал доступ к нему (подтверждение). Телефона в нём нет
 func UserSuccessResponse(user *users.User) *proto.UserResponse {
+	response := PublicUserResponse(user)
+	response.Email = &user.Email
+	return response
+}
+
+// ProfileResponse - профиль целиком, в ответ на изменение профиля, подтверждённое паролем
+func ProfileResponse(user *users.User) *proto.UserResponse {
+	response := UserSuccessResponse(user)
+	response.Phone = &user.Phone
+	return response
+}
+
+// PublicUserResponse - профиль без email и телефона: так пользователь отдаётся в чтениях
+func PublicUserResponse(user *users.User) *proto.UserResponse {
 	return &proto.UserResponse{
-		Id:       user.ID,
-		Nickname: user.Nickname,
-		Email:    user.Email,
+		Id:            user.ID,
+		Nickname:      user.Nickname,
+		EmailVerified: user.EmailVerified,
+		DisplayName:   user.DisplayName,
+		City:          user.City,
+		AvatarUrl:     user.AvatarURL,
+		RegisteredAt:  timestamppb.New(user.CreatedAt),
+		LastSeenAt:    timestamppb.New(user.LastSeenAt),
 	}
 }
 
@@ -36,3 +61,44 @@ func AdsSuccessResponse(list []ads.Ad) *proto.ListAdResponse {
 
 	return &response
 }
+
+func AdEventResponse(event outbox.Event) *proto.AdEvent {
+	return &proto.AdEvent{
+		Id:        event.ID,
+		Type:      string(event.Type),
+		Ad:        AdSuccessResponse(&event.Ad),
+		CreatedAt: timestamppb.New(event.CreatedAt),
+	}
+}
+
+// ImportReportResponse - отчёт импорта; в failures не больше app.MaxImportFailures сообщений
+func ImportReportResponse(report app.ImportReport) *proto.ImportAdsResponse {
+	response := &proto.ImportAdsResponse{Imported: int64(report.Imported), Failed: int64(report.Failed)}
+	for _, failure := range report.Failures {
+		item := &proto.ImportAdFailure{Row: int64(failure.Line), Message: errmap.Message(failure.Err)}
+		var fields *app.InvalidFieldsError
+		if errors.As(failure.Err, &fields) {
+			for _, v := range fields.Violations {
+				item.FieldViolations = append(item.FieldViolations, &proto.FieldViolation{Field: v.Field, Description: v.Description})
+			}
+		}
+		response.Failures = append(response.Failures, item)
+	}
+	return response
+}
+
+func BatchGetAdsResponse(found []ads.Ad, missing []int64) *proto.BatchGetAdsResponse {
+	response := &proto.BatchGetAdsResponse{MissingIds: missing}
+	for _, v := range found {
+		response.Ads = append(response.Ads, AdSuccessResponse(&v))
+	}
+	return response
+}
+
+func BatchGetUsersResponse(found []users.User, missing []int64) *proto.BatchGetUsersResponse {
+	response := &proto.BatchGetUsersResponse{MissingIds: missing}
+	for _, v := range found {
+		response.Users = append(response.Users, PublicUserResponse(&v))
+	}
+	return response
+}
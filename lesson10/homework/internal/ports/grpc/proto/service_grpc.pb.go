@@ -20,17 +20,29 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	AdService_CreateAd_FullMethodName          = "/ad.AdService/CreateAd"
-	AdService_ChangeAdStatus_FullMethodName    = "/ad.AdService/ChangeAdStatus"
-	AdService_UpdateAd_FullMethodName          = "/ad.AdService/UpdateAd"
-	AdService_ListAdsWithFilter_FullMethodName = "/ad.AdService/ListAdsWithFilter"
-	AdService_ListAdsByTitle_FullMethodName    = "/ad.AdService/ListAdsByTitle"
-	AdService_CreateUser_FullMethodName        = "/ad.AdService/CreateUser"
-	AdService_UpdateUser_FullMethodName        = "/ad.AdService/UpdateUser"
-	AdService_GetUser_FullMethodName           = "/ad.AdService/GetUser"
-	AdService_DeleteUser_FullMethodName        = "/ad.AdService/DeleteUser"
-	AdService_GetAd_FullMethodName             = "/ad.AdService/GetAd"
-	AdService_DeleteAd_FullMethodName          = "/ad.AdService/DeleteAd"
+	AdService_CreateAd_FullMethodName                 = "/ad.AdService/CreateAd"
+	AdService_ChangeAdStatus_FullMethodName           = "/ad.AdService/ChangeAdStatus"
+	AdService_UpdateAd_FullMethodName                 = "/ad.AdService/UpdateAd"
+	AdService_GetAd_FullMethodName                    = "/ad.AdService/GetAd"
+	AdService_ListAdsWithFilter_FullMethodName        = "/ad.AdService/ListAdsWithFilter"
+	AdService_ListAdsByTitle_FullMethodName           = "/ad.AdService/ListAdsByTitle"
+	AdService_CreateUser_FullMethodName               = "/ad.AdService/CreateUser"
+	AdService_UpdateUser_FullMethodName               = "/ad.AdService/UpdateUser"
+	AdService_GetUser_FullMethodName                  = "/ad.AdService/GetUser"
+	AdService_UpdateProfile_FullMethodName            = "/ad.AdService/UpdateProfile"
+	AdService_ListUserAds_FullMethodName              = "/ad.AdService/ListUserAds"
+	AdService_DeleteUser_FullMethodName               = "/ad.AdService/DeleteUser"
+	AdService_DeleteAd_FullMethodName                 = "/ad.AdService/DeleteAd"
+	AdService_WatchAds_FullMethodName                 = "/ad.AdService/WatchAds"
+	AdService_ImportAds_FullMethodName                = "/ad.AdService/ImportAds"
+	AdService_BatchGetAds_FullMethodName              = "/ad.AdService/BatchGetAds"
+	AdService_BatchGetUsers_FullMethodName            = "/ad.AdService/BatchGetUsers"
+	AdService_BatchChangeAdStatus_FullMethodName      = "/ad.AdService/BatchChangeAdStatus"
+	AdService_GetUserByEmail_FullMethodName           = "/ad.AdService/GetUserByEmail"
+	AdService_RequestEmailVerification_FullMethodName = "/ad.AdService/RequestEmailVerification"
+	AdService_ConfirmEmail_FullMethodName             = "/ad.AdService/ConfirmEmail"
+	AdService_RequestPasswordReset_FullMethodName     = "/ad.AdService/RequestPasswordReset"
+	AdService_ResetPassword_FullMethodName            = "/ad.AdService/ResetPassword"
 )
 
 // AdServiceClient is the client API for AdService service.
@@ -40,14 +52,45 @@ type AdServiceClient interface {
 	CreateAd(ctx context.Context, in *CreateAdRequest, opts ...grpc.CallOption) (*AdResponse, error)
 	ChangeAdStatus(ctx context.Context, in *ChangeAdStatusRequest, opts ...grpc.CallOption) (*AdResponse, error)
 	UpdateAd(ctx context.Context, in *UpdateAdRequest, opts ...grpc.CallOption) (*AdResponse, error)
+	GetAd(ctx context.Context, in *GetAdRequest, opts ...grpc.CallOption) (*AdResponse, error)
 	ListAdsWithFilter(ctx context.Context, in *GetListAdsWithFilterRequest, opts ...grpc.CallOption) (*ListAdResponse, error)
 	ListAdsByTitle(ctx context.Context, in *GetListAdsByTitleRequest, opts ...grpc.CallOption) (*ListAdResponse, error)
 	CreateUser(ctx context.Context, in *CreateUserRequest, opts ...grpc.CallOption) (*UserResponse, error)
 	UpdateUser(ctx context.Context, in *UpdateUserRequest, opts ...grpc.CallOption) (*UserResponse, error)
+	// Профиль отдаётся без email и телефона: пока клиент не аутентифицирован, любой запрос считается чужим
 	GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*UserResponse, error)
+	// Заменяет поля профиля целиком: незаполненное поле очищает значение.
+	// Запрос подтверждается паролем пользователя, без него отвечает PermissionDenied
+	UpdateProfile(ctx context.Context, in *UpdateProfileRequest, opts ...grpc.CallOption) (*UserResponse, error)
+	// Опубликованные объявления пользователя, от новых к старым
+	ListUserAds(ctx context.Context, in *ListUserAdsRequest, opts ...grpc.CallOption) (*ListAdResponse, error)
 	DeleteUser(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
-	GetAd(ctx context.Context, in *GetAdRequest, opts ...grpc.CallOption) (*AdResponse, error)
 	DeleteAd(ctx context.Context, in *DeleteAdRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Поток событий объявлений (те же, что уходят в вебхуки). В REST API его нет:
+	// по HTTP события отдаются как server-sent events на /api/v1/ads/events
+	WatchAds(ctx context.Context, in *WatchAdsRequest, opts ...grpc.CallOption) (AdService_WatchAdsClient, error)
+	// Импорт объявлений потоком: каждое сообщение проверяется как в CreateAd, ошибки отдельных
+	// сообщений попадают в ответ и не прерывают импорт. По HTTP импорт - POST /api/v1/ads/import.
+	// Каждое сообщение списывает токен из лимита на запись; поток длиннее 10000 сообщений обрывается
+	ImportAds(ctx context.Context, opts ...grpc.CallOption) (AdService_ImportAdsClient, error)
+	// Пакетные методы принимают до 100 ID. Объявлены последними, чтобы шлюз проверял
+	// batch_get и batch_status раньше путей с {ad_id}
+	BatchGetAds(ctx context.Context, in *BatchGetRequest, opts ...grpc.CallOption) (*BatchGetAdsResponse, error)
+	BatchGetUsers(ctx context.Context, in *BatchGetRequest, opts ...grpc.CallOption) (*BatchGetUsersResponse, error)
+	// Всё или ничего: если хоть одно объявление не найдено или чужое, не меняется ни одно
+	BatchChangeAdStatus(ctx context.Context, in *BatchChangeAdStatusRequest, opts ...grpc.CallOption) (*ListAdResponse, error)
+	// Поиск по email без учёта регистра для входа. Email передаётся в теле, а не в URL,
+	// чтобы он не попадал в журналы запросов. Служебный метод: по нему можно узнать, зарегистрирован ли
+	// адрес, поэтому нужны метаданные authorization: Bearer с токеном администратора
+	GetUserByEmail(ctx context.Context, in *GetUserByEmailRequest, opts ...grpc.CallOption) (*UserResponse, error)
+	// Заново отправляет письмо для подтверждения email; токен из прежнего письма перестаёт действовать
+	RequestEmailVerification(ctx context.Context, in *RequestEmailVerificationRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Подтверждает email одноразовым токеном из письма. Без подтверждённого email нельзя публиковать объявления
+	ConfirmEmail(ctx context.Context, in *ConfirmEmailRequest, opts ...grpc.CallOption) (*UserResponse, error)
+	// Отправляет письмо со сбросом пароля. Отвечает одинаково, есть ли пользователь с таким email или нет
+	RequestPasswordReset(ctx context.Context, in *RequestPasswordResetRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Задаёт новый пароль по одноразовому токену из письма
+	ResetPassword(ctx context.Context, in *ResetPasswordRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 }
 
 type adServiceClient struct {
@@ -85,6 +128,15 @@ func (c *adServiceClient) UpdateAd(ctx context.Context, in *UpdateAdRequest, opt
 	return out, nil
 }
 
+func (c *adServiceClient) GetAd(ctx context.Context, in *GetAdRequest, opts ...grpc.CallOption) (*AdResponse, error) {
+	out := new(AdResponse)
+	err := c.cc.Invoke(ctx, AdService_GetAd_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *adServiceClient) ListAdsWithFilter(ctx context.Context, in *GetListAdsWithFilterRequest, opts ...grpc.CallOption) (*ListAdResponse, error) {
 	out := new(ListAdResponse)
 	err := c.cc.Invoke(ctx, AdService_ListAdsWithFilter_FullMethodName, in, out, opts...)
@@ -130,6 +182,24 @@ func (c *adServiceClient) GetUser(ctx context.Context, in *GetUserRequest, opts
 	return out, nil
 }
 
+func (c *adServiceClient) UpdateProfile(ctx context.Context, in *UpdateProfileRequest, opts ...grpc.CallOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	err := c.cc.Invoke(ctx, AdService_UpdateProfile_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) ListUserAds(ctx context.Context, in *ListUserAdsRequest, opts ...grpc.CallOption) (*ListAdResponse, error) {
+	out := new(ListAdResponse)
+	err := c.cc.Invoke(ctx, AdService_ListUserAds_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *adServiceClient) DeleteUser(ctx context.Context, in *DeleteUserRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
 	out := new(emptypb.Empty)
 	err := c.cc.Invoke(ctx, AdService_DeleteUser_FullMethodName, in, out, opts...)
@@ -139,18 +209,147 @@ func (c *adServiceClient) DeleteUser(ctx context.Context, in *DeleteUserRequest,
 	return out, nil
 }
 
-func (c *adServiceClient) GetAd(ctx context.Context, in *GetAdRequest, opts ...grpc.CallOption) (*AdResponse, error) {
-	out := new(AdResponse)
-	err := c.cc.Invoke(ctx, AdService_GetAd_FullMethodName, in, out, opts...)
+func (c *adServiceClient) DeleteAd(ctx context.Context, in *DeleteAdRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, AdService_DeleteAd_FullMethodName, in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *adServiceClient) DeleteAd(ctx context.Context, in *DeleteAdRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+func (c *adServiceClient) WatchAds(ctx context.Context, in *WatchAdsRequest, opts ...grpc.CallOption) (AdService_WatchAdsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AdService_ServiceDesc.Streams[0], AdService_WatchAds_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &adServiceWatchAdsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type AdService_WatchAdsClient interface {
+	Recv() (*AdEvent, error)
+	grpc.ClientStream
+}
+
+type adServiceWatchAdsClient struct {
+	grpc.ClientStream
+}
+
+func (x *adServiceWatchAdsClient) Recv() (*AdEvent, error) {
+	m := new(AdEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *adServiceClient) ImportAds(ctx context.Context, opts ...grpc.CallOption) (AdService_ImportAdsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &AdService_ServiceDesc.Streams[1], AdService_ImportAds_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &adServiceImportAdsClient{stream}
+	return x, nil
+}
+
+type AdService_ImportAdsClient interface {
+	Send(*CreateAdRequest) error
+	CloseAndRecv() (*ImportAdsResponse, error)
+	grpc.ClientStream
+}
+
+type adServiceImportAdsClient struct {
+	grpc.ClientStream
+}
+
+func (x *adServiceImportAdsClient) Send(m *CreateAdRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *adServiceImportAdsClient) CloseAndRecv() (*ImportAdsResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(ImportAdsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *adServiceClient) BatchGetAds(ctx context.Context, in *BatchGetRequest, opts ...grpc.CallOption) (*BatchGetAdsResponse, error) {
+	out := new(BatchGetAdsResponse)
+	err := c.cc.Invoke(ctx, AdService_BatchGetAds_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) BatchGetUsers(ctx context.Context, in *BatchGetRequest, opts ...grpc.CallOption) (*BatchGetUsersResponse, error) {
+	out := new(BatchGetUsersResponse)
+	err := c.cc.Invoke(ctx, AdService_BatchGetUsers_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) BatchChangeAdStatus(ctx context.Context, in *BatchChangeAdStatusRequest, opts ...grpc.CallOption) (*ListAdResponse, error) {
+	out := new(ListAdResponse)
+	err := c.cc.Invoke(ctx, AdService_BatchChangeAdStatus_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) GetUserByEmail(ctx context.Context, in *GetUserByEmailRequest, opts ...grpc.CallOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	err := c.cc.Invoke(ctx, AdService_GetUserByEmail_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) RequestEmailVerification(ctx context.Context, in *RequestEmailVerificationRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
 	out := new(emptypb.Empty)
-	err := c.cc.Invoke(ctx, AdService_DeleteAd_FullMethodName, in, out, opts...)
+	err := c.cc.Invoke(ctx, AdService_RequestEmailVerification_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) ConfirmEmail(ctx context.Context, in *ConfirmEmailRequest, opts ...grpc.CallOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	err := c.cc.Invoke(ctx, AdService_ConfirmEmail_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) RequestPasswordReset(ctx context.Context, in *RequestPasswordResetRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, AdService_RequestPasswordReset_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adServiceClient) ResetPassword(ctx context.Context, in *ResetPasswordRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, AdService_ResetPassword_FullMethodName, in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -164,14 +363,45 @@ type AdServiceServer interface {
 	CreateAd(context.Context, *CreateAdRequest) (*AdResponse, error)
 	ChangeAdStatus(context.Context, *ChangeAdStatusRequest) (*AdResponse, error)
 	UpdateAd(context.Context, *UpdateAdRequest) (*AdResponse, error)
+	GetAd(context.Context, *GetAdRequest) (*AdResponse, error)
 	ListAdsWithFilter(context.Context, *GetListAdsWithFilterRequest) (*ListAdResponse, error)
 	ListAdsByTitle(context.Context, *GetListAdsByTitleRequest) (*ListAdResponse, error)
 	CreateUser(context.Context, *CreateUserRequest) (*UserResponse, error)
 	UpdateUser(context.Context, *UpdateUserRequest) (*UserResponse, error)
+	// Профиль отдаётся без email и телефона: пока клиент не аутентифицирован, любой запрос считается чужим
 	GetUser(context.Context, *GetUserRequest) (*UserResponse, error)
+	// Заменяет поля профиля целиком: незаполненное поле очищает значение.
+	// Запрос подтверждается паролем пользователя, без него отвечает PermissionDenied
+	UpdateProfile(context.Context, *UpdateProfileRequest) (*UserResponse, error)
+	// Опубликованные объявления пользователя, от новых к старым
+	ListUserAds(context.Context, *ListUserAdsRequest) (*ListAdResponse, error)
 	DeleteUser(context.Context, *DeleteUserRequest) (*emptypb.Empty, error)
-	GetAd(context.Context, *GetAdRequest) (*AdResponse, error)
 	DeleteAd(context.Context, *DeleteAdRequest) (*emptypb.Empty, error)
+	// Поток событий объявлений (те же, что уходят в вебхуки). В REST API его нет:
+	// по HTTP события отдаются как server-sent events на /api/v1/ads/events
+	WatchAds(*WatchAdsRequest, AdService_WatchAdsServer) error
+	// Импорт объявлений потоком: каждое сообщение проверяется как в CreateAd, ошибки отдельных
+	// сообщений попадают в ответ и не прерывают импорт. По HTTP импорт - POST /api/v1/ads/import.
+	// Каждое сообщение списывает токен из лимита на запись; поток длиннее 10000 сообщений обрывается
+	ImportAds(AdService_ImportAdsServer) error
+	// Пакетные методы принимают до 100 ID. Объявлены последними, чтобы шлюз проверял
+	// batch_get и batch_status раньше путей с {ad_id}
+	BatchGetAds(context.Context, *BatchGetRequest) (*BatchGetAdsResponse, error)
+	BatchGetUsers(context.Context, *BatchGetRequest) (*BatchGetUsersResponse, error)
+	// Всё или ничего: если хоть одно объявление не найдено или чужое, не меняется ни одно
+	BatchChangeAdStatus(context.Context, *BatchChangeAdStatusRequest) (*ListAdResponse, error)
+	// Поиск по email без учёта регистра для входа. Email передаётся в теле, а не в URL,
+	// чтобы он не попадал в журналы запросов. Служебный метод: по нему можно узнать, зарегистрирован ли
+	// адрес, поэтому нужны метаданные authorization: Bearer с токеном администратора
+	GetUserByEmail(context.Context, *GetUserByEmailRequest) (*UserResponse, error)
+	// Заново отправляет письмо для подтверждения email; токен из прежнего письма перестаёт действовать
+	RequestEmailVerification(context.Context, *RequestEmailVerificationRequest) (*emptypb.Empty, error)
+	// Подтверждает email одноразовым токеном из письма. Без подтверждённого email нельзя публиковать объявления
+	ConfirmEmail(context.Context, *ConfirmEmailRequest) (*UserResponse, error)
+	// Отправляет письмо со сбросом пароля. Отвечает одинаково, есть ли пользователь с таким email или нет
+	RequestPasswordReset(context.Context, *RequestPasswordResetRequest) (*emptypb.Empty, error)
+	// Задаёт новый пароль по одноразовому токену из письма
+	ResetPassword(context.Context, *ResetPasswordRequest) (*emptypb.Empty, error)
 }
 
 // UnimplementedAdServiceServer should be embedded to have forward compatible implementations.
@@ -187,6 +417,9 @@ func (UnimplementedAdServiceServer) ChangeAdStatus(context.Context, *ChangeAdSta
 func (UnimplementedAdServiceServer) UpdateAd(context.Context, *UpdateAdRequest) (*AdResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateAd not implemented")
 }
+func (UnimplementedAdServiceServer) GetAd(context.Context, *GetAdRequest) (*AdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAd not implemented")
+}
 func (UnimplementedAdServiceServer) ListAdsWithFilter(context.Context, *GetListAdsWithFilterRequest) (*ListAdResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListAdsWithFilter not implemented")
 }
@@ -202,15 +435,48 @@ func (UnimplementedAdServiceServer) UpdateUser(context.Context, *UpdateUserReque
 func (UnimplementedAdServiceServer) GetUser(context.Context, *GetUserRequest) (*UserResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetUser not implemented")
 }
+func (UnimplementedAdServiceServer) UpdateProfile(context.Context, *UpdateProfileRequest) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateProfile not implemented")
+}
+func (UnimplementedAdServiceServer) ListUserAds(context.Context, *ListUserAdsRequest) (*ListAdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUserAds not implemented")
+}
 func (UnimplementedAdServiceServer) DeleteUser(context.Context, *DeleteUserRequest) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteUser not implemented")
 }
-func (UnimplementedAdServiceServer) GetAd(context.Context, *GetAdRequest) (*AdResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetAd not implemented")
-}
 func (UnimplementedAdServiceServer) DeleteAd(context.Context, *DeleteAdRequest) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteAd not implemented")
 }
+func (UnimplementedAdServiceServer) WatchAds(*WatchAdsRequest, AdService_WatchAdsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchAds not implemented")
+}
+func (UnimplementedAdServiceServer) ImportAds(AdService_ImportAdsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ImportAds not implemented")
+}
+func (UnimplementedAdServiceServer) BatchGetAds(context.Context, *BatchGetRequest) (*BatchGetAdsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchGetAds not implemented")
+}
+func (UnimplementedAdServiceServer) BatchGetUsers(context.Context, *BatchGetRequest) (*BatchGetUsersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchGetUsers not implemented")
+}
+func (UnimplementedAdServiceServer) BatchChangeAdStatus(context.Context, *BatchChangeAdStatusRequest) (*ListAdResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BatchChangeAdStatus not implemented")
+}
+func (UnimplementedAdServiceServer) GetUserByEmail(context.Context, *GetUserByEmailRequest) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserByEmail not implemented")
+}
+func (UnimplementedAdServiceServer) RequestEmailVerification(context.Context, *RequestEmailVerificationRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestEmailVerification not implemented")
+}
+func (UnimplementedAdServiceServer) ConfirmEmail(context.Context, *ConfirmEmailRequest) (*UserResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConfirmEmail not implemented")
+}
+func (UnimplementedAdServiceServer) RequestPasswordReset(context.Context, *RequestPasswordResetRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestPasswordReset not implemented")
+}
+func (UnimplementedAdServiceServer) ResetPassword(context.Context, *ResetPasswordRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetPassword not implemented")
+}
 
 // UnsafeAdServiceServer may be embedded to opt out of forward compatibility for this service.
 // Use of this interface is not recommended, as added methods to AdServiceServer will
@@ -277,6 +543,24 @@ func _AdService_UpdateAd_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AdService_GetAd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAdRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).GetAd(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_GetAd_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).GetAd(ctx, req.(*GetAdRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _AdService_ListAdsWithFilter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetListAdsWithFilterRequest)
 	if err := dec(in); err != nil {
@@ -367,38 +651,56 @@ func _AdService_GetUser_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
-func _AdService_DeleteUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(DeleteUserRequest)
+func _AdService_UpdateProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateProfileRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(AdServiceServer).DeleteUser(ctx, in)
+		return srv.(AdServiceServer).UpdateProfile(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: AdService_DeleteUser_FullMethodName,
+		FullMethod: AdService_UpdateProfile_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AdServiceServer).DeleteUser(ctx, req.(*DeleteUserRequest))
+		return srv.(AdServiceServer).UpdateProfile(ctx, req.(*UpdateProfileRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _AdService_GetAd_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetAdRequest)
+func _AdService_ListUserAds_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUserAdsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(AdServiceServer).GetAd(ctx, in)
+		return srv.(AdServiceServer).ListUserAds(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: AdService_GetAd_FullMethodName,
+		FullMethod: AdService_ListUserAds_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(AdServiceServer).GetAd(ctx, req.(*GetAdRequest))
+		return srv.(AdServiceServer).ListUserAds(ctx, req.(*ListUserAdsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_DeleteUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).DeleteUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_DeleteUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).DeleteUser(ctx, req.(*DeleteUserRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -421,6 +723,197 @@ func _AdService_DeleteAd_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AdService_WatchAds_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchAdsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AdServiceServer).WatchAds(m, &adServiceWatchAdsServer{stream})
+}
+
+type AdService_WatchAdsServer interface {
+	Send(*AdEvent) error
+	grpc.ServerStream
+}
+
+type adServiceWatchAdsServer struct {
+	grpc.ServerStream
+}
+
+func (x *adServiceWatchAdsServer) Send(m *AdEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _AdService_ImportAds_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(AdServiceServer).ImportAds(&adServiceImportAdsServer{stream})
+}
+
+type AdService_ImportAdsServer interface {
+	SendAndClose(*ImportAdsResponse) error
+	Recv() (*CreateAdRequest, error)
+	grpc.ServerStream
+}
+
+type adServiceImportAdsServer struct {
+	grpc.ServerStream
+}
+
+func (x *adServiceImportAdsServer) SendAndClose(m *ImportAdsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *adServiceImportAdsServer) Recv() (*CreateAdRequest, error) {
+	m := new(CreateAdRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _AdService_BatchGetAds_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).BatchGetAds(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_BatchGetAds_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).BatchGetAds(ctx, req.(*BatchGetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_BatchGetUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).BatchGetUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_BatchGetUsers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).BatchGetUsers(ctx, req.(*BatchGetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_BatchChangeAdStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchChangeAdStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).BatchChangeAdStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_BatchChangeAdStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).BatchChangeAdStatus(ctx, req.(*BatchChangeAdStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_GetUserByEmail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserByEmailRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).GetUserByEmail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_GetUserByEmail_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).GetUserByEmail(ctx, req.(*GetUserByEmailRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_RequestEmailVerification_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestEmailVerificationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).RequestEmailVerification(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_RequestEmailVerification_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).RequestEmailVerification(ctx, req.(*RequestEmailVerificationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_ConfirmEmail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfirmEmailRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).ConfirmEmail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_ConfirmEmail_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).ConfirmEmail(ctx, req.(*ConfirmEmailRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_RequestPasswordReset_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestPasswordResetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).RequestPasswordReset(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_RequestPasswordReset_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).RequestPasswordReset(ctx, req.(*RequestPasswordResetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdService_ResetPassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetPasswordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdServiceServer).ResetPassword(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdService_ResetPassword_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdServiceServer).ResetPassword(ctx, req.(*ResetPasswordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // AdService_ServiceDesc is the grpc.ServiceDesc for AdService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -440,6 +933,10 @@ var AdService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UpdateAd",
 			Handler:    _AdService_UpdateAd_Handler,
 		},
+		{
+			MethodName: "GetAd",
+			Handler:    _AdService_GetAd_Handler,
+		},
 		{
 			MethodName: "ListAdsWithFilter",
 			Handler:    _AdService_ListAdsWithFilter_Handler,
@@ -461,18 +958,65 @@ var AdService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _AdService_GetUser_Handler,
 		},
 		{
-			MethodName: "DeleteUser",
-			Handler:    _AdService_DeleteUser_Handler,
+			MethodName: "UpdateProfile",
+			Handler:    _AdService_UpdateProfile_Handler,
 		},
 		{
-			MethodName: "GetAd",
-			Handler:    _AdService_GetAd_Handler,
+			MethodName: "ListUserAds",
+			Handler:    _AdService_ListUserAds_Handler,
+		},
+		{
+			MethodName: "DeleteUser",
+			Handler:    _AdService_DeleteUser_Handler,
 		},
 		{
 			MethodName: "DeleteAd",
 			Handler:    _AdService_DeleteAd_Handler,
 		},
+		{
+			MethodName: "BatchGetAds",
+			Handler:    _AdService_BatchGetAds_Handler,
+		},
+		{
+			MethodName: "BatchGetUsers",
+			Handler:    _AdService_BatchGetUsers_Handler,
+		},
+		{
+			MethodName: "BatchChangeAdStatus",
+			Handler:    _AdService_BatchChangeAdStatus_Handler,
+		},
+		{
+			MethodName: "GetUserByEmail",
+			Handler:    _AdService_GetUserByEmail_Handler,
+		},
+		{
+			MethodName: "RequestEmailVerification",
+			Handler:    _AdService_RequestEmailVerification_Handler,
+		},
+		{
+			MethodName: "ConfirmEmail",
+			Handler:    _AdService_ConfirmEmail_Handler,
+		},
+		{
+			MethodName: "RequestPasswordReset",
+			Handler:    _AdService_RequestPasswordReset_Handler,
+		},
+		{
+			MethodName: "ResetPassword",
+			Handler:    _AdService_ResetPassword_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchAds",
+			Handler:       _AdService_WatchAds_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "ImportAds",
+			Handler:       _AdService_ImportAds_Handler,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "service.proto",
 }
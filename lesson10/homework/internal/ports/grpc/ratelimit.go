@@ -0,0 +1,105 @@
+package grpc
+
+import (
+	"context"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"homework10/internal/ratelimit"
+)
+
+var ErrTooManyRequests = status.New(codes.ResourceExhausted, "too many requests")
+
+// методы только на чтение, всё остальное считается записью
+func isReadMethod(fullMethod string) bool {
+	name := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	return strings.HasPrefix(name, "Get") || strings.HasPrefix(name, "List") || strings.HasPrefix(name, "BatchGet") ||
+		strings.HasPrefix(name, "Watch")
+}
+
+// пробы оркестратора не должны съедать бюджет клиентов и получать отказ
+func isHealthMethod(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/grpc.health.v1.Health/")
+}
+
+// RateLimitInterceptor ограничивает частоту запросов с одного IP с раздельными бюджетами на чтение и запись.
+// Метаданные x-user-id клиент выбирает сам, поэтому ключом они не служат
+func RateLimitInterceptor(limiter *ratelimit.Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if isHealthMethod(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		if ok, wait := limiter.Allow(clientKey(ctx), !isReadMethod(info.FullMethod)); !ok {
+			_ = grpc.SetHeader(ctx, retryAfter(wait))
+			return nil, ErrTooManyRequests.Err()
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// RateLimitStreamInterceptor - RateLimitInterceptor для потоковых методов. Поток от сервера (WatchAds)
+// списывает токен при открытии, как обычный вызов. В потоке от клиента (ImportAds) каждое сообщение -
+// отдельная запись: оно списывает токен на запись, и без токенов поток обрывается с ResourceExhausted
+func RateLimitStreamInterceptor(limiter *ratelimit.Limiter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if isHealthMethod(info.FullMethod) {
+			return handler(srv, stream)
+		}
+
+		key := clientKey(stream.Context())
+		if info.IsClientStream {
+			return handler(srv, &limitedStream{ServerStream: stream, limiter: limiter, key: key})
+		}
+		if ok, wait := limiter.Allow(key, !isReadMethod(info.FullMethod)); !ok {
+			_ = stream.SetHeader(retryAfter(wait))
+			return ErrTooManyRequests.Err()
+		}
+
+		return handler(srv, stream)
+	}
+}
+
+// limitedStream списывает токен на запись за каждое принятое сообщение. Посреди потока заголовки
+// могут быть уже отправлены, поэтому retry-after уходит в трейлере
+type limitedStream struct {
+	grpc.ServerStream
+	limiter *ratelimit.Limiter
+	key     string
+}
+
+func (s *limitedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if ok, wait := s.limiter.Allow(s.key, true); !ok {
+		s.SetTrailer(retryAfter(wait))
+		return ErrTooManyRequests.Err()
+	}
+	return nil
+}
+
+func clientKey(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr := p.Addr.String()
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			addr = host
+		}
+		return "ip:" + addr
+	}
+	return "ip:unknown"
+}
+
+func retryAfter(wait time.Duration) metadata.MD {
+	return metadata.Pairs("retry-after", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+}
@@ -1,36 +1,166 @@
 package grpc
 
 import (
+	"crypto/tls"
 	grpcmiddleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/test/bufconn"
 	"homework10/internal/app"
+	"homework10/internal/health"
+	"homework10/internal/idempotency"
+	"homework10/internal/outbox"
 	"homework10/internal/ports/grpc/loggers"
+	"homework10/internal/ports/grpc/metrics"
 	"homework10/internal/ports/grpc/proto"
+	"homework10/internal/ports/grpc/tracing"
+	"homework10/internal/ratelimit"
 	"log"
 	"net"
 )
 
-func NewGRPCServer(port string, a app.App) (*grpc.Server, net.Listener) {
+type Option func(*options)
+
+type options struct {
+	idempotencyStore idempotency.Store
+	limiter          *ratelimit.Limiter
+	registerer       prometheus.Registerer
+	tracerProvider   trace.TracerProvider
+	healthChecker    *health.Checker
+	tlsConfig        *tls.Config
+	events           *outbox.Broker
+	adminToken       string
+}
+
+// WithIdempotencyStore задаёт хранилище ответов для метаданных idempotency-key.
+// По умолчанию ответы хранятся в памяти процесса
+func WithIdempotencyStore(store idempotency.Store) Option {
+	return func(o *options) {
+		o.idempotencyStore = store
+	}
+}
+
+// WithRateLimiter включает ограничение частоты запросов
+func WithRateLimiter(limiter *ratelimit.Limiter) Option {
+	return func(o *options) {
+		o.limiter = limiter
+	}
+}
+
+// WithMetricsRegistry включает сбор метрик запросов в reg
+func WithMetricsRegistry(reg prometheus.Registerer) Option {
+	return func(o *options) {
+		o.registerer = reg
+	}
+}
+
+// WithTracerProvider включает трассировку вызовов
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracerProvider = tp
+	}
+}
+
+// WithHealthChecker задаёт проверки, по которым отвечает сервис grpc.health.v1.Health.
+// По умолчанию сервер считается готовым, пока не начал останавливаться
+func WithHealthChecker(checker *health.Checker) Option {
+	return func(o *options) {
+		o.healthChecker = checker
+	}
+}
+
+// WithTLSConfig включает TLS; если в cfg заданы ClientCAs, клиенты проверяются по сертификату (mTLS)
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = cfg
+	}
+}
+
+// WithAdEvents включает поток WatchAds; без него метод отвечает Unimplemented
+func WithAdEvents(broker *outbox.Broker) Option {
+	return func(o *options) {
+		o.events = broker
+	}
+}
+
+// WithAdminToken открывает служебные методы для вызовов с этим bearer-токеном.
+// Без токена служебные методы отвечают Unauthenticated
+func WithAdminToken(token string) Option {
+	return func(o *options) {
+		o.adminToken = token
+	}
+}
+
+// NewServer собирает gRPC-сервер без слушателя, например чтобы обслуживать его через ServeHTTP
+func NewServer(a app.App, opts ...Option) *grpc.Server {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.idempotencyStore == nil {
+		o.idempotencyStore = idempotency.NewMemoryStore(idempotency.DefaultTTL)
+	}
+	if o.healthChecker == nil {
+		o.healthChecker = health.New(health.DefaultTimeout)
+	}
+
+	var interceptors []grpc.UnaryServerInterceptor
+	if o.tracerProvider != nil {
+		interceptors = append(interceptors, tracing.Interceptor(o.tracerProvider))
+	}
+	interceptors = append(interceptors, loggers.Logger)
+	var requestMetrics *metrics.Metrics
+	if o.registerer != nil {
+		requestMetrics = metrics.New(o.registerer)
+		interceptors = append(interceptors, requestMetrics.Interceptor)
+	}
+	interceptors = append(interceptors, loggers.PanicInterceptor)
+	if o.limiter != nil {
+		interceptors = append(interceptors, RateLimitInterceptor(o.limiter))
+	}
+	interceptors = append(interceptors, AdminInterceptor(o.adminToken), IdempotencyInterceptor(o.idempotencyStore))
+
+	// у потоковых методов (WatchAds, ImportAds) те же журнал, метрики, трассировка, защита от panic и лимиты
+	var streamInterceptors []grpc.StreamServerInterceptor
+	if o.tracerProvider != nil {
+		streamInterceptors = append(streamInterceptors, tracing.StreamInterceptor(o.tracerProvider))
+	}
+	streamInterceptors = append(streamInterceptors, loggers.StreamLogger)
+	if o.registerer != nil {
+		streamInterceptors = append(streamInterceptors, requestMetrics.StreamInterceptor)
+	}
+	streamInterceptors = append(streamInterceptors, loggers.PanicStreamInterceptor)
+	if o.limiter != nil {
+		streamInterceptors = append(streamInterceptors, RateLimitStreamInterceptor(o.limiter))
+	}
+
+	serverOpts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(grpcmiddleware.ChainUnaryServer(interceptors...)),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	}
+	if o.tlsConfig != nil {
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(o.tlsConfig)))
+	}
+	grpcServer := grpc.NewServer(serverOpts...)
+	grpcClient := NewService(a)
+	grpcClient.events = o.events
+	proto.RegisterAdServiceServer(grpcServer, grpcClient)
+	healthpb.RegisterHealthServer(grpcServer, NewHealthServer(o.healthChecker))
+	return grpcServer
+}
+
+func NewGRPCServer(port string, a app.App, opts ...Option) (*grpc.Server, net.Listener) {
 	lis, err := net.Listen("tcp", port)
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
 	}
-	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(grpcmiddleware.ChainUnaryServer(
-			loggers.Logger, loggers.PanicInterceptor)))
-	grpcClient := NewService(a)
-	proto.RegisterAdServiceServer(grpcServer, grpcClient)
-	return grpcServer, lis
+	return NewServer(a, opts...), lis
 }
 
-func TestNewGRPCServer(sizeBuff int, a app.App) (*grpc.Server, *bufconn.Listener) {
+func TestNewGRPCServer(sizeBuff int, a app.App, opts ...Option) (*grpc.Server, *bufconn.Listener) {
 	lis := bufconn.Listen(sizeBuff)
-
-	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(grpcmiddleware.ChainUnaryServer(
-			loggers.Logger, loggers.PanicInterceptor)))
-	grpcClient := NewService(a)
-	proto.RegisterAdServiceServer(grpcServer, grpcClient)
-	return grpcServer, lis
+	return NewServer(a, opts...), lis
 }
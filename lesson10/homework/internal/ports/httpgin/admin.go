@@ -0,0 +1,32 @@
+package httpgin
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var ErrUnauthorized = errors.New("admin token is missing or wrong")
+
+// AdminAuth пускает к служебному API только запросы с заголовком Authorization: Bearer <token>.
+// Пустой token закрывает служебный API целиком: открытым по умолчанию он быть не должен
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !ValidAdminToken(token, c.GetHeader("Authorization")) {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse(ErrUnauthorized))
+			return
+		}
+		c.Next()
+	}
+}
+
+// ValidAdminToken проверяет значение заголовка Authorization. Сравнение идёт за постоянное время,
+// чтобы токен нельзя было подобрать по времени ответа
+func ValidAdminToken(token string, authorization string) bool {
+	got, ok := strings.CutPrefix(authorization, "Bearer ")
+	return ok && token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
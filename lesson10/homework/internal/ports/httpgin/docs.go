@@ -0,0 +1,60 @@
+package httpgin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files"
+)
+
+// swaggerInitializer заменяет стартовый скрипт Swagger UI, который по умолчанию открывает petstore
+var swaggerInitializer = []byte(`window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: "../openapi.json",
+    dom_id: "#swagger-ui",
+    deepLinking: true,
+    presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+    plugins: [SwaggerUIBundle.plugins.DownloadUrl],
+    layout: "StandaloneLayout"
+  });
+};
+`)
+
+// DocsRouter отдаёт спецификацию OpenAPI на /openapi.json и Swagger UI на /docs/
+func DocsRouter(r gin.IRouter) {
+	spec, err := OpenAPI()
+	if err != nil {
+		panic(err)
+	}
+
+	r.GET("/openapi.json", func(c *gin.Context) { // Метод для получения спецификации OpenAPI
+		c.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+	})
+	r.GET("/docs/*filepath", swaggerUI) // Метод для просмотра спецификации в Swagger UI
+}
+
+// Метод для просмотра спецификации в Swagger UI
+func swaggerUI(c *gin.Context) {
+	name := c.Param("filepath")
+	switch name {
+	case "/":
+		name = "/index.html"
+	case "/swagger-initializer.js":
+		c.Data(http.StatusOK, "text/javascript; charset=utf-8", swaggerInitializer)
+		return
+	}
+
+	file, err := swaggerFiles.HTTP.Open(name)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil || stat.IsDir() {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	http.ServeContent(c.Writer, c.Request, stat.Name(), stat.ModTime(), file)
+}
@@ -0,0 +1,72 @@
+package httpgin
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"homework10/internal/idempotency"
+	"homework10/internal/ratelimit"
+)
+
+// GatewayRoute - маршрут REST-шлюза: метод и полный путь в синтаксисе google.api.http, /api/v1/ads/{ad_id}
+type GatewayRoute struct {
+	Method string
+	Path   string
+}
+
+type gatewayOptions struct {
+	backend http.Handler
+	routes  []GatewayRoute
+}
+
+// WithGateway отдаёт запросы к API обработчику backend (REST-шлюзу grpc-gateway) вместо обработчиков gin.
+// Шлюз получает те же middleware, что и gin: журнал с request-ID, восстановление после panic, лимиты,
+// Idempotency-Key, токен служебных маршрутов, метрики и трассировку
+func WithGateway(backend http.Handler, routes []GatewayRoute) Option {
+	return func(o *options) {
+		o.gateway = &gatewayOptions{backend: backend, routes: routes}
+	}
+}
+
+// GatewayRouter регистрирует маршруты шлюза с теми же middleware, что и в AppRouter. Маршрут ищется
+// в описании API по методу и форме пути: параметры у шлюза и gin называются по-разному ({id} и :user_id).
+// Маршрут без описания - ошибка сборки сервиса, поэтому, как и gin при конфликте маршрутов, паникуем
+func GatewayRouter(r *gin.RouterGroup, backend http.Handler, routes []GatewayRoute, store idempotency.Store, limiter *ratelimit.Limiter, adminToken string) {
+	apiMiddleware(r, limiter)
+
+	byShape := make(map[string]apiRoute, len(apiRoutes))
+	for _, route := range apiRoutes {
+		byShape[route.method+" "+pathShape(route.path)] = route
+	}
+	handler := gin.WrapH(backend)
+	for _, gw := range routes {
+		path, ok := strings.CutPrefix(gw.Path, OpenAPIPrefix)
+		route, found := byShape[gw.Method+" "+pathShape(path)]
+		if !ok || !found {
+			panic(fmt.Sprintf("gateway route %s %s is not described in the API", gw.Method, gw.Path))
+		}
+
+		var handlers []gin.HandlerFunc
+		if route.admin {
+			handlers = append(handlers, AdminAuth(adminToken))
+		}
+		if route.idempotent {
+			handlers = append(handlers, Idempotency(store))
+		}
+		r.Handle(route.method, route.path, append(handlers, handler)...)
+	}
+}
+
+// pathShape заменяет параметры пути на *, чтобы сравнивать пути gin (/ads/:ad_id) и шлюза (/ads/{ad_id})
+func pathShape(path string) string {
+	segments := strings.Split(path, "/")
+	for idx, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "{") {
+			segments[idx] = "*"
+		}
+	}
+	return strings.Join(segments, "/")
+}
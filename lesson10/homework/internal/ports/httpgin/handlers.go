@@ -2,12 +2,45 @@ package httpgin
 
 import (
 	"errors"
+	"fmt"
+	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin"
+	"homework10/internal/adsio"
 	"homework10/internal/app"
+	"homework10/internal/logging"
+	"homework10/internal/outbox"
+	"homework10/internal/ports/errmap"
+	"homework10/internal/ports/problem"
+	"homework10/internal/ratelimit"
+	"homework10/internal/users"
+	"io"
+	"log/slog"
+	"math"
 	"net/http"
 	"strconv"
+	"time"
 )
 
+var ErrWatchDisabled = errors.New("ad events are not enabled on this server")
+var ErrWatchTooSlow = errors.New("watcher is too slow, resubscribe")
+var ErrBadDateFilter = errors.New("date_creating should start with a date like 2006-01-02")
+
+const (
+	// MaxImportBodySize - наибольший размер файла импорта
+	MaxImportBodySize = 16 << 20
+	// importReadTimeout - сколько читается файл импорта; ReadTimeout сервера для него слишком мал
+	importReadTimeout = 10 * time.Minute
+)
+
+// errorResponse отвечает на ошибку приложения статусом из общей с gRPC таблицы errmap
+func errorResponse(c *gin.Context, err error) {
+	if errors.Is(err, app.ValidateError) {
+		problem.Write(c.Writer, ValidationProblem(err))
+		return
+	}
+	c.JSON(errmap.HTTPStatus(err), AppErrorResponse(err))
+}
+
 // Метод для создания объявления (ad)
 func createAd(a app.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -18,22 +51,12 @@ func createAd(a app.App) gin.HandlerFunc {
 			return
 		}
 
-		ad, ok := a.CreateAd(reqBody.Title, reqBody.Text, reqBody.UserID)
-
-		if errors.Is(ok, app.ValidateError) {
-			c.JSON(http.StatusBadRequest, ErrorResponse(ok))
-			return
-		}
-
-		if errors.Is(ok, app.IncorrectUserId) {
-			c.JSON(http.StatusForbidden, ErrorResponse(ok))
+		ad, ok := a.CreateAd(c.Request.Context(), reqBody.Title, reqBody.Text, reqBody.UserID)
+		if ok != nil {
+			errorResponse(c, ok)
 			return
 		}
 
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, ErrorResponse(err))
-			return
-		}
 		c.JSON(http.StatusOK, AdSuccessResponse(ad))
 	}
 }
@@ -41,24 +64,19 @@ func createAd(a app.App) gin.HandlerFunc {
 // Метод для создания пользователя (user)
 func createUser(a app.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var reqBody createUpdateUserRequest
+		var reqBody createUserRequest
 		err := c.Bind(&reqBody)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, ErrorResponse(err))
 			return
 		}
 
-		user, ok := a.CreateUser(reqBody.NickName, reqBody.Email)
-
-		if errors.Is(ok, app.ValidateError) {
-			c.JSON(http.StatusBadRequest, ErrorResponse(ok))
+		user, ok := a.CreateUser(c.Request.Context(), reqBody.NickName, reqBody.Email)
+		if ok != nil {
+			errorResponse(c, ok)
 			return
 		}
 
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, ErrorResponse(err))
-			return
-		}
 		c.JSON(http.StatusOK, UserSuccessResponse(user))
 	}
 }
@@ -80,14 +98,9 @@ func changeAdStatus(a app.App) gin.HandlerFunc {
 			return
 		}
 
-		ad, ok := a.ChangeAdStatus(int64(num), reqBody.UserID, reqBody.Published)
-		if errors.Is(ok, app.IncorrectUserId) {
-			c.JSON(http.StatusForbidden, ErrorResponse(ok))
-			return
-		}
-
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, ErrorResponse(err))
+		ad, ok := a.ChangeAdStatus(c.Request.Context(), int64(num), reqBody.UserID, reqBody.Published)
+		if ok != nil {
+			errorResponse(c, ok)
 			return
 		}
 
@@ -112,19 +125,9 @@ func updateAd(a app.App) gin.HandlerFunc {
 			return
 		}
 
-		ad, ok := a.UpdateAd(int64(num), reqBody.UserID, reqBody.Title, reqBody.Text)
-		if errors.Is(ok, app.IncorrectUserId) {
-			c.JSON(http.StatusForbidden, ErrorResponse(ok))
-			return
-		}
-
-		if errors.Is(ok, app.ValidateError) {
-			c.JSON(http.StatusBadRequest, ErrorResponse(ok))
-			return
-		}
-
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, ErrorResponse(err))
+		ad, ok := a.UpdateAd(c.Request.Context(), int64(num), reqBody.UserID, reqBody.Title, reqBody.Text)
+		if ok != nil {
+			errorResponse(c, ok)
 			return
 		}
 
@@ -135,7 +138,7 @@ func updateAd(a app.App) gin.HandlerFunc {
 // Метод для редактирования данных пользователя
 func updateUser(a app.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var reqBody createUpdateUserRequest
+		var reqBody updateUserRequest
 		err := c.Bind(&reqBody)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, ErrorResponse(err))
@@ -149,19 +152,9 @@ func updateUser(a app.App) gin.HandlerFunc {
 			return
 		}
 
-		user, ok := a.UpdateUser(int64(num), reqBody.NickName, reqBody.Email)
-		if errors.Is(ok, app.IncorrectUserId) {
-			c.JSON(http.StatusForbidden, ErrorResponse(ok))
-			return
-		}
-
-		if errors.Is(ok, app.ValidateError) {
-			c.JSON(http.StatusBadRequest, ErrorResponse(ok))
-			return
-		}
-
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, ErrorResponse(err))
+		user, ok := a.UpdateUser(c.Request.Context(), int64(num), reqBody.NickName, reqBody.Email, reqBody.Password)
+		if ok != nil {
+			errorResponse(c, ok)
 			return
 		}
 
@@ -172,35 +165,47 @@ func updateUser(a app.App) gin.HandlerFunc {
 // Метод для получения списка выложенных объявлений
 func getListAds(a app.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		filters := make(map[string]any)
-		published := c.Query("published")
-		if published != "" {
-			if published == "true" {
-				filters["published"] = true
-			} else {
-				filters["published"] = false
-			}
+		filters, err := listFilters(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse(err))
+			return
 		}
 
-		userId := c.Query("user_id")
-		if userId != "" {
-			id, errToInt := strconv.Atoi(userId)
-			if errToInt != nil {
-				c.JSON(http.StatusBadRequest, ErrorResponse(errToInt))
-				return
-			}
-			filters["user_id"] = int64(id)
-		}
+		ads := a.GetListAds(c.Request.Context(), filters)
+
+		c.JSON(http.StatusOK, AdsSuccessResponse(ads))
+	}
+}
 
-		dateCreating := c.Query("date_creating")
-		if dateCreating != "" {
-			filters["date_creating"] = dateCreating
+// listFilters собирает фильтры списка объявлений из query: published, user_id, date_creating
+func listFilters(c *gin.Context) (map[string]any, error) {
+	filters := make(map[string]any)
+	published := c.Query("published")
+	if published != "" {
+		if published == "true" {
+			filters["published"] = true
+		} else {
+			filters["published"] = false
 		}
+	}
 
-		ads := a.GetListAds(filters)
+	userId := c.Query("user_id")
+	if userId != "" {
+		id, errToInt := strconv.Atoi(userId)
+		if errToInt != nil {
+			return nil, errToInt
+		}
+		filters["user_id"] = int64(id)
+	}
 
-		c.JSON(http.StatusOK, AdsSuccessResponse(ads))
+	dateCreating := c.Query("date_creating")
+	if dateCreating != "" {
+		if _, err := time.Parse(time.DateOnly, dateCreating[:min(len(dateCreating), len(time.DateOnly))]); err != nil {
+			return nil, ErrBadDateFilter
+		}
+		filters["date_creating"] = dateCreating
 	}
+	return filters, nil
 }
 
 // Метод для вывода объявления по id
@@ -213,9 +218,9 @@ func getAd(a app.App) gin.HandlerFunc {
 			return
 		}
 
-		ad, ok := a.GetAd(int64(num))
-		if errors.Is(ok, app.IncorrectAdId) {
-			c.JSON(http.StatusNotFound, ErrorResponse(ok))
+		ad, ok := a.GetAd(c.Request.Context(), int64(num))
+		if ok != nil {
+			errorResponse(c, ok)
 			return
 		}
 
@@ -223,16 +228,188 @@ func getAd(a app.App) gin.HandlerFunc {
 	}
 }
 
+// Метод для подписки на события объявлений (server-sent events).
+// Если клиент отстаёт больше чем на буфер брокера, последним приходит событие error
+func watchAds(events *outbox.Broker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if events == nil {
+			c.JSON(http.StatusNotImplemented, ErrorResponse(ErrWatchDisabled))
+			return
+		}
+
+		var userID *int64
+		if raw := c.Query("user_id"); raw != "" {
+			id, errToInt := strconv.ParseInt(raw, 10, 64)
+			if errToInt != nil {
+				c.JSON(http.StatusBadRequest, ErrorResponse(errToInt))
+				return
+			}
+			userID = &id
+		}
+
+		ctx := c.Request.Context()
+		ch := events.Subscribe(ctx)
+
+		// поток живёт дольше WriteTimeout сервера, поэтому снимаем дедлайн для этого соединения
+		_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		c.Stream(func(w io.Writer) bool {
+			event, ok := <-ch
+			if !ok {
+				if ctx.Err() == nil {
+					c.Render(-1, sse.Event{Event: "error", Data: ErrorResponse(ErrWatchTooSlow)})
+				}
+				return false
+			}
+			if userID != nil && event.Ad.AuthorID != *userID {
+				return true
+			}
+			c.Render(-1, sse.Event{Id: strconv.FormatInt(event.ID, 10), Event: string(event.Type), Data: AdEventResponse(event)})
+			return true
+		})
+	}
+}
+
+// Метод для импорта объявлений из CSV (text/csv) или NDJSON (application/x-ndjson).
+// Тело читается построчно; ошибки строк попадают в отчёт и не прерывают импорт.
+// Файл ограничен MaxImportBodySize и app.MaxImportRows строк, каждая строка списывает токен из лимита на запись
+func importAds(a app.App, limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format, err := adsio.FormatFromContentType(c.GetHeader("Content-Type"))
+		if err != nil {
+			c.JSON(http.StatusUnsupportedMediaType, ErrorResponse(err))
+			return
+		}
+		reader, err := adsio.NewReader(format, http.MaxBytesReader(c.Writer, c.Request.Body, MaxImportBodySize))
+		if err != nil {
+			c.JSON(http.StatusUnsupportedMediaType, ErrorResponse(err))
+			return
+		}
+
+		// большой файл читается дольше ReadTimeout сервера, поэтому дедлайн этого запроса отодвигается
+		_ = http.NewResponseController(c.Writer).SetReadDeadline(time.Now().Add(importReadTimeout))
+		var wait time.Duration
+		report, err := a.ImportAds(c.Request.Context(), func() (app.ImportRow, error) {
+			row, err := reader.Read()
+			var rowErr *adsio.RowError
+			if err != nil && !errors.As(err, &rowErr) {
+				return app.ImportRow{}, err
+			}
+			if limiter != nil {
+				var ok bool
+				if ok, wait = limiter.Allow(rateLimitKey(c), true); !ok {
+					return app.ImportRow{}, ErrTooManyRequests
+				}
+			}
+			if rowErr != nil {
+				return app.ImportRow{Line: rowErr.Line, Err: fmt.Errorf("%w: %s", app.ValidateError, rowErr.Err)}, nil
+			}
+			return app.ImportRow{Line: row.Line, Title: row.Title, Text: row.Text, UserID: row.UserID}, nil
+		})
+		if err != nil {
+			// отчёт описывает строки до сбоя, чтобы клиент знал, с какого места продолжить
+			code := http.StatusBadRequest
+			var tooLarge *http.MaxBytesError
+			switch {
+			case errors.As(err, &tooLarge), errors.Is(err, app.TooManyImportRows):
+				code = http.StatusRequestEntityTooLarge
+			case errors.Is(err, ErrTooManyRequests):
+				code = http.StatusTooManyRequests
+				c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			}
+			c.JSON(code, gin.H{"data": ImportReportResponse(report), "error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": ImportReportResponse(report), "error": nil})
+	}
+}
+
+// Метод для выгрузки объявлений в CSV или NDJSON (?format=csv|ndjson) по тем же фильтрам, что список
+func exportAds(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.DefaultQuery("format", adsio.FormatNDJSON)
+		writer, err := adsio.NewWriter(format, c.Writer)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse(err))
+			return
+		}
+		filters, err := listFilters(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse(err))
+			return
+		}
+
+		// выгрузка пишется дольше WriteTimeout сервера, поэтому снимаем дедлайн для этого соединения
+		_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+		c.Header("Content-Type", adsio.ContentType(format))
+		c.Header("Content-Disposition", `attachment; filename="ads.`+format+`"`)
+		c.Status(http.StatusOK)
+
+		ctx := c.Request.Context()
+		err = a.ExportAds(ctx, filters, writer.Write)
+		if err == nil {
+			err = writer.Flush()
+		}
+		if err != nil {
+			// заголовки уже отправлены, остаётся оборвать выгрузку и записать причину в лог
+			logging.FromContext(ctx).Warn("ads export interrupted", slog.String("error", err.Error()))
+		}
+	}
+}
+
 // Метод для поиска объявлений по названию
 func getListAdsByTitle(a app.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		title := c.Param("ad_title")
-		ads := a.GetListAdsByTitle(title)
+		ads := a.GetListAdsByTitle(c.Request.Context(), title)
 
 		c.JSON(http.StatusOK, AdsSuccessResponse(ads))
 	}
 }
 
+// Метод для вывода нескольких объявлений по id
+func batchGetAds(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody batchGetRequest
+		if err := c.Bind(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse(err))
+			return
+		}
+
+		found, missing, err := a.BatchGetAds(c.Request.Context(), reqBody.IDs)
+		if err != nil {
+			errorResponse(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, BatchGetAdsResponse(found, missing))
+	}
+}
+
+// Метод для изменения статуса нескольких объявлений пользователя: все или ни одного
+func batchChangeAdStatus(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody batchChangeAdStatusRequest
+		if err := c.Bind(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse(err))
+			return
+		}
+
+		list, err := a.BatchChangeAdStatus(c.Request.Context(), reqBody.AdIDs, reqBody.UserID, reqBody.Published)
+		if err != nil {
+			errorResponse(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, AdsSuccessResponse(list))
+	}
+}
+
 // Метод для вывода пользователя по id
 func getUser(a app.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -243,9 +420,113 @@ func getUser(a app.App) gin.HandlerFunc {
 			return
 		}
 
-		user, ok := a.GetUser(int64(num))
-		if errors.Is(ok, app.IncorrectUserId) {
-			c.JSON(http.StatusNotFound, ErrorResponse(ok))
+		user, ok := a.GetUser(c.Request.Context(), int64(num))
+		if ok != nil {
+			errorResponse(c, ok)
+			return
+		}
+
+		c.JSON(http.StatusOK, PublicUserResponse(user))
+	}
+}
+
+// Метод для поиска пользователя по email
+func getUserByEmail(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody userByEmailRequest
+		if err := c.Bind(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse(err))
+			return
+		}
+
+		user, err := a.GetUserByEmail(c.Request.Context(), reqBody.Email)
+		if err != nil {
+			errorResponse(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, PublicUserResponse(user))
+	}
+}
+
+// Метод для редактирования профиля пользователя
+func updateProfile(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse(err))
+			return
+		}
+		var reqBody updateProfileRequest
+		if err := c.Bind(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse(err))
+			return
+		}
+
+		user, err := a.UpdateProfile(c.Request.Context(), userId, reqBody.Password, users.Profile{
+			DisplayName: reqBody.DisplayName,
+			Phone:       reqBody.Phone,
+			City:        reqBody.City,
+			AvatarURL:   reqBody.AvatarURL,
+		})
+		if err != nil {
+			errorResponse(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, ProfileResponse(user))
+	}
+}
+
+// Метод для вывода опубликованных объявлений пользователя
+func getUserAds(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse(err))
+			return
+		}
+
+		list, err := a.GetUserAds(c.Request.Context(), userId)
+		if err != nil {
+			errorResponse(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, AdsSuccessResponse(list))
+	}
+}
+
+// Метод для повторной отправки письма с подтверждением email
+func requestEmailVerification(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := strconv.ParseInt(c.Param("user_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse(err))
+			return
+		}
+
+		if err := a.RequestEmailVerification(c.Request.Context(), userId); err != nil {
+			errorResponse(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, SuccessResponse())
+	}
+}
+
+// Метод для подтверждения email токеном из письма
+func confirmEmail(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody confirmEmailRequest
+		if err := c.Bind(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse(err))
+			return
+		}
+
+		user, err := a.ConfirmEmail(c.Request.Context(), reqBody.Token)
+		if err != nil {
+			errorResponse(c, err)
 			return
 		}
 
@@ -253,6 +534,61 @@ func getUser(a app.App) gin.HandlerFunc {
 	}
 }
 
+// Метод для отправки письма со сбросом пароля
+func requestPasswordReset(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody passwordResetRequest
+		if err := c.Bind(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse(err))
+			return
+		}
+
+		if err := a.RequestPasswordReset(c.Request.Context(), reqBody.Email); err != nil {
+			errorResponse(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, SuccessResponse())
+	}
+}
+
+// Метод для установки нового пароля токеном из письма
+func resetPassword(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody resetPasswordRequest
+		if err := c.Bind(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse(err))
+			return
+		}
+
+		if err := a.ResetPassword(c.Request.Context(), reqBody.Token, reqBody.Password); err != nil {
+			errorResponse(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, SuccessResponse())
+	}
+}
+
+// Метод для вывода нескольких пользователей по id
+func batchGetUsers(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody batchGetRequest
+		if err := c.Bind(&reqBody); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse(err))
+			return
+		}
+
+		found, missing, err := a.BatchGetUsers(c.Request.Context(), reqBody.IDs)
+		if err != nil {
+			errorResponse(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, BatchGetUsersResponse(found, missing))
+	}
+}
+
 // Метод для удаления объявления по id
 func deleteUser(a app.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -263,9 +599,9 @@ func deleteUser(a app.App) gin.HandlerFunc {
 			return
 		}
 
-		ok := a.DeleteUser(int64(num))
-		if errors.Is(ok, app.IncorrectUserId) {
-			c.JSON(http.StatusNotFound, ErrorResponse(ok))
+		ok := a.DeleteUser(c.Request.Context(), int64(num))
+		if ok != nil {
+			errorResponse(c, ok)
 			return
 		}
 
@@ -290,21 +626,66 @@ func deleteAd(a app.App) gin.HandlerFunc {
 			return
 		}
 
-		ok := a.DeleteAd(int64(num), reqBody.UserID)
-		if errors.Is(ok, app.IncorrectAdId) {
-			c.JSON(http.StatusNotFound, ErrorResponse(ok))
+		ok := a.DeleteAd(c.Request.Context(), int64(num), reqBody.UserID)
+		if ok != nil {
+			errorResponse(c, ok)
+			return
+		}
+
+		c.JSON(http.StatusOK, DeleteSuccessResponse())
+	}
+}
+
+// Метод для подписки на события объявлений
+func createWebhook(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reqBody createWebhookRequest
+		err := c.Bind(&reqBody)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse(err))
 			return
 		}
-		if errors.Is(ok, app.IncorrectUserId) {
-			c.JSON(http.StatusForbidden, ErrorResponse(ok))
+
+		webhook, ok := a.CreateWebhook(c.Request.Context(), reqBody.URL, reqBody.Secret)
+		if ok != nil {
+			errorResponse(c, ok)
 			return
 		}
 
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, ErrorResponse(err))
+		c.JSON(http.StatusOK, WebhookSuccessResponse(webhook))
+	}
+}
+
+// Метод для вывода списка подписок
+func getWebhooks(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, WebhooksSuccessResponse(a.GetWebhooks(c.Request.Context())))
+	}
+}
+
+// Метод для удаления подписки по id
+func deleteWebhook(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		webhookId := c.Param("webhook_id")
+		num, errToInt := strconv.Atoi(webhookId)
+		if errToInt != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse(errToInt))
+			return
+		}
+
+		ok := a.DeleteWebhook(c.Request.Context(), int64(num))
+		if ok != nil {
+			errorResponse(c, ok)
 			return
 		}
 
 		c.JSON(http.StatusOK, DeleteSuccessResponse())
 	}
 }
+
+// Метод для вывода недоставленных событий
+func getDeadLetters(a app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, DeadLettersSuccessResponse(a.GetDeadLetters(c.Request.Context())))
+	}
+}
@@ -3,7 +3,9 @@ package httpgin
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"homework10/internal/ads"
 	"homework10/internal/app"
@@ -43,6 +45,7 @@ var (
 	ErrBadRequest = fmt.Errorf("bad request")
 	ErrForbidden  = fmt.Errorf("forbidden")
 	ErrNotFound   = fmt.Errorf("not found")
+	ErrInternal   = fmt.Errorf("internal error")
 )
 
 type testClient struct {
@@ -76,6 +79,9 @@ func (tc *testClient) getResponse(req *http.Request, out any) error {
 		if resp.StatusCode == http.StatusNotFound {
 			return ErrNotFound
 		}
+		if resp.StatusCode == http.StatusInternalServerError {
+			return ErrInternal
+		}
 		return fmt.Errorf("unexpected status code: %s", resp.Status)
 	}
 
@@ -216,9 +222,11 @@ func (tc *testClient) getListAdsWithFilter(filters map[string]any) (adsResponse,
 }
 
 type userData struct {
-	ID       int64  `json:"id"`
-	Nickname string `json:"nickname"`
-	Email    string `json:"email"`
+	ID          int64  `json:"id"`
+	Nickname    string `json:"nickname"`
+	Email       string `json:"email"`
+	DisplayName string `json:"display_name"`
+	Phone       string `json:"phone"`
 }
 
 type userDataResponse struct {
@@ -252,10 +260,11 @@ func (tc *testClient) createUser(nickname string, email string) (userDataRespons
 	return response, nil
 }
 
-func (tc *testClient) updateUser(userId int64, nickname string, email string) (userDataResponse, error) {
+func (tc *testClient) updateUser(userId int64, nickname string, email string, password string) (userDataResponse, error) {
 	body := map[string]any{
 		"nickname": nickname,
 		"email":    email,
+		"password": password,
 	}
 
 	data, err := json.Marshal(body)
@@ -294,6 +303,35 @@ func (tc *testClient) getAdById(adId int64) (adDataResponse, error) {
 	return response, nil
 }
 
+func (tc *testClient) updateProfile(userId int64, password string, displayName string, phone string) (userDataResponse, error) {
+	data, err := json.Marshal(map[string]any{"password": password, "display_name": displayName, "phone": phone})
+	if err != nil {
+		return userDataResponse{}, fmt.Errorf("unable to marshal: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf(tc.baseURL+"/api/v1/users/%d/profile", userId), bytes.NewReader(data))
+	if err != nil {
+		return userDataResponse{}, fmt.Errorf("unable to create request: %w", err)
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+
+	var response userDataResponse
+	err = tc.getResponse(req, &response)
+	return response, err
+}
+
+func (tc *testClient) getUserAds(userId int64) (adsResponse, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf(tc.baseURL+"/api/v1/users/%d/ads", userId), nil)
+	if err != nil {
+		return adsResponse{}, fmt.Errorf("unable to create request: %w", err)
+	}
+
+	var response adsResponse
+	err = tc.getResponse(req, &response)
+	return response, err
+}
+
 func (tc *testClient) getUserById(userId int64) (userDataResponse, error) {
 	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf(tc.baseURL+"/api/v1/users/%d", userId), nil)
 	if err != nil {
@@ -410,7 +448,7 @@ func EqualUsers(response *userData, ad *users.User) bool {
 
 func (s *AdServiceTestSuite) TestAdService_CreateAd() {
 	expect := &ads.Ad{ID: 1, Title: "title 1", Text: "text 1", AuthorID: 1}
-	s.app.On("CreateAd", expect.Title, expect.Text, expect.AuthorID).Return(expect, nil)
+	s.app.On("CreateAd", mock.Anything, expect.Title, expect.Text, expect.AuthorID).Return(expect, nil)
 
 	client := getTestClient(&s.app)
 
@@ -421,7 +459,7 @@ func (s *AdServiceTestSuite) TestAdService_CreateAd() {
 
 func (s *AdServiceTestSuite) TestAdService_CreateAdIncorrectUserId() {
 	expect := &ads.Ad{ID: 1, Title: "title 1", Text: "text 1", AuthorID: 1}
-	s.app.On("CreateAd", expect.Title, expect.Text, expect.AuthorID).Return(expect, app.IncorrectUserId)
+	s.app.On("CreateAd", mock.Anything, expect.Title, expect.Text, expect.AuthorID).Return(expect, app.IncorrectUserId)
 
 	client := getTestClient(&s.app)
 
@@ -431,7 +469,7 @@ func (s *AdServiceTestSuite) TestAdService_CreateAdIncorrectUserId() {
 
 func (s *AdServiceTestSuite) TestAdService_CreateAdValidationErr() {
 	expect := &ads.Ad{ID: 1, Title: "", Text: "text 1", AuthorID: 1}
-	s.app.On("CreateAd", expect.Title, expect.Text, expect.AuthorID).Return(expect, app.ValidateError)
+	s.app.On("CreateAd", mock.Anything, expect.Title, expect.Text, expect.AuthorID).Return(expect, app.ValidateError)
 
 	client := getTestClient(&s.app)
 
@@ -441,7 +479,7 @@ func (s *AdServiceTestSuite) TestAdService_CreateAdValidationErr() {
 
 func (s *AdServiceTestSuite) TestAdService_CreateUser() {
 	expect := &users.User{ID: 1, Nickname: "nickname", Email: "email"}
-	s.app.On("CreateUser", expect.Nickname, expect.Email).Return(expect, nil)
+	s.app.On("CreateUser", mock.Anything, expect.Nickname, expect.Email).Return(expect, nil)
 
 	client := getTestClient(&s.app)
 
@@ -452,7 +490,7 @@ func (s *AdServiceTestSuite) TestAdService_CreateUser() {
 
 func (s *AdServiceTestSuite) TestAdService_CreateUserValidationErr() {
 	expect := &users.User{ID: 1, Nickname: "", Email: "email"}
-	s.app.On("CreateUser", expect.Nickname, expect.Email).Return(expect, app.ValidateError)
+	s.app.On("CreateUser", mock.Anything, expect.Nickname, expect.Email).Return(expect, app.ValidateError)
 
 	client := getTestClient(&s.app)
 
@@ -462,7 +500,7 @@ func (s *AdServiceTestSuite) TestAdService_CreateUserValidationErr() {
 
 func (s *AdServiceTestSuite) TestAdService_ChangeAdStatus() {
 	expect := &ads.Ad{ID: 1, Title: "title 1", Text: "text 1", AuthorID: 1}
-	s.app.On("ChangeAdStatus", expect.ID, expect.AuthorID, expect.Published).Return(expect, nil)
+	s.app.On("ChangeAdStatus", mock.Anything, expect.ID, expect.AuthorID, expect.Published).Return(expect, nil)
 
 	client := getTestClient(&s.app)
 
@@ -473,7 +511,7 @@ func (s *AdServiceTestSuite) TestAdService_ChangeAdStatus() {
 
 func (s *AdServiceTestSuite) TestAdService_ChangeAdStatusIncorrectUserId() {
 	expect := &ads.Ad{ID: 1, Title: "title 1", Text: "text 1", AuthorID: 1}
-	s.app.On("ChangeAdStatus", expect.ID, expect.AuthorID, expect.Published).Return(expect, app.IncorrectUserId)
+	s.app.On("ChangeAdStatus", mock.Anything, expect.ID, expect.AuthorID, expect.Published).Return(expect, app.IncorrectUserId)
 
 	client := getTestClient(&s.app)
 
@@ -483,7 +521,7 @@ func (s *AdServiceTestSuite) TestAdService_ChangeAdStatusIncorrectUserId() {
 
 func (s *AdServiceTestSuite) TestAdService_UpdateAd() {
 	expect := &ads.Ad{ID: 1, Title: "title 1", Text: "text 1", AuthorID: 1}
-	s.app.On("UpdateAd", expect.ID, expect.AuthorID, expect.Title, expect.Text).Return(expect, nil)
+	s.app.On("UpdateAd", mock.Anything, expect.ID, expect.AuthorID, expect.Title, expect.Text).Return(expect, nil)
 
 	client := getTestClient(&s.app)
 
@@ -494,7 +532,7 @@ func (s *AdServiceTestSuite) TestAdService_UpdateAd() {
 
 func (s *AdServiceTestSuite) TestAdService_UpdateAdIncorrectUserId() {
 	expect := &ads.Ad{ID: 1, Title: "title 1", Text: "text 1", AuthorID: 1}
-	s.app.On("UpdateAd", expect.ID, expect.AuthorID, expect.Title, expect.Text).Return(expect, app.IncorrectUserId)
+	s.app.On("UpdateAd", mock.Anything, expect.ID, expect.AuthorID, expect.Title, expect.Text).Return(expect, app.IncorrectUserId)
 
 	client := getTestClient(&s.app)
 
@@ -504,7 +542,7 @@ func (s *AdServiceTestSuite) TestAdService_UpdateAdIncorrectUserId() {
 
 func (s *AdServiceTestSuite) TestAdService_UpdateAdValidationErr() {
 	expect := &ads.Ad{ID: 1, Title: "", Text: "text 1", AuthorID: 1}
-	s.app.On("UpdateAd", expect.ID, expect.AuthorID, expect.Title, expect.Text).Return(expect, app.ValidateError)
+	s.app.On("UpdateAd", mock.Anything, expect.ID, expect.AuthorID, expect.Title, expect.Text).Return(expect, app.ValidateError)
 
 	client := getTestClient(&s.app)
 
@@ -514,32 +552,32 @@ func (s *AdServiceTestSuite) TestAdService_UpdateAdValidationErr() {
 
 func (s *AdServiceTestSuite) TestAdService_UpdateUser() {
 	expect := &users.User{ID: 1, Nickname: "nickname", Email: "email"}
-	s.app.On("UpdateUser", expect.ID, expect.Nickname, expect.Email).Return(expect, nil)
+	s.app.On("UpdateUser", mock.Anything, expect.ID, expect.Nickname, expect.Email, "password").Return(expect, nil)
 
 	client := getTestClient(&s.app)
 
-	got, err := client.updateUser(expect.ID, expect.Nickname, expect.Email)
+	got, err := client.updateUser(expect.ID, expect.Nickname, expect.Email, "password")
 	s.NoError(err)
 	s.True(EqualUsers(&got.Data, expect))
 }
 
 func (s *AdServiceTestSuite) TestAdService_UpdateUserValidationErr() {
 	expect := &users.User{ID: 1, Nickname: "", Email: "email"}
-	s.app.On("UpdateUser", expect.ID, expect.Nickname, expect.Email).Return(expect, app.ValidateError)
+	s.app.On("UpdateUser", mock.Anything, expect.ID, expect.Nickname, expect.Email, "password").Return(expect, app.ValidateError)
 
 	client := getTestClient(&s.app)
 
-	_, err := client.updateUser(expect.ID, expect.Nickname, expect.Email)
+	_, err := client.updateUser(expect.ID, expect.Nickname, expect.Email, "password")
 	s.ErrorIs(err, ErrBadRequest)
 }
 
 func (s *AdServiceTestSuite) TestAdService_UpdateUserIncorrectUserId() {
 	expect := &users.User{ID: 1, Nickname: "", Email: "email"}
-	s.app.On("UpdateUser", expect.ID, expect.Nickname, expect.Email).Return(expect, app.IncorrectUserId)
+	s.app.On("UpdateUser", mock.Anything, expect.ID, expect.Nickname, expect.Email, "password").Return(expect, app.IncorrectUserId)
 
 	client := getTestClient(&s.app)
 
-	_, err := client.updateUser(expect.ID, expect.Nickname, expect.Email)
+	_, err := client.updateUser(expect.ID, expect.Nickname, expect.Email, "password")
 	s.ErrorIs(err, ErrForbidden)
 }
 
@@ -553,7 +591,7 @@ func (s *AdServiceTestSuite) TestAdService_GetListAds() {
 	adsList := []ads.Ad{expect1, expect2}
 
 	filters := map[string]any{"user_id": userId, "published": published, "date_creating": dateCreating}
-	s.app.On("GetListAds", filters).Return(adsList, nil)
+	s.app.On("GetListAds", mock.Anything, filters).Return(adsList, nil)
 
 	client := getTestClient(&s.app)
 	response, err := client.getListAdsWithFilter(filters)
@@ -563,7 +601,7 @@ func (s *AdServiceTestSuite) TestAdService_GetListAds() {
 
 func (s *AdServiceTestSuite) TestAdService_GetAd() {
 	expect := &ads.Ad{ID: 1, Title: "", Text: "text 1", AuthorID: 1}
-	s.app.On("GetAd", expect.ID).Return(expect, nil)
+	s.app.On("GetAd", mock.Anything, expect.ID).Return(expect, nil)
 
 	client := getTestClient(&s.app)
 
@@ -574,7 +612,7 @@ func (s *AdServiceTestSuite) TestAdService_GetAd() {
 
 func (s *AdServiceTestSuite) TestAdService_GetAdNotFound() {
 	expect := &ads.Ad{ID: 1, Title: "", Text: "text 1", AuthorID: 1}
-	s.app.On("GetAd", expect.ID).Return(expect, app.IncorrectAdId)
+	s.app.On("GetAd", mock.Anything, expect.ID).Return(expect, app.IncorrectAdId)
 
 	client := getTestClient(&s.app)
 
@@ -582,13 +620,31 @@ func (s *AdServiceTestSuite) TestAdService_GetAdNotFound() {
 	s.ErrorIs(err, ErrNotFound)
 }
 
+func (s *AdServiceTestSuite) TestAdService_GetAdInternalError() {
+	s.app.On("GetAd", mock.Anything, int64(1)).Return(nil, errors.New("storage is unavailable"))
+
+	client := getTestClient(&s.app)
+
+	_, err := client.getAdById(1)
+	s.ErrorIs(err, ErrInternal)
+}
+
+func (s *AdServiceTestSuite) TestAdService_ChangeAdStatusNotFound() {
+	s.app.On("ChangeAdStatus", mock.Anything, int64(1), int64(1), true).Return(nil, app.IncorrectAdId)
+
+	client := getTestClient(&s.app)
+
+	_, err := client.changeAdStatus(1, 1, true)
+	s.ErrorIs(err, ErrNotFound)
+}
+
 func (s *AdServiceTestSuite) TestAdService_GetListAdsByTitle() {
 	expect1 := ads.Ad{ID: 1, Title: "title", Text: "text 1", AuthorID: 1, Published: true}
 	expect2 := ads.Ad{ID: 2, Title: "title", Text: "text 2", AuthorID: 1, Published: true}
 	adsList := []ads.Ad{expect1, expect2}
 
 	title := expect1.Title
-	s.app.On("GetListAdsByTitle", title).Return(adsList, nil)
+	s.app.On("GetListAdsByTitle", mock.Anything, title).Return(adsList, nil)
 
 	client := getTestClient(&s.app)
 
@@ -599,18 +655,19 @@ func (s *AdServiceTestSuite) TestAdService_GetListAdsByTitle() {
 
 func (s *AdServiceTestSuite) TestAdService_GetUser() {
 	expect := &users.User{ID: 1, Nickname: "nickname", Email: "email"}
-	s.app.On("GetUser", expect.ID).Return(expect, nil)
+	s.app.On("GetUser", mock.Anything, expect.ID).Return(expect, nil)
 
 	client := getTestClient(&s.app)
 
 	got, err := client.getUserById(expect.ID)
 	s.NoError(err)
-	s.True(EqualUsers(&got.Data, expect))
+	s.Equal(expect.Nickname, got.Data.Nickname)
+	s.Empty(got.Data.Email, "профиль читается без email")
 }
 
 func (s *AdServiceTestSuite) TestAdService_GetUserNotFound() {
 	expect := &users.User{ID: 1, Nickname: "nickname", Email: "email"}
-	s.app.On("GetUser", expect.ID).Return(expect, app.IncorrectUserId)
+	s.app.On("GetUser", mock.Anything, expect.ID).Return(nil, app.UserNotFound)
 
 	client := getTestClient(&s.app)
 
@@ -618,9 +675,58 @@ func (s *AdServiceTestSuite) TestAdService_GetUserNotFound() {
 	s.ErrorIs(err, ErrNotFound)
 }
 
+func (s *AdServiceTestSuite) TestAdService_UpdateProfile() {
+	profile := users.Profile{DisplayName: "Buda", Phone: "+79991234567"}
+	expect := &users.User{ID: 1, Nickname: "nickname", Email: "email", Profile: profile}
+	s.app.On("UpdateProfile", mock.Anything, expect.ID, "password", profile).Return(expect, nil)
+
+	client := getTestClient(&s.app)
+
+	got, err := client.updateProfile(expect.ID, "password", profile.DisplayName, profile.Phone)
+	s.NoError(err)
+	s.Equal("Buda", got.Data.DisplayName)
+	s.Equal("+79991234567", got.Data.Phone, "ответ на изменение профиля отдаётся с телефоном")
+	s.Equal("email", got.Data.Email)
+}
+
+func (s *AdServiceTestSuite) TestAdService_UpdateProfileWrongPassword() {
+	s.app.On("UpdateProfile", mock.Anything, int64(1), "wrong", mock.Anything).Return(nil, app.WrongPassword)
+
+	client := getTestClient(&s.app)
+
+	_, err := client.updateProfile(1, "wrong", "Buda", "")
+	s.ErrorIs(err, ErrForbidden)
+}
+
+func (s *AdServiceTestSuite) TestAdService_UpdateProfileValidationErr() {
+	s.app.On("UpdateProfile", mock.Anything, int64(1), mock.Anything, mock.Anything).
+		Return(nil, &app.InvalidFieldsError{Violations: []app.FieldViolation{{Field: "phone", Description: "should be a phone number"}}})
+
+	client := getTestClient(&s.app)
+
+	_, err := client.updateProfile(1, "password", "Buda", "8 999")
+	s.ErrorIs(err, ErrBadRequest)
+}
+
+func (s *AdServiceTestSuite) TestAdService_GetUserAds() {
+	adsList := []ads.Ad{{ID: 2, Title: "title 2", AuthorID: 1, Published: true}, {ID: 1, Title: "title 1", AuthorID: 1, Published: true}}
+	s.app.On("GetUserAds", mock.Anything, int64(1)).Return(adsList, nil)
+	s.app.On("GetUserAds", mock.Anything, int64(2)).Return(nil, app.UserNotFound)
+
+	client := getTestClient(&s.app)
+
+	response, err := client.getUserAds(1)
+	s.NoError(err)
+	s.Len(response.Data, 2)
+	s.True(EqualAdsLists(response.Data, adsList))
+
+	_, err = client.getUserAds(2)
+	s.ErrorIs(err, ErrNotFound)
+}
+
 func (s *AdServiceTestSuite) TestAdService_DeleteUser() {
 	expect := &users.User{ID: 1, Nickname: "nickname", Email: "email"}
-	s.app.On("DeleteUser", expect.ID).Return(nil)
+	s.app.On("DeleteUser", mock.Anything, expect.ID).Return(nil)
 
 	client := getTestClient(&s.app)
 
@@ -628,9 +734,9 @@ func (s *AdServiceTestSuite) TestAdService_DeleteUser() {
 	s.NoError(err)
 }
 
-func (s *AdServiceTestSuite) TestAdService_DeleteUserIncorrectUserId() {
+func (s *AdServiceTestSuite) TestAdService_DeleteUserNotFound() {
 	expect := &users.User{ID: 1, Nickname: "nickname", Email: "email"}
-	s.app.On("DeleteUser", expect.ID).Return(app.IncorrectUserId)
+	s.app.On("DeleteUser", mock.Anything, expect.ID).Return(app.UserNotFound)
 
 	client := getTestClient(&s.app)
 
@@ -640,7 +746,7 @@ func (s *AdServiceTestSuite) TestAdService_DeleteUserIncorrectUserId() {
 
 func (s *AdServiceTestSuite) TestAdService_DeleteAd() {
 	expect := &ads.Ad{ID: 1, Title: "", Text: "text 1", AuthorID: 1}
-	s.app.On("DeleteAd", expect.ID, expect.AuthorID).Return(nil)
+	s.app.On("DeleteAd", mock.Anything, expect.ID, expect.AuthorID).Return(nil)
 
 	client := getTestClient(&s.app)
 
@@ -650,7 +756,7 @@ func (s *AdServiceTestSuite) TestAdService_DeleteAd() {
 
 func (s *AdServiceTestSuite) TestAdService_DeleteAdNotFound() {
 	expect := &ads.Ad{ID: 1, Title: "", Text: "text 1", AuthorID: 1}
-	s.app.On("DeleteAd", expect.ID, expect.AuthorID).Return(app.IncorrectAdId)
+	s.app.On("DeleteAd", mock.Anything, expect.ID, expect.AuthorID).Return(app.IncorrectAdId)
 
 	client := getTestClient(&s.app)
 
@@ -660,7 +766,7 @@ func (s *AdServiceTestSuite) TestAdService_DeleteAdNotFound() {
 
 func (s *AdServiceTestSuite) TestAdService_DeleteAdIncorrectUserId() {
 	expect := &ads.Ad{ID: 1, Title: "", Text: "text 1", AuthorID: 1}
-	s.app.On("DeleteAd", expect.ID, expect.AuthorID).Return(app.IncorrectUserId)
+	s.app.On("DeleteAd", mock.Anything, expect.ID, expect.AuthorID).Return(app.IncorrectUserId)
 
 	client := getTestClient(&s.app)
 
@@ -0,0 +1,34 @@
+package httpgin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"homework10/internal/health"
+)
+
+// HealthRouter регистрирует пробы для оркестратора вне /api/v1
+func HealthRouter(r gin.IRouter, checker *health.Checker) {
+	r.GET("/healthz", liveness())        // Метод для проверки, что процесс жив
+	r.GET("/readyz", readiness(checker)) // Метод для проверки готовности принимать запросы
+}
+
+// Метод для проверки, что процесс жив
+func liveness() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": health.StatusOK})
+	}
+}
+
+// Метод для проверки готовности принимать запросы
+func readiness(checker *health.Checker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result := checker.Check(c.Request.Context())
+		code := http.StatusOK
+		if !result.Ready() {
+			code = http.StatusServiceUnavailable
+		}
+		c.JSON(code, gin.H{"status": result.Status, "checks": result.Checks})
+	}
+}
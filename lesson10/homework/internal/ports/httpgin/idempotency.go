@@ -0,0 +1,82 @@
+package httpgin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"homework10/internal/idempotency"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotent-Replayed"
+)
+
+var ErrIdempotencyKeyReused = errors.New("idempotency key was already used with a different request")
+
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency сохраняет успешный ответ на запрос с заголовком Idempotency-Key и отдаёт его повторно
+// на ретраи с тем же ключом и телом. Тот же ключ с другим телом получает 422
+func Idempotency(store idempotency.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, ErrorResponse(err))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.Sum256(body)
+		requestHash := hex.EncodeToString(hash[:])
+		storeKey := "http:" + c.Request.Method + ":" + c.FullPath() + ":" + key
+
+		unlock := store.Lock(storeKey)
+		defer unlock()
+
+		if record, ok := store.Get(storeKey); ok {
+			if record.RequestHash != requestHash {
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, ErrorResponse(ErrIdempotencyKeyReused))
+				return
+			}
+
+			c.Header(IdempotencyReplayedHeader, "true")
+			c.Data(record.StatusCode, "application/json; charset=utf-8", record.Body)
+			c.Abort()
+			return
+		}
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		if status := recorder.Status(); status >= 200 && status < 300 {
+			store.Save(storeKey, idempotency.Record{RequestHash: requestHash, StatusCode: status, Body: recorder.body.Bytes()})
+		}
+	}
+}
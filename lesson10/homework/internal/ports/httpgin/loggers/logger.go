@@ -1,18 +1,49 @@
 package loggers
 
 import (
-	"github.com/gin-gonic/gin"
-	"log"
+	"log/slog"
 	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"homework10/internal/logging"
 )
 
+// Logger берёт X-Request-ID из запроса (или генерирует новый), возвращает его в ответе
+// и кладёт в контекст запроса логгер с этим идентификатором
 func Logger() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		log.Printf("Started %s %s", c.Request.Method, c.Request.URL.Path)
+		start := time.Now()
+
+		requestID := c.GetHeader(logging.RequestIDHeader)
+		if !logging.ValidRequestID(requestID) {
+			requestID = logging.NewRequestID()
+		}
+		c.Header(logging.RequestIDHeader, requestID)
+
+		logger := logging.FromContext(c.Request.Context()).With(slog.String("request_id", requestID))
+		ctx := logging.WithRequestID(c.Request.Context(), requestID)
+		c.Request = c.Request.WithContext(logging.WithLogger(ctx, logger))
 
 		c.Next()
 
-		log.Printf("Completed %s %s %d", c.Request.Method, c.Request.URL.Path, c.Writer.Status())
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		} else if status >= http.StatusBadRequest {
+			level = slog.LevelWarn
+		}
+
+		logger.LogAttrs(c.Request.Context(), level, "http request",
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+		)
 	}
 }
 
@@ -20,7 +51,7 @@ func RecoveryWithLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		defer func() {
 			if err := recover(); err != nil {
-				log.Printf("panic occurred: %v", err)
+				logging.FromContext(c.Request.Context()).Error("panic occurred", slog.Any("panic", err))
 
 				c.AbortWithStatus(http.StatusInternalServerError)
 			}
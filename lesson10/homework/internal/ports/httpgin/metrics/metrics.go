@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"homework10/internal/app"
+)
+
+const namespace = "adservice"
+
+type Metrics struct {
+	requests *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+}
+
+// New регистрирует метрики HTTP-сервера в reg. Каждый сервер должен получать собственный
+// реестр (или общий с gRPC-сервером), иначе повторная регистрация паникует
+func New(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by route and status.",
+		}, []string{"method", "route", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+	}
+	reg.MustRegister(m.requests, m.latency)
+	return m
+}
+
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		m.requests.WithLabelValues(c.Request.Method, route, status).Inc()
+		m.latency.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+type statsCollector struct {
+	a            app.App
+	ads          *prometheus.Desc
+	publishedAds *prometheus.Desc
+	users        *prometheus.Desc
+}
+
+// NewStatsCollector отдаёт бизнес-метрики, которые на каждый сбор читаются из репозитория
+func NewStatsCollector(a app.App) prometheus.Collector {
+	return &statsCollector{
+		a:            a,
+		ads:          prometheus.NewDesc(namespace+"_ads", "Number of ads.", nil, nil),
+		publishedAds: prometheus.NewDesc(namespace+"_published_ads", "Number of published ads.", nil, nil),
+		users:        prometheus.NewDesc(namespace+"_users", "Number of users.", nil, nil),
+	}
+}
+
+func (c *statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.ads
+	ch <- c.publishedAds
+	ch <- c.users
+}
+
+func (c *statsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.a.GetStats(context.Background())
+	ch <- prometheus.MustNewConstMetric(c.ads, prometheus.GaugeValue, float64(stats.Ads))
+	ch <- prometheus.MustNewConstMetric(c.publishedAds, prometheus.GaugeValue, float64(stats.PublishedAds))
+	ch <- prometheus.MustNewConstMetric(c.users, prometheus.GaugeValue, float64(stats.Users))
+}
@@ -4,9 +4,14 @@ package mocks
 
 import (
 	ads "homework10/internal/ads"
+	app "homework10/internal/app"
+
+	context "context"
 
 	mock "github.com/stretchr/testify/mock"
 
+	outbox "homework10/internal/outbox"
+
 	users "homework10/internal/users"
 )
 
@@ -15,25 +20,147 @@ type App struct {
 	mock.Mock
 }
 
-// ChangeAdStatus provides a mock function with given fields: adId, userId, published
-func (_m *App) ChangeAdStatus(adId int64, userId int64, published bool) (*ads.Ad, error) {
-	ret := _m.Called(adId, userId, published)
+// BatchChangeAdStatus provides a mock function with given fields: ctx, adIds, userId, published
+func (_m *App) BatchChangeAdStatus(ctx context.Context, adIds []int64, userId int64, published bool) ([]ads.Ad, error) {
+	ret := _m.Called(ctx, adIds, userId, published)
+
+	var r0 []ads.Ad
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int64, int64, bool) ([]ads.Ad, error)); ok {
+		return rf(ctx, adIds, userId, published)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int64, int64, bool) []ads.Ad); ok {
+		r0 = rf(ctx, adIds, userId, published)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ads.Ad)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int64, int64, bool) error); ok {
+		r1 = rf(ctx, adIds, userId, published)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// BatchGetAds provides a mock function with given fields: ctx, ids
+func (_m *App) BatchGetAds(ctx context.Context, ids []int64) ([]ads.Ad, []int64, error) {
+	ret := _m.Called(ctx, ids)
+
+	var r0 []ads.Ad
+	var r1 []int64
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int64) ([]ads.Ad, []int64, error)); ok {
+		return rf(ctx, ids)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int64) []ads.Ad); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ads.Ad)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int64) []int64); ok {
+		r1 = rf(ctx, ids)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]int64)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, []int64) error); ok {
+		r2 = rf(ctx, ids)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// BatchGetUsers provides a mock function with given fields: ctx, ids
+func (_m *App) BatchGetUsers(ctx context.Context, ids []int64) ([]users.User, []int64, error) {
+	ret := _m.Called(ctx, ids)
+
+	var r0 []users.User
+	var r1 []int64
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, []int64) ([]users.User, []int64, error)); ok {
+		return rf(ctx, ids)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []int64) []users.User); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]users.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []int64) []int64); ok {
+		r1 = rf(ctx, ids)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]int64)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, []int64) error); ok {
+		r2 = rf(ctx, ids)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ChangeAdStatus provides a mock function with given fields: ctx, adId, userId, published
+func (_m *App) ChangeAdStatus(ctx context.Context, adId int64, userId int64, published bool) (*ads.Ad, error) {
+	ret := _m.Called(ctx, adId, userId, published)
 
 	var r0 *ads.Ad
 	var r1 error
-	if rf, ok := ret.Get(0).(func(int64, int64, bool) (*ads.Ad, error)); ok {
-		return rf(adId, userId, published)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, bool) (*ads.Ad, error)); ok {
+		return rf(ctx, adId, userId, published)
 	}
-	if rf, ok := ret.Get(0).(func(int64, int64, bool) *ads.Ad); ok {
-		r0 = rf(adId, userId, published)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, bool) *ads.Ad); ok {
+		r0 = rf(ctx, adId, userId, published)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*ads.Ad)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(int64, int64, bool) error); ok {
-		r1 = rf(adId, userId, published)
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64, bool) error); ok {
+		r1 = rf(ctx, adId, userId, published)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ConfirmEmail provides a mock function with given fields: ctx, token
+func (_m *App) ConfirmEmail(ctx context.Context, token string) (*users.User, error) {
+	ret := _m.Called(ctx, token)
+
+	var r0 *users.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*users.User, error)); ok {
+		return rf(ctx, token)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *users.User); ok {
+		r0 = rf(ctx, token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*users.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, token)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -41,25 +168,25 @@ func (_m *App) ChangeAdStatus(adId int64, userId int64, published bool) (*ads.Ad
 	return r0, r1
 }
 
-// CreateAd provides a mock function with given fields: title, text, userId
-func (_m *App) CreateAd(title string, text string, userId int64) (*ads.Ad, error) {
-	ret := _m.Called(title, text, userId)
+// CreateAd provides a mock function with given fields: ctx, title, text, userId
+func (_m *App) CreateAd(ctx context.Context, title string, text string, userId int64) (*ads.Ad, error) {
+	ret := _m.Called(ctx, title, text, userId)
 
 	var r0 *ads.Ad
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, string, int64) (*ads.Ad, error)); ok {
-		return rf(title, text, userId)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64) (*ads.Ad, error)); ok {
+		return rf(ctx, title, text, userId)
 	}
-	if rf, ok := ret.Get(0).(func(string, string, int64) *ads.Ad); ok {
-		r0 = rf(title, text, userId)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64) *ads.Ad); ok {
+		r0 = rf(ctx, title, text, userId)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*ads.Ad)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string, string, int64) error); ok {
-		r1 = rf(title, text, userId)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, int64) error); ok {
+		r1 = rf(ctx, title, text, userId)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -67,25 +194,25 @@ func (_m *App) CreateAd(title string, text string, userId int64) (*ads.Ad, error
 	return r0, r1
 }
 
-// CreateUser provides a mock function with given fields: nickname, email
-func (_m *App) CreateUser(nickname string, email string) (*users.User, error) {
-	ret := _m.Called(nickname, email)
+// CreateUser provides a mock function with given fields: ctx, nickname, email
+func (_m *App) CreateUser(ctx context.Context, nickname string, email string) (*users.User, error) {
+	ret := _m.Called(ctx, nickname, email)
 
 	var r0 *users.User
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, string) (*users.User, error)); ok {
-		return rf(nickname, email)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*users.User, error)); ok {
+		return rf(ctx, nickname, email)
 	}
-	if rf, ok := ret.Get(0).(func(string, string) *users.User); ok {
-		r0 = rf(nickname, email)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *users.User); ok {
+		r0 = rf(ctx, nickname, email)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*users.User)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(string, string) error); ok {
-		r1 = rf(nickname, email)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, nickname, email)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -93,13 +220,53 @@ func (_m *App) CreateUser(nickname string, email string) (*users.User, error) {
 	return r0, r1
 }
 
-// DeleteAd provides a mock function with given fields: adId, userId
-func (_m *App) DeleteAd(adId int64, userId int64) error {
-	ret := _m.Called(adId, userId)
+// CreateWebhook provides a mock function with given fields: ctx, url, secret
+func (_m *App) CreateWebhook(ctx context.Context, url string, secret string) (*outbox.Webhook, error) {
+	ret := _m.Called(ctx, url, secret)
+
+	var r0 *outbox.Webhook
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (*outbox.Webhook, error)); ok {
+		return rf(ctx, url, secret)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) *outbox.Webhook); ok {
+		r0 = rf(ctx, url, secret)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*outbox.Webhook)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, url, secret)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteAd provides a mock function with given fields: ctx, adId, userId
+func (_m *App) DeleteAd(ctx context.Context, adId int64, userId int64) error {
+	ret := _m.Called(ctx, adId, userId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) error); ok {
+		r0 = rf(ctx, adId, userId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteUser provides a mock function with given fields: ctx, userId
+func (_m *App) DeleteUser(ctx context.Context, userId int64) error {
+	ret := _m.Called(ctx, userId)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(int64, int64) error); ok {
-		r0 = rf(adId, userId)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, userId)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -107,13 +274,13 @@ func (_m *App) DeleteAd(adId int64, userId int64) error {
 	return r0
 }
 
-// DeleteUser provides a mock function with given fields: userId
-func (_m *App) DeleteUser(userId int64) error {
-	ret := _m.Called(userId)
+// DeleteWebhook provides a mock function with given fields: ctx, webhookId
+func (_m *App) DeleteWebhook(ctx context.Context, webhookId int64) error {
+	ret := _m.Called(ctx, webhookId)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(int64) error); ok {
-		r0 = rf(userId)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, webhookId)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -121,25 +288,39 @@ func (_m *App) DeleteUser(userId int64) error {
 	return r0
 }
 
-// GetAd provides a mock function with given fields: id
-func (_m *App) GetAd(id int64) (*ads.Ad, error) {
-	ret := _m.Called(id)
+// ExportAds provides a mock function with given fields: ctx, filters, fn
+func (_m *App) ExportAds(ctx context.Context, filters map[string]interface{}, fn func(ads.Ad) error) error {
+	ret := _m.Called(ctx, filters, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, map[string]interface{}, func(ads.Ad) error) error); ok {
+		r0 = rf(ctx, filters, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetAd provides a mock function with given fields: ctx, id
+func (_m *App) GetAd(ctx context.Context, id int64) (*ads.Ad, error) {
+	ret := _m.Called(ctx, id)
 
 	var r0 *ads.Ad
 	var r1 error
-	if rf, ok := ret.Get(0).(func(int64) (*ads.Ad, error)); ok {
-		return rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*ads.Ad, error)); ok {
+		return rf(ctx, id)
 	}
-	if rf, ok := ret.Get(0).(func(int64) *ads.Ad); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *ads.Ad); ok {
+		r0 = rf(ctx, id)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*ads.Ad)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(int64) error); ok {
-		r1 = rf(id)
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, id)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -147,13 +328,29 @@ func (_m *App) GetAd(id int64) (*ads.Ad, error) {
 	return r0, r1
 }
 
-// GetListAds provides a mock function with given fields: filters
-func (_m *App) GetListAds(filters map[string]interface{}) []ads.Ad {
-	ret := _m.Called(filters)
+// GetDeadLetters provides a mock function with given fields: ctx
+func (_m *App) GetDeadLetters(ctx context.Context) []outbox.DeadLetter {
+	ret := _m.Called(ctx)
+
+	var r0 []outbox.DeadLetter
+	if rf, ok := ret.Get(0).(func(context.Context) []outbox.DeadLetter); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]outbox.DeadLetter)
+		}
+	}
+
+	return r0
+}
+
+// GetListAds provides a mock function with given fields: ctx, filters
+func (_m *App) GetListAds(ctx context.Context, filters map[string]interface{}) []ads.Ad {
+	ret := _m.Called(ctx, filters)
 
 	var r0 []ads.Ad
-	if rf, ok := ret.Get(0).(func(map[string]interface{}) []ads.Ad); ok {
-		r0 = rf(filters)
+	if rf, ok := ret.Get(0).(func(context.Context, map[string]interface{}) []ads.Ad); ok {
+		r0 = rf(ctx, filters)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]ads.Ad)
@@ -163,13 +360,13 @@ func (_m *App) GetListAds(filters map[string]interface{}) []ads.Ad {
 	return r0
 }
 
-// GetListAdsByTitle provides a mock function with given fields: pattern
-func (_m *App) GetListAdsByTitle(pattern string) []ads.Ad {
-	ret := _m.Called(pattern)
+// GetListAdsByTitle provides a mock function with given fields: ctx, pattern
+func (_m *App) GetListAdsByTitle(ctx context.Context, pattern string) []ads.Ad {
+	ret := _m.Called(ctx, pattern)
 
 	var r0 []ads.Ad
-	if rf, ok := ret.Get(0).(func(string) []ads.Ad); ok {
-		r0 = rf(pattern)
+	if rf, ok := ret.Get(0).(func(context.Context, string) []ads.Ad); ok {
+		r0 = rf(ctx, pattern)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]ads.Ad)
@@ -179,25 +376,65 @@ func (_m *App) GetListAdsByTitle(pattern string) []ads.Ad {
 	return r0
 }
 
-// GetUser provides a mock function with given fields: userId
-func (_m *App) GetUser(userId int64) (*users.User, error) {
-	ret := _m.Called(userId)
+// GetStats provides a mock function with given fields: ctx
+func (_m *App) GetStats(ctx context.Context) app.Stats {
+	ret := _m.Called(ctx)
+
+	var r0 app.Stats
+	if rf, ok := ret.Get(0).(func(context.Context) app.Stats); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Get(0).(app.Stats)
+	}
+
+	return r0
+}
+
+// GetUser provides a mock function with given fields: ctx, userId
+func (_m *App) GetUser(ctx context.Context, userId int64) (*users.User, error) {
+	ret := _m.Called(ctx, userId)
+
+	var r0 *users.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (*users.User, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) *users.User); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*users.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserByEmail provides a mock function with given fields: ctx, email
+func (_m *App) GetUserByEmail(ctx context.Context, email string) (*users.User, error) {
+	ret := _m.Called(ctx, email)
 
 	var r0 *users.User
 	var r1 error
-	if rf, ok := ret.Get(0).(func(int64) (*users.User, error)); ok {
-		return rf(userId)
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*users.User, error)); ok {
+		return rf(ctx, email)
 	}
-	if rf, ok := ret.Get(0).(func(int64) *users.User); ok {
-		r0 = rf(userId)
+	if rf, ok := ret.Get(0).(func(context.Context, string) *users.User); ok {
+		r0 = rf(ctx, email)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*users.User)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(int64) error); ok {
-		r1 = rf(userId)
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, email)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -205,25 +442,159 @@ func (_m *App) GetUser(userId int64) (*users.User, error) {
 	return r0, r1
 }
 
-// UpdateAd provides a mock function with given fields: adId, userId, title, text
-func (_m *App) UpdateAd(adId int64, userId int64, title string, text string) (*ads.Ad, error) {
-	ret := _m.Called(adId, userId, title, text)
+// GetUserAds provides a mock function with given fields: ctx, userId
+func (_m *App) GetUserAds(ctx context.Context, userId int64) ([]ads.Ad, error) {
+	ret := _m.Called(ctx, userId)
+
+	var r0 []ads.Ad
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) ([]ads.Ad, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) []ads.Ad); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ads.Ad)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetWebhooks provides a mock function with given fields: ctx
+func (_m *App) GetWebhooks(ctx context.Context) []outbox.Webhook {
+	ret := _m.Called(ctx)
+
+	var r0 []outbox.Webhook
+	if rf, ok := ret.Get(0).(func(context.Context) []outbox.Webhook); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]outbox.Webhook)
+		}
+	}
+
+	return r0
+}
+
+// ImportAds provides a mock function with given fields: ctx, next
+func (_m *App) ImportAds(ctx context.Context, next func() (app.ImportRow, error)) (app.ImportReport, error) {
+	ret := _m.Called(ctx, next)
+
+	var r0 app.ImportReport
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, func() (app.ImportRow, error)) (app.ImportReport, error)); ok {
+		return rf(ctx, next)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, func() (app.ImportRow, error)) app.ImportReport); ok {
+		r0 = rf(ctx, next)
+	} else {
+		r0 = ret.Get(0).(app.ImportReport)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, func() (app.ImportRow, error)) error); ok {
+		r1 = rf(ctx, next)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RequestEmailVerification provides a mock function with given fields: ctx, userId
+func (_m *App) RequestEmailVerification(ctx context.Context, userId int64) error {
+	ret := _m.Called(ctx, userId)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) error); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RequestPasswordReset provides a mock function with given fields: ctx, email
+func (_m *App) RequestPasswordReset(ctx context.Context, email string) error {
+	ret := _m.Called(ctx, email)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, email)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ResetPassword provides a mock function with given fields: ctx, token, password
+func (_m *App) ResetPassword(ctx context.Context, token string, password string) error {
+	ret := _m.Called(ctx, token, password)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, token, password)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateAd provides a mock function with given fields: ctx, adId, userId, title, text
+func (_m *App) UpdateAd(ctx context.Context, adId int64, userId int64, title string, text string) (*ads.Ad, error) {
+	ret := _m.Called(ctx, adId, userId, title, text)
 
 	var r0 *ads.Ad
 	var r1 error
-	if rf, ok := ret.Get(0).(func(int64, int64, string, string) (*ads.Ad, error)); ok {
-		return rf(adId, userId, title, text)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, string, string) (*ads.Ad, error)); ok {
+		return rf(ctx, adId, userId, title, text)
 	}
-	if rf, ok := ret.Get(0).(func(int64, int64, string, string) *ads.Ad); ok {
-		r0 = rf(adId, userId, title, text)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64, string, string) *ads.Ad); ok {
+		r0 = rf(ctx, adId, userId, title, text)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*ads.Ad)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(int64, int64, string, string) error); ok {
-		r1 = rf(adId, userId, title, text)
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64, string, string) error); ok {
+		r1 = rf(ctx, adId, userId, title, text)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateProfile provides a mock function with given fields: ctx, userId, password, profile
+func (_m *App) UpdateProfile(ctx context.Context, userId int64, password string, profile users.Profile) (*users.User, error) {
+	ret := _m.Called(ctx, userId, password, profile)
+
+	var r0 *users.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, users.Profile) (*users.User, error)); ok {
+		return rf(ctx, userId, password, profile)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, users.Profile) *users.User); ok {
+		r0 = rf(ctx, userId, password, profile)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*users.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string, users.Profile) error); ok {
+		r1 = rf(ctx, userId, password, profile)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -231,25 +602,25 @@ func (_m *App) UpdateAd(adId int64, userId int64, title string, text string) (*a
 	return r0, r1
 }
 
-// UpdateUser provides a mock function with given fields: userId, nickname, email
-func (_m *App) UpdateUser(userId int64, nickname string, email string) (*users.User, error) {
-	ret := _m.Called(userId, nickname, email)
+// UpdateUser provides a mock function with given fields: ctx, userId, nickname, email
+func (_m *App) UpdateUser(ctx context.Context, userId int64, nickname string, email string, password string) (*users.User, error) {
+	ret := _m.Called(ctx, userId, nickname, email, password)
 
 	var r0 *users.User
 	var r1 error
-	if rf, ok := ret.Get(0).(func(int64, string, string) (*users.User, error)); ok {
-		return rf(userId, nickname, email)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, string, string) (*users.User, error)); ok {
+		return rf(ctx, userId, nickname, email, password)
 	}
-	if rf, ok := ret.Get(0).(func(int64, string, string) *users.User); ok {
-		r0 = rf(userId, nickname, email)
+	if rf, ok := ret.Get(0).(func(context.Context, int64, string, string, string) *users.User); ok {
+		r0 = rf(ctx, userId, nickname, email, password)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*users.User)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(int64, string, string) error); ok {
-		r1 = rf(userId, nickname, email)
+	if rf, ok := ret.Get(1).(func(context.Context, int64, string, string, string) error); ok {
+		r1 = rf(ctx, userId, nickname, email, password)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -0,0 +1,427 @@
+package httpgin
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"homework10/internal/ports/problem"
+)
+
+// OpenAPIPrefix - путь, от которого в спецификации отсчитываются маршруты AppRouter
+const OpenAPIPrefix = "/api/v1"
+
+// apiRoute описывает один маршрут AppRouter. Схемы тела запроса и поля data ответа
+// строятся по типам из presenters.go, поэтому не расходятся с тем, что реально пишет gin
+type apiRoute struct {
+	method     string
+	path       string // в синтаксисе gin: /ads/:ad_id
+	summary    string
+	query      []apiParam
+	request    any // нулевое значение типа тела запроса, nil - тела нет
+	data       any // нулевое значение типа поля data ответа
+	errors     []int
+	idempotent bool // принимает заголовок Idempotency-Key
+	stream     bool // отвечает потоком text/event-stream, data описывает одно событие без обёртки
+	upload     bool // тело запроса - файл CSV или NDJSON, а не JSON
+	download   bool // отвечает файлом CSV или NDJSON без обёртки, data не используется
+	admin      bool // служебный маршрут, нужен заголовок Authorization: Bearer с токеном администратора
+}
+
+type apiParam struct {
+	name        string
+	value       any
+	description string
+}
+
+// apiRoutes - все маршруты AppRouter; тест в openapi_test.go следит, чтобы ни один не был пропущен
+var apiRoutes = []apiRoute{
+	{method: http.MethodPost, path: "/ads", summary: "Create an ad", request: createAdRequest{}, data: adResponse{},
+		errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusInternalServerError}, idempotent: true},
+	{method: http.MethodPut, path: "/ads/:ad_id/status", summary: "Publish or unpublish an ad; publishing needs a verified email", request: changeAdStatusRequest{}, data: adResponse{},
+		errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError}},
+	{method: http.MethodPut, path: "/ads/:ad_id", summary: "Update title and text of an ad", request: updateAdRequest{}, data: adResponse{},
+		errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError}},
+	{method: http.MethodDelete, path: "/ads/:ad_id", summary: "Delete an ad", request: deleteAdRequest{}, data: "",
+		errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError}},
+	{method: http.MethodPut, path: "/ads/batch_status", summary: "Publish or unpublish several ads of a user, all or none; publishing needs a verified email",
+		request: batchChangeAdStatusRequest{}, data: []adResponse{},
+		errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError}},
+	{method: http.MethodGet, path: "/ads/:ad_id", summary: "Get an ad by id", data: adResponse{},
+		errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError}},
+	{method: http.MethodGet, path: "/ads", summary: "List ads, only published ones unless filtered", query: adFilters, data: []adResponse{},
+		errors: []int{http.StatusBadRequest}},
+	{method: http.MethodGet, path: "/ads/with_filter", summary: "List ads matching the filters", query: adFilters, data: []adResponse{},
+		errors: []int{http.StatusBadRequest}},
+	{method: http.MethodGet, path: "/ads/search/:ad_title", summary: "List ads whose title starts with ad_title", data: []adResponse{}},
+	{method: http.MethodGet, path: "/ads/events", summary: "Stream ad events as server-sent events", query: []apiParam{
+		{name: "user_id", value: int64(0), description: "only events of ads by this author"},
+	}, data: adEventResponse{}, errors: []int{http.StatusBadRequest, http.StatusNotImplemented}, stream: true},
+	{method: http.MethodPost, path: "/ads/import", summary: "Import ads from a CSV or NDJSON file, reporting failed rows without aborting. " +
+		"Each row is counted against the write rate limit; the file is limited to 16 MiB and 10000 rows",
+		data: importReportResponse{}, errors: []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType}, upload: true},
+	{method: http.MethodGet, path: "/ads/export", summary: "Export ads matching the filters as CSV or NDJSON",
+		query:  append([]apiParam{{name: "format", value: "", description: "csv or ndjson (default)"}}, adFilters...),
+		errors: []int{http.StatusBadRequest}, download: true},
+	{method: http.MethodPost, path: "/ads/batch_get", summary: "Get up to 100 ads by id, listing the ids that were not found",
+		request: batchGetRequest{}, data: batchGetAdsResponse{}, errors: []int{http.StatusBadRequest, http.StatusInternalServerError}},
+	{method: http.MethodPost, path: "/users", summary: "Create a user; email and nickname must be unique", request: createUserRequest{}, data: userResponse{},
+		errors: []int{http.StatusBadRequest, http.StatusConflict, http.StatusInternalServerError}, idempotent: true},
+	{method: http.MethodPut, path: "/users/:user_id", summary: "Update a user; email and nickname must be unique, a new email is confirmed with the user's password",
+		request: updateUserRequest{}, data: userResponse{},
+		errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusConflict, http.StatusInternalServerError}},
+	{method: http.MethodGet, path: "/users/:user_id", summary: "Get a user by id, without email and phone", data: userResponse{},
+		errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError}},
+	{method: http.MethodPut, path: "/users/:user_id/profile", summary: "Replace the profile fields, confirmed with the user's password; an empty field clears the value",
+		request: updateProfileRequest{}, data: userResponse{}, errors: []int{http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError}},
+	{method: http.MethodGet, path: "/users/:user_id/ads", summary: "List published ads of a user, newest first", data: []adResponse{},
+		errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError}},
+	{method: http.MethodDelete, path: "/users/:user_id", summary: "Delete a user", data: "",
+		errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError}},
+	{method: http.MethodPost, path: "/users/batch_get", summary: "Get up to 100 users by id, listing the ids that were not found",
+		request: batchGetRequest{}, data: batchGetUsersResponse{}, errors: []int{http.StatusBadRequest, http.StatusInternalServerError}},
+	{method: http.MethodPost, path: "/users/by_email", summary: "Find a user by email, ignoring case; internal callers only, as it tells whether an email is registered",
+		request: userByEmailRequest{}, data: userResponse{}, errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError}, admin: true},
+	{method: http.MethodPost, path: "/users/:user_id/email_verification", summary: "Send a new email verification token; the previous one stops working",
+		data: "", errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError}},
+	{method: http.MethodPost, path: "/users/email_verification/confirm", summary: "Verify the email with a single-use token from the email",
+		request: confirmEmailRequest{}, data: userResponse{}, errors: []int{http.StatusBadRequest, http.StatusInternalServerError}},
+	{method: http.MethodPost, path: "/users/password_reset", summary: "Email a password reset token; answers the same whether the email is registered or not",
+		request: passwordResetRequest{}, data: "", errors: []int{http.StatusBadRequest, http.StatusInternalServerError}},
+	{method: http.MethodPost, path: "/users/password_reset/confirm", summary: "Set a new password with a single-use token from the email",
+		request: resetPasswordRequest{}, data: "", errors: []int{http.StatusBadRequest, http.StatusInternalServerError}},
+	{method: http.MethodPost, path: "/admin/webhooks", summary: "Subscribe a URL to ad events", request: createWebhookRequest{}, data: webhookResponse{},
+		errors: []int{http.StatusBadRequest, http.StatusInternalServerError}, admin: true},
+	{method: http.MethodGet, path: "/admin/webhooks", summary: "List webhook subscriptions", data: []webhookResponse{}, admin: true},
+	{method: http.MethodDelete, path: "/admin/webhooks/:webhook_id", summary: "Delete a webhook subscription", data: "",
+		errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError}, admin: true},
+	{method: http.MethodGet, path: "/admin/webhooks/dead_letters", summary: "List events that could not be delivered", data: []deadLetterResponse{}, admin: true},
+}
+
+// adFileTypes - типы файлов импорта и экспорта
+var adFileTypes = []string{"text/csv", "application/x-ndjson"}
+
+const adFileDescription = "CSV with a header row or NDJSON, one ad per line. Import reads the columns title, text and user_id; " +
+	"export writes id, title, text, user_id, published, date_creating and date_update, so an export can be imported back"
+
+var adFilters = []apiParam{
+	{name: "published", value: false, description: "only published (true) or unpublished (false) ads"},
+	{name: "user_id", value: int64(0), description: "only ads of this author"},
+	{name: "date_creating", value: "", description: "only ads created on this day, the first 10 characters are compared with YYYY-MM-DD"},
+}
+
+// pathParams - описания параметров пути; всё, чего здесь нет, считается числовым идентификатором
+var pathParams = map[string]apiParam{
+	"ad_title": {name: "ad_title", value: "", description: "title prefix"},
+}
+
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Servers    []openAPIServer                         `json:"servers"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+}
+
+type openAPIServer struct {
+	URL string `json:"url"`
+}
+
+type openAPIOperation struct {
+	Summary     string                      `json:"summary"`
+	OperationID string                      `json:"operationId"`
+	Tags        []string                    `json:"tags"`
+	Parameters  []openAPIParameter          `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+	Security    []map[string][]string       `json:"security,omitempty"`
+}
+
+type openAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Required    bool           `json:"required,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Schema      *openAPISchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Headers     map[string]openAPIHeader    `json:"headers,omitempty"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIHeader struct {
+	Description string         `json:"description"`
+	Schema      *openAPISchema `json:"schema"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*openAPISchema        `json:"schemas"`
+	SecuritySchemes map[string]openAPISecurityScheme `json:"securitySchemes"`
+}
+
+type openAPISecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme"`
+	Description string `json:"description"`
+}
+
+// adminSecurity - схема авторизации служебных маршрутов
+const adminSecurity = "adminToken"
+
+type openAPISchema struct {
+	Ref         string                    `json:"$ref,omitempty"`
+	Type        string                    `json:"type,omitempty"`
+	Format      string                    `json:"format,omitempty"`
+	Nullable    bool                      `json:"nullable,omitempty"`
+	Description string                    `json:"description,omitempty"`
+	Properties  map[string]*openAPISchema `json:"properties,omitempty"`
+	Required    []string                  `json:"required,omitempty"`
+	Items       *openAPISchema            `json:"items,omitempty"`
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// OpenAPI строит спецификацию OpenAPI 3 для маршрутов AppRouter
+func OpenAPI() ([]byte, error) {
+	components := make(map[string]*openAPISchema)
+	components["ErrorResponse"] = &openAPISchema{
+		Type:        "object",
+		Description: "every failed request, data is always null",
+		Properties: map[string]*openAPISchema{
+			"data":  {Nullable: true, Description: "always null"},
+			"error": {Type: "string", Description: "error message"},
+		},
+		Required: []string{"data", "error"},
+	}
+
+	doc := openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info: openAPIInfo{
+			Title:   "Ads service",
+			Version: "1.0.0",
+			Description: "Every response is an envelope {\"data\": ..., \"error\": ...}. " +
+				"With rate limiting enabled requests are counted per client IP.",
+		},
+		Servers: []openAPIServer{{URL: OpenAPIPrefix}},
+		Paths:   make(map[string]map[string]*openAPIOperation),
+		Components: openAPIComponents{
+			Schemas: components,
+			SecuritySchemes: map[string]openAPISecurityScheme{
+				adminSecurity: {Type: "http", Scheme: "bearer", Description: "admin token from the admin.token setting"},
+			},
+		},
+	}
+
+	for _, route := range apiRoutes {
+		path := openAPIPath(route.path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*openAPIOperation)
+		}
+		doc.Paths[path][strings.ToLower(route.method)] = route.operation(components)
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// openAPIPath переводит путь gin (/ads/:ad_id) в шаблон OpenAPI (/ads/{ad_id})
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for idx, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[idx] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func (route apiRoute) operation(components map[string]*openAPISchema) *openAPIOperation {
+	op := &openAPIOperation{
+		Summary:     route.summary,
+		OperationID: route.operationID(),
+		Tags:        []string{strings.Split(strings.TrimPrefix(route.path, "/"), "/")[0]},
+		Responses:   make(map[string]*openAPIResponse),
+	}
+
+	for _, segment := range strings.Split(route.path, "/") {
+		if !strings.HasPrefix(segment, ":") {
+			continue
+		}
+		param, ok := pathParams[segment[1:]]
+		if !ok {
+			param = apiParam{name: segment[1:], value: int64(0)}
+		}
+		op.Parameters = append(op.Parameters, openAPIParameter{
+			Name: param.name, In: "path", Required: true, Description: param.description,
+			Schema: schemaOf(reflect.TypeOf(param.value), components),
+		})
+	}
+	for _, param := range route.query {
+		op.Parameters = append(op.Parameters, openAPIParameter{
+			Name: param.name, In: "query", Description: param.description,
+			Schema: schemaOf(reflect.TypeOf(param.value), components),
+		})
+	}
+	if route.admin {
+		op.Security = []map[string][]string{{adminSecurity: {}}}
+	}
+	if route.idempotent {
+		op.Parameters = append(op.Parameters, openAPIParameter{
+			Name: IdempotencyKeyHeader, In: "header",
+			Description: "repeating a request with the same key returns the stored response instead of creating a duplicate",
+			Schema:      &openAPISchema{Type: "string"},
+		})
+	}
+
+	if route.request != nil {
+		op.RequestBody = &openAPIRequestBody{
+			Required: true,
+			Content:  jsonContent(schemaOf(reflect.TypeOf(route.request), components)),
+		}
+	}
+	if route.upload {
+		op.RequestBody = &openAPIRequestBody{Required: true, Content: fileContent()}
+	}
+
+	switch {
+	case route.download:
+		op.Responses["200"] = &openAPIResponse{Description: "file with the ads", Content: fileContent()}
+	case route.stream:
+		op.Responses["200"] = &openAPIResponse{
+			Description: "stream of events; the event field is the event type, data is JSON. " +
+				"An error event ends the stream when the client falls behind",
+			Content: map[string]openAPIMediaType{"text/event-stream": {Schema: schemaOf(reflect.TypeOf(route.data), components)}},
+		}
+	default:
+		op.Responses["200"] = &openAPIResponse{
+			Description: "success",
+			Content: jsonContent(&openAPISchema{
+				Type: "object",
+				Properties: map[string]*openAPISchema{
+					"data":  schemaOf(reflect.TypeOf(route.data), components),
+					"error": {Type: "string", Nullable: true, Description: "always null"},
+				},
+				Required: []string{"data", "error"},
+			}),
+		}
+	}
+	codes := append([]int(nil), route.errors...)
+	if route.idempotent {
+		codes = append(codes, http.StatusBadRequest, http.StatusUnprocessableEntity)
+	}
+	if route.admin {
+		codes = append(codes, http.StatusUnauthorized)
+	}
+	codes = append(codes, http.StatusTooManyRequests)
+	for _, code := range codes {
+		response := &openAPIResponse{
+			Description: http.StatusText(code),
+			Content:     jsonContent(&openAPISchema{Ref: "#/components/schemas/ErrorResponse"}),
+		}
+		if code == http.StatusBadRequest {
+			// ошибки валидации полей приходят в формате RFC 7807, остальные - в обычной обёртке
+			response.Content[problem.ContentType] = openAPIMediaType{Schema: schemaOf(reflect.TypeOf(problem.Problem{}), components)}
+		}
+		if code == http.StatusTooManyRequests {
+			response.Headers = map[string]openAPIHeader{
+				"Retry-After": {Description: "seconds until the next request is allowed", Schema: &openAPISchema{Type: "integer"}},
+			}
+		}
+		op.Responses[strconv.Itoa(code)] = response
+	}
+	return op
+}
+
+// operationID строит имя из метода и пути: PUT /ads/:ad_id/status -> putAdsAdIdStatus
+func (route apiRoute) operationID() string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(route.method))
+	for _, word := range strings.FieldsFunc(route.path, func(r rune) bool { return r == '/' || r == ':' || r == '_' }) {
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
+
+func fileContent() map[string]openAPIMediaType {
+	content := make(map[string]openAPIMediaType, len(adFileTypes))
+	for _, mediaType := range adFileTypes {
+		content[mediaType] = openAPIMediaType{Schema: &openAPISchema{Type: "string", Format: "binary", Description: adFileDescription}}
+	}
+	return content
+}
+
+func jsonContent(schema *openAPISchema) map[string]openAPIMediaType {
+	return map[string]openAPIMediaType{"application/json": {Schema: schema}}
+}
+
+// schemaOf описывает тип Go схемой JSON; структуры попадают в components.schemas
+// под своим именем с заглавной буквы и дальше используются по ссылке
+func schemaOf(t reflect.Type, components map[string]*openAPISchema) *openAPISchema {
+	switch {
+	case t == timeType:
+		return &openAPISchema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Pointer:
+		schema := schemaOf(t.Elem(), components)
+		schema.Nullable = true
+		return schema
+	case t.Kind() == reflect.Slice:
+		return &openAPISchema{Type: "array", Items: schemaOf(t.Elem(), components), Nullable: true}
+	case t.Kind() == reflect.Struct:
+		name := []rune(t.Name())
+		name[0] = unicode.ToUpper(name[0])
+		ref := &openAPISchema{Ref: "#/components/schemas/" + string(name)}
+		if _, ok := components[string(name)]; ok {
+			return ref
+		}
+
+		schema := &openAPISchema{Type: "object", Properties: make(map[string]*openAPISchema)}
+		components[string(name)] = schema
+		for idx := 0; idx < t.NumField(); idx++ {
+			field := t.Field(idx)
+			tag := strings.Split(field.Tag.Get("json"), ",")[0]
+			if !field.IsExported() || tag == "-" || tag == "" {
+				continue
+			}
+			schema.Properties[tag] = schemaOf(field.Type, components)
+			if !strings.Contains(field.Tag.Get("json"), ",omitempty") {
+				schema.Required = append(schema.Required, tag)
+			}
+		}
+		sort.Strings(schema.Required)
+		return ref
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &openAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Int32:
+		return &openAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Float32, reflect.Float64:
+		return &openAPISchema{Type: "number"}
+	default:
+		return &openAPISchema{Type: "string"}
+	}
+}
@@ -0,0 +1,146 @@
+package httpgin
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"homework10/internal/idempotency"
+	"homework10/internal/ports/httpgin/mocks"
+)
+
+type specDocument struct {
+	OpenAPI string                              `json:"openapi"`
+	Paths   map[string]map[string]specOperation `json:"paths"`
+}
+
+type specOperation struct {
+	OperationID string `json:"operationId"`
+	Parameters  []struct {
+		Name string `json:"name"`
+		In   string `json:"in"`
+	} `json:"parameters"`
+	Responses map[string]json.RawMessage `json:"responses"`
+	Security  []map[string][]string      `json:"security"`
+}
+
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+func getSpec(t *testing.T) specDocument {
+	server := httptest.NewServer(NewHTTPServer(":18080", &mocks.App{}).Handler)
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL + "/api/v1/openapi.json")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var spec specDocument
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&spec))
+	return spec
+}
+
+// TestOpenAPICoversRoutes падает, если в AppRouter есть маршрут без описания в спецификации или наоборот
+func TestOpenAPICoversRoutes(t *testing.T) {
+	spec := getSpec(t)
+	assert.Equal(t, "3.0.3", spec.OpenAPI)
+
+	engine := gin.New()
+	AppRouter(engine.Group(OpenAPIPrefix), &mocks.App{}, idempotency.NewMemoryStore(idempotency.DefaultTTL), nil, nil, "")
+
+	routes := make(map[string]bool)
+	for _, route := range engine.Routes() {
+		path := openAPIPath(strings.TrimPrefix(route.Path, OpenAPIPrefix))
+		key := route.Method + " " + path
+		routes[key] = true
+
+		op, ok := spec.Paths[path][strings.ToLower(route.Method)]
+		if !assert.Truef(t, ok, "route %s is missing from the OpenAPI spec", key) {
+			continue
+		}
+		assert.Containsf(t, op.Responses, "200", "route %s has no success response", key)
+
+		var params []string
+		for _, param := range op.Parameters {
+			if param.In == "path" {
+				params = append(params, param.Name)
+			}
+		}
+		var want []string
+		for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+			want = append(want, match[1])
+		}
+		assert.Equalf(t, want, params, "path parameters of %s", key)
+	}
+
+	for path, ops := range spec.Paths {
+		for method := range ops {
+			key := strings.ToUpper(method) + " " + path
+			assert.Truef(t, routes[key], "spec describes %s, but AppRouter has no such route", key)
+		}
+	}
+}
+
+func TestOpenAPIAdminRoutes(t *testing.T) {
+	admin := map[string]bool{"/users/by_email": true}
+	for path, ops := range getSpec(t).Paths {
+		for method, op := range ops {
+			key := strings.ToUpper(method) + " " + path
+			if strings.HasPrefix(path, "/admin/") || admin[path] {
+				assert.Equalf(t, []map[string][]string{{"adminToken": {}}}, op.Security, "security of %s", key)
+				assert.Containsf(t, op.Responses, "401", "admin route %s has no 401 response", key)
+			} else {
+				assert.Emptyf(t, op.Security, "public route %s requires a token", key)
+			}
+		}
+	}
+}
+
+func TestOpenAPIOperationIDsAreUnique(t *testing.T) {
+	seen := make(map[string]string)
+	for path, ops := range getSpec(t).Paths {
+		for method, op := range ops {
+			key := strings.ToUpper(method) + " " + path
+			if other, ok := seen[op.OperationID]; ok {
+				t.Errorf("%s and %s share operationId %q", key, other, op.OperationID)
+			}
+			seen[op.OperationID] = key
+		}
+	}
+}
+
+func TestSwaggerUI(t *testing.T) {
+	server := httptest.NewServer(NewHTTPServer(":18080", &mocks.App{}).Handler)
+	defer server.Close()
+
+	tests := []struct {
+		path     string
+		code     int
+		contains string
+	}{
+		{path: "/api/v1/docs/", code: http.StatusOK, contains: "swagger-ui-bundle.js"},
+		{path: "/api/v1/docs/swagger-initializer.js", code: http.StatusOK, contains: "../openapi.json"},
+		{path: "/api/v1/docs/swagger-ui.css", code: http.StatusOK, contains: ".swagger-ui"},
+		{path: "/api/v1/docs/missing.js", code: http.StatusNotFound},
+	}
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			resp, err := server.Client().Get(server.URL + tc.path)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tc.code, resp.StatusCode)
+			assert.Contains(t, string(body), tc.contains)
+		})
+	}
+}
@@ -1,8 +1,13 @@
 package httpgin
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"homework10/internal/ads"
+	"homework10/internal/app"
+	"homework10/internal/outbox"
+	"homework10/internal/ports/errmap"
+	"homework10/internal/ports/problem"
 	"homework10/internal/users"
 	"time"
 )
@@ -23,10 +28,18 @@ type adResponse struct {
 	DateCreating time.Time `json:"date_creating"`
 }
 
+// userResponse - профиль пользователя; email и phone есть только в ответах самому пользователю
 type userResponse struct {
-	ID       int64  `json:"id"`
-	Nickname string `json:"nickname"`
-	Email    string `json:"email"`
+	ID            int64     `json:"id"`
+	Nickname      string    `json:"nickname"`
+	Email         *string   `json:"email,omitempty"`
+	EmailVerified bool      `json:"email_verified"`
+	DisplayName   string    `json:"display_name"`
+	Phone         *string   `json:"phone,omitempty"`
+	City          string    `json:"city"`
+	AvatarURL     string    `json:"avatar_url"`
+	RegisteredAt  time.Time `json:"registered_at"`
+	LastSeenAt    time.Time `json:"last_seen_at"`
 }
 
 type changeAdStatusRequest struct {
@@ -40,15 +53,107 @@ type updateAdRequest struct {
 	UserID int64  `json:"user_id"`
 }
 
-type createUpdateUserRequest struct {
+type createUserRequest struct {
+	NickName string `json:"nickname"`
+	Email    string `json:"email"`
+}
+
+// updateUserRequest - пароль нужен только для смены email
+type updateUserRequest struct {
 	NickName string `json:"nickname"`
 	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type updateProfileRequest struct {
+	DisplayName string `json:"display_name"`
+	Phone       string `json:"phone"`
+	City        string `json:"city"`
+	AvatarURL   string `json:"avatar_url"`
+	Password    string `json:"password"`
 }
 
 type deleteAdRequest struct {
 	UserID int64 `json:"user_id"`
 }
 
+type userByEmailRequest struct {
+	Email string `json:"email"`
+}
+
+type confirmEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type passwordResetRequest struct {
+	Email string `json:"email"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type batchGetRequest struct {
+	IDs []int64 `json:"ids"`
+}
+
+type batchChangeAdStatusRequest struct {
+	AdIDs     []int64 `json:"ad_ids"`
+	UserID    int64   `json:"user_id"`
+	Published bool    `json:"published"`
+}
+
+type createWebhookRequest struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret"`
+}
+
+type webhookResponse struct {
+	ID  int64  `json:"id"`
+	URL string `json:"url"`
+}
+
+type adEventResponse struct {
+	ID        int64      `json:"id"`
+	Type      string     `json:"type"`
+	Ad        adResponse `json:"ad"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// batchGetAdsResponse - найденные объявления в порядке запроса и ID, которых нет
+type batchGetAdsResponse struct {
+	Ads        []adResponse `json:"ads"`
+	MissingIDs []int64      `json:"missing_ids"`
+}
+
+type batchGetUsersResponse struct {
+	Users      []userResponse `json:"users"`
+	MissingIDs []int64        `json:"missing_ids"`
+}
+
+type importFailureResponse struct {
+	Line          int                    `json:"line"`
+	Error         string                 `json:"error"`
+	InvalidParams []problem.InvalidParam `json:"invalid_params,omitempty"`
+}
+
+type importReportResponse struct {
+	Imported int                     `json:"imported"`
+	Failed   int                     `json:"failed"`
+	Failures []importFailureResponse `json:"failures"`
+}
+
+type deadLetterResponse struct {
+	EventID   int64      `json:"event_id"`
+	EventType string     `json:"event_type"`
+	Ad        adResponse `json:"ad"`
+	WebhookID int64      `json:"webhook_id"`
+	Attempts  int        `json:"attempts"`
+	LastError string     `json:"last_error"`
+	FailedAt  time.Time  `json:"failed_at"`
+}
+
 func AdSuccessResponse(ad *ads.Ad) *gin.H {
 	return &gin.H{
 		"data": adResponse{
@@ -64,13 +169,29 @@ func AdSuccessResponse(ad *ads.Ad) *gin.H {
 	}
 }
 
-func UserSuccessResponse(ad *users.User) *gin.H {
+// UserSuccessResponse - ответ на запрос, в котором клиент сам прислал email пользователя
+// (создание, изменение) или доказал доступ к нему (подтверждение). Телефона в нём нет
+func UserSuccessResponse(user *users.User) *gin.H {
 	return &gin.H{
-		"data": userResponse{
-			ID:       ad.ID,
-			Nickname: ad.Nickname,
-			Email:    ad.Email,
-		},
+		"data":  newUserResponse(*user),
+		"error": nil,
+	}
+}
+
+// ProfileResponse - профиль целиком, в ответ на изменение профиля, подтверждённое паролем
+func ProfileResponse(user *users.User) *gin.H {
+	response := newUserResponse(*user)
+	response.Phone = &user.Phone
+	return &gin.H{
+		"data":  response,
+		"error": nil,
+	}
+}
+
+// PublicUserResponse - профиль без email и телефона: так пользователь отдаётся в чтениях
+func PublicUserResponse(user *users.User) *gin.H {
+	return &gin.H{
+		"data":  publicUserResponse(*user),
 		"error": nil,
 	}
 }
@@ -82,18 +203,43 @@ func ErrorResponse(err error) *gin.H {
 	}
 }
 
+// AppErrorResponse - ответ на ошибку приложения; текст внутренних ошибок наружу не отдаётся
+func AppErrorResponse(err error) *gin.H {
+	return &gin.H{
+		"data":  nil,
+		"error": errmap.Message(err),
+	}
+}
+
+// ValidationProblem описывает ошибку валидации по RFC 7807 со списком неверных полей
+func ValidationProblem(err error) problem.Problem {
+	var params []problem.InvalidParam
+	var fields *app.InvalidFieldsError
+	if errors.As(err, &fields) {
+		for _, v := range fields.Violations {
+			params = append(params, problem.InvalidParam{Name: v.Field, Reason: v.Description})
+		}
+	}
+	return problem.Validation(err.Error(), params)
+}
+
+// ImportReportResponse - отчёт импорта; в failures не больше app.MaxImportFailures строк
+func ImportReportResponse(report app.ImportReport) importReportResponse {
+	failures := make([]importFailureResponse, 0, len(report.Failures))
+	for _, failure := range report.Failures {
+		failures = append(failures, importFailureResponse{
+			Line:          failure.Line,
+			Error:         errmap.Message(failure.Err),
+			InvalidParams: ValidationProblem(failure.Err).InvalidParams,
+		})
+	}
+	return importReportResponse{Imported: report.Imported, Failed: report.Failed, Failures: failures}
+}
+
 func AdsSuccessResponse(a []ads.Ad) *gin.H {
 	var response []adResponse
 	for i := range a {
-		response = append(response, adResponse{
-			ID:           a[i].ID,
-			Title:        a[i].Title,
-			Text:         a[i].Text,
-			AuthorID:     a[i].AuthorID,
-			Published:    a[i].Published,
-			DateUpdate:   a[i].DateUpdate,
-			DateCreating: a[i].DateCreating,
-		})
+		response = append(response, newAdResponse(a[i]))
 	}
 
 	return &gin.H{
@@ -102,9 +248,143 @@ func AdsSuccessResponse(a []ads.Ad) *gin.H {
 	}
 }
 
+func BatchGetAdsResponse(found []ads.Ad, missing []int64) *gin.H {
+	response := batchGetAdsResponse{Ads: make([]adResponse, 0, len(found)), MissingIDs: missing}
+	for i := range found {
+		response.Ads = append(response.Ads, newAdResponse(found[i]))
+	}
+
+	return &gin.H{
+		"data":  response,
+		"error": nil,
+	}
+}
+
+func BatchGetUsersResponse(found []users.User, missing []int64) *gin.H {
+	response := batchGetUsersResponse{Users: make([]userResponse, 0, len(found)), MissingIDs: missing}
+	for i := range found {
+		response.Users = append(response.Users, publicUserResponse(found[i]))
+	}
+
+	return &gin.H{
+		"data":  response,
+		"error": nil,
+	}
+}
+
+func newUserResponse(user users.User) userResponse {
+	response := publicUserResponse(user)
+	response.Email = &user.Email
+	return response
+}
+
+// publicUserResponse - профиль без email и телефона
+func publicUserResponse(user users.User) userResponse {
+	return userResponse{
+		ID:            user.ID,
+		Nickname:      user.Nickname,
+		EmailVerified: user.EmailVerified,
+		DisplayName:   user.DisplayName,
+		City:          user.City,
+		AvatarURL:     user.AvatarURL,
+		RegisteredAt:  user.CreatedAt,
+		LastSeenAt:    user.LastSeenAt,
+	}
+}
+
+func newAdResponse(ad ads.Ad) adResponse {
+	return adResponse{
+		ID:           ad.ID,
+		Title:        ad.Title,
+		Text:         ad.Text,
+		AuthorID:     ad.AuthorID,
+		Published:    ad.Published,
+		DateUpdate:   ad.DateUpdate,
+		DateCreating: ad.DateCreating,
+	}
+}
+
 func DeleteSuccessResponse() *gin.H {
 	return &gin.H{
 		"data":  "success",
 		"error": nil,
 	}
 }
+
+// SuccessResponse - ответ на действие без результата: тот же, что у удаления
+func SuccessResponse() *gin.H {
+	return DeleteSuccessResponse()
+}
+
+func WebhookSuccessResponse(webhook *outbox.Webhook) *gin.H {
+	return &gin.H{
+		"data": webhookResponse{
+			ID:  webhook.ID,
+			URL: webhook.URL,
+		},
+		"error": nil,
+	}
+}
+
+func WebhooksSuccessResponse(list []outbox.Webhook) *gin.H {
+	response := make([]webhookResponse, 0, len(list))
+	for i := range list {
+		response = append(response, webhookResponse{
+			ID:  list[i].ID,
+			URL: list[i].URL,
+		})
+	}
+
+	return &gin.H{
+		"data":  response,
+		"error": nil,
+	}
+}
+
+func DeadLettersSuccessResponse(list []outbox.DeadLetter) *gin.H {
+	response := make([]deadLetterResponse, 0, len(list))
+	for i := range list {
+		ad := list[i].Event.Ad
+		response = append(response, deadLetterResponse{
+			EventID:   list[i].Event.ID,
+			EventType: string(list[i].Event.Type),
+			Ad: adResponse{
+				ID:           ad.ID,
+				Title:        ad.Title,
+				Text:         ad.Text,
+				AuthorID:     ad.AuthorID,
+				Published:    ad.Published,
+				DateUpdate:   ad.DateUpdate,
+				DateCreating: ad.DateCreating,
+			},
+			WebhookID: list[i].WebhookID,
+			Attempts:  list[i].Attempts,
+			LastError: list[i].LastError,
+			FailedAt:  list[i].FailedAt,
+		})
+	}
+
+	return &gin.H{
+		"data":  response,
+		"error": nil,
+	}
+}
+
+// AdEventResponse - данные одного server-sent event из /ads/events, без обёртки data/error
+func AdEventResponse(event outbox.Event) adEventResponse {
+	ad := event.Ad
+	return adEventResponse{
+		ID:   event.ID,
+		Type: string(event.Type),
+		Ad: adResponse{
+			ID:           ad.ID,
+			Title:        ad.Title,
+			Text:         ad.Text,
+			AuthorID:     ad.AuthorID,
+			Published:    ad.Published,
+			DateUpdate:   ad.DateUpdate,
+			DateCreating: ad.DateCreating,
+		},
+		CreatedAt: event.CreatedAt,
+	}
+}
@@ -0,0 +1,37 @@
+package httpgin
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"homework10/internal/ratelimit"
+)
+
+var ErrTooManyRequests = errors.New("too many requests")
+
+// RateLimit ограничивает частоту запросов с одного IP с раздельными бюджетами на чтение и запись.
+// Заголовок X-User-Id клиент выбирает сам, поэтому ключом он не служит: иначе каждый новый
+// идентификатор получал бы свежий бюджет
+func RateLimit(limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// batch_get и by_email читают, хотя ID и email приходят в теле POST
+		write := c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead &&
+			!strings.HasSuffix(c.FullPath(), "/batch_get") && !strings.HasSuffix(c.FullPath(), "/by_email")
+		if ok, wait := limiter.Allow(rateLimitKey(c), write); !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, ErrorResponse(ErrTooManyRequests))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func rateLimitKey(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
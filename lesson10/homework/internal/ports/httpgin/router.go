@@ -3,27 +3,58 @@ package httpgin
 import (
 	"github.com/gin-gonic/gin"
 	"homework10/internal/app"
+	"homework10/internal/idempotency"
+	"homework10/internal/outbox"
 	"homework10/internal/ports/httpgin/loggers"
+	"homework10/internal/ratelimit"
 )
 
-func AppRouter(r *gin.RouterGroup, a app.App) {
-	r.Use(loggers.Logger())             // Middleware для логгирования всех запросов
-	r.Use(loggers.RecoveryWithLogger()) // Middleware для обработки panic с логгированием
+// AppRouter регистрирует маршруты API. adminToken открывает служебные маршруты (/admin, поиск по email), пустой - закрывает их
+func AppRouter(r *gin.RouterGroup, a app.App, store idempotency.Store, limiter *ratelimit.Limiter, events *outbox.Broker, adminToken string) {
+	apiMiddleware(r, limiter)
 
 	adsR := r.Group("/ads")
-	adsR.POST("", createAd(a))                    // Метод для создания объявления (ad)
-	adsR.PUT("/:ad_id/status", changeAdStatus(a)) // Метод для изменения статуса объявления (опубликовано - Published = true или снято с публикации Published = false)
-	adsR.PUT("/:ad_id", updateAd(a))              // Метод для обновления текста(Text) или заголовка(Title) объявления
-	adsR.DELETE("/:ad_id", deleteAd(a))           // Метод для удаления объявления по id
+	adsR.POST("", Idempotency(store), createAd(a)) // Метод для создания объявления (ad)
+	adsR.PUT("/:ad_id/status", changeAdStatus(a))  // Метод для изменения статуса объявления (опубликовано - Published = true или снято с публикации Published = false)
+	adsR.PUT("/:ad_id", updateAd(a))               // Метод для обновления текста(Text) или заголовка(Title) объявления
+	adsR.DELETE("/:ad_id", deleteAd(a))            // Метод для удаления объявления по id
 
 	adsR.GET("/:ad_id", getAd(a))                       // Метод для вывода объявления по id
 	adsR.GET("", getListAds(a))                         // Метод для вывода списка опубликаванных объявлений
 	adsR.GET("/with_filter", getListAds(a))             // Метод для вывода списка опубликаванных объявлений
 	adsR.GET("/search/:ad_title", getListAdsByTitle(a)) // Метод для поиска объявлений по названию
+	adsR.GET("/events", watchAds(events))               // Метод для подписки на события объявлений (server-sent events)
+	adsR.POST("/import", importAds(a, limiter))         // Метод для импорта объявлений из CSV или NDJSON
+	adsR.GET("/export", exportAds(a))                   // Метод для выгрузки объявлений в CSV или NDJSON
+	adsR.POST("/batch_get", batchGetAds(a))             // Метод для вывода нескольких объявлений по id
+	adsR.PUT("/batch_status", batchChangeAdStatus(a))   // Метод для изменения статуса нескольких объявлений пользователя: все или ни одного
 
 	userR := r.Group("/users")
-	userR.POST("", createUser(a))            // Метод для создания пользователя (user)
-	userR.PUT("/:user_id", updateUser(a))    // Метод для редактирования данных пользователя
-	userR.GET("/:user_id", getUser(a))       // Метод для вывода пользователя по id
-	userR.DELETE("/:user_id", deleteUser(a)) // Метод для удаления пользователя id
+	userR.POST("", Idempotency(store), createUser(a))                       // Метод для создания пользователя (user)
+	userR.PUT("/:user_id", updateUser(a))                                   // Метод для редактирования данных пользователя
+	userR.GET("/:user_id", getUser(a))                                      // Метод для вывода пользователя по id
+	userR.PUT("/:user_id/profile", updateProfile(a))                        // Метод для редактирования профиля пользователя
+	userR.GET("/:user_id/ads", getUserAds(a))                               // Метод для вывода опубликованных объявлений пользователя
+	userR.DELETE("/:user_id", deleteUser(a))                                // Метод для удаления пользователя id
+	userR.POST("/batch_get", batchGetUsers(a))                              // Метод для вывода нескольких пользователей по id
+	userR.POST("/by_email", AdminAuth(adminToken), getUserByEmail(a))       // Метод для поиска пользователя по email (только для служебных вызовов)
+	userR.POST("/:user_id/email_verification", requestEmailVerification(a)) // Метод для повторной отправки письма с подтверждением email
+	userR.POST("/email_verification/confirm", confirmEmail(a))              // Метод для подтверждения email токеном из письма
+	userR.POST("/password_reset", requestPasswordReset(a))                  // Метод для отправки письма со сбросом пароля
+	userR.POST("/password_reset/confirm", resetPassword(a))                 // Метод для установки нового пароля токеном из письма
+
+	adminR := r.Group("/admin", AdminAuth(adminToken))
+	adminR.POST("/webhooks", createWebhook(a))               // Метод для подписки на события объявлений
+	adminR.GET("/webhooks", getWebhooks(a))                  // Метод для вывода списка подписок
+	adminR.DELETE("/webhooks/:webhook_id", deleteWebhook(a)) // Метод для удаления подписки по id
+	adminR.GET("/webhooks/dead_letters", getDeadLetters(a))  // Метод для вывода недоставленных событий
+}
+
+// apiMiddleware - middleware всех маршрутов API, общие для AppRouter и GatewayRouter
+func apiMiddleware(r *gin.RouterGroup, limiter *ratelimit.Limiter) {
+	r.Use(loggers.Logger())             // Middleware для логгирования всех запросов
+	r.Use(loggers.RecoveryWithLogger()) // Middleware для обработки panic с логгированием
+	if limiter != nil {
+		r.Use(RateLimit(limiter)) // Middleware для ограничения частоты запросов
+	}
 }
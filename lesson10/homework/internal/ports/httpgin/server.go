@@ -1,18 +1,133 @@
 package httpgin
 
 import (
+	"crypto/tls"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/trace"
 
 	"homework10/internal/app"
+	"homework10/internal/health"
+	"homework10/internal/idempotency"
+	"homework10/internal/outbox"
+	"homework10/internal/ports/httpgin/metrics"
+	"homework10/internal/ports/httpgin/tracing"
+	"homework10/internal/ratelimit"
 )
 
-func NewHTTPServer(port string, a app.App) *http.Server {
+type Option func(*options)
+
+type options struct {
+	idempotencyStore idempotency.Store
+	limiter          *ratelimit.Limiter
+	registry         *prometheus.Registry
+	tracerProvider   trace.TracerProvider
+	healthChecker    *health.Checker
+	tlsConfig        *tls.Config
+	events           *outbox.Broker
+	adminToken       string
+	gateway          *gatewayOptions
+}
+
+// WithIdempotencyStore задаёт хранилище ответов для заголовка Idempotency-Key.
+// По умолчанию ответы хранятся в памяти процесса
+func WithIdempotencyStore(store idempotency.Store) Option {
+	return func(o *options) {
+		o.idempotencyStore = store
+	}
+}
+
+// WithRateLimiter включает ограничение частоты запросов
+func WithRateLimiter(limiter *ratelimit.Limiter) Option {
+	return func(o *options) {
+		o.limiter = limiter
+	}
+}
+
+// WithMetricsRegistry задаёт реестр, в котором регистрируются метрики и который отдаётся на /metrics.
+// По умолчанию каждый сервер создаёт собственный реестр
+func WithMetricsRegistry(registry *prometheus.Registry) Option {
+	return func(o *options) {
+		o.registry = registry
+	}
+}
+
+// WithTracerProvider включает трассировку запросов
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracerProvider = tp
+	}
+}
+
+// WithHealthChecker задаёт проверки, по которым отвечает /readyz.
+// По умолчанию сервис считается готовым, пока не начал останавливаться
+func WithHealthChecker(checker *health.Checker) Option {
+	return func(o *options) {
+		o.healthChecker = checker
+	}
+}
+
+// WithTLSConfig включает TLS. Сервер с TLS запускается через ListenAndServeTLS("", "")
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = cfg
+	}
+}
+
+// WithAdEvents включает поток событий /ads/events; без него маршрут отвечает 501
+func WithAdEvents(broker *outbox.Broker) Option {
+	return func(o *options) {
+		o.events = broker
+	}
+}
+
+// WithAdminToken открывает служебный API /admin для запросов с этим bearer-токеном.
+// Без токена служебный API отвечает 401
+func WithAdminToken(token string) Option {
+	return func(o *options) {
+		o.adminToken = token
+	}
+}
+
+func NewHTTPServer(port string, a app.App, opts ...Option) *http.Server {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.idempotencyStore == nil {
+		o.idempotencyStore = idempotency.NewMemoryStore(idempotency.DefaultTTL)
+	}
+	if o.registry == nil {
+		o.registry = prometheus.NewRegistry()
+	}
+	o.registry.MustRegister(metrics.NewStatsCollector(a))
+	if o.healthChecker == nil {
+		o.healthChecker = health.New(health.DefaultTimeout)
+	}
+
 	gin.SetMode(gin.ReleaseMode)
 	handler := gin.New()
-	s := &http.Server{Addr: port, Handler: handler}
-	api := handler.Group("/api/v1")
-	AppRouter(api, a)
+	// X-Forwarded-For и X-Real-IP клиент подставляет сам: без доверенных прокси ClientIP - адрес соединения,
+	// иначе подделкой заголовка можно получить свежий бюджет RateLimit
+	_ = handler.SetTrustedProxies(nil)
+	if o.tracerProvider != nil {
+		handler.Use(tracing.Middleware(o.tracerProvider))
+	}
+	handler.Use(metrics.New(o.registry).Middleware())
+	handler.GET("/metrics", gin.WrapH(promhttp.HandlerFor(o.registry, promhttp.HandlerOpts{})))
+	HealthRouter(handler, o.healthChecker)
+
+	s := &http.Server{Addr: port, Handler: handler, TLSConfig: o.tlsConfig}
+	api := handler.Group(OpenAPIPrefix)
+	if o.gateway != nil {
+		// спецификация описывает и маршруты, которых у шлюза нет (импорт, события, вебхуки), поэтому её шлюз не отдаёт
+		GatewayRouter(api, o.gateway.backend, o.gateway.routes, o.idempotencyStore, o.limiter, o.adminToken)
+		return s
+	}
+	DocsRouter(handler.Group(OpenAPIPrefix))
+	AppRouter(api, a, o.idempotencyStore, o.limiter, o.events, o.adminToken)
 	return s
 }
@@ -0,0 +1,136 @@
+package multiplex
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+)
+
+// IsGRPC отличает вызов gRPC от обычного HTTP-запроса: gRPC ходит только по HTTP/2
+// и всегда выставляет content-type application/grpc (возможно, с суффиксом +proto)
+func IsGRPC(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
+
+// Handler отправляет вызовы gRPC в grpcHandler, всё остальное - в httpHandler
+func Handler(grpcHandler http.Handler, httpHandler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if IsGRPC(r) {
+			grpcHandler.ServeHTTP(w, r)
+			return
+		}
+		httpHandler.ServeHTTP(w, r)
+	})
+}
+
+// Server обслуживает gin и AdService на одном порту. Без TLS HTTP/2 принимается в виде h2c,
+// с TLS - через ALPN
+type Server struct {
+	srv  *http.Server
+	grpc *grpc.Server
+	tls  bool
+
+	mu     sync.Mutex
+	active int
+	idle   chan struct{}
+}
+
+// New берёт у httpServer адрес, обработчик, TLS и таймауты на заголовки и простой соединения.
+// ReadTimeout и WriteTimeout не переносятся: они оборвали бы долгие gRPC-вызовы
+func New(grpcServer *grpc.Server, httpServer *http.Server) (*Server, error) {
+	s := &Server{grpc: grpcServer, tls: httpServer.TLSConfig != nil}
+	h2s := &http2.Server{IdleTimeout: httpServer.IdleTimeout}
+	s.srv = &http.Server{
+		Addr:              httpServer.Addr,
+		Handler:           h2c.NewHandler(s.track(Handler(grpcServer, httpServer.Handler)), h2s),
+		TLSConfig:         httpServer.TLSConfig,
+		ReadHeaderTimeout: httpServer.ReadHeaderTimeout,
+		IdleTimeout:       httpServer.IdleTimeout,
+	}
+	// ConfigureServer всегда заполняет TLSConfig, поэтому режим запоминается заранее в s.tls.
+	// Кроме ALPN для TLS, это рассылает GOAWAY всем HTTP/2-соединениям (и h2c тоже) при Shutdown
+	if err := http2.ConfigureServer(s.srv, h2s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Server) Addr() string {
+	return s.srv.Addr
+}
+
+func (s *Server) ListenAndServe() error {
+	lis, err := net.Listen("tcp", s.srv.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(lis)
+}
+
+// Serve возвращает http.ErrServerClosed после Shutdown, как и http.Server
+func (s *Server) Serve(lis net.Listener) error {
+	if s.tls {
+		return s.srv.ServeTLS(lis, "", "")
+	}
+	return s.srv.Serve(lis)
+}
+
+// Shutdown перестаёт принимать соединения, просит клиентов HTTP/2 уйти (GOAWAY) и ждёт,
+// пока завершатся начатые запросы обоих протоколов. h2c-соединения перехвачены у http.Server,
+// поэтому он сам их не дожидается, а grpc.Server.GracefulStop не умеет работать с ServeHTTP -
+// отсюда собственный счётчик запросов
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.srv.Shutdown(ctx)
+	if waitErr := s.waitIdle(ctx); err == nil {
+		err = waitErr
+	}
+	s.grpc.Stop()
+	return err
+}
+
+func (s *Server) track(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.active++
+		s.mu.Unlock()
+		defer s.release()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active--
+	if s.active == 0 && s.idle != nil {
+		close(s.idle)
+		s.idle = nil
+	}
+}
+
+func (s *Server) waitIdle(ctx context.Context) error {
+	s.mu.Lock()
+	if s.active == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	if s.idle == nil {
+		s.idle = make(chan struct{})
+	}
+	idle := s.idle
+	s.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
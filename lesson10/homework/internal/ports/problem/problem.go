@@ -0,0 +1,48 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ContentType - тип тела ответа с описанием ошибки по RFC 7807
+const ContentType = "application/problem+json"
+
+// InvalidParam - поле запроса, не прошедшее валидацию
+type InvalidParam struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// Problem - описание ошибки по RFC 7807 (problem details for HTTP APIs).
+// invalid_params - расширение из примера в самом RFC
+type Problem struct {
+	Type          string         `json:"type"`
+	Title         string         `json:"title"`
+	Status        int            `json:"status"`
+	Detail        string         `json:"detail,omitempty"`
+	InvalidParams []InvalidParam `json:"invalid_params,omitempty"`
+}
+
+// Validation описывает ошибку валидации с перечнем неверных полей
+func Validation(detail string, params []InvalidParam) Problem {
+	return Problem{
+		Type:          "about:blank",
+		Title:         http.StatusText(http.StatusBadRequest),
+		Status:        http.StatusBadRequest,
+		Detail:        detail,
+		InvalidParams: params,
+	}
+}
+
+// Write отвечает описанием ошибки с кодом p.Status
+func Write(w http.ResponseWriter, p Problem) {
+	body, err := json.Marshal(p)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"container/list"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit - параметры token bucket: Rate токенов в секунду, не больше Burst накопленных.
+// Нулевой Rate отключает ограничение
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+type Config struct {
+	Read  Limit
+	Write Limit
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Read:  Limit{Rate: 50, Burst: 100},
+		Write: Limit{Rate: 5, Burst: 10},
+	}
+}
+
+const maxBuckets = 100000
+
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+// Limiter хранит не больше maxBuckets корзин; при переполнении вытесняется та, к которой дольше всего
+// не обращались. Она успела наполниться раньше всех остальных, так что её ключ почти наверняка
+// ничего не теряет, а если нет - получит свежий бюджет
+type Limiter struct {
+	cfg        Config
+	maxBuckets int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	order   *list.List // в начале - последняя использованная корзина
+}
+
+func New(cfg Config) *Limiter {
+	return &Limiter{
+		cfg:        cfg,
+		maxBuckets: maxBuckets,
+		now:        time.Now,
+		buckets:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// Allow списывает токен из бюджета ключа на чтение или запись. Если токенов нет,
+// возвращает время, через которое появится следующий
+func (l *Limiter) Allow(key string, write bool) (bool, time.Duration) {
+	limit, prefix := l.cfg.Read, "r:"
+	if write {
+		limit, prefix = l.cfg.Write, "w:"
+	}
+	if limit.Rate <= 0 {
+		return true, 0
+	}
+	burst := math.Max(float64(limit.Burst), 1)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b := l.bucket(prefix+key, burst, now)
+
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+	return false, wait
+}
+
+// bucket возвращает корзину ключа, создавая полную, и отмечает её как последнюю использованную
+func (l *Limiter) bucket(key string, burst float64, now time.Time) *bucket {
+	if elem, ok := l.buckets[key]; ok {
+		l.order.MoveToFront(elem)
+		return elem.Value.(*bucket)
+	}
+
+	if len(l.buckets) >= l.maxBuckets {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.buckets, oldest.Value.(*bucket).key)
+	}
+	b := &bucket{key: key, tokens: burst, last: now}
+	l.buckets[key] = l.order.PushFront(b)
+	return b
+}
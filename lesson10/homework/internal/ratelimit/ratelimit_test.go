@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLimiter(cfg Config) (*Limiter, *time.Time) {
+	now := time.Date(2023, 4, 5, 0, 0, 0, 0, time.UTC)
+	l := New(cfg)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	l, now := newTestLimiter(Config{Read: Limit{Rate: 10, Burst: 2}, Write: Limit{Rate: 1, Burst: 1}})
+
+	tests := []struct {
+		name    string
+		key     string
+		write   bool
+		advance time.Duration
+		allowed bool
+		wait    time.Duration
+	}{
+		{"first read", "a", false, 0, true, 0},
+		{"burst read", "a", false, 0, true, 0},
+		{"read over burst", "a", false, 0, false, 100 * time.Millisecond},
+		{"other key has own budget", "b", false, 0, true, 0},
+		{"write budget is separate", "a", true, 0, true, 0},
+		{"write over burst", "a", true, 0, false, time.Second},
+		{"read refilled", "a", false, 100 * time.Millisecond, true, 0},
+		{"write not refilled yet", "a", true, 500 * time.Millisecond, false, 400 * time.Millisecond},
+		{"write refilled", "a", true, 400 * time.Millisecond, true, 0},
+	}
+
+	for _, test := range tests {
+		*now = now.Add(test.advance)
+		allowed, wait := l.Allow(test.key, test.write)
+		assert.Equal(t, test.allowed, allowed, test.name)
+		assert.InDelta(t, test.wait, wait, float64(time.Millisecond), test.name)
+	}
+}
+
+func TestLimiter_Disabled(t *testing.T) {
+	l, _ := newTestLimiter(Config{Read: Limit{Rate: 0}, Write: Limit{Rate: 1, Burst: 1}})
+
+	for i := 0; i < 100; i++ {
+		allowed, _ := l.Allow("a", false)
+		assert.True(t, allowed)
+	}
+}
+
+func TestLimiter_Evict(t *testing.T) {
+	l, _ := newTestLimiter(Config{Read: Limit{Rate: 1, Burst: 1}, Write: Limit{Rate: 1, Burst: 1}})
+	l.maxBuckets = 2
+
+	l.Allow("a", false)
+	l.Allow("b", true)
+	allowed, _ := l.Allow("a", false)
+	assert.False(t, allowed)
+
+	// корзин не больше maxBuckets, вытесняется самая давно использованная
+	l.Allow("c", false)
+	assert.Len(t, l.buckets, 2)
+	assert.Contains(t, l.buckets, "r:a")
+	assert.Contains(t, l.buckets, "r:c")
+
+	allowed, _ = l.Allow("a", false)
+	assert.False(t, allowed, "корзина a осталась пустой")
+	allowed, _ = l.Allow("b", true)
+	assert.True(t, allowed, "корзина b вытеснена, ключ получил свежий бюджет")
+}
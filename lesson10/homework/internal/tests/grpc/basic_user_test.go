@@ -2,6 +2,12 @@ package grpc
 
 import (
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/app"
+	"homework10/internal/mailer"
 	grpcPort "homework10/internal/ports/grpc"
 	"homework10/internal/ports/grpc/proto"
 	"testing"
@@ -18,19 +24,41 @@ func TestGRPCCreateUser(t *testing.T) {
 }
 
 func TestGRPCUpdateUser(t *testing.T) {
-	client, ctx := getTestClient(t)
+	mail := mailer.NewCapture()
+	client, ctx := newTestClient(t, app.NewApp(adrepo.New(), app.WithMailer(mail)))
 
 	_, errUser0 := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "og buda", Email: "buda@phystech.edu"})
 	assert.NoError(t, errUser0)
 	_, errUser1 := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "oxxxymiron", Email: "oxxxymiron@phystech.edu"})
 	assert.NoError(t, errUser1)
+	setPassword(t, ctx, client, mail, "oxxxymiron@phystech.edu", "long enough")
 
-	response, err := client.UpdateUser(ctx, &proto.UpdateUserRequest{UserId: 1, Nickname: "hello", Email: "hello@yandex.ru"})
+	response, err := client.UpdateUser(ctx, &proto.UpdateUserRequest{UserId: 1, Nickname: "hello", Email: "hello@yandex.ru", Password: "long enough"})
 	assert.NoError(t, err)
 	assert.Equal(t, response.GetNickname(), "hello")
 	assert.Equal(t, response.GetEmail(), "hello@yandex.ru")
 }
 
+func TestGRPCUpdateUser_EmailNeedsPassword(t *testing.T) {
+	mail := mailer.NewCapture()
+	client, ctx := newTestClient(t, app.NewApp(adrepo.New(), app.WithMailer(mail)))
+
+	user, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "og buda", Email: "buda@phystech.edu"})
+	assert.NoError(t, err)
+
+	// ник меняется и без пароля, а email - только с текущим паролем
+	_, err = client.UpdateUser(ctx, &proto.UpdateUserRequest{UserId: user.GetId(), Nickname: "buda", Email: "buda@phystech.edu"})
+	assert.NoError(t, err)
+	_, err = client.UpdateUser(ctx, &proto.UpdateUserRequest{UserId: user.GetId(), Nickname: "buda", Email: "attacker@phystech.edu"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	setPassword(t, ctx, client, mail, user.GetEmail(), "long enough")
+	_, err = client.UpdateUser(ctx, &proto.UpdateUserRequest{UserId: user.GetId(), Nickname: "buda", Email: "attacker@phystech.edu", Password: "wrong password"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, ok := mail.Last("attacker@phystech.edu")
+	assert.False(t, ok, "письмо на чужой адрес не уходит")
+}
+
 func TestGRPCGetUser(t *testing.T) {
 	client, ctx := getTestClient(t)
 
@@ -41,7 +69,13 @@ func TestGRPCGetUser(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Zero(t, resp.GetId())
 	assert.Equal(t, resp.GetNickname(), "og buda")
-	assert.Equal(t, resp.Email, "buda@phystech.edu")
+	assert.Nil(t, resp.Email)
+
+	// x-user-id клиент выбирает сам, поэтому email он не открывает
+	ownCtx := metadata.AppendToOutgoingContext(ctx, "x-user-id", "0")
+	resp, err = client.GetUser(ownCtx, &proto.GetUserRequest{Id: 0})
+	assert.NoError(t, err)
+	assert.Nil(t, resp.Email)
 }
 
 func TestGRPCDeleteUser(t *testing.T) {
@@ -70,3 +104,163 @@ func TestGRPCCreateAdByDeletedUser(t *testing.T) {
 	_, err = client.CreateAd(ctx, &proto.CreateAdRequest{UserId: 0, Title: "ok", Text: "ok"})
 	assert.ErrorIs(t, err, grpcPort.ErrIncorrectUserId.Err())
 }
+
+func TestGRPCCreateUser_EmailTaken(t *testing.T) {
+	client, ctx := getTestClient(t)
+
+	_, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "og buda", Email: "buda@phystech.edu"})
+	assert.NoError(t, err)
+
+	_, err = client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "mayot", Email: "BUDA@phystech.edu"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	_, err = client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "Og Buda", Email: "mayot@phystech.edu"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+}
+
+func TestGRPCGetUserByEmail(t *testing.T) {
+	client, ctx := newTestClient(t, app.NewApp(adrepo.New()), grpcPort.WithAdminToken("admin secret"))
+	adminCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer admin secret")
+
+	user, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "og buda", Email: " Buda@phystech.edu"})
+	assert.NoError(t, err)
+	assert.Equal(t, "buda@phystech.edu", user.GetEmail())
+
+	resp, err := client.GetUserByEmail(adminCtx, &proto.GetUserByEmailRequest{Email: "BUDA@PHYSTECH.EDU"})
+	assert.NoError(t, err)
+	assert.Equal(t, user.GetId(), resp.GetId())
+
+	_, err = client.GetUserByEmail(adminCtx, &proto.GetUserByEmailRequest{Email: "mayot@phystech.edu"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// без токена поиск по email не отвечает, есть ли такой адрес
+	_, err = client.GetUserByEmail(ctx, &proto.GetUserByEmailRequest{Email: "buda@phystech.edu"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	wrongCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer wrong")
+	_, err = client.GetUserByEmail(wrongCtx, &proto.GetUserByEmailRequest{Email: "buda@phystech.edu"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestGRPCGetUserByEmail_DisabledWithoutToken(t *testing.T) {
+	client, ctx := getTestClient(t)
+	adminCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer admin secret")
+
+	_, err := client.GetUserByEmail(adminCtx, &proto.GetUserByEmailRequest{Email: "buda@phystech.edu"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestGRPCEmailVerification(t *testing.T) {
+	mail := mailer.NewCapture()
+	client, ctx := newTestClient(t, app.NewApp(adrepo.New(), app.WithMailer(mail)))
+
+	user, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "og buda", Email: "buda@phystech.edu"})
+	assert.NoError(t, err)
+	assert.False(t, user.GetEmailVerified())
+	ad, err := client.CreateAd(ctx, &proto.CreateAdRequest{Title: "hello", Text: "world", UserId: user.GetId()})
+	assert.NoError(t, err)
+
+	_, err = client.ChangeAdStatus(ctx, &proto.ChangeAdStatusRequest{AdId: ad.GetId(), UserId: user.GetId(), Published: true})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	first := mailToken(mail, user.GetEmail())
+	_, err = client.RequestEmailVerification(ctx, &proto.RequestEmailVerificationRequest{UserId: user.GetId()})
+	assert.NoError(t, err)
+	_, err = client.ConfirmEmail(ctx, &proto.ConfirmEmailRequest{Token: first})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	confirmed, err := client.ConfirmEmail(ctx, &proto.ConfirmEmailRequest{Token: mailToken(mail, user.GetEmail())})
+	assert.NoError(t, err)
+	assert.True(t, confirmed.GetEmailVerified())
+
+	_, err = client.RequestEmailVerification(ctx, &proto.RequestEmailVerificationRequest{UserId: user.GetId()})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = client.ChangeAdStatus(ctx, &proto.ChangeAdStatusRequest{AdId: ad.GetId(), UserId: user.GetId(), Published: true})
+	assert.NoError(t, err)
+}
+
+func TestGRPCPasswordReset(t *testing.T) {
+	mail := mailer.NewCapture()
+	client, ctx := newTestClient(t, app.NewApp(adrepo.New(), app.WithMailer(mail)))
+
+	user, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "og buda", Email: "buda@phystech.edu"})
+	assert.NoError(t, err)
+
+	_, err = client.RequestPasswordReset(ctx, &proto.RequestPasswordResetRequest{Email: "nobody@phystech.edu"})
+	assert.NoError(t, err)
+	_, err = client.RequestPasswordReset(ctx, &proto.RequestPasswordResetRequest{Email: user.GetEmail()})
+	assert.NoError(t, err)
+	token := mailToken(mail, user.GetEmail())
+
+	_, err = client.ResetPassword(ctx, &proto.ResetPasswordRequest{Token: token, Password: "short"})
+	assertValidationError(t, err, "password")
+	_, err = client.ResetPassword(ctx, &proto.ResetPasswordRequest{Token: token, Password: "long enough"})
+	assert.NoError(t, err)
+	_, err = client.ResetPassword(ctx, &proto.ResetPasswordRequest{Token: token, Password: "long enough"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	got, err := client.GetUser(ctx, &proto.GetUserRequest{Id: user.GetId()})
+	assert.NoError(t, err)
+	assert.True(t, got.GetEmailVerified())
+}
+
+func TestGRPCUpdateProfile(t *testing.T) {
+	mail := mailer.NewCapture()
+	client, ctx := newTestClient(t, app.NewApp(adrepo.New(), app.WithMailer(mail)))
+
+	user, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "og buda", Email: "buda@phystech.edu"})
+	assert.NoError(t, err)
+	assert.NotNil(t, user.GetRegisteredAt())
+
+	// без пароля профиль не меняется
+	_, err = client.UpdateProfile(ctx, &proto.UpdateProfileRequest{UserId: user.GetId(), City: "Moscow"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.RequestPasswordReset(ctx, &proto.RequestPasswordResetRequest{Email: user.GetEmail()})
+	assert.NoError(t, err)
+	_, err = client.ResetPassword(ctx, &proto.ResetPasswordRequest{Token: mailToken(mail, user.GetEmail()), Password: "long enough"})
+	assert.NoError(t, err)
+
+	_, err = client.UpdateProfile(ctx, &proto.UpdateProfileRequest{UserId: user.GetId(), City: "Moscow", Password: "wrong password"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	updated, err := client.UpdateProfile(ctx, &proto.UpdateProfileRequest{
+		UserId:      user.GetId(),
+		Password:    "long enough",
+		DisplayName: "Buda",
+		Phone:       "+7 999 123-45-67",
+		City:        "Moscow",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Buda", updated.GetDisplayName())
+	assert.Equal(t, "+79991234567", updated.GetPhone())
+
+	public, err := client.GetUser(ctx, &proto.GetUserRequest{Id: user.GetId()})
+	assert.NoError(t, err)
+	assert.Equal(t, "Moscow", public.GetCity())
+	assert.Nil(t, public.Phone)
+
+	_, err = client.UpdateProfile(ctx, &proto.UpdateProfileRequest{UserId: user.GetId(), Password: "long enough", AvatarUrl: "ftp://example.com/buda.png"})
+	assertValidationError(t, err, "avatar_url")
+	_, err = client.UpdateProfile(ctx, &proto.UpdateProfileRequest{UserId: 42})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGRPCListUserAds(t *testing.T) {
+	client, ctx := getTestClient(t)
+
+	user, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "og buda", Email: "buda@phystech.edu"})
+	assert.NoError(t, err)
+	published, err := client.CreateAd(ctx, &proto.CreateAdRequest{Title: "hello", Text: "world", UserId: user.GetId()})
+	assert.NoError(t, err)
+	_, err = client.CreateAd(ctx, &proto.CreateAdRequest{Title: "draft", Text: "world", UserId: user.GetId()})
+	assert.NoError(t, err)
+	_, err = client.ChangeAdStatus(ctx, &proto.ChangeAdStatusRequest{AdId: published.GetId(), UserId: user.GetId(), Published: true})
+	assert.NoError(t, err)
+
+	list, err := client.ListUserAds(ctx, &proto.ListUserAdsRequest{UserId: user.GetId()})
+	assert.NoError(t, err)
+	assert.Len(t, list.GetList(), 1)
+	assert.Equal(t, published.GetId(), list.GetList()[0].GetId())
+
+	_, err = client.ListUserAds(ctx, &proto.ListUserAdsRequest{UserId: 42})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
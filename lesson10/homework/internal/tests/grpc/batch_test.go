@@ -0,0 +1,66 @@
+package grpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"homework10/internal/ports/grpc/proto"
+)
+
+func TestGRPCBatchGet(t *testing.T) {
+	client, ctx := getTestClient(t)
+
+	user, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "og buda", Email: "buda@phystech.edu"})
+	require.NoError(t, err)
+	ad, err := client.CreateAd(ctx, &proto.CreateAdRequest{UserId: user.Id, Title: "hello", Text: "world"})
+	require.NoError(t, err)
+
+	ads, err := client.BatchGetAds(ctx, &proto.BatchGetRequest{Ids: []int64{ad.Id, 100}})
+	require.NoError(t, err)
+	require.Len(t, ads.Ads, 1)
+	assert.Equal(t, "hello", ads.Ads[0].Title)
+	assert.Equal(t, []int64{100}, ads.MissingIds)
+
+	users, err := client.BatchGetUsers(ctx, &proto.BatchGetRequest{Ids: []int64{7, user.Id}})
+	require.NoError(t, err)
+	require.Len(t, users.Users, 1)
+	assert.Equal(t, "og buda", users.Users[0].Nickname)
+	assert.Equal(t, []int64{7}, users.MissingIds)
+
+	_, err = client.BatchGetAds(ctx, &proto.BatchGetRequest{Ids: make([]int64, 101)})
+	assertValidationError(t, err, "ids")
+}
+
+func TestGRPCBatchChangeAdStatus(t *testing.T) {
+	client, ctx := getTestClient(t)
+
+	user0, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "og buda", Email: "buda@phystech.edu"})
+	require.NoError(t, err)
+	user1, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "oxxxymiron", Email: "oxxxymiron@phystech.edu"})
+	require.NoError(t, err)
+	ad0, err := client.CreateAd(ctx, &proto.CreateAdRequest{UserId: user0.Id, Title: "first", Text: "text"})
+	require.NoError(t, err)
+	ad1, err := client.CreateAd(ctx, &proto.CreateAdRequest{UserId: user0.Id, Title: "second", Text: "text"})
+	require.NoError(t, err)
+	foreign, err := client.CreateAd(ctx, &proto.CreateAdRequest{UserId: user1.Id, Title: "foreign", Text: "text"})
+	require.NoError(t, err)
+
+	_, err = client.BatchChangeAdStatus(ctx, &proto.BatchChangeAdStatusRequest{AdIds: []int64{ad0.Id, foreign.Id}, UserId: user0.Id, Published: true})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.BatchChangeAdStatus(ctx, &proto.BatchChangeAdStatusRequest{AdIds: []int64{ad0.Id, 100}, UserId: user0.Id, Published: true})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	got, err := client.GetAd(ctx, &proto.GetAdRequest{AdId: ad0.Id})
+	require.NoError(t, err)
+	assert.False(t, got.Published, "неудачный пакет не должен менять объявления")
+
+	list, err := client.BatchChangeAdStatus(ctx, &proto.BatchChangeAdStatusRequest{AdIds: []int64{ad0.Id, ad1.Id}, UserId: user0.Id, Published: true})
+	require.NoError(t, err)
+	require.Len(t, list.List, 2)
+	assert.True(t, list.List[0].Published)
+	assert.True(t, list.List[1].Published)
+}
@@ -3,20 +3,69 @@ package grpc
 import (
 	"context"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 	"homework10/internal/adapters/adrepo"
 	"homework10/internal/app"
+	"homework10/internal/mailer"
 	grpcPort "homework10/internal/ports/grpc"
 	"homework10/internal/ports/grpc/proto"
 	"net"
+	"strings"
 	"testing"
 	"time"
 )
 
 func getTestClient(t *testing.T) (proto.AdServiceClient, context.Context) {
-	adApp := app.NewApp(adrepo.New())
-	srv, lis := grpcPort.TestNewGRPCServer(1024*1024, adApp)
+	mail := mailer.NewCapture()
+	client, ctx := newTestClient(t, app.NewApp(adrepo.New(), app.WithMailer(mail)))
+	return verifyingClient{AdServiceClient: client, mail: mail}, ctx
+}
+
+// verifyingClient сразу подтверждает email созданных пользователей, чтобы они могли публиковать объявления
+type verifyingClient struct {
+	proto.AdServiceClient
+	mail *mailer.Capture
+}
+
+func (c verifyingClient) CreateUser(ctx context.Context, in *proto.CreateUserRequest, opts ...grpc.CallOption) (*proto.UserResponse, error) {
+	user, err := c.AdServiceClient.CreateUser(ctx, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return c.ConfirmEmail(ctx, &proto.ConfirmEmailRequest{Token: mailToken(c.mail, user.GetEmail())})
+}
+
+// setPassword задаёт пароль пользователю через письмо со сбросом пароля
+func setPassword(t *testing.T, ctx context.Context, client proto.AdServiceClient, mail *mailer.Capture, email string, password string) {
+	t.Helper()
+	_, err := client.RequestPasswordReset(ctx, &proto.RequestPasswordResetRequest{Email: email})
+	assert.NoError(t, err)
+	_, err = client.ResetPassword(ctx, &proto.ResetPasswordRequest{Token: mailToken(mail, email), Password: password})
+	assert.NoError(t, err)
+}
+
+// mailToken достаёт токен из последнего письма на адрес to
+func mailToken(mail *mailer.Capture, to string) string {
+	msg, _ := mail.Last(to)
+	for _, line := range strings.Split(msg.Body, "\n") {
+		if token, ok := strings.CutPrefix(line, "Token: "); ok {
+			return token
+		}
+	}
+	return ""
+}
+
+func newTestClient(t *testing.T, adApp app.App, opts ...grpcPort.Option) (proto.AdServiceClient, context.Context) {
+	conn, ctx := dialTestServer(t, adApp, opts...)
+	return proto.NewAdServiceClient(conn), ctx
+}
+
+func dialTestServer(t *testing.T, adApp app.App, opts ...grpcPort.Option) (*grpc.ClientConn, context.Context) {
+	srv, lis := grpcPort.TestNewGRPCServer(1024*1024, adApp, opts...)
 
 	t.Cleanup(func() {
 		_ = lis.Close()
@@ -46,6 +95,23 @@ func getTestClient(t *testing.T) (proto.AdServiceClient, context.Context) {
 		_ = conn.Close()
 	})
 
-	client := proto.NewAdServiceClient(conn)
-	return client, ctx
+	return conn, ctx
+}
+
+// assertValidationError проверяет, что ошибка валидации указывает на поле field
+func assertValidationError(t *testing.T, err error, field string) {
+	t.Helper()
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+
+	var fields []string
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			for _, v := range badRequest.GetFieldViolations() {
+				fields = append(fields, v.GetField())
+			}
+		}
+	}
+	assert.Contains(t, fields, field)
 }
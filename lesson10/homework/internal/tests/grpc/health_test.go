@@ -0,0 +1,49 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/app"
+	"homework10/internal/health"
+	grpcPort "homework10/internal/ports/grpc"
+	"homework10/internal/ratelimit"
+)
+
+func TestGRPCHealth(t *testing.T) {
+	checker := health.New(health.DefaultTimeout)
+	checker.Register("repository", func(ctx context.Context) error { return nil })
+	limiter := ratelimit.New(ratelimit.Config{Write: ratelimit.Limit{Rate: 0.001, Burst: 1}, Read: ratelimit.Limit{Rate: 0.001, Burst: 1}})
+	conn, ctx := dialTestServer(t, app.NewApp(adrepo.New()), grpcPort.WithHealthChecker(checker), grpcPort.WithRateLimiter(limiter))
+	client := healthpb.NewHealthClient(conn)
+
+	for _, service := range []string{"", "ad.AdService"} {
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		assert.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+	}
+
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "unknown"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	watch, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	resp, err := watch.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	checker.Shutdown()
+	resp, err = client.Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+
+	resp, err = watch.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+}
@@ -0,0 +1,36 @@
+package grpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/app"
+	grpcPort "homework10/internal/ports/grpc"
+	"homework10/internal/ports/grpc/proto"
+)
+
+func TestGRPCCreateUserIdempotencyKey(t *testing.T) {
+	// без подтверждения email: повтор отдаёт сохранённый ответ, и письма с новым токеном нет
+	client, ctx := newTestClient(t, app.NewApp(adrepo.New()))
+	keyCtx := metadata.AppendToOutgoingContext(ctx, grpcPort.IdempotencyKeyMetadata, "key-1")
+
+	first, err := client.CreateUser(keyCtx, &proto.CreateUserRequest{Nickname: "Oleg", Email: "oleg@phystech.edu"})
+	assert.NoError(t, err)
+
+	var header metadata.MD
+	retry, err := client.CreateUser(keyCtx, &proto.CreateUserRequest{Nickname: "Oleg", Email: "oleg@phystech.edu"}, grpc.Header(&header))
+	assert.NoError(t, err)
+	assert.Equal(t, first.GetId(), retry.GetId())
+	assert.Equal(t, []string{"true"}, header.Get(grpcPort.IdempotencyReplayedMetadata))
+
+	_, err = client.CreateUser(keyCtx, &proto.CreateUserRequest{Nickname: "Ivan", Email: "ivan@phystech.edu"})
+	assert.ErrorIs(t, err, grpcPort.ErrIdempotencyKeyReused.Err())
+
+	other, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "Oleg 2", Email: "oleg2@phystech.edu"})
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.GetId(), other.GetId())
+}
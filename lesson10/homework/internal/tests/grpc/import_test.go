@@ -0,0 +1,44 @@
+package grpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"homework10/internal/ports/grpc/proto"
+)
+
+func TestGRPCImportAds(t *testing.T) {
+	client, ctx := getTestClient(t)
+
+	_, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "og buda", Email: "buda@phystech.edu"})
+	require.NoError(t, err)
+
+	stream, err := client.ImportAds(ctx)
+	require.NoError(t, err)
+	for _, req := range []*proto.CreateAdRequest{
+		{UserId: 0, Title: "hello", Text: "world"},
+		{UserId: 0, Title: "", Text: "no title"},
+		{UserId: 100, Title: "stranger", Text: "text"},
+		{UserId: 0, Title: "second", Text: "ad"},
+	} {
+		require.NoError(t, stream.Send(req))
+	}
+	res, err := stream.CloseAndRecv()
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(2), res.Imported)
+	assert.Equal(t, int64(2), res.Failed)
+	require.Len(t, res.Failures, 2)
+	assert.Equal(t, int64(2), res.Failures[0].Row)
+	require.Len(t, res.Failures[0].FieldViolations, 1)
+	assert.Equal(t, "title", res.Failures[0].FieldViolations[0].Field)
+	assert.Equal(t, int64(3), res.Failures[1].Row)
+	assert.Empty(t, res.Failures[1].FieldViolations)
+	assert.NotEmpty(t, res.Failures[1].Message)
+
+	list, err := client.ListAdsByTitle(ctx, &proto.GetListAdsByTitleRequest{Title: "second"})
+	require.NoError(t, err)
+	assert.Len(t, list.List, 1)
+}
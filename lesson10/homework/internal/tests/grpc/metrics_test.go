@@ -0,0 +1,56 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/app"
+	grpcPort "homework10/internal/ports/grpc"
+	"homework10/internal/ports/grpc/proto"
+)
+
+func TestGRPCMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	srv, lis := grpcPort.TestNewGRPCServer(1024*1024, app.NewApp(adrepo.New()), grpcPort.WithMetricsRegistry(registry))
+	t.Cleanup(srv.Stop)
+	go func() {
+		assert.NoError(t, srv.Serve(lis), "srv.Serve")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t.Cleanup(cancel)
+	conn, err := grpc.DialContext(ctx, "", grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	client := proto.NewAdServiceClient(conn)
+
+	_, err = client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "Oleg", Email: "oleg@phystech.edu"})
+	assert.NoError(t, err)
+	_, err = client.GetAd(ctx, &proto.GetAdRequest{AdId: 100})
+	assert.Error(t, err)
+	stream, err := client.ImportAds(ctx)
+	assert.NoError(t, err)
+	_, err = stream.CloseAndRecv()
+	assert.NoError(t, err)
+
+	expected := `
+# HELP adservice_grpc_requests_total Number of gRPC requests by method and status code.
+# TYPE adservice_grpc_requests_total counter
+adservice_grpc_requests_total{code="NotFound",method="/ad.AdService/GetAd"} 1
+adservice_grpc_requests_total{code="OK",method="/ad.AdService/CreateUser"} 1
+adservice_grpc_requests_total{code="OK",method="/ad.AdService/ImportAds"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "adservice_grpc_requests_total"))
+}
@@ -0,0 +1,83 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/app"
+	grpcPort "homework10/internal/ports/grpc"
+	"homework10/internal/ports/grpc/proto"
+	"homework10/internal/ratelimit"
+)
+
+func TestGRPCRateLimit(t *testing.T) {
+	limiter := ratelimit.New(ratelimit.Config{Read: ratelimit.Limit{Rate: 0.001, Burst: 1}, Write: ratelimit.Limit{Rate: 0.001, Burst: 1}})
+	srv, lis := grpcPort.TestNewGRPCServer(1024*1024, app.NewApp(adrepo.New()), grpcPort.WithRateLimiter(limiter))
+	t.Cleanup(srv.Stop)
+	go func() {
+		assert.NoError(t, srv.Serve(lis), "srv.Serve")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t.Cleanup(cancel)
+	conn, err := grpc.DialContext(ctx, "", grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	client := proto.NewAdServiceClient(conn)
+
+	user, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "Oleg", Email: "oleg@phystech.edu"})
+	assert.NoError(t, err)
+	_, err = client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "Ivan", Email: "ivan@phystech.edu"})
+	assert.ErrorIs(t, err, grpcPort.ErrTooManyRequests.Err())
+
+	_, err = client.GetUser(ctx, &proto.GetUserRequest{Id: user.GetId()})
+	assert.NoError(t, err)
+
+	var header metadata.MD
+	_, err = client.GetUser(ctx, &proto.GetUserRequest{Id: user.GetId()}, grpc.Header(&header))
+	assert.ErrorIs(t, err, grpcPort.ErrTooManyRequests.Err())
+	assert.NotEmpty(t, header.Get("retry-after"))
+
+	// новый x-user-id не даёт нового бюджета
+	userCtx := metadata.AppendToOutgoingContext(ctx, "x-user-id", "1")
+	_, err = client.GetUser(userCtx, &proto.GetUserRequest{Id: user.GetId()})
+	assert.ErrorIs(t, err, grpcPort.ErrTooManyRequests.Err())
+}
+
+func TestGRPCImportAdsRateLimit(t *testing.T) {
+	limiter := ratelimit.New(ratelimit.Config{Read: ratelimit.Limit{Rate: 0.001, Burst: 1}, Write: ratelimit.Limit{Rate: 0.001, Burst: 2}})
+	srv, lis := grpcPort.TestNewGRPCServer(1024*1024, app.NewApp(adrepo.New()), grpcPort.WithRateLimiter(limiter))
+	t.Cleanup(srv.Stop)
+	go func() {
+		assert.NoError(t, srv.Serve(lis), "srv.Serve")
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	t.Cleanup(cancel)
+	conn, err := grpc.DialContext(ctx, "", grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	client := proto.NewAdServiceClient(conn)
+
+	// каждое сообщение импорта списывает токен на запись
+	stream, err := client.ImportAds(ctx)
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_ = stream.Send(&proto.CreateAdRequest{UserId: 100, Title: "hello", Text: "world"})
+	}
+	_, err = stream.CloseAndRecv()
+	assert.ErrorIs(t, err, grpcPort.ErrTooManyRequests.Err())
+	assert.NotEmpty(t, stream.Trailer().Get("retry-after"))
+}
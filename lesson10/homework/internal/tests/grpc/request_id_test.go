@@ -0,0 +1,34 @@
+package grpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"homework10/internal/ports/grpc/proto"
+)
+
+func TestGRPCRequestID(t *testing.T) {
+	client, ctx := getTestClient(t)
+
+	var header metadata.MD
+	_, err := client.ListAdsByTitle(ctx, &proto.GetListAdsByTitleRequest{Title: "hello"}, grpc.Header(&header))
+	assert.NoError(t, err)
+	assert.Len(t, header.Get("x-request-id"), 1)
+	assert.Len(t, header.Get("x-request-id")[0], 32)
+
+	requestCtx := metadata.AppendToOutgoingContext(ctx, "x-request-id", "my-request-1")
+	_, err = client.ListAdsByTitle(requestCtx, &proto.GetListAdsByTitleRequest{Title: "hello"}, grpc.Header(&header))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"my-request-1"}, header.Get("x-request-id"))
+
+	stream, err := client.ImportAds(requestCtx)
+	assert.NoError(t, err)
+	_, err = stream.CloseAndRecv()
+	assert.NoError(t, err)
+	header, err = stream.Header()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"my-request-1"}, header.Get("x-request-id"))
+}
@@ -0,0 +1,81 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/app"
+	grpcPort "homework10/internal/ports/grpc"
+	"homework10/internal/ports/grpc/proto"
+	"homework10/internal/tlsutil"
+	"homework10/internal/tlsutil/tlstest"
+)
+
+func TestGRPCMutualTLS(t *testing.T) {
+	ca, err := tlstest.NewCA()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	serverCert, err := ca.Issue("adservice")
+	require.NoError(t, err)
+	certFile, keyFile, err := serverCert.Write(dir, "server")
+	require.NoError(t, err)
+	caFile, err := ca.WriteCA(dir)
+	require.NoError(t, err)
+
+	tlsConfig, err := tlsutil.ServerConfig(certFile, keyFile, caFile)
+	require.NoError(t, err)
+	srv, lis := grpcPort.TestNewGRPCServer(1024*1024, app.NewApp(adrepo.New()), grpcPort.WithTLSConfig(tlsConfig))
+	t.Cleanup(srv.Stop)
+	go func() {
+		assert.NoError(t, srv.Serve(lis), "srv.Serve")
+	}()
+
+	dial := func(clientTLS *tls.Config) proto.AdServiceClient {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		t.Cleanup(cancel)
+		conn, err := grpc.DialContext(ctx, "localhost", grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}), grpc.WithTransportCredentials(credentials.NewTLS(clientTLS)))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+		return proto.NewAdServiceClient(conn)
+	}
+
+	clientCert, err := ca.Issue("billing")
+	require.NoError(t, err)
+	certificate, err := clientCert.TLSCertificate()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client := dial(&tls.Config{RootCAs: ca.Pool(), ServerName: "localhost", Certificates: []tls.Certificate{certificate}})
+	user, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "Oleg", Email: "oleg@phystech.edu"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Oleg", user.GetNickname())
+
+	// без клиентского сертификата сервер обрывает рукопожатие
+	anonymous := dial(&tls.Config{RootCAs: ca.Pool(), ServerName: "localhost"})
+	_, err = anonymous.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "Oleg", Email: "oleg@phystech.edu"})
+	assert.Error(t, err)
+
+	// сертификат от чужого CA тоже не подходит
+	otherCA, err := tlstest.NewCA()
+	require.NoError(t, err)
+	stranger, err := otherCA.Issue("stranger")
+	require.NoError(t, err)
+	strangerCert, err := stranger.TLSCertificate()
+	require.NoError(t, err)
+	untrusted := dial(&tls.Config{RootCAs: ca.Pool(), ServerName: "localhost", Certificates: []tls.Certificate{strangerCert}})
+	_, err = untrusted.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "Oleg", Email: "oleg@phystech.edu"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,42 @@
+package grpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc/metadata"
+
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/app"
+	grpcPort "homework10/internal/ports/grpc"
+	"homework10/internal/ports/grpc/proto"
+	"homework10/internal/tracing"
+)
+
+func TestGRPCTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	adApp := tracing.NewApp(app.NewApp(tracing.NewRepository(adrepo.New(), tp)), tp)
+	client, ctx := newTestClient(t, adApp, grpcPort.WithTracerProvider(tp))
+
+	ctx = metadata.AppendToOutgoingContext(ctx, "traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	_, err := client.GetAd(ctx, &proto.GetAdRequest{AdId: 100})
+	assert.Error(t, err)
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext.TraceID().String(), span.Name)
+	}
+
+	serverSpan, ok := spans["ad.AdService/GetAd"]
+	assert.True(t, ok)
+	assert.Equal(t, "00f067aa0ba902b7", serverSpan.Parent.SpanID().String())
+	assert.Equal(t, codes.Error, serverSpan.Status.Code)
+	assert.Equal(t, serverSpan.SpanContext.SpanID(), spans["App.GetAd"].Parent.SpanID())
+	assert.Equal(t, codes.Error, spans["App.GetAd"].Status.Code)
+	assert.Equal(t, spans["App.GetAd"].SpanContext.SpanID(), spans["Repository.GetAdById"].Parent.SpanID())
+}
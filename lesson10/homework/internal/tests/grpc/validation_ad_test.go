@@ -2,7 +2,6 @@ package grpc
 
 import (
 	"github.com/stretchr/testify/assert"
-	grpcPort "homework10/internal/ports/grpc"
 	"homework10/internal/ports/grpc/proto"
 	"strings"
 	"testing"
@@ -17,7 +16,7 @@ func TestGRPCCreateAd_EmptyTitle(t *testing.T) {
 	assert.NoError(t, errUser1)
 
 	_, err := client.CreateAd(ctx, &proto.CreateAdRequest{UserId: 1, Title: "", Text: "world"})
-	assert.ErrorIs(t, err, grpcPort.ErrValidate.Err())
+	assertValidationError(t, err, "title")
 }
 
 func TestGRPCCreateAd_TooLongTitle(t *testing.T) {
@@ -31,7 +30,7 @@ func TestGRPCCreateAd_TooLongTitle(t *testing.T) {
 	title := strings.Repeat("a", 101)
 
 	_, err := client.CreateAd(ctx, &proto.CreateAdRequest{UserId: 1, Title: title, Text: "world"})
-	assert.ErrorIs(t, err, grpcPort.ErrValidate.Err())
+	assertValidationError(t, err, "title")
 }
 
 func TestGRPCCreateAd_EmptyText(t *testing.T) {
@@ -43,7 +42,7 @@ func TestGRPCCreateAd_EmptyText(t *testing.T) {
 	assert.NoError(t, errUser1)
 
 	_, err := client.CreateAd(ctx, &proto.CreateAdRequest{UserId: 1, Title: "title", Text: ""})
-	assert.ErrorIs(t, err, grpcPort.ErrValidate.Err())
+	assertValidationError(t, err, "text")
 }
 
 func TestGRPCCreateAd_TooLongText(t *testing.T) {
@@ -57,7 +56,7 @@ func TestGRPCCreateAd_TooLongText(t *testing.T) {
 	text := strings.Repeat("a", 501)
 
 	_, err := client.CreateAd(ctx, &proto.CreateAdRequest{UserId: 1, Title: "title", Text: text})
-	assert.ErrorIs(t, err, grpcPort.ErrValidate.Err())
+	assertValidationError(t, err, "text")
 }
 
 func TestGRPCUpdateAd_EmptyTitle(t *testing.T) {
@@ -72,7 +71,7 @@ func TestGRPCUpdateAd_EmptyTitle(t *testing.T) {
 	assert.NoError(t, err)
 
 	_, err = client.UpdateAd(ctx, &proto.UpdateAdRequest{UserId: 1, AdId: resp.Id, Title: "", Text: "new_world"})
-	assert.ErrorIs(t, err, grpcPort.ErrValidate.Err())
+	assertValidationError(t, err, "title")
 }
 
 func TestGRPCUpdateAd_TooLongTitle(t *testing.T) {
@@ -89,7 +88,7 @@ func TestGRPCUpdateAd_TooLongTitle(t *testing.T) {
 	title := strings.Repeat("a", 101)
 
 	_, err = client.UpdateAd(ctx, &proto.UpdateAdRequest{UserId: 1, AdId: resp.Id, Title: title, Text: "new_world"})
-	assert.ErrorIs(t, err, grpcPort.ErrValidate.Err())
+	assertValidationError(t, err, "title")
 }
 
 func TestGRPCUpdateAd_EmptyText(t *testing.T) {
@@ -104,7 +103,7 @@ func TestGRPCUpdateAd_EmptyText(t *testing.T) {
 	assert.NoError(t, err)
 
 	_, err = client.UpdateAd(ctx, &proto.UpdateAdRequest{UserId: 1, AdId: resp.Id, Title: "title", Text: ""})
-	assert.ErrorIs(t, err, grpcPort.ErrValidate.Err())
+	assertValidationError(t, err, "text")
 }
 
 func TestGRPCUpdateAd_TooLongText(t *testing.T) {
@@ -121,5 +120,5 @@ func TestGRPCUpdateAd_TooLongText(t *testing.T) {
 	assert.NoError(t, err)
 
 	_, err = client.UpdateAd(ctx, &proto.UpdateAdRequest{UserId: 1, AdId: resp.Id, Title: "title", Text: text})
-	assert.ErrorIs(t, err, grpcPort.ErrValidate.Err())
+	assertValidationError(t, err, "text")
 }
@@ -2,7 +2,6 @@ package grpc
 
 import (
 	"github.com/stretchr/testify/assert"
-	grpcPort "homework10/internal/ports/grpc"
 	"homework10/internal/ports/grpc/proto"
 	"strings"
 	"testing"
@@ -12,7 +11,7 @@ func TestGRPCCreateUser_EmptyNickname(t *testing.T) {
 	client, ctx := getTestClient(t)
 
 	_, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "", Email: "buda@phystech.edu"})
-	assert.ErrorIs(t, err, grpcPort.ErrValidate.Err())
+	assertValidationError(t, err, "nickname")
 }
 
 func TestGRPCCreateUser_TooLongNickname(t *testing.T) {
@@ -20,14 +19,14 @@ func TestGRPCCreateUser_TooLongNickname(t *testing.T) {
 
 	nickname := strings.Repeat("a", 101)
 	_, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: nickname, Email: "buda@phystech.edu"})
-	assert.ErrorIs(t, err, grpcPort.ErrValidate.Err())
+	assertValidationError(t, err, "nickname")
 }
 
 func TestGRPCCreateUser_EmptyEmail(t *testing.T) {
 	client, ctx := getTestClient(t)
 
 	_, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "nickname", Email: ""})
-	assert.ErrorIs(t, err, grpcPort.ErrValidate.Err())
+	assertValidationError(t, err, "email")
 }
 
 func TestGRPCCreateUser_TooLongEmail(t *testing.T) {
@@ -35,49 +34,49 @@ func TestGRPCCreateUser_TooLongEmail(t *testing.T) {
 
 	email := strings.Repeat("a", 501)
 	_, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "nickname", Email: email})
-	assert.ErrorIs(t, err, grpcPort.ErrValidate.Err())
+	assertValidationError(t, err, "email")
 }
 
 func TestGRPCUpdateUser_EmptyNickname(t *testing.T) {
 	client, ctx := getTestClient(t)
 
-	_, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "nickname", Email: "email"})
+	_, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "nickname", Email: "email@mail.ru"})
 	assert.NoError(t, err)
 
-	_, err = client.UpdateUser(ctx, &proto.UpdateUserRequest{UserId: 0, Nickname: "", Email: "new_world"})
-	assert.ErrorIs(t, err, grpcPort.ErrValidate.Err())
+	_, err = client.UpdateUser(ctx, &proto.UpdateUserRequest{UserId: 0, Nickname: "", Email: "new_world@mail.ru"})
+	assertValidationError(t, err, "nickname")
 }
 
 func TestGRPCUpdateUser_TooLongNickname(t *testing.T) {
 	client, ctx := getTestClient(t)
 
-	_, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "nickname", Email: "email"})
+	_, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "nickname", Email: "email@mail.ru"})
 	assert.NoError(t, err)
 
 	nickname := strings.Repeat("a", 101)
 
-	_, err = client.UpdateUser(ctx, &proto.UpdateUserRequest{UserId: 0, Nickname: nickname, Email: "world"})
-	assert.ErrorIs(t, err, grpcPort.ErrValidate.Err())
+	_, err = client.UpdateUser(ctx, &proto.UpdateUserRequest{UserId: 0, Nickname: nickname, Email: "world@mail.ru"})
+	assertValidationError(t, err, "nickname")
 }
 
 func TestGRPCUpdateAd_EmptyEmail(t *testing.T) {
 	client, ctx := getTestClient(t)
 
-	_, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "nickname", Email: "email"})
+	_, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "nickname", Email: "email@mail.ru"})
 	assert.NoError(t, err)
 
 	_, err = client.UpdateUser(ctx, &proto.UpdateUserRequest{UserId: 0, Nickname: "nickname", Email: ""})
-	assert.ErrorIs(t, err, grpcPort.ErrValidate.Err())
+	assertValidationError(t, err, "email")
 }
 
 func TestGRPCUpdateUser_TooLongEmail(t *testing.T) {
 	client, ctx := getTestClient(t)
 
-	_, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "nickname", Email: "email"})
+	_, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "nickname", Email: "email@mail.ru"})
 	assert.NoError(t, err)
 
 	email := strings.Repeat("a", 501)
 
 	_, err = client.UpdateUser(ctx, &proto.UpdateUserRequest{UserId: 0, Nickname: "nickname", Email: email})
-	assert.ErrorIs(t, err, grpcPort.ErrValidate.Err())
+	assertValidationError(t, err, "email")
 }
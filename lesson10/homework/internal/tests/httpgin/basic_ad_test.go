@@ -2,14 +2,14 @@ package httpgin
 
 import (
 	"github.com/stretchr/testify/assert"
-	"testing"
 	"time"
 )
 
 const dateFormat string = "2006-01-02"
 
-func TestCreateAd(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestCreateAd() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser)
@@ -26,8 +26,9 @@ func TestCreateAd(t *testing.T) {
 	assert.Equal(t, response.Data.DateUpdate.Format(dateFormat), now.Format(dateFormat))
 }
 
-func TestChangeAdStatus(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestChangeAdStatus() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser0 := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser0)
@@ -50,8 +51,9 @@ func TestChangeAdStatus(t *testing.T) {
 	assert.False(t, response.Data.Published)
 }
 
-func TestUpdateAd(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestUpdateAd() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser0 := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser0)
@@ -67,8 +69,9 @@ func TestUpdateAd(t *testing.T) {
 	assert.Equal(t, response.Data.Text, "мир")
 }
 
-func TestListAds(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestListAds() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser)
@@ -95,8 +98,9 @@ func TestListAds(t *testing.T) {
 	assert.Equal(t, ads.Data[0].DateUpdate.Format(dateFormat), now.Format(dateFormat))
 }
 
-func TestAdById(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestAdById() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser)
@@ -116,8 +120,9 @@ func TestAdById(t *testing.T) {
 	assert.Equal(t, ad.Data.DateUpdate.Format(dateFormat), now.Format(dateFormat))
 }
 
-func TestDeleteAdById(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestDeleteAdById() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser)
@@ -140,8 +145,9 @@ func TestDeleteAdById(t *testing.T) {
 	assert.Len(t, res.Data, 0)
 }
 
-func TestUpdatedAdById(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestUpdatedAdById() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser)
@@ -1,13 +1,10 @@
 package httpgin
 
-import (
-	"testing"
+import "github.com/stretchr/testify/assert"
 
-	"github.com/stretchr/testify/assert"
-)
-
-func TestCreateUser(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestCreateUser() {
+	t := s.T()
+	client := s.client()
 
 	response, err := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, err)
@@ -16,22 +13,26 @@ func TestCreateUser(t *testing.T) {
 	assert.Equal(t, response.Data.Email, "buda@phystech.edu")
 }
 
-func TestUpdateUser(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestUpdateUser() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser0 := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser0)
 	res, errUser1 := client.createUser("mayot", "mayot@phystech.edu")
 	assert.NoError(t, errUser1)
 
-	response, err := client.updateUser(res.Data.ID, "oxxximiron", "oxxximiron@phystech.edu")
+	assert.NoError(t, client.setPassword(res.Data.Email, "long enough"))
+
+	response, err := client.updateUser(res.Data.ID, "oxxximiron", "oxxximiron@phystech.edu", "long enough")
 	assert.NoError(t, err)
 	assert.Equal(t, response.Data.Nickname, "oxxximiron")
 	assert.Equal(t, response.Data.Email, "oxxximiron@phystech.edu")
 }
 
-func TestGetUser(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestGetUser() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser0 := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser0)
@@ -40,11 +41,17 @@ func TestGetUser(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Zero(t, resp.Data.ID)
 	assert.Equal(t, resp.Data.Nickname, "og buda")
-	assert.Equal(t, resp.Data.Email, "buda@phystech.edu")
+	assert.Empty(t, resp.Data.Email)
+
+	// X-User-Id клиент выбирает сам, поэтому email он не открывает
+	resp, err = client.getUserByIdAs(0, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, resp.Data.Email)
 }
 
-func TestDeleteUser(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestDeleteUser() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser0 := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser0)
@@ -55,3 +62,26 @@ func TestDeleteUser(t *testing.T) {
 	_, err := client.deleteUserById(1)
 	assert.NoError(t, err)
 }
+
+func (s *RESTSuite) TestDeleteUserDeletesAds() {
+	t := s.T()
+	client := s.client()
+
+	user, err := client.createUser("og buda", "buda@phystech.edu")
+	assert.NoError(t, err)
+	other, err := client.createUser("mayot", "mayot@phystech.edu")
+	assert.NoError(t, err)
+
+	ad, err := client.createAd(user.Data.ID, "hello", "world")
+	assert.NoError(t, err)
+	otherAd, err := client.createAd(other.Data.ID, "kept", "ad")
+	assert.NoError(t, err)
+
+	_, err = client.deleteUserById(user.Data.ID)
+	assert.NoError(t, err)
+
+	_, err = client.getAdById(ad.Data.ID)
+	assert.Error(t, err)
+	_, err = client.getAdById(otherAd.Data.ID)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,92 @@
+package httpgin
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (s *RESTSuite) TestBatchGetAds() {
+	t := s.T()
+	client := s.client()
+	user, err := client.createUser("og buda", "buda@phystech.edu")
+	require.NoError(t, err)
+	ad0, err := client.createAd(user.Data.ID, "first", "text")
+	require.NoError(t, err)
+	ad1, err := client.createAd(user.Data.ID, "second", "text")
+	require.NoError(t, err)
+
+	resp, err := client.batchGetAds(ad1.Data.ID, 100, ad0.Data.ID, ad1.Data.ID)
+	require.NoError(t, err)
+	require.Len(t, resp.Data.Ads, 2)
+	assert.Equal(t, "second", resp.Data.Ads[0].Title)
+	assert.Equal(t, "first", resp.Data.Ads[1].Title)
+	assert.Equal(t, []int64{100}, resp.Data.MissingIDs)
+
+	resp, err = client.batchGetAds(100)
+	require.NoError(t, err)
+	assert.Empty(t, resp.Data.Ads)
+	assert.Equal(t, []int64{100}, resp.Data.MissingIDs)
+}
+
+func (s *RESTSuite) TestBatchGetAds_TooMany() {
+	t := s.T()
+	client := s.client()
+
+	ids := make([]int64, 101)
+	for idx := range ids {
+		ids[idx] = int64(idx)
+	}
+	_, err := client.batchGetAds(ids...)
+	assert.ErrorIs(t, err, ErrBadRequest)
+}
+
+func (s *RESTSuite) TestBatchGetUsers() {
+	t := s.T()
+	client := s.client()
+	user0, err := client.createUser("og buda", "buda@phystech.edu")
+	require.NoError(t, err)
+	user1, err := client.createUser("oxxxymiron", "oxxxymiron@phystech.edu")
+	require.NoError(t, err)
+
+	resp, err := client.batchGetUsers(user1.Data.ID, 7, user0.Data.ID)
+	require.NoError(t, err)
+	require.Len(t, resp.Data.Users, 2)
+	assert.Equal(t, "oxxxymiron", resp.Data.Users[0].Nickname)
+	assert.Equal(t, "og buda", resp.Data.Users[1].Nickname)
+	assert.Equal(t, []int64{7}, resp.Data.MissingIDs)
+}
+
+func (s *RESTSuite) TestBatchChangeAdStatus() {
+	t := s.T()
+	client := s.client()
+	user0, err := client.createUser("og buda", "buda@phystech.edu")
+	require.NoError(t, err)
+	user1, err := client.createUser("oxxxymiron", "oxxxymiron@phystech.edu")
+	require.NoError(t, err)
+	ad0, err := client.createAd(user0.Data.ID, "first", "text")
+	require.NoError(t, err)
+	ad1, err := client.createAd(user0.Data.ID, "second", "text")
+	require.NoError(t, err)
+	foreign, err := client.createAd(user1.Data.ID, "foreign", "text")
+	require.NoError(t, err)
+
+	// чужое объявление в пакете - не меняется ни одно
+	_, err = client.batchChangeAdStatus(user0.Data.ID, true, ad0.Data.ID, foreign.Data.ID)
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = client.batchChangeAdStatus(user0.Data.ID, true, ad0.Data.ID, 100)
+	assert.Error(t, err)
+
+	list, err := client.listAds()
+	require.NoError(t, err)
+	assert.Empty(t, list.Data)
+
+	resp, err := client.batchChangeAdStatus(user0.Data.ID, true, ad0.Data.ID, ad1.Data.ID)
+	require.NoError(t, err)
+	require.Len(t, resp.Data, 2)
+	assert.True(t, resp.Data[0].Published)
+	assert.True(t, resp.Data[1].Published)
+
+	list, err = client.listAds()
+	require.NoError(t, err)
+	assert.Len(t, list.Data, 2)
+}
@@ -1,13 +1,10 @@
 package httpgin
 
-import (
-	"testing"
+import "github.com/stretchr/testify/assert"
 
-	"github.com/stretchr/testify/assert"
-)
-
-func TestChangeStatusAdOfAnotherUser(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestChangeStatusAdOfAnotherUser() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser0 := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser0)
@@ -23,8 +20,9 @@ func TestChangeStatusAdOfAnotherUser(t *testing.T) {
 	assert.ErrorIs(t, err, ErrForbidden)
 }
 
-func TestUpdateAdOfAnotherUser(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestUpdateAdOfAnotherUser() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser0 := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser0)
@@ -38,8 +36,9 @@ func TestUpdateAdOfAnotherUser(t *testing.T) {
 	assert.ErrorIs(t, err, ErrForbidden)
 }
 
-func TestCreateAd_ID(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestCreateAd_ID() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser0 := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser0)
@@ -61,8 +60,9 @@ func TestCreateAd_ID(t *testing.T) {
 	assert.Equal(t, resp.Data.ID, int64(2))
 }
 
-func TestDeleteAd(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestDeleteAd() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser0 := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser0)
@@ -1,32 +1,31 @@
 package httpgin
 
-import (
-	"testing"
+import "github.com/stretchr/testify/assert"
 
-	"github.com/stretchr/testify/assert"
-)
-
-func TestUpdateNonexistentUser(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestUpdateNonexistentUser() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser0 := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser0)
 	_, errUser1 := client.createUser("oxxxymiron", "oxxxymiron@phystech.edu")
 	assert.NoError(t, errUser1)
 
-	_, err := client.updateUser(123, "mayot", "mayot@phystech.edu")
+	_, err := client.updateUser(123, "mayot", "mayot@phystech.edu", "")
 	assert.ErrorIs(t, err, ErrForbidden)
 }
 
-func TestCreateAdOfNonexistentUser(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestCreateAdOfNonexistentUser() {
+	t := s.T()
+	client := s.client()
 
 	_, err := client.createAd(123, "hello", "world")
 	assert.ErrorIs(t, err, ErrForbidden)
 }
 
-func TestCreateAdByDeletedUser(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestCreateAdByDeletedUser() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser0 := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser0)
@@ -40,3 +39,218 @@ func TestCreateAdByDeletedUser(t *testing.T) {
 	_, err = client.createAd(0, "ok", "ok")
 	assert.ErrorIs(t, err, ErrForbidden)
 }
+
+func (s *RESTSuite) TestCreateUser_EmailTaken() {
+	t := s.T()
+	client := s.client()
+
+	_, err := client.createUser("og buda", "buda@phystech.edu")
+	assert.NoError(t, err)
+
+	_, err = client.createUser("mayot", " Buda@Phystech.edu")
+	assert.ErrorIs(t, err, ErrConflict)
+	_, err = client.createUser("OG BUDA", "mayot@phystech.edu")
+	assert.ErrorIs(t, err, ErrConflict)
+}
+
+func (s *RESTSuite) TestUpdateUser_EmailTaken() {
+	t := s.T()
+	client := s.client()
+
+	_, err := client.createUser("og buda", "buda@phystech.edu")
+	assert.NoError(t, err)
+	mayot, err := client.createUser("mayot", "mayot@phystech.edu")
+	assert.NoError(t, err)
+
+	assert.NoError(t, client.setPassword(mayot.Data.Email, "long enough"))
+
+	_, err = client.updateUser(mayot.Data.ID, "mayot", "buda@phystech.edu", "long enough")
+	assert.ErrorIs(t, err, ErrConflict)
+
+	// смена email сняла бы подтверждение
+	resp, err := client.getUserById(mayot.Data.ID)
+	assert.NoError(t, err)
+	assert.True(t, resp.Data.EmailVerified)
+}
+
+func (s *RESTSuite) TestUpdateUser_EmailNeedsPassword() {
+	t := s.T()
+	client := s.client()
+
+	user, err := client.createUser("og buda", "buda@phystech.edu")
+	assert.NoError(t, err)
+
+	// ник меняется и без пароля, а email - только с текущим паролем
+	_, err = client.updateUser(user.Data.ID, "buda", "buda@phystech.edu", "")
+	assert.NoError(t, err)
+	_, err = client.updateUser(user.Data.ID, "buda", "attacker@phystech.edu", "")
+	assert.ErrorIs(t, err, ErrForbidden)
+	assert.NoError(t, client.setPassword(user.Data.Email, "long enough"))
+	_, err = client.updateUser(user.Data.ID, "buda", "attacker@phystech.edu", "wrong password")
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	resp, err := client.getUserByEmail("buda@phystech.edu", adminToken)
+	assert.NoError(t, err)
+	assert.Equal(t, user.Data.ID, resp.Data.ID)
+}
+
+func (s *RESTSuite) TestGetUserByEmail() {
+	t := s.T()
+	client := s.client()
+
+	user, err := client.createUser("og buda", "Buda@Phystech.edu")
+	assert.NoError(t, err)
+	assert.Equal(t, "buda@phystech.edu", user.Data.Email)
+
+	resp, err := client.getUserByEmail("BUDA@phystech.edu ", adminToken)
+	assert.NoError(t, err)
+	assert.Equal(t, user.Data.ID, resp.Data.ID)
+	assert.Equal(t, user.Data.Nickname, resp.Data.Nickname)
+	assert.Empty(t, resp.Data.Email)
+
+	_, err = client.getUserByEmail("mayot@phystech.edu", adminToken)
+	assert.Error(t, err)
+}
+
+func (s *RESTSuite) TestGetUserByEmail_RequiresAdmin() {
+	t := s.T()
+	client := s.client()
+
+	_, err := client.createUser("og buda", "buda@phystech.edu")
+	assert.NoError(t, err)
+
+	// без токена поиск по email не отвечает, есть ли такой адрес
+	_, err = client.getUserByEmail("buda@phystech.edu", "")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	_, err = client.getUserByEmail("mayot@phystech.edu", "wrong")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
+
+func (s *RESTSuite) TestPublishRequiresVerifiedEmail() {
+	t := s.T()
+	client := s.client()
+
+	user, err := client.registerUser("og buda", "buda@phystech.edu")
+	assert.NoError(t, err)
+	assert.False(t, user.Data.EmailVerified)
+	ad, err := client.createAd(user.Data.ID, "hello", "world")
+	assert.NoError(t, err)
+
+	_, err = client.changeAdStatus(user.Data.ID, ad.Data.ID, true)
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	// письмо можно запросить заново, тогда действует только новый токен
+	first := client.mailToken("buda@phystech.edu")
+	assert.NoError(t, client.requestEmailVerification(user.Data.ID))
+	_, err = client.confirmEmail(first)
+	assert.ErrorIs(t, err, ErrBadRequest)
+
+	confirmed, err := client.confirmEmail(client.mailToken("buda@phystech.edu"))
+	assert.NoError(t, err)
+	assert.True(t, confirmed.Data.EmailVerified)
+	assert.ErrorIs(t, client.requestEmailVerification(user.Data.ID), ErrConflict)
+
+	published, err := client.changeAdStatus(user.Data.ID, ad.Data.ID, true)
+	assert.NoError(t, err)
+	assert.True(t, published.Data.Published)
+}
+
+func (s *RESTSuite) TestPasswordReset() {
+	t := s.T()
+	client := s.client()
+
+	user, err := client.createUser("og buda", "buda@phystech.edu")
+	assert.NoError(t, err)
+
+	assert.NoError(t, client.requestPasswordReset("nobody@phystech.edu"))
+	assert.NoError(t, client.requestPasswordReset("buda@phystech.edu"))
+	token := client.mailToken(user.Data.Email)
+
+	assert.ErrorIs(t, client.resetPassword(token, "short"), ErrBadRequest)
+	assert.NoError(t, client.resetPassword(token, "long enough"))
+	assert.ErrorIs(t, client.resetPassword(token, "long enough"), ErrBadRequest)
+}
+
+func (s *RESTSuite) TestUpdateProfile() {
+	t := s.T()
+	client := s.client()
+
+	user, err := client.createUser("og buda", "buda@phystech.edu")
+	assert.NoError(t, err)
+	assert.False(t, user.Data.RegisteredAt.IsZero())
+
+	// без пароля профиль не меняется
+	_, err = client.updateProfile(user.Data.ID, map[string]any{"city": "Moscow"})
+	assert.ErrorIs(t, err, ErrForbidden)
+	assert.NoError(t, client.setPassword(user.Data.Email, "long enough"))
+	_, err = client.updateProfile(user.Data.ID, map[string]any{"city": "Moscow", "password": "wrong password"})
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	updated, err := client.updateProfile(user.Data.ID, map[string]any{
+		"password":     "long enough",
+		"display_name": " Buda ",
+		"phone":        "+7 (999) 123-45-67",
+		"city":         "Moscow",
+		"avatar_url":   "https://example.com/buda.png",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Buda", updated.Data.DisplayName)
+	assert.Equal(t, "+79991234567", updated.Data.Phone)
+	assert.Equal(t, "buda@phystech.edu", updated.Data.Email)
+
+	// читается профиль без контактов
+	public, err := client.getUserById(user.Data.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Buda", public.Data.DisplayName)
+	assert.Equal(t, "Moscow", public.Data.City)
+	assert.Empty(t, public.Data.Phone)
+	assert.Empty(t, public.Data.Email)
+	assert.True(t, public.Data.RegisteredAt.Equal(user.Data.RegisteredAt))
+
+	own, err := client.getUserByIdAs(user.Data.ID, user.Data.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, own.Data.Phone, "X-User-Id не открывает телефон")
+
+	_, err = client.updateProfile(user.Data.ID, map[string]any{"password": "long enough", "phone": "8 999 123"})
+	assert.ErrorIs(t, err, ErrBadRequest)
+	_, err = client.updateProfile(user.Data.ID, map[string]any{"password": "long enough", "avatar_url": "buda.png"})
+	assert.ErrorIs(t, err, ErrBadRequest)
+	_, err = client.updateProfile(42, map[string]any{"city": "Moscow"})
+	assert.Error(t, err)
+}
+
+func (s *RESTSuite) TestListUserAds() {
+	t := s.T()
+	client := s.client()
+
+	buda, err := client.createUser("og buda", "buda@phystech.edu")
+	assert.NoError(t, err)
+	mayot, err := client.createUser("mayot", "mayot@phystech.edu")
+	assert.NoError(t, err)
+
+	first, err := client.createAd(buda.Data.ID, "first", "text")
+	assert.NoError(t, err)
+	_, err = client.createAd(buda.Data.ID, "draft", "text")
+	assert.NoError(t, err)
+	second, err := client.createAd(buda.Data.ID, "second", "text")
+	assert.NoError(t, err)
+	other, err := client.createAd(mayot.Data.ID, "other", "text")
+	assert.NoError(t, err)
+	for _, ad := range []adResponse{first, second} {
+		_, err = client.changeAdStatus(buda.Data.ID, ad.Data.ID, true)
+		assert.NoError(t, err)
+	}
+	_, err = client.changeAdStatus(mayot.Data.ID, other.Data.ID, true)
+	assert.NoError(t, err)
+
+	list, err := client.listUserAds(buda.Data.ID)
+	assert.NoError(t, err)
+	assert.Len(t, list.Data, 2)
+	if len(list.Data) == 2 {
+		assert.Equal(t, second.Data.ID, list.Data[0].ID)
+		assert.Equal(t, first.Data.ID, list.Data[1].ID)
+	}
+
+	_, err = client.listUserAds(42)
+	assert.Error(t, err)
+}
@@ -1,12 +1,10 @@
 package httpgin
 
-import (
-	"github.com/stretchr/testify/assert"
-	"testing"
-)
+import "github.com/stretchr/testify/assert"
 
-func TestGetAdsByTitle(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestGetAdsByTitle() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser)
@@ -30,8 +28,9 @@ func TestGetAdsByTitle(t *testing.T) {
 	assert.Equal(t, response.Data[1].Text, "friend")
 }
 
-func TestGetFilteredAdsOnlyUnpublished(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestGetFilteredAdsOnlyUnpublished() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser)
@@ -56,8 +55,9 @@ func TestGetFilteredAdsOnlyUnpublished(t *testing.T) {
 	assert.Len(t, response.Data, 2)
 }
 
-func TestGetFilteredAdsByAuthor(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestGetFilteredAdsByAuthor() {
+	t := s.T()
+	client := s.client()
 
 	user1, errUser := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser)
@@ -82,8 +82,9 @@ func TestGetFilteredAdsByAuthor(t *testing.T) {
 	assert.Len(t, response.Data, 2)
 }
 
-func TestGetFilteredAdsByDateCreating(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestGetFilteredAdsByDateCreating() {
+	t := s.T()
+	client := s.client()
 
 	user1, errUser := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser)
@@ -106,8 +107,9 @@ func TestGetFilteredAdsByDateCreating(t *testing.T) {
 }
 
 // Let's combine some filters
-func TestGetAdsByOnlyUnpublishedByAuthor(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestGetAdsByOnlyUnpublishedByAuthor() {
+	t := s.T()
+	client := s.client()
 
 	user1, errUser := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser)
@@ -137,8 +139,9 @@ func TestGetAdsByOnlyUnpublishedByAuthor(t *testing.T) {
 	assert.Equal(t, response.Data[0], ad2.Data)
 }
 
-func TestGetAdsByOnlyUnpublishedByDateCreatingByAuthor(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestGetAdsByOnlyUnpublishedByDateCreatingByAuthor() {
+	t := s.T()
+	client := s.client()
 
 	user1, errUser := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser)
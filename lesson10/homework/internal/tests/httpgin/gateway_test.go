@@ -0,0 +1,24 @@
+package httpgin
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"homework10/internal/app"
+	"homework10/internal/ports/gateway"
+	"homework10/internal/ports/httpgin"
+)
+
+func gatewayHandler(t *testing.T, a app.App, opts ...httpgin.Option) http.Handler {
+	server, err := gateway.NewHTTPServer(":18080", a, append(opts, httpgin.WithAdminToken(adminToken))...)
+	require.NoError(t, err)
+	return server.Handler
+}
+
+// TestGateway прогоняет тесты REST API, вместе с тестами middleware, на шлюзе, собранном из service.proto
+func TestGateway(t *testing.T) {
+	suite.Run(t, &RESTSuite{newHandler: gatewayHandler})
+}
@@ -0,0 +1,73 @@
+package httpgin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/app"
+	"homework10/internal/health"
+	"homework10/internal/ports/httpgin"
+)
+
+type readinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+func getReadiness(t *testing.T, url string) (int, readinessResponse) {
+	resp, err := http.Get(url + "/readyz")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var out readinessResponse
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	return resp.StatusCode, out
+}
+
+func TestHealthProbes(t *testing.T) {
+	checker := health.New(health.DefaultTimeout)
+	var dispatcherRunning atomic.Bool
+	dispatcherRunning.Store(true)
+	checker.Register("dispatcher", func(ctx context.Context) error {
+		if !dispatcherRunning.Load() {
+			return errors.New("not running")
+		}
+		return nil
+	})
+
+	server := httpgin.NewHTTPServer(":18080", app.NewApp(adrepo.New()), httpgin.WithHealthChecker(checker))
+	testServer := httptest.NewServer(server.Handler)
+	defer testServer.Close()
+
+	resp, err := http.Get(testServer.URL + "/healthz")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	code, readiness := getReadiness(t, testServer.URL)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, readinessResponse{Status: "ok", Checks: map[string]string{"dispatcher": "ok"}}, readiness)
+
+	dispatcherRunning.Store(false)
+	code, readiness = getReadiness(t, testServer.URL)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "unavailable", readiness.Status)
+	assert.Equal(t, "not running", readiness.Checks["dispatcher"])
+
+	dispatcherRunning.Store(true)
+	checker.Shutdown()
+	code, _ = getReadiness(t, testServer.URL)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+
+	// живость от готовности не зависит
+	resp, err = http.Get(testServer.URL + "/healthz")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,71 @@
+package httpgin
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (tc *testClient) createAdWithKey(key string, userID int64, title string, text string) (*http.Response, adResponse, error) {
+	data, err := json.Marshal(map[string]any{"user_id": userID, "title": title, "text": text})
+	if err != nil {
+		return nil, adResponse{}, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, tc.baseURL+"/api/v1/ads", bytes.NewReader(data))
+	if err != nil {
+		return nil, adResponse{}, err
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Idempotency-Key", key)
+
+	resp, err := tc.client.Do(req)
+	if err != nil {
+		return nil, adResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	var response adResponse
+	if resp.StatusCode == http.StatusOK {
+		err = json.NewDecoder(resp.Body).Decode(&response)
+	}
+	return resp, response, err
+}
+
+func (s *RESTSuite) TestCreateAdIdempotencyKey() {
+	t := s.T()
+	client := s.client()
+
+	_, err := client.createUser("og buda", "buda@phystech.edu")
+	assert.NoError(t, err)
+
+	resp, first, err := client.createAdWithKey("key-1", 0, "hello", "world")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get("Idempotent-Replayed"))
+
+	resp, retry, err := client.createAdWithKey("key-1", 0, "hello", "world")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "true", resp.Header.Get("Idempotent-Replayed"))
+	assert.Equal(t, first, retry)
+
+	resp, _, err = client.createAdWithKey("key-1", 0, "hello", "another world")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	resp, other, err := client.createAdWithKey("key-2", 0, "hello", "world")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEqual(t, first.Data.ID, other.Data.ID)
+
+	_, err = client.changeAdStatus(0, first.Data.ID, true)
+	assert.NoError(t, err)
+	_, err = client.changeAdStatus(0, other.Data.ID, true)
+	assert.NoError(t, err)
+	list, err := client.listAds()
+	assert.NoError(t, err)
+	assert.Len(t, list.Data, 2)
+}
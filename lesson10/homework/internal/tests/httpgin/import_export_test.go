@@ -0,0 +1,184 @@
+package httpgin
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/app"
+	"homework10/internal/ports/httpgin"
+	"homework10/internal/ratelimit"
+)
+
+type importReport struct {
+	Data struct {
+		Imported int `json:"imported"`
+		Failed   int `json:"failed"`
+		Failures []struct {
+			Line          int    `json:"line"`
+			Error         string `json:"error"`
+			InvalidParams []struct {
+				Name string `json:"name"`
+			} `json:"invalid_params"`
+		} `json:"failures"`
+	} `json:"data"`
+}
+
+func (tc *testClient) importAds(contentType string, body string) (int, importReport) {
+	req, _ := http.NewRequest(http.MethodPost, tc.baseURL+"/api/v1/ads/import", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	resp, err := tc.client.Do(req)
+	if err != nil {
+		return 0, importReport{}
+	}
+	defer resp.Body.Close()
+
+	var report importReport
+	_ = json.NewDecoder(resp.Body).Decode(&report)
+	return resp.StatusCode, report
+}
+
+func (tc *testClient) exportAds(query string) (*http.Response, string, error) {
+	resp, err := tc.client.Get(tc.baseURL + "/api/v1/ads/export" + query)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return resp, string(body), err
+}
+
+func TestImportAds_CSV(t *testing.T) {
+	client := getTestClient(t)
+	_, err := client.createUser("og buda", "buda@phystech.edu")
+	require.NoError(t, err)
+
+	body := "title,text,user_id\n" +
+		"hello,world,0\n" +
+		",no title,0\n" +
+		"stranger,text,100\n" +
+		"bad,user,zero\n" +
+		"\"multi\nline\",text,0\n"
+	code, report := client.importAds("text/csv", body)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2, report.Data.Imported)
+	assert.Equal(t, 3, report.Data.Failed)
+	require.Len(t, report.Data.Failures, 3)
+
+	assert.Equal(t, 3, report.Data.Failures[0].Line)
+	require.Len(t, report.Data.Failures[0].InvalidParams, 1)
+	assert.Equal(t, "title", report.Data.Failures[0].InvalidParams[0].Name)
+	assert.Equal(t, 4, report.Data.Failures[1].Line)
+	assert.Equal(t, 5, report.Data.Failures[2].Line)
+	assert.Contains(t, report.Data.Failures[2].Error, "user_id")
+
+	list, err := client.getListAdsWithFilter(map[string]any{"published": false})
+	require.NoError(t, err)
+	assert.Len(t, list.Data, 2)
+}
+
+func TestImportAds_NDJSON(t *testing.T) {
+	client := getTestClient(t)
+	_, err := client.createUser("og buda", "buda@phystech.edu")
+	require.NoError(t, err)
+
+	body := `{"title":"hello","text":"world","user_id":0}
+{"title":"broken"
+{"title":"second","text":"ad","user_id":0}
+`
+	code, report := client.importAds("application/x-ndjson", body)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2, report.Data.Imported)
+	require.Len(t, report.Data.Failures, 1)
+	assert.Equal(t, 2, report.Data.Failures[0].Line)
+}
+
+func TestImportAds_Errors(t *testing.T) {
+	client := getTestClient(t)
+
+	code, _ := client.importAds("application/json", `[]`)
+	assert.Equal(t, http.StatusUnsupportedMediaType, code)
+
+	code, _ = client.importAds("text/csv", "title,text\nhello,world\n")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestImportAds_Limits(t *testing.T) {
+	client := getTestClient(t)
+
+	code, _ := client.importAds("application/x-ndjson", strings.Repeat(strings.Repeat(" ", 1023)+"\n", httpgin.MaxImportBodySize/1024+1))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code)
+
+	code, report := client.importAds("text/csv", "title,text,user_id\n"+strings.Repeat("hello,world,100\n", app.MaxImportRows+1))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code)
+	assert.Equal(t, app.MaxImportRows, report.Data.Failed)
+}
+
+func TestImportAds_RateLimit(t *testing.T) {
+	// один токен уходит на сам запрос, остальные - на строки
+	limiter := ratelimit.New(ratelimit.Config{Read: ratelimit.Limit{Rate: 0.001, Burst: 1}, Write: ratelimit.Limit{Rate: 0.001, Burst: 3}})
+	testServer := httptest.NewServer(ginHandler(t, app.NewApp(adrepo.New()), httpgin.WithRateLimiter(limiter)))
+	defer testServer.Close()
+	client := &testClient{client: testServer.Client(), baseURL: testServer.URL}
+
+	code, report := client.importAds("text/csv", "title,text,user_id\n"+strings.Repeat("hello,world,100\n", 5))
+	assert.Equal(t, http.StatusTooManyRequests, code)
+	assert.Equal(t, 2, report.Data.Failed)
+}
+
+func TestExportAds(t *testing.T) {
+	client := getTestClient(t)
+	user, err := client.createUser("og buda", "buda@phystech.edu")
+	require.NoError(t, err)
+	for _, title := range []string{"first", "second, with comma", "third"} {
+		_, err := client.createAd(user.Data.ID, title, "text")
+		require.NoError(t, err)
+	}
+	_, err = client.changeAdStatus(user.Data.ID, 1, true)
+	require.NoError(t, err)
+
+	resp, body, err := client.exportAds("?published=false")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+	assert.Contains(t, resp.Header.Get("Content-Disposition"), "attachment")
+
+	var titles []string
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		var ad struct {
+			Title string `json:"title"`
+		}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &ad))
+		titles = append(titles, ad.Title)
+	}
+	assert.Equal(t, []string{"first", "third"}, titles)
+
+	resp, body, err = client.exportAds("?format=csv&published=false")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "text/csv"))
+	assert.Equal(t, 3, strings.Count(body, "\n"))
+
+	// выгрузку можно загрузить обратно
+	code, report := client.importAds("text/csv", body)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2, report.Data.Imported)
+	assert.Zero(t, report.Data.Failed)
+
+	resp, _, err = client.exportAds("?format=xml")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _, err = client.exportAds("?date_creating=yesterday")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
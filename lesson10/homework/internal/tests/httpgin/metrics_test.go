@@ -0,0 +1,50 @@
+package httpgin
+
+import (
+	"io"
+	"net/http/httptest"
+
+	"github.com/stretchr/testify/assert"
+
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/app"
+)
+
+func (s *RESTSuite) TestMetrics() {
+	t := s.T()
+	client := s.client()
+	// второй сервер в том же процессе не должен паниковать на регистрации метрик
+	second := httptest.NewServer(s.handler(app.NewApp(adrepo.New())))
+	defer second.Close()
+
+	user, err := client.createUser("og buda", "buda@phystech.edu")
+	assert.NoError(t, err)
+	ad, err := client.createAd(user.Data.ID, "hello", "world")
+	assert.NoError(t, err)
+	_, err = client.createAd(user.Data.ID, "hello", "world")
+	assert.NoError(t, err)
+	_, err = client.changeAdStatus(user.Data.ID, ad.Data.ID, true)
+	assert.NoError(t, err)
+	_, err = client.getAdById(100)
+	assert.Error(t, err)
+
+	resp, err := client.client.Get(client.baseURL + "/metrics")
+	assert.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	metrics := string(body)
+
+	assert.Contains(t, metrics, `adservice_http_requests_total{method="POST",route="/api/v1/ads",status="200"} 2`)
+	assert.Contains(t, metrics, `adservice_http_requests_total{method="GET",route="/api/v1/ads/:ad_id",status="404"} 1`)
+	assert.Contains(t, metrics, `adservice_http_request_duration_seconds_count{method="PUT",route="/api/v1/ads/:ad_id/status",status="200"} 1`)
+	assert.Contains(t, metrics, "adservice_ads 2")
+	assert.Contains(t, metrics, "adservice_published_ads 1")
+	assert.Contains(t, metrics, "adservice_users 1")
+
+	resp, err = second.Client().Get(second.URL + "/metrics")
+	assert.NoError(t, err)
+	body, err = io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "adservice_ads 0")
+	assert.NotContains(t, string(body), `route="/api/v1/ads"`)
+}
@@ -0,0 +1,67 @@
+package httpgin
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/stretchr/testify/assert"
+
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/app"
+	"homework10/internal/ports/httpgin"
+	"homework10/internal/ratelimit"
+)
+
+func (s *RESTSuite) TestRateLimit() {
+	t := s.T()
+	limiter := ratelimit.New(ratelimit.Config{Read: ratelimit.Limit{Rate: 0.001, Burst: 2}, Write: ratelimit.Limit{Rate: 0.001, Burst: 1}})
+	testServer := httptest.NewServer(s.handler(app.NewApp(adrepo.New()), httpgin.WithRateLimiter(limiter)))
+	defer testServer.Close()
+	client := &testClient{client: testServer.Client(), baseURL: testServer.URL}
+
+	_, err := client.createUser("og buda", "buda@phystech.edu")
+	assert.NoError(t, err)
+	_, err = client.createUser("mayot", "mayot@phystech.edu")
+	assert.ErrorContains(t, err, "429")
+
+	_, err = client.listAds()
+	assert.NoError(t, err)
+	_, err = client.listAds()
+	assert.NoError(t, err)
+
+	resp, err := client.client.Get(client.baseURL + "/api/v1/ads")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("Retry-After"))
+
+	req, err := http.NewRequest(http.MethodGet, client.baseURL+"/api/v1/ads", nil)
+	assert.NoError(t, err)
+	// новый X-User-Id не даёт нового бюджета
+	req.Header.Set("X-User-Id", "1")
+	resp, err = client.client.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+}
+
+func (s *RESTSuite) TestRateLimit_ForwardedFor() {
+	t := s.T()
+	limiter := ratelimit.New(ratelimit.Config{Read: ratelimit.Limit{Rate: 0.001, Burst: 1}, Write: ratelimit.Limit{Rate: 0.001, Burst: 1}})
+	testServer := httptest.NewServer(s.handler(app.NewApp(adrepo.New()), httpgin.WithRateLimiter(limiter)))
+	defer testServer.Close()
+
+	// X-Forwarded-For клиент подставляет сам, поэтому запросы с разными адресами в нём делят один бюджет
+	for i, forwarded := range []string{"203.0.113.1", "203.0.113.2", "198.51.100.7, 203.0.113.3"} {
+		req, err := http.NewRequest(http.MethodGet, testServer.URL+"/api/v1/ads", nil)
+		assert.NoError(t, err)
+		req.Header.Set("X-Forwarded-For", forwarded)
+		req.Header.Set("X-Real-IP", forwarded)
+		resp, err := testServer.Client().Do(req)
+		assert.NoError(t, err)
+		_ = resp.Body.Close()
+		if i == 0 {
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		} else {
+			assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode, forwarded)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package httpgin
+
+import (
+	"net/http"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func (s *RESTSuite) TestRequestID() {
+	t := s.T()
+	client := s.client()
+
+	resp, err := client.client.Get(client.baseURL + "/api/v1/ads")
+	assert.NoError(t, err)
+	generated := resp.Header.Get("X-Request-ID")
+	assert.Len(t, generated, 32)
+
+	req, err := http.NewRequest(http.MethodGet, client.baseURL+"/api/v1/ads", nil)
+	assert.NoError(t, err)
+	req.Header.Set("X-Request-ID", "my-request-1")
+	resp, err = client.client.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "my-request-1", resp.Header.Get("X-Request-ID"))
+}
@@ -0,0 +1,70 @@
+package httpgin
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/app"
+	"homework10/internal/ports/httpgin"
+	"homework10/internal/tlsutil"
+	"homework10/internal/tlsutil/tlstest"
+)
+
+func TestTLSCertificateReload(t *testing.T) {
+	ca, err := tlstest.NewCA()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	first, err := ca.Issue("first")
+	require.NoError(t, err)
+	certFile, keyFile, err := first.Write(dir, "server")
+	require.NoError(t, err)
+
+	tlsConfig, err := tlsutil.ServerConfig(certFile, keyFile, "")
+	require.NoError(t, err)
+	server := httpgin.NewHTTPServer("127.0.0.1:0", app.NewApp(adrepo.New()), httpgin.WithTLSConfig(tlsConfig))
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		if err := server.ServeTLS(lis, "", ""); !errors.Is(err, http.ErrServerClosed) {
+			assert.NoError(t, err)
+		}
+	}()
+	defer server.Close()
+
+	// каждый запрос - новое соединение, иначе сертификат не перепроверяется
+	get := func() *http.Response {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: ca.Pool()},
+			DisableKeepAlives: true,
+		}}
+		resp, err := client.Get("https://" + lis.Addr().String() + "/api/v1/ads")
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		return resp
+	}
+
+	resp := get()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "first", resp.TLS.PeerCertificates[0].Subject.CommonName)
+
+	second, err := ca.Issue("second")
+	require.NoError(t, err)
+	_, _, err = second.Write(dir, "server")
+	require.NoError(t, err)
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	require.NoError(t, os.Chtimes(keyFile, later, later))
+
+	resp = get()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "second", resp.TLS.PeerCertificates[0].Subject.CommonName)
+}
@@ -0,0 +1,52 @@
+package httpgin
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/app"
+	"homework10/internal/ports/httpgin"
+	"homework10/internal/tracing"
+)
+
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	adApp := tracing.NewApp(app.NewApp(tracing.NewRepository(adrepo.New(), tp)), tp)
+
+	server := httpgin.NewHTTPServer(":18080", adApp, httpgin.WithTracerProvider(tp))
+	testServer := httptest.NewServer(server.Handler)
+	defer testServer.Close()
+	client := &testClient{client: testServer.Client(), baseURL: testServer.URL}
+
+	data, err := json.Marshal(map[string]any{"nickname": "og buda", "email": "buda@phystech.edu"})
+	assert.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, client.baseURL+"/api/v1/users", bytes.NewReader(data))
+	assert.NoError(t, err)
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	var user userResponse
+	assert.NoError(t, client.getResponse(req, &user))
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext.TraceID().String(), span.Name)
+	}
+
+	serverSpan, ok := spans["POST /api/v1/users"]
+	assert.True(t, ok)
+	assert.Equal(t, "00f067aa0ba902b7", serverSpan.Parent.SpanID().String())
+	assert.Equal(t, serverSpan.SpanContext.SpanID(), spans["App.CreateUser"].Parent.SpanID())
+	assert.Equal(t, spans["App.CreateUser"].SpanContext.SpanID(), spans["Repository.AddUser"].Parent.SpanID())
+	assert.Equal(t, spans["App.CreateUser"].SpanContext.SpanID(), spans["Repository.GetUsersPrimaryKey"].Parent.SpanID())
+}
@@ -8,10 +8,16 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
+	"strings"
+	"testing"
 	"time"
 
+	"github.com/stretchr/testify/suite"
+
 	"homework10/internal/adapters/adrepo"
 	"homework10/internal/app"
+	"homework10/internal/mailer"
 	"homework10/internal/ports/httpgin"
 )
 
@@ -38,22 +44,64 @@ type adsResponse struct {
 }
 
 var (
-	ErrBadRequest = fmt.Errorf("bad request")
-	ErrForbidden  = fmt.Errorf("forbidden")
+	ErrBadRequest   = fmt.Errorf("bad request")
+	ErrUnauthorized = fmt.Errorf("unauthorized")
+	ErrForbidden    = fmt.Errorf("forbidden")
+	ErrConflict     = fmt.Errorf("conflict")
 )
 
 type testClient struct {
 	client  *http.Client
 	baseURL string
+	// mail - письма приложения; если задано, createUser сразу подтверждает email
+	mail *mailer.Capture
+}
+
+// adminToken - токен служебного API тестовых серверов
+const adminToken = "admin secret"
+
+// handlerFactory собирает REST API, на котором идут тесты: сервер gin или шлюз
+type handlerFactory func(t *testing.T, a app.App, opts ...httpgin.Option) http.Handler
+
+func ginHandler(_ *testing.T, a app.App, opts ...httpgin.Option) http.Handler {
+	return httpgin.NewHTTPServer(":18080", a, append(opts, httpgin.WithAdminToken(adminToken))...).Handler
+}
+
+// RESTSuite - тесты REST API, общие для сервера gin и шлюза: каждый тест-метод прогоняется на обоих
+// (TestGin и TestGateway). Тесты возможностей, которых у шлюза нет, пишутся обычными функциями
+type RESTSuite struct {
+	suite.Suite
+	newHandler handlerFactory
+}
+
+func TestGin(t *testing.T) {
+	suite.Run(t, &RESTSuite{newHandler: ginHandler})
+}
+
+// client поднимает API текущего транспорта с новым приложением
+func (s *RESTSuite) client() *testClient {
+	return newTestClient(s.T(), s.newHandler)
+}
+
+// handler собирает API текущего транспорта, например чтобы передать опции сервера
+func (s *RESTSuite) handler(a app.App, opts ...httpgin.Option) http.Handler {
+	return s.newHandler(s.T(), a, opts...)
+}
+
+// getTestClient - клиент сервера gin для тестов, которые на шлюзе не идут
+func getTestClient(t *testing.T) *testClient {
+	return newTestClient(t, ginHandler)
 }
 
-func getTestClient() *testClient {
-	server := httpgin.NewHTTPServer(":18080", app.NewApp(adrepo.New()))
-	testServer := httptest.NewServer(server.Handler)
+func newTestClient(t *testing.T, newHandler handlerFactory) *testClient {
+	mail := mailer.NewCapture()
+	testServer := httptest.NewServer(newHandler(t, app.NewApp(adrepo.New(), app.WithMailer(mail))))
+	t.Cleanup(testServer.Close)
 
 	return &testClient{
 		client:  testServer.Client(),
 		baseURL: testServer.URL,
+		mail:    mail,
 	}
 }
 
@@ -67,9 +115,15 @@ func (tc *testClient) getResponse(req *http.Request, out any) error {
 		if resp.StatusCode == http.StatusBadRequest {
 			return ErrBadRequest
 		}
+		if resp.StatusCode == http.StatusUnauthorized {
+			return ErrUnauthorized
+		}
 		if resp.StatusCode == http.StatusForbidden {
 			return ErrForbidden
 		}
+		if resp.StatusCode == http.StatusConflict {
+			return ErrConflict
+		}
 		return fmt.Errorf("unexpected status code: %s", resp.Status)
 	}
 
@@ -227,16 +281,32 @@ func (tc *testClient) getListAdsWithFilter(filters map[string]any) (adsResponse,
 }
 
 type userData struct {
-	ID       int64  `json:"id"`
-	Nickname string `json:"nickname"`
-	Email    string `json:"email"`
+	ID            int64     `json:"id"`
+	Nickname      string    `json:"nickname"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	DisplayName   string    `json:"display_name"`
+	Phone         string    `json:"phone"`
+	City          string    `json:"city"`
+	AvatarURL     string    `json:"avatar_url"`
+	RegisteredAt  time.Time `json:"registered_at"`
+	LastSeenAt    time.Time `json:"last_seen_at"`
 }
 
 type userResponse struct {
 	Data userData `json:"data"`
 }
 
+// createUser регистрирует пользователя и подтверждает его email, чтобы он мог публиковать объявления
 func (tc *testClient) createUser(nickname string, email string) (userResponse, error) {
+	response, err := tc.registerUser(nickname, email)
+	if err != nil || tc.mail == nil {
+		return response, err
+	}
+	return tc.confirmEmail(tc.mailToken(response.Data.Email))
+}
+
+func (tc *testClient) registerUser(nickname string, email string) (userResponse, error) {
 	body := map[string]any{
 		"nickname": nickname,
 		"email":    email,
@@ -263,10 +333,11 @@ func (tc *testClient) createUser(nickname string, email string) (userResponse, e
 	return response, nil
 }
 
-func (tc *testClient) updateUser(userId int64, nickname string, email string) (userResponse, error) {
+func (tc *testClient) updateUser(userId int64, nickname string, email string, password string) (userResponse, error) {
 	body := map[string]any{
 		"nickname": nickname,
 		"email":    email,
+		"password": password,
 	}
 
 	data, err := json.Marshal(body)
@@ -320,6 +391,24 @@ func (tc *testClient) getUserById(userId int64) (userResponse, error) {
 	return response, nil
 }
 
+// getUserByIdAs запрашивает пользователя с заголовком X-User-Id: viewerId
+func (tc *testClient) getUserByIdAs(userId int64, viewerId int64) (userResponse, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf(tc.baseURL+"/api/v1/users/%d", userId), nil)
+	if err != nil {
+		return userResponse{}, fmt.Errorf("unable to create request: %w", err)
+	}
+
+	req.Header.Add("X-User-Id", strconv.FormatInt(viewerId, 10))
+
+	var response userResponse
+	err = tc.getResponse(req, &response)
+	if err != nil {
+		return userResponse{}, err
+	}
+
+	return response, nil
+}
+
 func (tc *testClient) deleteAdById(adId int64, userId int64) (deleteResponse, error) {
 	body := map[string]any{
 		"user_id": userId,
@@ -360,3 +449,152 @@ func (tc *testClient) deleteUserById(userId int64) (deleteResponse, error) {
 
 	return response, nil
 }
+
+type batchAdsResponse struct {
+	Data struct {
+		Ads        []adData `json:"ads"`
+		MissingIDs []int64  `json:"missing_ids"`
+	} `json:"data"`
+}
+
+type batchUsersResponse struct {
+	Data struct {
+		Users      []userData `json:"users"`
+		MissingIDs []int64    `json:"missing_ids"`
+	} `json:"data"`
+}
+
+func (tc *testClient) postJSON(path string, body any, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("unable to marshal: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, tc.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+	return tc.getResponse(req, out)
+}
+
+func (tc *testClient) batchGetAds(ids ...int64) (batchAdsResponse, error) {
+	var response batchAdsResponse
+	err := tc.postJSON("/api/v1/ads/batch_get", map[string]any{"ids": ids}, &response)
+	return response, err
+}
+
+func (tc *testClient) batchGetUsers(ids ...int64) (batchUsersResponse, error) {
+	var response batchUsersResponse
+	err := tc.postJSON("/api/v1/users/batch_get", map[string]any{"ids": ids}, &response)
+	return response, err
+}
+
+// getUserByEmail ищет пользователя служебным запросом с токеном token
+func (tc *testClient) getUserByEmail(email string, token string) (userResponse, error) {
+	data, err := json.Marshal(map[string]any{"email": email})
+	if err != nil {
+		return userResponse{}, fmt.Errorf("unable to marshal: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, tc.baseURL+"/api/v1/users/by_email", bytes.NewReader(data))
+	if err != nil {
+		return userResponse{}, fmt.Errorf("unable to create request: %w", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Add("Authorization", "Bearer "+token)
+	}
+
+	var response userResponse
+	err = tc.getResponse(req, &response)
+	return response, err
+}
+
+func (tc *testClient) batchChangeAdStatus(userID int64, published bool, adIDs ...int64) (adsResponse, error) {
+	data, err := json.Marshal(map[string]any{"user_id": userID, "published": published, "ad_ids": adIDs})
+	if err != nil {
+		return adsResponse{}, fmt.Errorf("unable to marshal: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, tc.baseURL+"/api/v1/ads/batch_status", bytes.NewReader(data))
+	if err != nil {
+		return adsResponse{}, fmt.Errorf("unable to create request: %w", err)
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+
+	var response adsResponse
+	err = tc.getResponse(req, &response)
+	return response, err
+}
+
+// mailToken достаёт токен из последнего письма на адрес to
+func (tc *testClient) mailToken(to string) string {
+	msg, _ := tc.mail.Last(to)
+	for _, line := range strings.Split(msg.Body, "\n") {
+		if token, ok := strings.CutPrefix(line, "Token: "); ok {
+			return token
+		}
+	}
+	return ""
+}
+
+func (tc *testClient) requestEmailVerification(userID int64) error {
+	var response deleteResponse
+	return tc.postJSON(fmt.Sprintf("/api/v1/users/%d/email_verification", userID), map[string]any{}, &response)
+}
+
+func (tc *testClient) confirmEmail(token string) (userResponse, error) {
+	var response userResponse
+	err := tc.postJSON("/api/v1/users/email_verification/confirm", map[string]any{"token": token}, &response)
+	return response, err
+}
+
+func (tc *testClient) requestPasswordReset(email string) error {
+	var response deleteResponse
+	return tc.postJSON("/api/v1/users/password_reset", map[string]any{"email": email}, &response)
+}
+
+func (tc *testClient) resetPassword(token string, password string) error {
+	var response deleteResponse
+	return tc.postJSON("/api/v1/users/password_reset/confirm", map[string]any{"token": token, "password": password}, &response)
+}
+
+// setPassword задаёт пароль пользователю через письмо со сбросом пароля
+func (tc *testClient) setPassword(email string, password string) error {
+	if err := tc.requestPasswordReset(email); err != nil {
+		return err
+	}
+	return tc.resetPassword(tc.mailToken(email), password)
+}
+
+func (tc *testClient) updateProfile(userId int64, profile map[string]any) (userResponse, error) {
+	data, err := json.Marshal(profile)
+	if err != nil {
+		return userResponse{}, fmt.Errorf("unable to marshal: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf(tc.baseURL+"/api/v1/users/%d/profile", userId), bytes.NewReader(data))
+	if err != nil {
+		return userResponse{}, fmt.Errorf("unable to create request: %w", err)
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+
+	var response userResponse
+	err = tc.getResponse(req, &response)
+	return response, err
+}
+
+func (tc *testClient) listUserAds(userId int64) (adsResponse, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf(tc.baseURL+"/api/v1/users/%d/ads", userId), nil)
+	if err != nil {
+		return adsResponse{}, fmt.Errorf("unable to create request: %w", err)
+	}
+
+	var response adsResponse
+	err = tc.getResponse(req, &response)
+	return response, err
+}
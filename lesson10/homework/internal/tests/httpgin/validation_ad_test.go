@@ -2,13 +2,13 @@ package httpgin
 
 import (
 	"strings"
-	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
-func TestCreateAd_EmptyTitle(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestCreateAd_EmptyTitle() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser0 := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser0)
@@ -19,8 +19,9 @@ func TestCreateAd_EmptyTitle(t *testing.T) {
 	assert.ErrorIs(t, err, ErrBadRequest)
 }
 
-func TestCreateAd_TooLongTitle(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestCreateAd_TooLongTitle() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser0 := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser0)
@@ -33,8 +34,9 @@ func TestCreateAd_TooLongTitle(t *testing.T) {
 	assert.ErrorIs(t, err, ErrBadRequest)
 }
 
-func TestCreateAd_EmptyText(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestCreateAd_EmptyText() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser0 := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser0)
@@ -45,8 +47,9 @@ func TestCreateAd_EmptyText(t *testing.T) {
 	assert.ErrorIs(t, err, ErrBadRequest)
 }
 
-func TestCreateAd_TooLongText(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestCreateAd_TooLongText() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser0 := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser0)
@@ -59,8 +62,9 @@ func TestCreateAd_TooLongText(t *testing.T) {
 	assert.ErrorIs(t, err, ErrBadRequest)
 }
 
-func TestUpdateAd_EmptyTitle(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestUpdateAd_EmptyTitle() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser0 := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser0)
@@ -74,8 +78,9 @@ func TestUpdateAd_EmptyTitle(t *testing.T) {
 	assert.ErrorIs(t, err, ErrBadRequest)
 }
 
-func TestUpdateAd_TooLongTitle(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestUpdateAd_TooLongTitle() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser0 := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser0)
@@ -91,8 +96,9 @@ func TestUpdateAd_TooLongTitle(t *testing.T) {
 	assert.ErrorIs(t, err, ErrBadRequest)
 }
 
-func TestUpdateAd_EmptyText(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestUpdateAd_EmptyText() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser0 := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser0)
@@ -106,8 +112,9 @@ func TestUpdateAd_EmptyText(t *testing.T) {
 	assert.ErrorIs(t, err, ErrBadRequest)
 }
 
-func TestUpdateAd_TooLongText(t *testing.T) {
-	client := getTestClient()
+func (s *RESTSuite) TestUpdateAd_TooLongText() {
+	t := s.T()
+	client := s.client()
 
 	_, errUser0 := client.createUser("og buda", "buda@phystech.edu")
 	assert.NoError(t, errUser0)
@@ -0,0 +1,38 @@
+package httpgin
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"homework10/internal/ports/problem"
+)
+
+func (s *RESTSuite) TestValidationProblem() {
+	t := s.T()
+	client := s.client()
+
+	data, err := json.Marshal(map[string]any{"nickname": "", "email": "buda@phystech.edu"})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, client.baseURL+"/api/v1/users", bytes.NewReader(data))
+	require.NoError(t, err)
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := client.client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, problem.ContentType, resp.Header.Get("Content-Type"))
+
+	var p problem.Problem
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+	assert.Equal(t, http.StatusBadRequest, p.Status)
+	require.Len(t, p.InvalidParams, 1)
+	assert.Equal(t, "nickname", p.InvalidParams[0].Name)
+	assert.NotEmpty(t, p.InvalidParams[0].Reason)
+}
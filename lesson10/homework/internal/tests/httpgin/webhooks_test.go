@@ -0,0 +1,128 @@
+package httpgin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/app"
+	"homework10/internal/mailer"
+	"homework10/internal/outbox"
+	"homework10/internal/ports/httpgin"
+)
+
+type webhookResponse struct {
+	Data struct {
+		ID  int64  `json:"id"`
+		URL string `json:"url"`
+	} `json:"data"`
+}
+
+// createWebhook подписывает url на события через служебный API с токеном token
+func (tc *testClient) createWebhook(url string, token string) (webhookResponse, error) {
+	data, err := json.Marshal(map[string]any{"url": url, "secret": "top secret"})
+	if err != nil {
+		return webhookResponse{}, fmt.Errorf("unable to marshal: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, tc.baseURL+"/api/v1/admin/webhooks", bytes.NewReader(data))
+	if err != nil {
+		return webhookResponse{}, fmt.Errorf("unable to create request: %w", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Add("Authorization", "Bearer "+token)
+	}
+
+	var response webhookResponse
+	err = tc.getResponse(req, &response)
+	return response, err
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	var mu sync.Mutex
+	var eventTypes []string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, outbox.Sign("top secret", body), r.Header.Get(outbox.SignatureHeader))
+
+		mu.Lock()
+		eventTypes = append(eventTypes, r.Header.Get(outbox.EventTypeHeader))
+		mu.Unlock()
+	}))
+	defer receiver.Close()
+
+	repo := adrepo.New()
+	mail := mailer.NewCapture()
+	// получатель слушает на loopback, поэтому частные адреса разрешены явно
+	server := httpgin.NewHTTPServer(":18080", app.NewApp(repo, app.WithMailer(mail), app.WithPrivateWebhookTargets(true)),
+		httpgin.WithAdminToken(adminToken))
+	testServer := httptest.NewServer(server.Handler)
+	defer testServer.Close()
+	client := &testClient{client: testServer.Client(), baseURL: testServer.URL, mail: mail}
+
+	webhook, err := client.createWebhook(receiver.URL, adminToken)
+	assert.NoError(t, err)
+	assert.Equal(t, receiver.URL, webhook.Data.URL)
+
+	user, err := client.createUser("og buda", "buda@phystech.edu")
+	assert.NoError(t, err)
+	ad, err := client.createAd(user.Data.ID, "hello", "world")
+	assert.NoError(t, err)
+	_, err = client.changeAdStatus(user.Data.ID, ad.Data.ID, true)
+	assert.NoError(t, err)
+	_, err = client.deleteAdById(ad.Data.ID, user.Data.ID)
+	assert.NoError(t, err)
+
+	outbox.NewDispatcher(repo, outbox.Config{AllowPrivateTargets: true}).Flush(context.Background())
+
+	assert.Equal(t, []string{string(outbox.AdCreated), string(outbox.AdStatusChanged), string(outbox.AdDeleted)}, eventTypes)
+	assert.Empty(t, repo.GetOutboxEvents(context.Background(), 10))
+	assert.Empty(t, repo.GetDeadLetters(context.Background()))
+}
+
+func TestWebhookAdminAuth(t *testing.T) {
+	server := httpgin.NewHTTPServer(":18080", app.NewApp(adrepo.New()), httpgin.WithAdminToken(adminToken))
+	testServer := httptest.NewServer(server.Handler)
+	defer testServer.Close()
+	client := &testClient{client: testServer.Client(), baseURL: testServer.URL}
+
+	_, err := client.createWebhook("https://example.com/hook", "")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	_, err = client.createWebhook("https://example.com/hook", "wrong")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	req, err := http.NewRequest(http.MethodGet, client.baseURL+"/api/v1/admin/webhooks", nil)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, client.getResponse(req, &webhookResponse{}), ErrUnauthorized)
+
+	// адреса внутренней сети в подписку не попадают
+	for _, url := range []string{"http://127.0.0.1:8080/hook", "http://localhost/hook", "http://169.254.169.254/latest/meta-data", "http://[::1]/hook"} {
+		_, err = client.createWebhook(url, adminToken)
+		assert.ErrorIs(t, err, ErrBadRequest, url)
+	}
+
+	webhook, err := client.createWebhook("https://example.com/hook", adminToken)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/hook", webhook.Data.URL)
+}
+
+func TestWebhookAdminDisabledWithoutToken(t *testing.T) {
+	testServer := httptest.NewServer(httpgin.NewHTTPServer(":18080", app.NewApp(adrepo.New())).Handler)
+	defer testServer.Close()
+	client := &testClient{client: testServer.Client(), baseURL: testServer.URL}
+
+	_, err := client.createWebhook("https://example.com/hook", "")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	_, err = client.createWebhook("https://example.com/hook", adminToken)
+	assert.ErrorIs(t, err, ErrUnauthorized, "без настроенного токена служебный API закрыт")
+}
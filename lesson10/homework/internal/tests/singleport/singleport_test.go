@@ -0,0 +1,163 @@
+package singleport
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/app"
+	"homework10/internal/health"
+	grpcPort "homework10/internal/ports/grpc"
+	"homework10/internal/ports/grpc/proto"
+	"homework10/internal/ports/httpgin"
+	"homework10/internal/ports/multiplex"
+	"homework10/internal/tlsutil/tlstest"
+)
+
+type userResponse struct {
+	Data struct {
+		ID       int64  `json:"id"`
+		Nickname string `json:"nickname"`
+	} `json:"data"`
+}
+
+// startServer поднимает gin и AdService поверх одного приложения на одном слушателе
+func startServer(t *testing.T, checker *health.Checker, tlsConfig *tls.Config) (*multiplex.Server, string) {
+	adApp := app.NewApp(adrepo.New())
+	httpServer := httpgin.NewHTTPServer("127.0.0.1:0", adApp, httpgin.WithHealthChecker(checker), httpgin.WithTLSConfig(tlsConfig))
+	server, err := multiplex.New(grpcPort.NewServer(adApp, grpcPort.WithHealthChecker(checker)), httpServer)
+	require.NoError(t, err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		if err := server.Serve(lis); !errors.Is(err, http.ErrServerClosed) {
+			assert.NoError(t, err)
+		}
+	}()
+	return server, lis.Addr().String()
+}
+
+func dial(t *testing.T, addr string, creds credentials.TransportCredentials) *grpc.ClientConn {
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(creds))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestSinglePort_BothProtocols(t *testing.T) {
+	server, addr := startServer(t, health.New(health.DefaultTimeout), nil)
+	defer func() { _ = server.Shutdown(context.Background()) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client := proto.NewAdServiceClient(dial(t, addr, insecure.NewCredentials()))
+	user, err := client.CreateUser(ctx, &proto.CreateUserRequest{Nickname: "Oleg", Email: "oleg@phystech.edu"})
+	require.NoError(t, err)
+
+	// пользователь, созданный по gRPC, виден через REST на том же порту
+	resp, err := http.Get("http://" + addr + "/api/v1/users/" + strconv.FormatInt(user.GetId(), 10))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var out userResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	assert.Equal(t, user.GetId(), out.Data.ID)
+	assert.Equal(t, "Oleg", out.Data.Nickname)
+
+	body, err := json.Marshal(map[string]any{"user_id": user.GetId(), "title": "hello", "text": "world"})
+	require.NoError(t, err)
+	resp, err = http.Post("http://"+addr+"/api/v1/ads", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	ad, err := client.GetAd(ctx, &proto.GetAdRequest{AdId: 0})
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", ad.GetTitle())
+}
+
+func TestSinglePort_TLS(t *testing.T) {
+	ca, err := tlstest.NewCA()
+	require.NoError(t, err)
+	cert, err := ca.Issue("adservice")
+	require.NoError(t, err)
+	certificate, err := cert.TLSCertificate()
+	require.NoError(t, err)
+
+	server, addr := startServer(t, health.New(health.DefaultTimeout), &tls.Config{Certificates: []tls.Certificate{certificate}})
+	defer func() { _ = server.Shutdown(context.Background()) }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn := dial(t, addr, credentials.NewTLS(&tls.Config{RootCAs: ca.Pool(), ServerName: "localhost"}))
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: ca.Pool()}}}
+	httpResp, err := httpClient.Get("https://" + addr + "/healthz")
+	require.NoError(t, err)
+	_ = httpResp.Body.Close()
+	assert.Equal(t, http.StatusOK, httpResp.StatusCode)
+}
+
+func TestSinglePort_ShutdownDrainsGRPC(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	checker := health.New(5 * time.Second)
+	checker.Register("slow", func(ctx context.Context) error {
+		close(entered)
+		<-release
+		return nil
+	})
+	server, addr := startServer(t, checker, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client := healthpb.NewHealthClient(dial(t, addr, insecure.NewCredentials()))
+
+	type result struct {
+		resp *healthpb.HealthCheckResponse
+		err  error
+	}
+	results := make(chan result, 1)
+	go func() {
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+		results <- result{resp, err}
+	}()
+	<-entered
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- server.Shutdown(ctx)
+	}()
+
+	select {
+	case <-stopped:
+		t.Fatal("shutdown did not wait for the grpc call")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	assert.NoError(t, <-stopped)
+	res := <-results
+	require.NoError(t, res.err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, res.resp.Status)
+}
@@ -0,0 +1,104 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"homework10/internal/tlsutil/tlstest"
+)
+
+type TLSUtilTestSuite struct {
+	suite.Suite
+	ca  *tlstest.CA
+	dir string
+}
+
+func (s *TLSUtilTestSuite) SetupTest() {
+	ca, err := tlstest.NewCA()
+	s.Require().NoError(err)
+	s.ca = ca
+	s.dir = s.T().TempDir()
+}
+
+func TestTLSUtil(t *testing.T) {
+	suite.Run(t, new(TLSUtilTestSuite))
+}
+
+func (s *TLSUtilTestSuite) issue(name string) (string, string) {
+	cert, err := s.ca.Issue(name)
+	s.Require().NoError(err)
+	certFile, keyFile, err := cert.Write(s.dir, "server")
+	s.Require().NoError(err)
+	return certFile, keyFile
+}
+
+func commonName(cert *tls.Certificate) string {
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return ""
+	}
+	return parsed.Subject.CommonName
+}
+
+func (s *TLSUtilTestSuite) TestReloader_PicksUpNewCertificate() {
+	certFile, keyFile := s.issue("first")
+	reloader, err := NewReloader(certFile, keyFile)
+	s.Require().NoError(err)
+
+	cert, err := reloader.GetCertificate(nil)
+	s.NoError(err)
+	s.Equal("first", commonName(cert))
+
+	s.issue("second")
+	later := time.Now().Add(time.Minute)
+	s.NoError(os.Chtimes(certFile, later, later))
+	s.NoError(os.Chtimes(keyFile, later, later))
+
+	cert, err = reloader.GetCertificate(nil)
+	s.NoError(err)
+	s.Equal("second", commonName(cert))
+}
+
+func (s *TLSUtilTestSuite) TestReloader_KeepsCertificateOnBrokenFiles() {
+	certFile, keyFile := s.issue("first")
+	reloader, err := NewReloader(certFile, keyFile)
+	s.Require().NoError(err)
+
+	s.NoError(os.WriteFile(keyFile, []byte("half written"), 0o600))
+	later := time.Now().Add(time.Minute)
+	s.NoError(os.Chtimes(keyFile, later, later))
+
+	cert, err := reloader.GetCertificate(nil)
+	s.NoError(err)
+	s.Equal("first", commonName(cert))
+}
+
+func (s *TLSUtilTestSuite) TestServerConfig() {
+	cfg, err := ServerConfig("", "", "")
+	s.NoError(err)
+	s.Nil(cfg)
+
+	certFile, keyFile := s.issue("server")
+	cfg, err = ServerConfig(certFile, keyFile, "")
+	s.NoError(err)
+	s.Equal(tls.NoClientCert, cfg.ClientAuth)
+
+	caFile, err := s.ca.WriteCA(s.dir)
+	s.Require().NoError(err)
+	cfg, err = ServerConfig(certFile, keyFile, caFile)
+	s.NoError(err)
+	s.Equal(tls.RequireAndVerifyClientCert, cfg.ClientAuth)
+	s.NotNil(cfg.ClientCAs)
+
+	_, err = ServerConfig(certFile, keyFile, certFile+".missing")
+	s.Error(err)
+	_, err = ServerConfig(certFile, certFile, "")
+	s.Error(err)
+	_, err = ServerConfig(certFile, keyFile, keyFile)
+	s.Error(err)
+}
@@ -0,0 +1,234 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"homework10/internal/ads"
+	"homework10/internal/app"
+	"homework10/internal/outbox"
+	"homework10/internal/users"
+)
+
+type tracedApp struct {
+	next   app.App
+	tracer trace.Tracer
+}
+
+// NewApp оборачивает app.App так, что каждый вызов пишется отдельным спаном "App.<метод>"
+func NewApp(a app.App, tp trace.TracerProvider) app.App {
+	return &tracedApp{next: a, tracer: tp.Tracer(InstrumentationName)}
+}
+
+func (t *tracedApp) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx, span := start(ctx, t.tracer, "App."+method)
+	span.SetAttributes(attrs...)
+	return ctx, span
+}
+
+func (t *tracedApp) CreateAd(ctx context.Context, title string, text string, userId int64) (*ads.Ad, error) {
+	ctx, span := t.start(ctx, "CreateAd", attribute.Int64("user.id", userId))
+	ad, err := t.next.CreateAd(ctx, title, text, userId)
+	end(span, err)
+	return ad, err
+}
+
+func (t *tracedApp) ChangeAdStatus(ctx context.Context, adId int64, userId int64, published bool) (*ads.Ad, error) {
+	ctx, span := t.start(ctx, "ChangeAdStatus", attribute.Int64("ad.id", adId), attribute.Int64("user.id", userId))
+	ad, err := t.next.ChangeAdStatus(ctx, adId, userId, published)
+	end(span, err)
+	return ad, err
+}
+
+func (t *tracedApp) UpdateAd(ctx context.Context, adId int64, userId int64, title string, text string) (*ads.Ad, error) {
+	ctx, span := t.start(ctx, "UpdateAd", attribute.Int64("ad.id", adId), attribute.Int64("user.id", userId))
+	ad, err := t.next.UpdateAd(ctx, adId, userId, title, text)
+	end(span, err)
+	return ad, err
+}
+
+func (t *tracedApp) DeleteAd(ctx context.Context, adId int64, userId int64) error {
+	ctx, span := t.start(ctx, "DeleteAd", attribute.Int64("ad.id", adId), attribute.Int64("user.id", userId))
+	err := t.next.DeleteAd(ctx, adId, userId)
+	end(span, err)
+	return err
+}
+
+func (t *tracedApp) GetAd(ctx context.Context, id int64) (*ads.Ad, error) {
+	ctx, span := t.start(ctx, "GetAd", attribute.Int64("ad.id", id))
+	ad, err := t.next.GetAd(ctx, id)
+	end(span, err)
+	return ad, err
+}
+
+func (t *tracedApp) GetListAds(ctx context.Context, filters map[string]any) []ads.Ad {
+	ctx, span := t.start(ctx, "GetListAds")
+	list := t.next.GetListAds(ctx, filters)
+	span.SetAttributes(attribute.Int("ads.count", len(list)))
+	end(span, nil)
+	return list
+}
+
+func (t *tracedApp) GetListAdsByTitle(ctx context.Context, pattern string) []ads.Ad {
+	ctx, span := t.start(ctx, "GetListAdsByTitle")
+	list := t.next.GetListAdsByTitle(ctx, pattern)
+	span.SetAttributes(attribute.Int("ads.count", len(list)))
+	end(span, nil)
+	return list
+}
+
+func (t *tracedApp) BatchGetAds(ctx context.Context, ids []int64) ([]ads.Ad, []int64, error) {
+	ctx, span := t.start(ctx, "BatchGetAds", attribute.Int("ads.requested", len(ids)))
+	found, missing, err := t.next.BatchGetAds(ctx, ids)
+	span.SetAttributes(attribute.Int("ads.count", len(found)), attribute.Int("ads.missing", len(missing)))
+	end(span, err)
+	return found, missing, err
+}
+
+func (t *tracedApp) BatchChangeAdStatus(ctx context.Context, adIds []int64, userId int64, published bool) ([]ads.Ad, error) {
+	ctx, span := t.start(ctx, "BatchChangeAdStatus", attribute.Int("ads.requested", len(adIds)), attribute.Int64("user.id", userId))
+	list, err := t.next.BatchChangeAdStatus(ctx, adIds, userId, published)
+	end(span, err)
+	return list, err
+}
+
+func (t *tracedApp) ImportAds(ctx context.Context, next func() (app.ImportRow, error)) (app.ImportReport, error) {
+	ctx, span := t.start(ctx, "ImportAds")
+	report, err := t.next.ImportAds(ctx, next)
+	span.SetAttributes(attribute.Int("ads.imported", report.Imported), attribute.Int("ads.failed", report.Failed))
+	end(span, err)
+	return report, err
+}
+
+func (t *tracedApp) ExportAds(ctx context.Context, filters map[string]any, fn func(ad ads.Ad) error) error {
+	ctx, span := t.start(ctx, "ExportAds")
+	err := t.next.ExportAds(ctx, filters, fn)
+	end(span, err)
+	return err
+}
+
+func (t *tracedApp) CreateUser(ctx context.Context, nickname string, email string) (*users.User, error) {
+	ctx, span := t.start(ctx, "CreateUser")
+	user, err := t.next.CreateUser(ctx, nickname, email)
+	end(span, err)
+	return user, err
+}
+
+func (t *tracedApp) UpdateUser(ctx context.Context, userId int64, nickname string, email string, password string) (*users.User, error) {
+	ctx, span := t.start(ctx, "UpdateUser", attribute.Int64("user.id", userId))
+	user, err := t.next.UpdateUser(ctx, userId, nickname, email, password)
+	end(span, err)
+	return user, err
+}
+
+func (t *tracedApp) DeleteUser(ctx context.Context, userId int64) error {
+	ctx, span := t.start(ctx, "DeleteUser", attribute.Int64("user.id", userId))
+	err := t.next.DeleteUser(ctx, userId)
+	end(span, err)
+	return err
+}
+
+func (t *tracedApp) GetUser(ctx context.Context, userId int64) (*users.User, error) {
+	ctx, span := t.start(ctx, "GetUser", attribute.Int64("user.id", userId))
+	user, err := t.next.GetUser(ctx, userId)
+	end(span, err)
+	return user, err
+}
+
+func (t *tracedApp) GetUserByEmail(ctx context.Context, email string) (*users.User, error) {
+	ctx, span := t.start(ctx, "GetUserByEmail")
+	user, err := t.next.GetUserByEmail(ctx, email)
+	end(span, err)
+	return user, err
+}
+
+// UpdateProfile не пишет в спан пароль и поля профиля: там телефон
+func (t *tracedApp) UpdateProfile(ctx context.Context, userId int64, password string, profile users.Profile) (*users.User, error) {
+	ctx, span := t.start(ctx, "UpdateProfile", attribute.Int64("user.id", userId))
+	user, err := t.next.UpdateProfile(ctx, userId, password, profile)
+	end(span, err)
+	return user, err
+}
+
+func (t *tracedApp) GetUserAds(ctx context.Context, userId int64) ([]ads.Ad, error) {
+	ctx, span := t.start(ctx, "GetUserAds", attribute.Int64("user.id", userId))
+	list, err := t.next.GetUserAds(ctx, userId)
+	span.SetAttributes(attribute.Int("ads.count", len(list)))
+	end(span, err)
+	return list, err
+}
+
+func (t *tracedApp) BatchGetUsers(ctx context.Context, ids []int64) ([]users.User, []int64, error) {
+	ctx, span := t.start(ctx, "BatchGetUsers", attribute.Int("users.requested", len(ids)))
+	found, missing, err := t.next.BatchGetUsers(ctx, ids)
+	span.SetAttributes(attribute.Int("users.count", len(found)), attribute.Int("users.missing", len(missing)))
+	end(span, err)
+	return found, missing, err
+}
+
+func (t *tracedApp) RequestEmailVerification(ctx context.Context, userId int64) error {
+	ctx, span := t.start(ctx, "RequestEmailVerification", attribute.Int64("user.id", userId))
+	err := t.next.RequestEmailVerification(ctx, userId)
+	end(span, err)
+	return err
+}
+
+// токены, пароли и email в спаны не попадают
+
+func (t *tracedApp) ConfirmEmail(ctx context.Context, token string) (*users.User, error) {
+	ctx, span := t.start(ctx, "ConfirmEmail")
+	user, err := t.next.ConfirmEmail(ctx, token)
+	end(span, err)
+	return user, err
+}
+
+func (t *tracedApp) RequestPasswordReset(ctx context.Context, email string) error {
+	ctx, span := t.start(ctx, "RequestPasswordReset")
+	err := t.next.RequestPasswordReset(ctx, email)
+	end(span, err)
+	return err
+}
+
+func (t *tracedApp) ResetPassword(ctx context.Context, token string, password string) error {
+	ctx, span := t.start(ctx, "ResetPassword")
+	err := t.next.ResetPassword(ctx, token, password)
+	end(span, err)
+	return err
+}
+
+func (t *tracedApp) CreateWebhook(ctx context.Context, url string, secret string) (*outbox.Webhook, error) {
+	ctx, span := t.start(ctx, "CreateWebhook")
+	webhook, err := t.next.CreateWebhook(ctx, url, secret)
+	end(span, err)
+	return webhook, err
+}
+
+func (t *tracedApp) DeleteWebhook(ctx context.Context, webhookId int64) error {
+	ctx, span := t.start(ctx, "DeleteWebhook", attribute.Int64("webhook.id", webhookId))
+	err := t.next.DeleteWebhook(ctx, webhookId)
+	end(span, err)
+	return err
+}
+
+func (t *tracedApp) GetWebhooks(ctx context.Context) []outbox.Webhook {
+	ctx, span := t.start(ctx, "GetWebhooks")
+	webhooks := t.next.GetWebhooks(ctx)
+	end(span, nil)
+	return webhooks
+}
+
+func (t *tracedApp) GetDeadLetters(ctx context.Context) []outbox.DeadLetter {
+	ctx, span := t.start(ctx, "GetDeadLetters")
+	letters := t.next.GetDeadLetters(ctx)
+	end(span, nil)
+	return letters
+}
+
+func (t *tracedApp) GetStats(ctx context.Context) app.Stats {
+	ctx, span := t.start(ctx, "GetStats")
+	stats := t.next.GetStats(ctx)
+	end(span, nil)
+	return stats
+}
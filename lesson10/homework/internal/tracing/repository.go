@@ -0,0 +1,240 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"homework10/internal/ads"
+	"homework10/internal/app"
+	"homework10/internal/outbox"
+	"homework10/internal/users"
+)
+
+type tracedRepository struct {
+	next   app.Repository
+	tracer trace.Tracer
+}
+
+// NewRepository оборачивает app.Repository так, что каждый вызов пишется спаном "Repository.<метод>"
+func NewRepository(r app.Repository, tp trace.TracerProvider) app.Repository {
+	return &tracedRepository{next: r, tracer: tp.Tracer(InstrumentationName)}
+}
+
+func (t *tracedRepository) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx, span := start(ctx, t.tracer, "Repository."+method)
+	span.SetAttributes(attrs...)
+	return ctx, span
+}
+
+func (t *tracedRepository) GetAdById(ctx context.Context, id int64) (ads.Ad, error) {
+	ctx, span := t.start(ctx, "GetAdById", attribute.Int64("ad.id", id))
+	ad, err := t.next.GetAdById(ctx, id)
+	end(span, err)
+	return ad, err
+}
+
+func (t *tracedRepository) AddAd(ctx context.Context, ad *ads.Ad) {
+	ctx, span := t.start(ctx, "AddAd", attribute.Int64("ad.id", ad.ID))
+	t.next.AddAd(ctx, ad)
+	end(span, nil)
+}
+
+func (t *tracedRepository) GetAdsPrimaryKey(ctx context.Context) int64 {
+	ctx, span := t.start(ctx, "GetAdsPrimaryKey")
+	id := t.next.GetAdsPrimaryKey(ctx)
+	end(span, nil)
+	return id
+}
+
+func (t *tracedRepository) ChangeAd(ctx context.Context, ad *ads.Ad) bool {
+	ctx, span := t.start(ctx, "ChangeAd", attribute.Int64("ad.id", ad.ID))
+	ok := t.next.ChangeAd(ctx, ad)
+	end(span, nil)
+	return ok
+}
+
+func (t *tracedRepository) ChangeAds(ctx context.Context, ids []int64, change func(ad *ads.Ad) error) ([]ads.Ad, error) {
+	ctx, span := t.start(ctx, "ChangeAds", attribute.Int("ads.requested", len(ids)))
+	list, err := t.next.ChangeAds(ctx, ids, change)
+	end(span, err)
+	return list, err
+}
+
+func (t *tracedRepository) GetAds(ctx context.Context, filters map[string]any) []ads.Ad {
+	ctx, span := t.start(ctx, "GetAds")
+	list := t.next.GetAds(ctx, filters)
+	span.SetAttributes(attribute.Int("ads.count", len(list)))
+	end(span, nil)
+	return list
+}
+
+func (t *tracedRepository) GetAdsByTitle(ctx context.Context, pattern string) []ads.Ad {
+	ctx, span := t.start(ctx, "GetAdsByTitle")
+	list := t.next.GetAdsByTitle(ctx, pattern)
+	span.SetAttributes(attribute.Int("ads.count", len(list)))
+	end(span, nil)
+	return list
+}
+
+func (t *tracedRepository) GetAdsPage(ctx context.Context, filters map[string]any, afterID int64, limit int) []ads.Ad {
+	ctx, span := t.start(ctx, "GetAdsPage", attribute.Int64("ads.after_id", afterID))
+	page := t.next.GetAdsPage(ctx, filters, afterID, limit)
+	span.SetAttributes(attribute.Int("ads.count", len(page)))
+	end(span, nil)
+	return page
+}
+
+func (t *tracedRepository) GetUserById(ctx context.Context, id int64) (users.User, error) {
+	ctx, span := t.start(ctx, "GetUserById", attribute.Int64("user.id", id))
+	user, err := t.next.GetUserById(ctx, id)
+	end(span, err)
+	return user, err
+}
+
+func (t *tracedRepository) GetUserByEmail(ctx context.Context, email string) (users.User, error) {
+	ctx, span := t.start(ctx, "GetUserByEmail")
+	user, err := t.next.GetUserByEmail(ctx, email)
+	end(span, err)
+	return user, err
+}
+
+func (t *tracedRepository) AddUser(ctx context.Context, user *users.User) error {
+	ctx, span := t.start(ctx, "AddUser", attribute.Int64("user.id", user.ID))
+	err := t.next.AddUser(ctx, user)
+	end(span, err)
+	return err
+}
+
+func (t *tracedRepository) ChangeUser(ctx context.Context, user *users.User) (bool, error) {
+	ctx, span := t.start(ctx, "ChangeUser", attribute.Int64("user.id", user.ID))
+	ok, err := t.next.ChangeUser(ctx, user)
+	end(span, err)
+	return ok, err
+}
+
+func (t *tracedRepository) TouchUser(ctx context.Context, id int64, at time.Time) {
+	ctx, span := t.start(ctx, "TouchUser", attribute.Int64("user.id", id))
+	t.next.TouchUser(ctx, id, at)
+	end(span, nil)
+}
+
+func (t *tracedRepository) GetUsersPrimaryKey(ctx context.Context) int64 {
+	ctx, span := t.start(ctx, "GetUsersPrimaryKey")
+	id := t.next.GetUsersPrimaryKey(ctx)
+	end(span, nil)
+	return id
+}
+
+func (t *tracedRepository) DeleteAd(ctx context.Context, adId int64) {
+	ctx, span := t.start(ctx, "DeleteAd", attribute.Int64("ad.id", adId))
+	t.next.DeleteAd(ctx, adId)
+	end(span, nil)
+}
+
+func (t *tracedRepository) DeleteUser(ctx context.Context, userId int64) {
+	ctx, span := t.start(ctx, "DeleteUser", attribute.Int64("user.id", userId))
+	t.next.DeleteUser(ctx, userId)
+	end(span, nil)
+}
+
+func (t *tracedRepository) AddToken(ctx context.Context, token users.Token) {
+	ctx, span := t.start(ctx, "AddToken", attribute.Int64("user.id", token.UserID))
+	t.next.AddToken(ctx, token)
+	end(span, nil)
+}
+
+// TakeToken не пишет в спан ни хэш, ни пользователя: по ним можно подбирать токены
+func (t *tracedRepository) TakeToken(ctx context.Context, hash string) (users.Token, bool) {
+	ctx, span := t.start(ctx, "TakeToken")
+	token, ok := t.next.TakeToken(ctx, hash)
+	end(span, nil)
+	return token, ok
+}
+
+func (t *tracedRepository) GetOutboxEvents(ctx context.Context, limit int) []outbox.Event {
+	ctx, span := t.start(ctx, "GetOutboxEvents")
+	events := t.next.GetOutboxEvents(ctx, limit)
+	span.SetAttributes(attribute.Int("events.count", len(events)))
+	end(span, nil)
+	return events
+}
+
+func (t *tracedRepository) DeleteOutboxEvent(ctx context.Context, id int64) {
+	ctx, span := t.start(ctx, "DeleteOutboxEvent", attribute.Int64("event.id", id))
+	t.next.DeleteOutboxEvent(ctx, id)
+	end(span, nil)
+}
+
+func (t *tracedRepository) AddWebhook(ctx context.Context, webhook *outbox.Webhook) {
+	ctx, span := t.start(ctx, "AddWebhook", attribute.Int64("webhook.id", webhook.ID))
+	t.next.AddWebhook(ctx, webhook)
+	end(span, nil)
+}
+
+func (t *tracedRepository) GetWebhooksPrimaryKey(ctx context.Context) int64 {
+	ctx, span := t.start(ctx, "GetWebhooksPrimaryKey")
+	id := t.next.GetWebhooksPrimaryKey(ctx)
+	end(span, nil)
+	return id
+}
+
+func (t *tracedRepository) GetWebhooks(ctx context.Context) []outbox.Webhook {
+	ctx, span := t.start(ctx, "GetWebhooks")
+	webhooks := t.next.GetWebhooks(ctx)
+	end(span, nil)
+	return webhooks
+}
+
+func (t *tracedRepository) DeleteWebhook(ctx context.Context, id int64) bool {
+	ctx, span := t.start(ctx, "DeleteWebhook", attribute.Int64("webhook.id", id))
+	ok := t.next.DeleteWebhook(ctx, id)
+	end(span, nil)
+	return ok
+}
+
+func (t *tracedRepository) AddDeadLetter(ctx context.Context, letter *outbox.DeadLetter) {
+	ctx, span := t.start(ctx, "AddDeadLetter", attribute.Int64("event.id", letter.Event.ID), attribute.Int64("webhook.id", letter.WebhookID))
+	t.next.AddDeadLetter(ctx, letter)
+	end(span, nil)
+}
+
+func (t *tracedRepository) GetDeadLetters(ctx context.Context) []outbox.DeadLetter {
+	ctx, span := t.start(ctx, "GetDeadLetters")
+	letters := t.next.GetDeadLetters(ctx)
+	end(span, nil)
+	return letters
+}
+
+func (t *tracedRepository) CountAds(ctx context.Context) (int, int) {
+	ctx, span := t.start(ctx, "CountAds")
+	total, published := t.next.CountAds(ctx)
+	end(span, nil)
+	return total, published
+}
+
+func (t *tracedRepository) CountUsers(ctx context.Context) int {
+	ctx, span := t.start(ctx, "CountUsers")
+	count := t.next.CountUsers(ctx)
+	end(span, nil)
+	return count
+}
+
+func (t *tracedRepository) Ping(ctx context.Context) error {
+	ctx, span := t.start(ctx, "Ping")
+	err := t.next.Ping(ctx)
+	end(span, err)
+	return err
+}
+
+// WithTx пишет спан на всю транзакцию; вызовы через tx тоже трассируются, со своим контекстом
+func (t *tracedRepository) WithTx(ctx context.Context, fn func(tx app.Repository) error) error {
+	ctx, span := t.start(ctx, "WithTx")
+	err := t.next.WithTx(ctx, func(tx app.Repository) error {
+		return fn(&tracedRepository{next: tx, tracer: t.tracer})
+	})
+	end(span, err)
+	return err
+}
@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ServiceName = "adservice"
+	// InstrumentationName - имя трейсера, которым пишутся все спаны сервиса
+	InstrumentationName = "homework10"
+
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+)
+
+// Propagator разбирает и пишет заголовки traceparent/tracestate (W3C Trace Context)
+var Propagator propagation.TextMapPropagator = propagation.TraceContext{}
+
+// NewExporter возвращает экспортёр по имени. Для ExporterNone возвращается nil:
+// спаны всё равно создаются (и пробрасываются дальше), но никуда не отправляются
+func NewExporter(name string, w io.Writer) (sdktrace.SpanExporter, error) {
+	switch name {
+	case ExporterNone, "":
+		return nil, nil
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(w))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", name)
+	}
+}
+
+// NewProvider создаёт провайдер, который пачками отправляет спаны в exporter.
+// В тестах удобнее передавать tracetest.NewInMemoryExporter()
+func NewProvider(exporter sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(ServiceName))),
+	}
+	if exporter != nil {
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	}
+	return sdktrace.NewTracerProvider(opts...)
+}
+
+func start(ctx context.Context, tracer trace.Tracer, name string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindInternal))
+}
+
+// end закрывает спан, помечая его ошибочным, если err != nil
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
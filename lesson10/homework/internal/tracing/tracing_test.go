@@ -0,0 +1,54 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"homework10/internal/adapters/adrepo"
+	"homework10/internal/app"
+)
+
+func TestDecorators(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	a := NewApp(app.NewApp(NewRepository(adrepo.New(), tp)), tp)
+
+	_, err := a.CreateAd(context.Background(), "hello", "world", 1)
+	assert.ErrorIs(t, err, app.IncorrectUserId)
+
+	// CreateAd идёт в транзакции: WithTx и вызовы через tx - дочерние спаны CreateAd
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 3)
+	assert.Equal(t, "Repository.GetUserById", spans[0].Name)
+	assert.Equal(t, "Repository.WithTx", spans[1].Name)
+	assert.Equal(t, "App.CreateAd", spans[2].Name)
+	assert.Equal(t, spans[2].SpanContext.SpanID(), spans[0].Parent.SpanID())
+	assert.Equal(t, spans[2].SpanContext.SpanID(), spans[1].Parent.SpanID())
+	for _, span := range spans {
+		assert.Equal(t, codes.Error, span.Status.Code, span.Name)
+	}
+}
+
+func TestNewExporter(t *testing.T) {
+	exporter, err := NewExporter(ExporterNone, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, exporter)
+
+	var buf bytes.Buffer
+	exporter, err = NewExporter(ExporterStdout, &buf)
+	assert.NoError(t, err)
+	tp := NewProvider(exporter)
+	_, span := tp.Tracer(InstrumentationName).Start(context.Background(), "test")
+	span.End()
+	assert.NoError(t, tp.Shutdown(context.Background()))
+	assert.Contains(t, buf.String(), `"Name":"test"`)
+
+	_, err = NewExporter("jaeger", nil)
+	assert.Error(t, err)
+}
@@ -1,7 +1,71 @@
 package users
 
+import (
+	"strings"
+	"time"
+)
+
 type User struct {
 	ID       int64
 	Nickname string `validate:"min:1;max:30"`
 	Email    string `validate:"min:1;max:30"`
+	// EmailVerified - пользователь подтвердил, что Email его; сбрасывается при смене Email
+	EmailVerified bool
+	// PasswordHash - bcrypt-хэш пароля; пустой, пока пароль не задан
+	PasswordHash string
+	Profile
+	CreatedAt  time.Time // дата регистрации
+	LastSeenAt time.Time // последнее действие пользователя, которое что-то изменило: в профиле или в его объявлениях
+}
+
+// Profile - необязательные поля профиля, которые пользователь меняет сам.
+// Phone хранится в международном формате: +79991234567
+type Profile struct {
+	DisplayName string `validate:"max:60"`
+	Phone       string
+	City        string `validate:"max:60"`
+	AvatarURL   string `validate:"max:300"`
+}
+
+// Purpose - для чего выдан токен
+type Purpose uint8
+
+const (
+	PurposeVerifyEmail Purpose = iota + 1
+	PurposeResetPassword
+)
+
+// Token - одноразовый токен из письма. Хранится только хэш: по утечке хранилища токен не восстановить.
+// Токен привязан к email, на который ушло письмо, и не действует после смены email
+type Token struct {
+	Hash      string
+	UserID    int64
+	Email     string
+	Purpose   Purpose
+	ExpiresAt time.Time
+}
+
+// NormalizeEmail приводит адрес к виду, в котором он хранится и сравнивается
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// NormalizeNickname убирает пробелы по краям ника; регистр ника сохраняется
+func NormalizeNickname(nickname string) string {
+	return strings.TrimSpace(nickname)
+}
+
+// NormalizePhone убирает из номера пробелы, дефисы и скобки: +7 (999) 123-45-67 -> +79991234567
+func NormalizePhone(phone string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(" -()", r) {
+			return -1
+		}
+		return r
+	}, phone)
+}
+
+// NicknameKey - ник для проверки уникальности: ники, отличающиеся только регистром, совпадают
+func NicknameKey(nickname string) string {
+	return strings.ToLower(NormalizeNickname(nickname))
 }